@@ -3,10 +3,17 @@ package main
 import (
 	"backendService/internals/common/logger"
 	"backendService/internals/setup/app"
+	"os"
 )
 
 // main is the entry point of the application.
+// Run with the migrate subcommand to manage the database schema instead of serving requests.
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		migrate(os.Args[2:])
+		return
+	}
+
 	logger.Info("main", "main", "Starting Application...")
 	app.Start()
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"backendService/internals/common/logger"
+	"backendService/internals/setup/config"
+	"backendService/internals/setup/database"
+	"backendService/internals/setup/migrations"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const migrateUsage = `usage: migrate <command> [arguments]
+
+commands:
+  up [n]         apply the pending migrations, or only the next n
+  down [n]       roll back the latest migration, or the latest n
+  status         list the migrations and whether they are applied
+  create <name>  write empty up and down SQL migrations for every dialect`
+
+// migrate runs a migrate subcommand and exits with a non-zero status when it fails.
+func migrate(args []string) {
+	if len(args) == 0 {
+		exitWithUsage()
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			exitWithUsage()
+		}
+		paths, err := migrations.Create(migrations.SourceDirectory, args[1])
+		if err != nil {
+			logger.Fatal("main", "migrate", "create", err)
+		}
+		for _, path := range paths {
+			fmt.Println("created", path)
+		}
+		return
+	}
+
+	steps := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			exitWithUsage()
+		}
+		steps = n
+	}
+
+	config.LoadConfig()
+	if err := database.InitializeDataBase(config.Config.Database.Type); err != nil {
+		logger.Fatal("main", "migrate", "connect", err)
+	}
+	defer database.CloseDB()
+
+	migrator, err := migrations.NewMigrator(database.Db)
+	if err != nil {
+		logger.Fatal("main", "migrate", "load", err)
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up(steps)
+		printMigrations("applied", done)
+		if err != nil {
+			logger.Fatal("main", "migrate", "up", err)
+		}
+		if len(done) == 0 {
+			fmt.Println("the database schema is up to date")
+		}
+	case "down":
+		done, err := migrator.Down(steps)
+		printMigrations("rolled back", done)
+		if err != nil {
+			logger.Fatal("main", "migrate", "down", err)
+		}
+		if len(done) == 0 {
+			fmt.Println("no migration to roll back")
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			logger.Fatal("main", "migrate", "status", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Missing {
+				state += ", unknown to this version"
+			}
+			fmt.Printf("%d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		exitWithUsage()
+	}
+}
+
+func printMigrations(action string, done []migrations.Migration) {
+	for _, migration := range done {
+		fmt.Printf("%s %d_%s\n", action, migration.Version, migration.Name)
+	}
+}
+
+func exitWithUsage() {
+	fmt.Fprintln(os.Stderr, migrateUsage)
+	os.Exit(2)
+}
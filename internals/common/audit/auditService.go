@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"backendService/internals/common/logger"
+	"backendService/internals/common/privacy"
+	"backendService/internals/common/repository"
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditLog records a sensitive action performed by an actor on a target entity.
+// Details holds action specific information serialized as JSON. The log is append-only,
+// so unlike most models it has no update or soft delete columns.
+type AuditLog struct {
+	ID         uint64    `json:"-" gorm:"primary_key"`
+	Action     string    `json:"action" gorm:"index;not null"`
+	ActorId    string    `json:"actorId" gorm:"index"`
+	TargetType string    `json:"targetType" gorm:"index:idx_audit_target"`
+	TargetId   string    `json:"targetId" gorm:"index:idx_audit_target"`
+	Details    string    `json:"details"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"not null"`
+}
+
+// Entry describes an action to record in the audit log.
+type Entry struct {
+	Action     string
+	ActorId    string
+	TargetType string
+	TargetId   string
+	Details    map[string]interface{}
+}
+
+// AuditService records sensitive actions in an append-only audit log.
+type AuditService struct {
+	auditRepository *repository.BaseRepository[AuditLog]
+}
+
+// NewAuditService creates a new instance of AuditService using the given database connection.
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{
+		auditRepository: repository.NewBaseRepository[AuditLog](db, "audit_logs"),
+	}
+}
+
+// Record appends an entry to the audit log. The entry is also written to the application log,
+// so it is not lost when the database write fails. The action being recorded already happened,
+// so the entry is written even when ctx was cancelled, e.g. because the client went away.
+func (as *AuditService) Record(ctx context.Context, entry Entry) error {
+	details, err := json.Marshal(entry.Details)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("audit", "AuditService", "Record", entry.Action, " actor=", entry.ActorId, " target=", entry.TargetType, ":", entry.TargetId, " details=", string(details))
+
+	_, err = as.auditRepository.WithContext(context.WithoutCancel(ctx)).Create(&AuditLog{
+		Action:     entry.Action,
+		ActorId:    entry.ActorId,
+		TargetType: entry.TargetType,
+		TargetId:   entry.TargetId,
+		Details:    string(details),
+	})
+	if err != nil {
+		logger.Error("audit", "AuditService", "Record", "failed to save audit log", err)
+	}
+	return err
+}
+
+// FindByUser returns every entry the user performed or was the target of, oldest first.
+func (as *AuditService) FindByUser(ctx context.Context, userId string) ([]AuditLog, error) {
+	var logs []AuditLog
+	err := as.auditRepository.Db.WithContext(ctx).
+		Where("actor_id = ? OR (target_type = ? AND target_id = ?)", userId, "user", userId).
+		Order("id").
+		Find(&logs).Error
+	return logs, err
+}
+
+// RegisterPersonalData registers the audit log with the personal data registry.
+// Data exports include the entries about the user. Erasure keeps the entries, as the log must stay
+// complete, but clears the details of those the user performed or was the target of, which may quote
+// their personal data, such as the reason they gave for an impersonation.
+func (as *AuditService) RegisterPersonalData(registry *privacy.Registry) {
+	registry.RegisterSection("activity", func(ctx context.Context, subject privacy.Subject) (interface{}, error) {
+		return as.FindByUser(ctx, subject.UserId)
+	})
+	registry.RegisterEraser("activity", func(ctx context.Context, subject privacy.Subject) error {
+		return as.auditRepository.Db.WithContext(ctx).
+			Where("actor_id = ? OR (target_type = ? AND target_id = ?)", subject.UserId, "user", subject.UserId).
+			Update("details", "null").Error
+	})
+}
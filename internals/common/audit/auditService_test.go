@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"backendService/internals/common/privacy"
+	"backendService/internals/setup/database/databasetest"
+	"context"
+	"testing"
+)
+
+// detailsByAction returns the details of the entries about the user, by action.
+func detailsByAction(t *testing.T, as *AuditService, userId string) map[string]string {
+	t.Helper()
+	logs, err := as.FindByUser(context.Background(), userId)
+	if err != nil {
+		t.Fatalf("reading the audit log: %v", err)
+	}
+	details := map[string]string{}
+	for _, log := range logs {
+		details[log.Action] = log.Details
+	}
+	return details
+}
+
+func TestEraseActivity(t *testing.T) {
+	as := NewAuditService(databasetest.Open(t))
+	ctx := context.Background()
+	entries := []Entry{
+		{Action: "user.impersonated", ActorId: "ada", TargetType: "user", TargetId: "grace", Details: map[string]interface{}{"reason": "ada's ticket"}},
+		{Action: "user.deactivated", ActorId: "grace", TargetType: "user", TargetId: "ada", Details: map[string]interface{}{"reason": "left"}},
+		{Action: "organization.created", ActorId: "grace", TargetType: "organization", TargetId: "engines", Details: map[string]interface{}{"name": "Engines"}},
+	}
+	for _, entry := range entries {
+		if err := as.Record(ctx, entry); err != nil {
+			t.Fatalf("recording %s: %v", entry.Action, err)
+		}
+	}
+
+	registry := privacy.NewRegistry()
+	as.RegisterPersonalData(registry)
+	if err := registry.Erase(ctx, privacy.Subject{UserId: "ada"}); err != nil {
+		t.Fatalf("Erase: %v", err)
+	}
+
+	// The entries ada performed or was the target of are kept without their details
+	details := detailsByAction(t, as, "grace")
+	want := map[string]string{
+		"user.impersonated":    "null",
+		"user.deactivated":     "null",
+		"organization.created": `{"name":"Engines"}`,
+	}
+	for action, wantDetails := range want {
+		if got, ok := details[action]; !ok || got != wantDetails {
+			t.Errorf("details of %s: got %q, want %q", action, got, wantDetails)
+		}
+	}
+}
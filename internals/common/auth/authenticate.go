@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"backendService/internals/common/errors"
+	"backendService/internals/common/logger"
+	"backendService/internals/common/request"
+	"backendService/internals/common/router"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const sessionContextKey = "session"
+
+// Authenticate returns a middleware that requires a valid bearer access token.
+// The session of the token is stored in the request context and can be read with CurrentSession,
+// and its user becomes the actor of the request.
+func Authenticate(sessionStore *SessionStore) router.HandlerFunc {
+	return func(c *gin.Context) (router.Response, *errors.ApplicationError) {
+		scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return router.Response{}, errors.NewUnauthorizedError("missing_token", "authorization token is required")
+		}
+
+		session, err := sessionStore.Get(c.Request.Context(), token)
+		if err != nil {
+			logger.Error("auth", "Authenticate", "Get", "failed to retrieve session", err)
+			return router.Response{}, errors.NewInternalServerError("failed to retrieve session", err)
+		}
+		if session == nil {
+			return router.Response{}, errors.NewUnauthorizedError("invalid_token", "authorization token is invalid or expired")
+		}
+
+		c.Set(sessionContextKey, session)
+		c.Request = c.Request.WithContext(request.WithActor(c.Request.Context(), request.Actor{
+			UserId:         session.UserId,
+			ImpersonatorId: session.ImpersonatorId,
+		}))
+		return router.Response{}, nil
+	}
+}
+
+// CurrentSession returns the session stored in the request context by Authenticate.
+// It returns nil when the request did not go through Authenticate.
+func CurrentSession(c *gin.Context) *Session {
+	value, ok := c.Get(sessionContextKey)
+	if !ok {
+		return nil
+	}
+	session, _ := value.(*Session)
+	return session
+}
+
+// RequireRole returns a middleware that only lets through sessions having one of the given roles.
+// It must be registered after Authenticate. Impersonated sessions never pass, so staff cannot
+// escalate their privileges by impersonating another staff member.
+func RequireRole(roles ...string) router.HandlerFunc {
+	return func(c *gin.Context) (router.Response, *errors.ApplicationError) {
+		session := CurrentSession(c)
+		if session == nil {
+			return router.Response{}, errors.NewUnauthorizedError("missing_token", "authorization token is required")
+		}
+		if !session.IsImpersonated() {
+			for _, role := range roles {
+				if session.Role == role {
+					return router.Response{}, nil
+				}
+			}
+		}
+		return router.Response{}, errors.NewApplicationError("forbidden", "you are not allowed to perform this action", http.StatusForbidden)
+	}
+}
+
+// RequireRecentAuthentication returns an error unless the session's user re-authenticated recently.
+// Impersonated sessions are always rejected, as only the user themselves may perform sensitive operations.
+func RequireRecentAuthentication(session *Session) *errors.ApplicationError {
+	if session != nil && session.IsImpersonated() {
+		return errors.NewApplicationError("forbidden", "this action is not allowed while impersonating a user", http.StatusForbidden)
+	}
+	if session == nil || !session.IsRecentlyAuthenticated() {
+		return errors.NewUnauthorizedError("reauthentication_required", "please sign in again to perform this action")
+	}
+	return nil
+}
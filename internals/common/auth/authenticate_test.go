@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"backendService/internals/common/request"
+	"backendService/internals/setup/config"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestContext returns a gin context for a request sent with the given Authorization header.
+func newTestContext(authorization string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if authorization != "" {
+		c.Request.Header.Set("Authorization", authorization)
+	}
+	return c
+}
+
+func TestAuthenticate(t *testing.T) {
+	ss, _ := newTestSessionStore(t)
+	token, _, err := ss.Create(context.Background(), "user-1", "user")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	authenticate := Authenticate(ss)
+
+	for authorization, want := range map[string]string{
+		"":                  "missing_token",
+		token:               "missing_token",
+		"Basic " + token:    "missing_token",
+		"Bearer ":           "missing_token",
+		"Bearer not-issued": "invalid_token",
+	} {
+		c := newTestContext(authorization)
+		if _, appErr := authenticate(c); appErr == nil || appErr.ErrorCode != want || appErr.HttpStatusCode != http.StatusUnauthorized {
+			t.Errorf("authenticating with %q: got %v, want %s", authorization, appErr, want)
+		}
+		if CurrentSession(c) != nil {
+			t.Errorf("authenticating with %q: got a session", authorization)
+		}
+	}
+
+	c := newTestContext("bearer " + token)
+	if _, appErr := authenticate(c); appErr != nil {
+		t.Fatalf("authenticating with a valid token: %s", appErr.Message)
+	}
+	if session := CurrentSession(c); session == nil || session.UserId != "user-1" {
+		t.Errorf("got session %+v, want the session of user-1", session)
+	}
+	if actor, ok := request.CurrentActor(c.Request.Context()); !ok || actor.UserId != "user-1" {
+		t.Errorf("got actor %+v, want user-1", actor)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	requireAdmin := RequireRole("admin", "support")
+
+	tests := []struct {
+		name    string
+		session *Session
+		status  int // status is 0 when the session gets through
+	}{
+		{"no session", nil, http.StatusUnauthorized},
+		{"user", &Session{Role: "user"}, http.StatusForbidden},
+		{"admin", &Session{Role: "admin"}, 0},
+		{"support", &Session{Role: "support"}, 0},
+		{"admin impersonating an admin", &Session{Role: "admin", ImpersonatorId: "admin-2"}, http.StatusForbidden},
+	}
+	for _, test := range tests {
+		c := newTestContext("")
+		if test.session != nil {
+			c.Set(sessionContextKey, test.session)
+		}
+		status := 0
+		if _, appErr := requireAdmin(c); appErr != nil {
+			status = appErr.HttpStatusCode
+		}
+		if status != test.status {
+			t.Errorf("%s: got status %d, want %d", test.name, status, test.status)
+		}
+	}
+}
+
+func TestRequireRecentAuthentication(t *testing.T) {
+	previous := config.Config
+	config.Config.Auth.ReauthWindowMinutes = 5
+	t.Cleanup(func() { config.Config = previous })
+
+	if appErr := RequireRecentAuthentication(&Session{AuthenticatedAt: time.Now()}); appErr != nil {
+		t.Errorf("recent session: got %v", appErr)
+	}
+	if appErr := RequireRecentAuthentication(&Session{AuthenticatedAt: time.Now().Add(-time.Hour)}); appErr == nil || appErr.ErrorCode != "reauthentication_required" {
+		t.Errorf("old session: got %v, want reauthentication_required", appErr)
+	}
+	if appErr := RequireRecentAuthentication(&Session{AuthenticatedAt: time.Now(), ImpersonatorId: "admin-1"}); appErr == nil || appErr.ErrorCode != "forbidden" {
+		t.Errorf("impersonated session: got %v, want forbidden", appErr)
+	}
+	if appErr := RequireRecentAuthentication(nil); appErr == nil || appErr.ErrorCode != "reauthentication_required" {
+		t.Errorf("no session: got %v, want reauthentication_required", appErr)
+	}
+}
@@ -0,0 +1,22 @@
+package auth
+
+import "golang.org/x/crypto/bcrypt"
+
+// MaxPasswordBytes is the length in bytes of the longest password bcrypt can hash.
+const MaxPasswordBytes = 72
+
+// ErrPasswordTooLong is returned by HashPassword for a password longer than MaxPasswordBytes,
+// rather than hashing only its first bytes.
+var ErrPasswordTooLong = bcrypt.ErrPasswordTooLong
+
+// HashPassword returns the bcrypt hash of password. Passwords are only ever stored hashed.
+func HashPassword(password string) (string, error) {
+	if len(password) > MaxPasswordBytes {
+		return "", ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
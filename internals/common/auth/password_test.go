@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte("correct horse battery staple")); err != nil {
+		t.Errorf("comparing the password with its hash: %v", err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte("wrong password")); err == nil {
+		t.Error("comparing another password with the hash: got no error")
+	}
+
+	// Hashes are salted, so hashing the same password twice gives different hashes.
+	if other, _ := HashPassword("correct horse battery staple"); other == hash {
+		t.Error("got the same hash twice")
+	}
+}
+
+func TestHashPasswordTooLong(t *testing.T) {
+	if _, err := HashPassword(strings.Repeat("a", MaxPasswordBytes)); err != nil {
+		t.Errorf("hashing a password of %d bytes: %v", MaxPasswordBytes, err)
+	}
+	// 37 characters, but 74 bytes
+	if _, err := HashPassword(strings.Repeat("é", 37)); !errors.Is(err, ErrPasswordTooLong) {
+		t.Errorf("hashing a password of 74 bytes: got %v, want ErrPasswordTooLong", err)
+	}
+}
@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"backendService/internals/common/cache"
+	"backendService/internals/setup/config"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	sessionKeyPrefix     = "session:"
+	userSessionKeyPrefix = "user_sessions:"
+	tokenBytes           = 32
+)
+
+// Session represents an authenticated session of a user.
+// AuthenticatedAt is when the user last proved their identity, which sensitive operations check.
+// ImpersonatorId is set when a staff member acts as the user, and holds the staff member's user ID.
+type Session struct {
+	ID              string    `json:"id"`
+	UserId          string    `json:"userId"`
+	Role            string    `json:"role"`
+	ImpersonatorId  string    `json:"impersonatorId,omitempty"`
+	AuthenticatedAt time.Time `json:"authenticatedAt"`
+	ExpiresAt       time.Time `json:"expiresAt"`
+}
+
+// SessionStore keeps sessions in the cache, keyed by a hash of their access token.
+// The raw token is only ever handed to the client, so a leaked cache does not leak usable tokens.
+type SessionStore struct {
+	cacheService *cache.CacheService
+}
+
+// NewSessionStore creates a new SessionStore backed by the given CacheService.
+func NewSessionStore(cacheService *cache.CacheService) *SessionStore {
+	return &SessionStore{cacheService: cacheService}
+}
+
+// Create starts a new session for the user and returns the access token together with the session.
+func (ss *SessionStore) Create(ctx context.Context, userId string, role string) (string, *Session, error) {
+	return ss.create(ctx, &Session{UserId: userId, Role: role}, sessionTTL())
+}
+
+// CreateImpersonation starts a session in which the staff member identified by impersonatorId acts as the user.
+// The session lasts for ttl and is revoked together with the user's other sessions.
+func (ss *SessionStore) CreateImpersonation(ctx context.Context, userId string, role string, impersonatorId string, ttl time.Duration) (string, *Session, error) {
+	return ss.create(ctx, &Session{UserId: userId, Role: role, ImpersonatorId: impersonatorId}, ttl)
+}
+
+// create generates an access token for the session and stores the session for ttl.
+func (ss *SessionStore) create(ctx context.Context, session *Session, ttl time.Duration) (string, *Session, error) {
+	raw := make([]byte, tokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	now := time.Now()
+	session.ID = hashToken(token)
+	session.AuthenticatedAt = now
+	session.ExpiresAt = now.Add(ttl)
+
+	if err := ss.cacheService.Set(ctx, sessionKeyPrefix+session.ID, session, ttl); err != nil {
+		return "", nil, err
+	}
+	// The index of the user's sessions must outlive every session it lists
+	indexTTL := sessionTTL()
+	if ttl > indexTTL {
+		indexTTL = ttl
+	}
+	if err := ss.cacheService.AddToSet(ctx, userSessionKeyPrefix+session.UserId, indexTTL, session.ID); err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// Get returns the session for the given access token.
+// It returns nil without an error when the token is unknown or the session has expired.
+func (ss *SessionStore) Get(ctx context.Context, token string) (*Session, error) {
+	var session Session
+	err := ss.cacheService.Get(ctx, sessionKeyPrefix+hashToken(token), &session)
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Revoke ends a single session.
+func (ss *SessionStore) Revoke(ctx context.Context, session *Session) error {
+	if err := ss.cacheService.Delete(ctx, sessionKeyPrefix+session.ID); err != nil {
+		return err
+	}
+	return ss.cacheService.RemoveFromSet(ctx, userSessionKeyPrefix+session.UserId, session.ID)
+}
+
+// RevokeAll ends every session of the given user. It usually follows a change already saved,
+// such as a deactivation, so it goes on even when ctx was cancelled.
+func (ss *SessionStore) RevokeAll(ctx context.Context, userId string) error {
+	ctx = context.WithoutCancel(ctx)
+	sessionIds, err := ss.cacheService.SetMembers(ctx, userSessionKeyPrefix+userId)
+	if err != nil {
+		return err
+	}
+	for _, sessionId := range sessionIds {
+		if err := ss.cacheService.Delete(ctx, sessionKeyPrefix+sessionId); err != nil {
+			return err
+		}
+	}
+	return ss.cacheService.Delete(ctx, userSessionKeyPrefix+userId)
+}
+
+// List returns the active sessions of the given user.
+func (ss *SessionStore) List(ctx context.Context, userId string) ([]Session, error) {
+	sessionIds, err := ss.cacheService.SetMembers(ctx, userSessionKeyPrefix+userId)
+	if err != nil {
+		return nil, err
+	}
+	sessions := []Session{}
+	for _, sessionId := range sessionIds {
+		var session Session
+		err := ss.cacheService.Get(ctx, sessionKeyPrefix+sessionId, &session)
+		if err == redis.Nil {
+			// The session expired but is still listed in the index
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// IsImpersonated reports whether a staff member is acting as the user in this session.
+func (s *Session) IsImpersonated() bool {
+	return s.ImpersonatorId != ""
+}
+
+// IsRecentlyAuthenticated reports whether the user proved their identity within the re-authentication window.
+func (s *Session) IsRecentlyAuthenticated() bool {
+	window := time.Duration(config.Config.Auth.ReauthWindowMinutes) * time.Minute
+	return time.Since(s.AuthenticatedAt) <= window
+}
+
+// hashToken returns the hex encoded SHA-256 hash of an access token.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionTTL returns the configured session lifetime, defaulting to one day.
+func sessionTTL() time.Duration {
+	if config.Config.Auth.SessionTTLMinutes <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(config.Config.Auth.SessionTTLMinutes) * time.Minute
+}
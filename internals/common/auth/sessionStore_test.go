@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"backendService/internals/common/cache/cachetest"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSessionStore returns a SessionStore backed by an in-memory cache, along with the cache server.
+func newTestSessionStore(t *testing.T) (*SessionStore, *cachetest.Server) {
+	t.Helper()
+	cacheService, server := cachetest.NewCacheService(t)
+	return NewSessionStore(cacheService), server
+}
+
+func TestSessionStore(t *testing.T) {
+	ss, server := newTestSessionStore(t)
+	ctx := context.Background()
+
+	token, created, err := ss.Create(ctx, "user-1", "user")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	otherToken, _, err := ss.Create(ctx, "user-1", "user")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, _, err := ss.Create(ctx, "user-2", "admin"); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Only a hash of the token reaches the cache.
+	for _, key := range server.Keys() {
+		if strings.Contains(key, token) {
+			t.Errorf("cache key %s holds the raw token", key)
+		}
+	}
+
+	session, err := ss.Get(ctx, token)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if session == nil || session.ID != created.ID || session.UserId != "user-1" || session.Role != "user" || session.IsImpersonated() {
+		t.Fatalf("got session %+v, want %+v", session, created)
+	}
+	if unknown, err := ss.Get(ctx, "unknown"); err != nil || unknown != nil {
+		t.Errorf("getting an unknown token: got %+v, %v, want nil without an error", unknown, err)
+	}
+
+	sessions, err := ss.List(ctx, "user-1")
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("List: got %d sessions, %v, want 2", len(sessions), err)
+	}
+
+	if err := ss.Revoke(ctx, session); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if revoked, err := ss.Get(ctx, token); err != nil || revoked != nil {
+		t.Errorf("getting a revoked session: got %+v, %v, want nil", revoked, err)
+	}
+	if sessions, err := ss.List(ctx, "user-1"); err != nil || len(sessions) != 1 {
+		t.Errorf("List after Revoke: got %d sessions, %v, want 1", len(sessions), err)
+	}
+
+	if err := ss.RevokeAll(ctx, "user-1"); err != nil {
+		t.Fatalf("RevokeAll: %v", err)
+	}
+	if revoked, err := ss.Get(ctx, otherToken); err != nil || revoked != nil {
+		t.Errorf("getting a session after RevokeAll: got %+v, %v, want nil", revoked, err)
+	}
+	if sessions, err := ss.List(ctx, "user-2"); err != nil || len(sessions) != 1 {
+		t.Errorf("sessions of another user after RevokeAll: got %d, %v, want 1", len(sessions), err)
+	}
+}
+
+func TestCreateImpersonation(t *testing.T) {
+	ss, _ := newTestSessionStore(t)
+	ctx := context.Background()
+
+	token, created, err := ss.CreateImpersonation(ctx, "user-1", "user", "admin-1", time.Minute)
+	if err != nil {
+		t.Fatalf("CreateImpersonation: %v", err)
+	}
+	if !created.IsImpersonated() || created.ExpiresAt.After(time.Now().Add(time.Minute)) {
+		t.Errorf("got session %+v, want an impersonation expiring within a minute", created)
+	}
+
+	// Impersonations end with the other sessions of the user.
+	if err := ss.RevokeAll(ctx, "user-1"); err != nil {
+		t.Fatalf("RevokeAll: %v", err)
+	}
+	if session, err := ss.Get(ctx, token); err != nil || session != nil {
+		t.Errorf("getting the impersonation after RevokeAll: got %+v, %v, want nil", session, err)
+	}
+}
@@ -61,10 +61,38 @@ func (c *CacheService) Get(ctx context.Context, key string, target interface{})
 	return json.Unmarshal([]byte(jsonValue), target)
 }
 
-// Delete removes the given key from the cache.
-// If the key does not exist, the operation is a no-op.
-func (c *CacheService) Delete(ctx context.Context, key string) error {
-	return c.client.Del(ctx, key).Err()
+// SetBytes stores the provided bytes as they are in the cache with the given key and expiration duration,
+// for values encoded otherwise than as JSON.
+func (c *CacheService) SetBytes(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return c.client.Set(ctx, key, value, expiration).Err()
+}
+
+// SetBytesIfAbsent stores the provided bytes under the given key like SetBytes, unless the key already
+// holds a value. It reports whether the bytes were stored.
+func (c *CacheService) SetBytesIfAbsent(ctx context.Context, key string, value []byte, expiration time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, key, value, expiration).Result()
+}
+
+// SetManyBytes stores the same bytes under every given key with the given expiration, in a single round trip.
+func (c *CacheService) SetManyBytes(ctx context.Context, keys []string, value []byte, expiration time.Duration) error {
+	pipe := c.client.Pipeline()
+	for _, key := range keys {
+		pipe.Set(ctx, key, value, expiration)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetBytes returns the bytes stored with SetBytes under the given key.
+// It returns redis.Nil when the key does not exist.
+func (c *CacheService) GetBytes(ctx context.Context, key string) ([]byte, error) {
+	return c.client.Get(ctx, key).Bytes()
+}
+
+// Delete removes the given keys from the cache.
+// Keys that do not exist are ignored.
+func (c *CacheService) Delete(ctx context.Context, keys ...string) error {
+	return c.client.Del(ctx, keys...).Err()
 }
 
 // Exists checks if the given key exists in the cache.
@@ -89,6 +117,37 @@ func (c *CacheService) Decrement(ctx context.Context, key string) error {
 	return c.client.Decr(ctx, key).Err()
 }
 
+// AddToSet adds the given members to the set stored at key and sets the expiration of the whole set.
+// A zero expiration leaves the set without an expiry.
+func (c *CacheService) AddToSet(ctx context.Context, key string, expiration time.Duration, members ...string) error {
+	values := make([]interface{}, len(members))
+	for i, member := range members {
+		values[i] = member
+	}
+	if err := c.client.SAdd(ctx, key, values...).Err(); err != nil {
+		return err
+	}
+	if expiration > 0 {
+		return c.client.Expire(ctx, key, expiration).Err()
+	}
+	return nil
+}
+
+// SetMembers returns all the members of the set stored at key.
+// If the key does not exist, an empty slice is returned.
+func (c *CacheService) SetMembers(ctx context.Context, key string) ([]string, error) {
+	return c.client.SMembers(ctx, key).Result()
+}
+
+// RemoveFromSet removes the given members from the set stored at key.
+func (c *CacheService) RemoveFromSet(ctx context.Context, key string, members ...string) error {
+	values := make([]interface{}, len(members))
+	for i, member := range members {
+		values[i] = member
+	}
+	return c.client.SRem(ctx, key, values...).Err()
+}
+
 // Close closes the underlying cache client connection.
 func (c *CacheService) Close() error {
 	return c.client.Close()
@@ -0,0 +1,234 @@
+// Package cachetest provides an in-memory stand-in for the Redis server behind CacheService, for tests.
+package cachetest
+
+import (
+	"backendService/internals/common/cache"
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Server is an in-memory server answering the Redis commands sent by CacheService.
+// Expirations are accepted but never applied, as tests do not outlive them.
+type Server struct {
+	mu     sync.Mutex
+	values map[string]string
+	sets   map[string]map[string]bool
+}
+
+// NewCacheService starts a Server for the duration of the test and returns a CacheService connected to it.
+func NewCacheService(t testing.TB) (*cache.CacheService, *Server) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("starting the test cache: %v", err)
+	}
+	server := &Server{values: map[string]string{}, sets: map[string]map[string]bool{}}
+	go server.serve(listener)
+
+	password := ""
+	cacheService := cache.NewCacheService(listener.Addr().String(), &password, 0)
+	t.Cleanup(func() {
+		cacheService.Close()
+		listener.Close()
+	})
+	return cacheService, server
+}
+
+// Keys returns the keys holding a value or a set, in order.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.values)+len(s.sets))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	for key := range s.sets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get returns the value stored under key, and whether there is one.
+func (s *Server) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+func (s *Server) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+// handle answers the commands sent on conn until it is closed.
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings.
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	count, err := readLength(reader, '*')
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		size, err := readLength(reader, '$')
+		if err != nil {
+			return nil, err
+		}
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, arg); err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:size])
+	}
+	return args, nil
+}
+
+// readLength reads a line holding a length prefixed with kind.
+func readLength(reader *bufio.Reader, kind byte) (int, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	if len(line) < 3 || line[0] != kind {
+		return 0, fmt.Errorf("unexpected line %q", line)
+	}
+	return strconv.Atoi(strings.TrimSpace(line[1:]))
+}
+
+// exec runs a command and returns its encoded reply.
+func (s *Server) exec(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "SET":
+		if _, exists := s.values[args[1]]; exists && hasOption(args[3:], "NX") {
+			return "$-1\r\n"
+		}
+		s.values[args[1]] = args[2]
+		return "+OK\r\n"
+	case "GET":
+		value, ok := s.values[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulkString(value)
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.values[key]; ok {
+				deleted++
+			} else if _, ok := s.sets[key]; ok {
+				deleted++
+			}
+			delete(s.values, key)
+			delete(s.sets, key)
+		}
+		return integer(deleted)
+	case "EXISTS":
+		exists := 0
+		for _, key := range args[1:] {
+			if _, ok := s.values[key]; ok {
+				exists++
+			} else if _, ok := s.sets[key]; ok {
+				exists++
+			}
+		}
+		return integer(exists)
+	case "INCR", "DECR":
+		value, _ := strconv.Atoi(s.values[args[1]])
+		if strings.ToUpper(args[0]) == "INCR" {
+			value++
+		} else {
+			value--
+		}
+		s.values[args[1]] = strconv.Itoa(value)
+		return integer(value)
+	case "EXPIRE":
+		return integer(1)
+	case "SADD":
+		if s.sets[args[1]] == nil {
+			s.sets[args[1]] = map[string]bool{}
+		}
+		added := 0
+		for _, member := range args[2:] {
+			if !s.sets[args[1]][member] {
+				s.sets[args[1]][member] = true
+				added++
+			}
+		}
+		return integer(added)
+	case "SREM":
+		removed := 0
+		for _, member := range args[2:] {
+			if s.sets[args[1]][member] {
+				delete(s.sets[args[1]], member)
+				removed++
+			}
+		}
+		if len(s.sets[args[1]]) == 0 {
+			delete(s.sets, args[1])
+		}
+		return integer(removed)
+	case "SMEMBERS":
+		members := make([]string, 0, len(s.sets[args[1]]))
+		for member := range s.sets[args[1]] {
+			members = append(members, member)
+		}
+		sort.Strings(members)
+		reply := fmt.Sprintf("*%d\r\n", len(members))
+		for _, member := range members {
+			reply += bulkString(member)
+		}
+		return reply
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+// hasOption reports whether the options of a command include option, whatever its case.
+func hasOption(options []string, option string) bool {
+	for _, candidate := range options {
+		if strings.EqualFold(candidate, option) {
+			return true
+		}
+	}
+	return false
+}
+
+func bulkString(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+func integer(value int) string {
+	return fmt.Sprintf(":%d\r\n", value)
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"backendService/internals/common/errors"
+	"backendService/internals/common/validation"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -28,7 +29,7 @@ func (c *BaseController) TransformAndValidate(ctx *gin.Context, dtoStruct interf
 		}
 		return nil, errorResponse
 	}
-	validate := validator.New()
+	validate := validation.New()
 	if err := validate.Struct(dtoStruct); err != nil {
 		validationErrors := c.extractValidationErrors(err)
 		if len(validationErrors) > 0 {
@@ -39,6 +40,30 @@ func (c *BaseController) TransformAndValidate(ctx *gin.Context, dtoStruct interf
 	return dtoStruct, nil
 }
 
+// TransformAndValidateQuery binds the query string of the request into the given DTO struct and validates it.
+func (c *BaseController) TransformAndValidateQuery(ctx *gin.Context, dtoStruct interface{}) (interface{}, *errors.ApplicationError) {
+	if !c.shouldValidate(dtoStruct) {
+		return dtoStruct, nil
+	}
+
+	if err := ctx.ShouldBindQuery(dtoStruct); err != nil {
+		validationErrors := c.extractValidationErrors(err)
+		if len(validationErrors) > 0 {
+			return nil, errors.NewUnprocessableEntityError("invalid_query", c.newValidationError(validationErrors))
+		}
+		return nil, errors.NewUnprocessableEntityError("invalid_query", []ValidationErrorData{{Field: "query", Message: err.Error()}})
+	}
+	validate := validation.New()
+	if err := validate.Struct(dtoStruct); err != nil {
+		validationErrors := c.extractValidationErrors(err)
+		if len(validationErrors) > 0 {
+			return nil, errors.NewUnprocessableEntityError("invalid_query", c.newValidationError(validationErrors))
+		}
+	}
+
+	return dtoStruct, nil
+}
+
 // shouldValidate checks if the provided DTO struct needs to be validated.
 func (c *BaseController) shouldValidate(dtoStruct interface{}) bool {
 	dtoType := reflect.TypeOf(dtoStruct)
@@ -130,6 +155,8 @@ func (c *BaseController) formatValidationErrorMessage(err error, field string) s
 				return fmt.Sprintf("%s must be at least %s characters long", strings.Title(field), c.getTagValue(validatorError.Tag()))
 			case "max":
 				return fmt.Sprintf("%s must not exceed %s characters", strings.Title(field), c.getTagValue(validatorError.Tag()))
+			case "maxbytes":
+				return fmt.Sprintf("%s must not exceed %s bytes", strings.Title(field), validatorError.Param())
 			case "gte":
 				return fmt.Sprintf("%s must be greater than or equal to %s", strings.Title(field), c.getTagValue(validatorError.Tag()))
 			case "email":
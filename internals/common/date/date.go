@@ -0,0 +1,133 @@
+package date
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Layout is the format dates are written in, in JSON as well as in the database.
+const Layout = "2006-01-02"
+
+// Date is a calendar date without a time of day or a time zone, such as a date of birth.
+// Unlike a time.Time at midnight, it reads the same in every time zone.
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// Parse parses a date written as YYYY-MM-DD.
+func Parse(value string) (Date, error) {
+	parsed, err := time.Parse(Layout, value)
+	if err != nil {
+		return Date{}, err
+	}
+	return Of(parsed), nil
+}
+
+// Of returns the date of t in the time zone of t.
+func Of(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// Today returns the current date in UTC.
+func Today() Date {
+	return Of(time.Now().UTC())
+}
+
+// String formats the date as YYYY-MM-DD.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// Time returns the start of the date in UTC.
+func (d Date) Time() time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, time.UTC)
+}
+
+// Before reports whether the date is before other.
+func (d Date) Before(other Date) bool {
+	return d.Time().Before(other.Time())
+}
+
+// After reports whether the date is after other.
+func (d Date) After(other Date) bool {
+	return d.Time().After(other.Time())
+}
+
+// AddYears returns the date the given number of years later, or earlier when years is negative.
+// February 29 becomes March 1 in years that are not leap years.
+func (d Date) AddYears(years int) Date {
+	return Of(d.Time().AddDate(years, 0, 0))
+}
+
+// YearsOn returns the number of full years from the date to on, such as an age on a given day.
+// Someone born on February 29 turns a year older on March 1 in years that are not leap years.
+func (d Date) YearsOn(on Date) int {
+	years := on.Year - d.Year
+	if on.Before(d.AddYears(years)) {
+		years--
+	}
+	return years
+}
+
+// MarshalJSON writes the date as a YYYY-MM-DD string.
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON reads a date written as a YYYY-MM-DD string.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	parsed, err := Parse(value)
+	if err != nil {
+		return &json.UnmarshalTypeError{Value: "string " + value, Type: reflect.TypeOf(d).Elem()}
+	}
+	*d = parsed
+	return nil
+}
+
+// Value stores the date as a YYYY-MM-DD string, which every supported database reads into a date column.
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan reads a date column, which drivers return either as a time.Time or as text.
+func (d *Date) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		*d = Of(v)
+		return nil
+	case string:
+		return d.scanText(v)
+	case []byte:
+		return d.scanText(string(v))
+	default:
+		return fmt.Errorf("date: cannot scan %T", value)
+	}
+}
+
+// scanText reads a date stored as text, ignoring the time of day some drivers append.
+func (d *Date) scanText(value string) error {
+	if len(value) > len(Layout) {
+		value = value[:len(Layout)]
+	}
+	parsed, err := Parse(value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// GormDataType makes gorm store dates in a date column.
+func (Date) GormDataType() string {
+	return "date"
+}
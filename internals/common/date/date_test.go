@@ -0,0 +1,104 @@
+package date
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	parsed, err := Parse("1815-12-10")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want := (Date{Year: 1815, Month: time.December, Day: 10}); parsed != want {
+		t.Errorf("got %v, want %v", parsed, want)
+	}
+	for _, value := range []string{"", "1815-13-10", "2023-02-29", "10/12/1815", "1815-12-10T00:00:00Z"} {
+		if _, err := Parse(value); err == nil {
+			t.Errorf("parsing %q: got no error", value)
+		}
+	}
+}
+
+func TestOf(t *testing.T) {
+	// Late evening in New York is already the next day in UTC, the date follows the time zone of the time
+	newYork := time.FixedZone("EST", -5*60*60)
+	evening := time.Date(2024, time.March, 9, 22, 0, 0, 0, newYork)
+	if got := Of(evening); got.String() != "2024-03-09" {
+		t.Errorf("got %s, want 2024-03-09", got)
+	}
+	if got := Of(evening.UTC()); got.String() != "2024-03-10" {
+		t.Errorf("got %s in UTC, want 2024-03-10", got)
+	}
+}
+
+func TestYearsOn(t *testing.T) {
+	tests := []struct {
+		born string
+		on   string
+		want int
+	}{
+		{"2000-06-15", "2024-06-14", 23},
+		{"2000-06-15", "2024-06-15", 24},
+		{"2000-06-15", "2000-06-15", 0},
+		{"2000-02-29", "2023-02-28", 22},
+		{"2000-02-29", "2023-03-01", 23},
+		{"2000-02-29", "2024-02-29", 24},
+		{"1999-12-31", "2000-01-01", 0},
+	}
+	for _, test := range tests {
+		born, _ := Parse(test.born)
+		on, _ := Parse(test.on)
+		if got := born.YearsOn(on); got != test.want {
+			t.Errorf("%s.YearsOn(%s): got %d, want %d", test.born, test.on, got, test.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var value struct {
+		DOB *Date `json:"dob"`
+	}
+	if err := json.Unmarshal([]byte(`{"dob":"1906-12-09"}`), &value); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if string(encoded) != `{"dob":"1906-12-09"}` {
+		t.Errorf("got %s, want the date unchanged", encoded)
+	}
+	for _, data := range []string{`{"dob":"1906-12-9"}`, `{"dob":19061209}`} {
+		if err := json.Unmarshal([]byte(data), &value); err == nil {
+			t.Errorf("unmarshaling %s: got no error", data)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []interface{}{
+		"1906-12-09",
+		[]byte("1906-12-09"),
+		"1906-12-09 00:00:00+00:00",
+		time.Date(1906, time.December, 9, 0, 0, 0, 0, time.UTC),
+	}
+	for _, value := range tests {
+		var scanned Date
+		if err := scanned.Scan(value); err != nil {
+			t.Errorf("scanning %v: %v", value, err)
+			continue
+		}
+		if scanned.String() != "1906-12-09" {
+			t.Errorf("scanning %v: got %s, want 1906-12-09", value, scanned)
+		}
+	}
+	var scanned Date
+	if err := scanned.Scan(int64(19061209)); err == nil {
+		t.Error("scanning an integer: got no error")
+	}
+	if stored, err := (Date{Year: 1906, Month: time.December, Day: 9}).Value(); err != nil || stored != "1906-12-09" {
+		t.Errorf("got value %v, %v, want 1906-12-09", stored, err)
+	}
+}
@@ -0,0 +1,144 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Formats an export can be written in.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatXLSX   = "xlsx"
+)
+
+// ErrUnsupportedFormat is returned by NewWriter for an unknown export format.
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// Writer writes tabular data row by row, so exports can be streamed without holding every row in memory.
+// WriteHeader must be called once before the first row, and Close must be called to flush the output.
+// Row values may be strings, booleans, integers, floats, times or nil.
+type Writer interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// NewWriter returns a Writer producing the given format into w.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{writer: bufio.NewWriter(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// ContentType returns the media type of files in the given format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// formatValue renders a row value as text for the formats without native types.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// csvWriter writes rows as comma separated values with a header line.
+type csvWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func (cw *csvWriter) WriteHeader(columns []string) error {
+	return cw.writer.Write(columns)
+}
+
+func (cw *csvWriter) WriteRow(values []interface{}) error {
+	cw.record = cw.record[:0]
+	for _, value := range values {
+		cw.record = append(cw.record, formatValue(value))
+	}
+	return cw.writer.Write(cw.record)
+}
+
+func (cw *csvWriter) Close() error {
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
+
+// ndjsonWriter writes every row as a JSON object keyed by the column names, one object per line.
+type ndjsonWriter struct {
+	writer  *bufio.Writer
+	columns []string
+}
+
+func (nw *ndjsonWriter) WriteHeader(columns []string) error {
+	nw.columns = columns
+	return nil
+}
+
+func (nw *ndjsonWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(nw.columns) {
+		return errors.New("row does not match the header")
+	}
+
+	// Objects are built by hand to keep the keys in column order
+	if err := nw.writer.WriteByte('{'); err != nil {
+		return err
+	}
+	for i, column := range nw.columns {
+		if i > 0 {
+			nw.writer.WriteByte(',')
+		}
+		key, _ := json.Marshal(column)
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		nw.writer.Write(key)
+		nw.writer.WriteByte(':')
+		nw.writer.Write(value)
+	}
+	_, err := nw.writer.WriteString("}\n")
+	return err
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nw.writer.Flush()
+}
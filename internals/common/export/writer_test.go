@@ -0,0 +1,125 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeRows writes the header and rows in the given format and returns the output.
+func writeRows(t *testing.T, format string, header []string, rows ...[]interface{}) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer, err := NewWriter(format, &buffer)
+	if err != nil {
+		t.Fatalf("NewWriter(%s): %v", format, err)
+	}
+	if err := writer.WriteHeader(header); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+	for _, row := range rows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+var (
+	testHeader = []string{"name", "active", "count", "createdAt", "note"}
+	testRows   = [][]interface{}{
+		{"Ada, \"Countess\"", true, 3, time.Date(2026, 10, 19, 14, 0, 0, 0, time.FixedZone("", 2*60*60)), nil},
+		{"<Grace> & co", false, int64(0), (*time.Time)(nil), "x"},
+	}
+)
+
+func TestCSVWriter(t *testing.T) {
+	got := string(writeRows(t, FormatCSV, testHeader, testRows...))
+	want := "name,active,count,createdAt,note\n" +
+		"\"Ada, \"\"Countess\"\"\",true,3,2026-10-19T12:00:00Z,\n" +
+		"<Grace> & co,false,0,,x\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	got := string(writeRows(t, FormatNDJSON, testHeader, testRows...))
+	want := `{"name":"Ada, \"Countess\"","active":true,"count":3,"createdAt":"2026-10-19T14:00:00+02:00","note":null}` + "\n" +
+		`{"name":"\u003cGrace\u003e \u0026 co","active":false,"count":0,"createdAt":null,"note":"x"}` + "\n"
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	var buffer bytes.Buffer
+	writer, _ := NewWriter(FormatNDJSON, &buffer)
+	writer.WriteHeader(testHeader)
+	if err := writer.WriteRow([]interface{}{"too short"}); err == nil {
+		t.Error("writing a row that does not match the header: got no error")
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	output := writeRows(t, FormatXLSX, testHeader, testRows...)
+	archive, err := zip.NewReader(bytes.NewReader(output), int64(len(output)))
+	if err != nil {
+		t.Fatalf("reading the workbook: %v", err)
+	}
+
+	var names []string
+	var sheet string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			content, err := file.Open()
+			if err != nil {
+				t.Fatalf("opening the worksheet: %v", err)
+			}
+			data, _ := io.ReadAll(content)
+			sheet = string(data)
+		}
+	}
+	if len(names) != 5 || names[4] != "xl/worksheets/sheet1.xml" {
+		t.Errorf("got parts %v, want the worksheet last of 5 parts", names)
+	}
+	for _, cell := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">name</t></is></c>`,
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">Ada, &#34;Countess&#34;</t></is></c>`,
+		`<c r="B2" t="b"><v>1</v></c>`,
+		`<c r="C2"><v>3</v></c>`,
+		`<c r="D2" t="inlineStr"><is><t xml:space="preserve">2026-10-19T12:00:00Z</t></is></c>`,
+		`<c r="A3" t="inlineStr"><is><t xml:space="preserve">&lt;Grace&gt; &amp; co</t></is></c>`,
+		`<c r="C3"><v>0</v></c>`,
+	} {
+		if !strings.Contains(sheet, cell) {
+			t.Errorf("worksheet is missing cell %s", cell)
+		}
+	}
+	// Empty values have no cell at all.
+	for _, reference := range []string{`r="E2"`, `r="D3"`} {
+		if strings.Contains(sheet, reference) {
+			t.Errorf("worksheet has a cell %s for an empty value", reference)
+		}
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(index); got != want {
+			t.Errorf("columnName(%d): got %s, want %s", index, got, want)
+		}
+	}
+}
+
+func TestNewWriterRejectsUnknownFormats(t *testing.T) {
+	if _, err := NewWriter("pdf", io.Discard); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("got %v, want ErrUnsupportedFormat", err)
+	}
+}
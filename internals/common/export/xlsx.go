@@ -0,0 +1,123 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+// The fixed parts of a workbook with a single worksheet.
+// Only the worksheet itself depends on the exported rows.
+var xlsxStaticParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// xlsxWriter writes rows into an Office Open XML workbook.
+// The workbook is a zip archive, and the worksheet is its last entry so rows can be streamed into it
+// as they come. Strings are written inline rather than into a shared strings table for the same reason.
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	row     int
+}
+
+func newXLSXWriter(w io.Writer) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxStaticParts {
+		entry, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(entry, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(entry)
+	sheet.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return &xlsxWriter{archive: archive, sheet: sheet}, nil
+}
+
+func (xw *xlsxWriter) WriteHeader(columns []string) error {
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		values[i] = column
+	}
+	return xw.WriteRow(values)
+}
+
+func (xw *xlsxWriter) WriteRow(values []interface{}) error {
+	xw.row++
+	rowNumber := strconv.Itoa(xw.row)
+	xw.sheet.WriteString(`<row r="` + rowNumber + `">`)
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		if t, ok := value.(*time.Time); ok && t == nil {
+			continue
+		}
+
+		reference := columnName(i) + rowNumber
+		switch v := value.(type) {
+		case bool:
+			cell := "0"
+			if v {
+				cell = "1"
+			}
+			xw.sheet.WriteString(`<c r="` + reference + `" t="b"><v>` + cell + `</v></c>`)
+		case int, int32, int64, uint, uint32, uint64, float32, float64:
+			xw.sheet.WriteString(`<c r="` + reference + `"><v>` + formatValue(v) + `</v></c>`)
+		default:
+			xw.sheet.WriteString(`<c r="` + reference + `" t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(xw.sheet, []byte(formatValue(v))); err != nil {
+				return err
+			}
+			xw.sheet.WriteString(`</t></is></c>`)
+		}
+	}
+	_, err := xw.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (xw *xlsxWriter) Close() error {
+	xw.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+	return xw.archive.Close()
+}
+
+// columnName converts a zero based column index into its spreadsheet name, e.g. 0 to "A" and 27 to "AB".
+func columnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,198 @@
+package history
+
+import (
+	"backendService/internals/common/privacy"
+	"backendService/internals/common/repository"
+	"backendService/internals/common/request"
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// tableName is the table change records are appended to.
+const tableName = "change_history"
+
+// ignoredColumns are left out of recorded changes: the internal ID never reaches clients,
+// and the update time changes along with every other column.
+var ignoredColumns = map[string]bool{"id": true, "updated_at": true}
+
+// ChangeRecord records how a write changed a single record: who made it, on which request, and the columns
+// it changed. Records are only ever appended, so the history of an entity can be told from them.
+type ChangeRecord struct {
+	ID             uint64                 `json:"-" gorm:"primary_key"`
+	Entity         string                 `json:"entity" gorm:"not null;index:idx_change_history_entity"`
+	EntityId       string                 `json:"entityId" gorm:"not null;index:idx_change_history_entity"`
+	Action         string                 `json:"action" gorm:"not null"`
+	ActorId        *string                `json:"actorId"`
+	ImpersonatorId *string                `json:"impersonatorId,omitempty"`
+	RequestId      *string                `json:"requestId"`
+	Changes        map[string]FieldChange `json:"changes" gorm:"serializer:json"`
+	CreatedAt      time.Time              `json:"createdAt" gorm:"not null"`
+}
+
+// FieldChange is the change of a single column, holding its values as JSON. The values of redacted
+// columns, such as password hashes or erased personal data, are left out.
+type FieldChange struct {
+	Before   json.RawMessage `json:"before,omitempty"`
+	After    json.RawMessage `json:"after,omitempty"`
+	Redacted bool            `json:"redacted,omitempty"`
+}
+
+// ChangePage is a single page of change records.
+type ChangePage = repository.PageResult[ChangeRecord]
+
+// Recorder returns a change hook appending the changes made by the writes of a repository to the history,
+// for models opting in with WithChangeHook. The values of the redacted columns, such as secrets or internal IDs,
+// are never recorded, only the fact that they changed. Erasures and permanent deletions are recorded with every value redacted,
+// so that the history does not keep the data they removed.
+func Recorder(redactedColumns ...string) repository.ChangeHook {
+	redacted := make(map[string]bool, len(redactedColumns))
+	for _, column := range redactedColumns {
+		redacted[column] = true
+	}
+
+	return func(tx *gorm.DB, changes []repository.Change) error {
+		ctx := tx.Statement.Context
+		var actorId, impersonatorId, requestId *string
+		if actor, ok := request.CurrentActor(ctx); ok {
+			actorId = optional(actor.UserId)
+			impersonatorId = optional(actor.ImpersonatorId)
+		}
+		requestId = optional(request.ID(ctx))
+
+		records := make([]ChangeRecord, 0, len(changes))
+		now := time.Now()
+		for _, change := range changes {
+			fields, err := diff(change, redacted)
+			if err != nil {
+				return err
+			}
+			if len(fields) == 0 {
+				continue
+			}
+			records = append(records, ChangeRecord{
+				Entity:         change.Table,
+				EntityId:       change.EntityId,
+				Action:         change.Action,
+				ActorId:        actorId,
+				ImpersonatorId: impersonatorId,
+				RequestId:      requestId,
+				Changes:        fields,
+				CreatedAt:      now,
+			})
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		return tx.Session(&gorm.Session{NewDB: true}).Table(tableName).Create(&records).Error
+	}
+}
+
+// diff returns the columns whose value differs before and after the change.
+func diff(change repository.Change, redacted map[string]bool) (map[string]FieldChange, error) {
+	columns := make(map[string]bool, len(change.Before)+len(change.After))
+	for column := range change.Before {
+		columns[column] = true
+	}
+	for column := range change.After {
+		columns[column] = true
+	}
+
+	fields := make(map[string]FieldChange)
+	for column := range columns {
+		if ignoredColumns[column] {
+			continue
+		}
+		before, err := json.Marshal(change.Before[column])
+		if err != nil {
+			return nil, err
+		}
+		after, err := json.Marshal(change.After[column])
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(before, after) {
+			continue
+		}
+		if redacted[column] || change.Action == repository.ActionErase || change.Action == repository.ActionHardDelete {
+			fields[column] = FieldChange{Redacted: true}
+			continue
+		}
+		fields[column] = FieldChange{Before: before, After: after}
+	}
+	return fields, nil
+}
+
+// optional returns a pointer to value, or nil when it is empty.
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// HistoryService reads the history of the entities whose repositories record their changes with Recorder.
+type HistoryService struct {
+	historyRepository *repository.BaseRepository[ChangeRecord]
+}
+
+// NewHistoryService creates a new instance of HistoryService using the given database connection.
+func NewHistoryService(db *gorm.DB) *HistoryService {
+	return &HistoryService{
+		historyRepository: repository.NewBaseRepository[ChangeRecord](db, tableName),
+	}
+}
+
+// FindByEntity returns a page of the changes made to the entity, newest first. entity is the table
+// of the entity, and entityId its public ID, or its internal ID when it has none.
+func (hs *HistoryService) FindByEntity(ctx context.Context, entity string, entityId string, page, pageSize int) (*ChangePage, error) {
+	return hs.historyRepository.WithContext(ctx).FindPage(repository.PageRequest{
+		Page:     page,
+		PageSize: pageSize,
+		Sort:     []repository.SortOrder{{Column: "id", Desc: true}},
+		Scopes:   []repository.Scope{entityScope(entity, entityId)},
+	})
+}
+
+// entityScope selects the change records of a single entity.
+func entityScope(entity string, entityId string) repository.Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("entity = ? AND entity_id = ?", entity, entityId)
+	}
+}
+
+// RegisterPersonalData registers the history of users with the personal data registry.
+// Data exports include every change made to the user. Erasure keeps the records, as the history must stay
+// complete, but redacts the values they hold, leaving which columns changed, when and by whom.
+func (hs *HistoryService) RegisterPersonalData(registry *privacy.Registry) {
+	registry.RegisterSection("history", func(ctx context.Context, subject privacy.Subject) (interface{}, error) {
+		var records []ChangeRecord
+		err := hs.historyRepository.Db.WithContext(ctx).
+			Scopes(entityScope("users", subject.UserId)).
+			Order("id").
+			Find(&records).Error
+		return records, err
+	})
+	registry.RegisterEraser("history", func(ctx context.Context, subject privacy.Subject) error {
+		scopes := []repository.Scope{entityScope("users", subject.UserId)}
+		return hs.historyRepository.WithContext(ctx).FindInBatches(scopes, 100, func(batch []ChangeRecord) error {
+			for _, record := range batch {
+				for column := range record.Changes {
+					record.Changes[column] = FieldChange{Redacted: true}
+				}
+				changes, err := json.Marshal(record.Changes)
+				if err != nil {
+					return err
+				}
+				err = hs.historyRepository.Db.WithContext(ctx).Where("id = ?", record.ID).Update("changes", string(changes)).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
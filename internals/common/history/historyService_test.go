@@ -0,0 +1,156 @@
+package history
+
+import (
+	"backendService/internals/common/privacy"
+	"backendService/internals/common/repository"
+	"backendService/internals/common/request"
+	"backendService/internals/setup/database/databasetest"
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// account is a model recording its changes, with a secret column.
+type account struct {
+	repository.BaseModel
+	Code     string `gorm:"uniqueIndex"`
+	Name     string
+	Password string
+}
+
+// newTestHistory returns a repository of accounts recording their changes with the password redacted,
+// and the history service reading them.
+func newTestHistory(t *testing.T) (*repository.BaseRepository[account], *HistoryService) {
+	t.Helper()
+	db := databasetest.Open(t)
+	if err := db.AutoMigrate(&account{}); err != nil {
+		t.Fatalf("migrating accounts: %v", err)
+	}
+	accounts := repository.NewBaseRepository[account](db, "accounts").WithPublicID("code").WithChangeHook(Recorder("password"))
+	return accounts, NewHistoryService(db)
+}
+
+// findHistory returns the changes made to the entity, newest first.
+func findHistory(t *testing.T, hs *HistoryService, entity string, entityId string) []ChangeRecord {
+	t.Helper()
+	page, err := hs.FindByEntity(context.Background(), entity, entityId, 1, 50)
+	if err != nil {
+		t.Fatalf("FindByEntity: %v", err)
+	}
+	return page.Items
+}
+
+func TestRecorder(t *testing.T) {
+	accounts, hs := newTestHistory(t)
+	ctx := request.WithID(request.WithActor(context.Background(), request.Actor{UserId: "ada", ImpersonatorId: "alan"}), "req-1")
+
+	created, err := accounts.WithContext(ctx).Create(&account{Code: "A1", Name: "alpha", Password: "hash-1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := accounts.Update(map[string]interface{}{"id": created.ID}, map[string]interface{}{"name": "beta", "password": "hash-2"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	// Writes leaving every column as it was are not recorded
+	if err := accounts.Update(map[string]interface{}{"id": created.ID}, map[string]interface{}{"name": "beta"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	records := findHistory(t, hs, "accounts", "A1")
+	if len(records) != 2 {
+		t.Fatalf("got %d change records, want 2", len(records))
+	}
+	update, create := records[0], records[1]
+	if create.Action != repository.ActionCreate || update.Action != repository.ActionUpdate {
+		t.Errorf("got actions %s then %s, want create then update", create.Action, update.Action)
+	}
+	if create.ActorId == nil || *create.ActorId != "ada" || create.ImpersonatorId == nil || *create.ImpersonatorId != "alan" {
+		t.Errorf("got actor %v impersonated by %v, want ada impersonated by alan", create.ActorId, create.ImpersonatorId)
+	}
+	if create.RequestId == nil || *create.RequestId != "req-1" {
+		t.Errorf("got request %v, want req-1", create.RequestId)
+	}
+	if update.ActorId != nil || update.RequestId != nil {
+		t.Errorf("got actor %v and request %v outside of a request, want none", update.ActorId, update.RequestId)
+	}
+	if _, ok := create.Changes["id"]; ok {
+		t.Error("got the internal ID recorded, want it left out")
+	}
+	if name := update.Changes["name"]; string(name.Before) != `"alpha"` || string(name.After) != `"beta"` {
+		t.Errorf("got name from %s to %s, want from \"alpha\" to \"beta\"", name.Before, name.After)
+	}
+	if password := update.Changes["password"]; !password.Redacted || password.Before != nil || password.After != nil {
+		t.Errorf("got password change %+v, want it redacted", password)
+	}
+	if len(findHistory(t, hs, "accounts", "B2")) != 0 {
+		t.Error("got changes of another account, want none")
+	}
+}
+
+func TestRecorderRedactsRemovedData(t *testing.T) {
+	accounts, hs := newTestHistory(t)
+	created, err := accounts.Create(&account{Code: "A1", Name: "alpha"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	err = accounts.Track(accounts.Db.Session(&gorm.Session{}), repository.ActionErase, map[string]interface{}{"id": created.ID}, func(tx *gorm.DB) error {
+		return tx.Model(&account{}).Where("id = ?", created.ID).Update("name", "").Error
+	})
+	if err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+	if err := accounts.HardDelete(created.ID); err != nil {
+		t.Fatalf("HardDelete: %v", err)
+	}
+
+	records := findHistory(t, hs, "accounts", "A1")
+	if len(records) != 3 {
+		t.Fatalf("got %d change records, want 3", len(records))
+	}
+	for _, record := range records[:2] {
+		if len(record.Changes) == 0 {
+			t.Errorf("got no changes on %s, want the changed columns", record.Action)
+		}
+		for column, change := range record.Changes {
+			if !change.Redacted || change.Before != nil || change.After != nil {
+				t.Errorf("got %s of %s recorded as %+v, want it redacted", column, record.Action, change)
+			}
+		}
+	}
+}
+
+func TestHistoryEraser(t *testing.T) {
+	_, hs := newTestHistory(t)
+	db := hs.historyRepository.Db
+	for _, userId := range []string{"ada", "grace"} {
+		record := ChangeRecord{
+			Entity:    "users",
+			EntityId:  userId,
+			Action:    repository.ActionUpdate,
+			Changes:   map[string]FieldChange{"first_name": {Before: []byte(`"Ada"`), After: []byte(`"Grace"`)}},
+			CreatedAt: time.Now(),
+		}
+		if err := db.Session(&gorm.Session{}).Create(&record).Error; err != nil {
+			t.Fatalf("recording a change: %v", err)
+		}
+	}
+	registry := privacy.NewRegistry()
+	hs.RegisterPersonalData(registry)
+
+	if err := registry.Erase(context.Background(), privacy.Subject{UserId: "ada"}); err != nil {
+		t.Fatalf("Erase: %v", err)
+	}
+	erased := findHistory(t, hs, "users", "ada")
+	if len(erased) != 1 {
+		t.Fatalf("got %d change records, want the record kept", len(erased))
+	}
+	if change := erased[0].Changes["first_name"]; !change.Redacted || change.Before != nil || change.After != nil {
+		t.Errorf("got %+v, want the erased change redacted", change)
+	}
+	if kept := findHistory(t, hs, "users", "grace"); len(kept) != 1 || kept[0].Changes["first_name"].Redacted {
+		t.Errorf("got %+v, want the changes of other users kept", kept)
+	}
+}
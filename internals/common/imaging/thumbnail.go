@@ -0,0 +1,64 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// SquareCrop returns the largest centered square of the image as an RGBA image.
+// Converting once up front lets several thumbnails be produced from the same pixels cheaply.
+func SquareCrop(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	origin := image.Point{
+		X: bounds.Min.X + (bounds.Dx()-side)/2,
+		Y: bounds.Min.Y + (bounds.Dy()-side)/2,
+	}
+
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), src, origin, draw.Src)
+	return square
+}
+
+// Thumbnail scales a square image down to size x size pixels, averaging the source pixels
+// covered by every target pixel. Images are never scaled up, so a smaller source keeps its size.
+// Transparent areas are flattened onto white, making the result suitable for JPEG encoding.
+func Thumbnail(square *image.RGBA, size int) *image.RGBA {
+	side := square.Bounds().Dx()
+	if size > side {
+		size = side
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		y0, y1 := y*side/size, (y+1)*side/size
+		for x := 0; x < size; x++ {
+			x0, x1 := x*side/size, (x+1)*side/size
+
+			var r, g, b, a, count uint32
+			for sy := y0; sy < y1; sy++ {
+				row := square.Pix[sy*square.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					pixel := row[sx*4 : sx*4+4]
+					r += uint32(pixel[0])
+					g += uint32(pixel[1])
+					b += uint32(pixel[2])
+					a += uint32(pixel[3])
+					count++
+				}
+			}
+
+			// RGBA pixels are alpha premultiplied, so adding the missing alpha as white composites onto white
+			white := 255 - a/count
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8(r/count + white)
+			dst.Pix[offset+1] = uint8(g/count + white)
+			dst.Pix[offset+2] = uint8(b/count + white)
+			dst.Pix[offset+3] = 255
+		}
+	}
+	return dst
+}
@@ -0,0 +1,46 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSquareCrop(t *testing.T) {
+	src := image.NewRGBA(image.Rect(10, 10, 40, 20))
+	src.Set(24, 15, color.RGBA{R: 255, A: 255})
+
+	square := SquareCrop(src)
+	if square.Bounds() != image.Rect(0, 0, 10, 10) {
+		t.Fatalf("got bounds %v, want a 10x10 square", square.Bounds())
+	}
+	// The square is centered, so it starts 10 pixels into the 30 pixel wide source.
+	if got := square.RGBAAt(4, 5); got.R != 255 {
+		t.Errorf("got %v at the center, want the red pixel", got)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	square := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	// The left half is opaque blue and the right half is left transparent.
+	for x := 0; x < 2; x++ {
+		for y := 0; y < 4; y++ {
+			square.SetRGBA(x, y, color.RGBA{B: 200, A: 255})
+		}
+	}
+
+	thumbnail := Thumbnail(square, 2)
+	if thumbnail.Bounds() != image.Rect(0, 0, 2, 2) {
+		t.Fatalf("got bounds %v, want 2x2", thumbnail.Bounds())
+	}
+	if got := thumbnail.RGBAAt(0, 0); got != (color.RGBA{B: 200, A: 255}) {
+		t.Errorf("got %v on the opaque side, want the averaged blue", got)
+	}
+	if got := thumbnail.RGBAAt(1, 1); got != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("got %v on the transparent side, want white", got)
+	}
+
+	if got := Thumbnail(square, 64).Bounds(); got != square.Bounds() {
+		t.Errorf("got bounds %v when asked to scale up, want the source size", got)
+	}
+}
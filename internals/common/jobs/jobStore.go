@@ -0,0 +1,195 @@
+package jobs
+
+import (
+	"backendService/internals/common/cache"
+	"backendService/internals/common/logger"
+	"backendService/internals/setup/config"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/oklog/ulid/v2"
+)
+
+const jobKeyPrefix = "job:"
+
+// Statuses a job goes through. A job ends either completed or failed.
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Job describes a background job producing a file, such as a large export.
+// OwnerId is the user who started the job, and the only one allowed to read it.
+// Processed is a job specific progress counter, e.g. the number of rows written so far.
+type Job struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	OwnerId     string     `json:"-"`
+	Status      string     `json:"status"`
+	FileName    string     `json:"fileName"`
+	ContentType string     `json:"contentType"`
+	Processed   int64      `json:"processed"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// storedJob is how a job is kept in the cache. Job hides its owner from API responses,
+// so it cannot be serialized as is.
+type storedJob struct {
+	Job
+	OwnerId string `json:"ownerId"`
+}
+
+// FileJobFunc produces the file of a job into w. It may call progress to publish how far it got.
+type FileJobFunc func(ctx context.Context, w io.Writer, progress func(processed int64)) error
+
+// JobStore runs file producing jobs in the background and keeps track of them in the cache.
+// Job files are written to the configured jobs directory and kept for the retention period.
+// Jobs do not survive a restart: a job running when the server stops stays running until it expires.
+type JobStore struct {
+	cacheService *cache.CacheService
+}
+
+// NewJobStore creates a new JobStore backed by the given CacheService.
+func NewJobStore(cacheService *cache.CacheService) *JobStore {
+	return &JobStore{cacheService: cacheService}
+}
+
+// StartFileJob starts a job of the given type on behalf of ownerId, running fn in the background.
+// fileName and contentType describe the produced file to whoever downloads it.
+func (js *JobStore) StartFileJob(ctx context.Context, jobType string, ownerId string, fileName string, contentType string, fn FileJobFunc) (*Job, error) {
+	if err := os.MkdirAll(directory(), 0o750); err != nil {
+		return nil, err
+	}
+	js.pruneFiles()
+
+	job := &Job{
+		ID:          ulid.Make().String(),
+		Type:        jobType,
+		OwnerId:     ownerId,
+		Status:      StatusRunning,
+		FileName:    fileName,
+		ContentType: contentType,
+		CreatedAt:   time.Now(),
+	}
+	if err := js.save(ctx, job); err != nil {
+		return nil, err
+	}
+
+	go js.run(*job, fn)
+	return job, nil
+}
+
+// run executes a job and records its outcome. The file is written under a temporary name
+// and only renamed once complete, so a failed job never leaves a partial file behind.
+func (js *JobStore) run(job Job, fn FileJobFunc) {
+	ctx := context.Background()
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+
+		partPath := js.FilePath(&job) + ".part"
+		file, err := os.OpenFile(partPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(partPath)
+
+		err = fn(ctx, file, func(processed int64) {
+			job.Processed = processed
+			if err := js.save(ctx, &job); err != nil {
+				logger.Error("jobs", "JobStore", "run", "failed to save job progress", job.ID, err)
+			}
+		})
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		return os.Rename(partPath, js.FilePath(&job))
+	}()
+
+	completedAt := time.Now()
+	job.CompletedAt = &completedAt
+	job.Status = StatusCompleted
+	if err != nil {
+		logger.Error("jobs", "JobStore", "run", "job failed", job.ID, job.Type, err)
+		job.Status = StatusFailed
+		job.Error = "the job could not be completed"
+	}
+	if err := js.save(ctx, &job); err != nil {
+		logger.Error("jobs", "JobStore", "run", "failed to save job", job.ID, err)
+	}
+}
+
+// Get returns the job with the given ID.
+// It returns nil without an error when the job is unknown or has expired.
+func (js *JobStore) Get(ctx context.Context, id string) (*Job, error) {
+	var stored storedJob
+	err := js.cacheService.Get(ctx, jobKeyPrefix+id, &stored)
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+	stored.Job.OwnerId = stored.OwnerId
+	return &stored.Job, nil
+}
+
+// FilePath returns where the file produced by the job is stored.
+func (js *JobStore) FilePath(job *Job) string {
+	return filepath.Join(directory(), job.ID)
+}
+
+// save stores the job in the cache for the retention period.
+func (js *JobStore) save(ctx context.Context, job *Job) error {
+	return js.cacheService.Set(ctx, jobKeyPrefix+job.ID, storedJob{Job: *job, OwnerId: job.OwnerId}, retention())
+}
+
+// pruneFiles removes job files older than the retention period. Failures are only logged,
+// as a leftover file does no harm besides using disk space.
+func (js *JobStore) pruneFiles() {
+	entries, err := os.ReadDir(directory())
+	if err != nil {
+		logger.Error("jobs", "JobStore", "pruneFiles", "failed to list job files", err)
+		return
+	}
+	cutoff := time.Now().Add(-retention())
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(directory(), entry.Name())); err != nil {
+			logger.Error("jobs", "JobStore", "pruneFiles", "failed to remove job file", entry.Name(), err)
+		}
+	}
+}
+
+// directory returns the configured jobs directory.
+func directory() string {
+	if config.Config.Jobs.Directory == "" {
+		return filepath.Join("storage", "jobs")
+	}
+	return config.Config.Jobs.Directory
+}
+
+// retention returns how long jobs and their files are kept, defaulting to one day.
+func retention() time.Duration {
+	if config.Config.Jobs.RetentionHours <= 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(config.Config.Jobs.RetentionHours) * time.Hour
+}
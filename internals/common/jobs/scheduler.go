@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"backendService/internals/common/logger"
+	"context"
+	"fmt"
+	"time"
+)
+
+// TaskFunc is a unit of periodic maintenance work, such as erasing accounts whose grace period ended.
+type TaskFunc func(ctx context.Context) error
+
+// Schedule runs fn every interval in the background until the returned stop function is called.
+// A failing or panicking run is logged and does not stop later runs. Runs never overlap, and every
+// instance of the server runs the task, so fn must tolerate running concurrently with other instances.
+func Schedule(name string, interval time.Duration, fn TaskFunc) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := runTask(ctx, fn); err != nil {
+					logger.Error("jobs", "Scheduler", "Schedule", "task failed", name, err)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// runTask runs a single task, turning a panic into an error.
+func runTask(ctx context.Context, fn TaskFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,25 @@
+package notification
+
+import (
+	"backendService/internals/common/logger"
+	"context"
+)
+
+// Notifier delivers short messages to a user's email address or mobile number.
+type Notifier interface {
+	Notify(ctx context.Context, recipient string, message string) error
+}
+
+// LogNotifier writes messages to the application log instead of delivering them.
+// It stands in for a real email or SMS provider, the same way one-time passwords are delivered today.
+type LogNotifier struct{}
+
+// NewLogNotifier creates a new LogNotifier.
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (ln *LogNotifier) Notify(ctx context.Context, recipient string, message string) error {
+	logger.Info("notification", "LogNotifier", "Notify", "Sending message to "+recipient+": "+message)
+	return nil
+}
@@ -0,0 +1,178 @@
+package privacy
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+)
+
+// Subject identifies the user whose personal data is exported or erased.
+// ID is the internal database ID, UserId the public ID other modules refer to the user by.
+type Subject struct {
+	ID     uint64
+	UserId string
+}
+
+// File is a file stored on behalf of a user, such as an uploaded picture, included as is in data exports.
+type File struct {
+	Name    string
+	Content []byte
+}
+
+// SectionFunc returns the personal data a module holds about the subject.
+// The value is serialized as JSON, and a nil value leaves the section out of the archive.
+type SectionFunc func(ctx context.Context, subject Subject) (interface{}, error)
+
+// FilesFunc returns the files a module stores on behalf of the subject.
+type FilesFunc func(ctx context.Context, subject Subject) ([]File, error)
+
+// EraseFunc removes or anonymizes the personal data a module holds about the subject.
+// It must be safe to call again for a subject already erased, as a failed erasure is retried.
+type EraseFunc func(ctx context.Context, subject Subject) error
+
+type section struct {
+	name    string
+	collect SectionFunc
+	files   FilesFunc
+}
+
+type eraser struct {
+	name  string
+	erase EraseFunc
+}
+
+// Registry knows where every module keeps personal data, so a user's data can be exported
+// or erased as a whole without the user module knowing about the other modules.
+// Modules register their sections and erasers when they are initialized.
+type Registry struct {
+	mu       sync.RWMutex
+	sections []section
+	erasers  []eraser
+}
+
+// DefaultRegistry is the registry modules register their personal data with.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// RegisterSection adds a section to data exports. It is archived as <name>.json.
+func (r *Registry) RegisterSection(name string, fn SectionFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sections = append(r.sections, section{name: name, collect: fn})
+}
+
+// RegisterFiles adds files to data exports. They are archived in the <name> folder.
+func (r *Registry) RegisterFiles(name string, fn FilesFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sections = append(r.sections, section{name: name, files: fn})
+}
+
+// RegisterEraser adds a step to erasures. Steps run in the order they were registered.
+func (r *Registry) RegisterEraser(name string, fn EraseFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.erasers = append(r.erasers, eraser{name: name, erase: fn})
+}
+
+// manifest describes a data export archive. It is always the first entry of the archive.
+type manifest struct {
+	UserId      string    `json:"userId"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	Sections    []string  `json:"sections"`
+}
+
+// WriteArchive collects every registered section about the subject into a zip archive written to w.
+// The archive holds a manifest.json followed by one JSON file or folder of files per section.
+func (r *Registry) WriteArchive(ctx context.Context, subject Subject, w io.Writer) error {
+	r.mu.RLock()
+	sections := append([]section(nil), r.sections...)
+	r.mu.RUnlock()
+
+	// Sections are collected before anything is written, so the manifest can list the ones present
+	type collected struct {
+		name  string
+		value interface{}
+		files []File
+	}
+	var contents []collected
+	names := []string{}
+	for _, s := range sections {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		c := collected{name: s.name}
+		var err error
+		if s.files != nil {
+			c.files, err = s.files(ctx, subject)
+		} else {
+			c.value, err = s.collect(ctx, subject)
+		}
+		if err != nil {
+			return fmt.Errorf("collecting %s: %w", s.name, err)
+		}
+		if c.value == nil && len(c.files) == 0 {
+			continue
+		}
+		contents = append(contents, c)
+		names = append(names, s.name)
+	}
+
+	archive := zip.NewWriter(w)
+	now := time.Now()
+	if err := writeJSON(archive, "manifest.json", now, manifest{UserId: subject.UserId, GeneratedAt: now, Sections: names}); err != nil {
+		return err
+	}
+	for _, c := range contents {
+		if c.files == nil {
+			if err := writeJSON(archive, c.name+".json", now, c.value); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, file := range c.files {
+			entry, err := archive.CreateHeader(&zip.FileHeader{Name: c.name + "/" + path.Base(file.Name), Method: zip.Deflate, Modified: now})
+			if err != nil {
+				return err
+			}
+			if _, err := entry.Write(file.Content); err != nil {
+				return err
+			}
+		}
+	}
+	return archive.Close()
+}
+
+// Erase runs every registered eraser for the subject, stopping at the first failure.
+func (r *Registry) Erase(ctx context.Context, subject Subject) error {
+	r.mu.RLock()
+	erasers := append([]eraser(nil), r.erasers...)
+	r.mu.RUnlock()
+
+	for _, e := range erasers {
+		if err := e.erase(ctx, subject); err != nil {
+			return fmt.Errorf("erasing %s: %w", e.name, err)
+		}
+	}
+	return nil
+}
+
+// writeJSON adds an indented JSON entry to the archive.
+func writeJSON(archive *zip.Writer, name string, modified time.Time, value interface{}) error {
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
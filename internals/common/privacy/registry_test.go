@@ -0,0 +1,86 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestWriteArchive(t *testing.T) {
+	registry := NewRegistry()
+	registry.RegisterSection("profile", func(ctx context.Context, subject Subject) (interface{}, error) {
+		return map[string]interface{}{"id": subject.UserId}, nil
+	})
+	registry.RegisterSection("settings", func(ctx context.Context, subject Subject) (interface{}, error) {
+		return nil, nil
+	})
+	registry.RegisterFiles("avatar", func(ctx context.Context, subject Subject) ([]File, error) {
+		return []File{{Name: "avatars/user/avatar.jpg", Content: []byte("jpeg")}}, nil
+	})
+
+	var output bytes.Buffer
+	if err := registry.WriteArchive(context.Background(), Subject{ID: 1, UserId: "user-1"}, &output); err != nil {
+		t.Fatalf("WriteArchive: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("reading the archive: %v", err)
+	}
+
+	entries := map[string]string{}
+	var names []string
+	for _, file := range archive.File {
+		content, _ := file.Open()
+		data, _ := io.ReadAll(content)
+		entries[file.Name] = string(data)
+		names = append(names, file.Name)
+	}
+	if len(names) != 3 || names[0] != "manifest.json" || names[1] != "profile.json" || names[2] != "avatar/avatar.jpg" {
+		t.Fatalf("got entries %v, want the manifest, the profile and the avatar", names)
+	}
+	var m manifest
+	if err := json.Unmarshal([]byte(entries["manifest.json"]), &m); err != nil {
+		t.Fatalf("decoding the manifest: %v", err)
+	}
+	// Sections without data are left out.
+	if m.UserId != "user-1" || len(m.Sections) != 2 || m.Sections[0] != "profile" || m.Sections[1] != "avatar" {
+		t.Errorf("got manifest %+v, want the profile and avatar sections of user-1", m)
+	}
+	if entries["avatar/avatar.jpg"] != "jpeg" {
+		t.Errorf("got avatar %q", entries["avatar/avatar.jpg"])
+	}
+
+	failing := errors.New("database is down")
+	registry.RegisterSection("orders", func(ctx context.Context, subject Subject) (interface{}, error) {
+		return nil, failing
+	})
+	if err := registry.WriteArchive(context.Background(), Subject{ID: 1}, io.Discard); !errors.Is(err, failing) {
+		t.Errorf("writing an archive with a failing section: got %v, want the section error", err)
+	}
+}
+
+func TestErase(t *testing.T) {
+	registry := NewRegistry()
+	var erased []string
+	step := func(name string, err error) EraseFunc {
+		return func(ctx context.Context, subject Subject) error {
+			erased = append(erased, name)
+			return err
+		}
+	}
+	failing := errors.New("storage is down")
+	registry.RegisterEraser("settings", step("settings", nil))
+	registry.RegisterEraser("avatar", step("avatar", failing))
+	registry.RegisterEraser("sessions", step("sessions", nil))
+
+	if err := registry.Erase(context.Background(), Subject{ID: 1}); !errors.Is(err, failing) {
+		t.Errorf("got %v, want the avatar error", err)
+	}
+	if len(erased) != 2 || erased[0] != "settings" || erased[1] != "avatar" {
+		t.Errorf("got steps %v, want the steps up to the failing one, in order", erased)
+	}
+}
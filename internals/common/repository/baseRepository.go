@@ -2,31 +2,91 @@ package repository
 
 import (
 	"backendService/internals/setup/database"
+	"context"
 	"errors"
 	"reflect"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-const defaultPageSize = 10
+const (
+	defaultPageSize = 10
+	// MaxPageSize is the largest page size a paginated query may request.
+	MaxPageSize = 100
+)
+
+// SortOrder describes ordering by a single column.
+type SortOrder struct {
+	Column string
+	Desc   bool
+}
+
+// Scope is a GORM scope used to add conditions to a query.
+type Scope = func(db *gorm.DB) *gorm.DB
 
+// PageRequest describes a paginated query: the page to fetch, its size,
+// the ordering to apply and any scopes that narrow down the result set.
+type PageRequest struct {
+	Page     int
+	PageSize int
+	Sort     []SortOrder
+	Scopes   []Scope
+}
+
+// PageResult holds a single page of models together with the pagination metadata.
+type PageResult[T any] struct {
+	Items      []T
+	Total      int64
+	Page       int
+	PageSize   int
+	TotalPages int
+}
 
 // BaseModel represents the base model for all entities in the repository.
 type BaseModel struct {
-	ID        uint64         `gorm:"primary_key" json:"id"`
+	ID        uint64         `gorm:"primary_key" json:"-"` // ID is internal only; models expose a public ID to clients instead
 	CreatedAt time.Time      `gorm:"not null" json:"createdAt"`
 	UpdatedAt time.Time      `gorm:"not null" json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deletedAt"`
 	IsDeleted bool           `gorm:"boolean" json:"isDeleted" default:"false"`
 }
 
+// baseModelType is the type of BaseModel, embedded by most models.
+var baseModelType = reflect.TypeOf(BaseModel{})
+
 // BaseRepository is a generic repository that provides common database operations.
 type BaseRepository[T any] struct {
 	Db        *gorm.DB
 	tableName string
+	keyColumn string // keyColumn is the unique column used as a tie-breaker by keyset pagination
+
+	publicIDColumn string // publicIDColumn is the column holding the identifier exposed to clients
+
+	search *searchIndex // search describes the columns searched by Search, when enabled with WithSearch
+
+	baseModel  []int         // baseModel is the index of the embedded BaseModel within the model, reset by creates
+	softDelete bool          // softDelete is set when the model embeds BaseModel and can be soft-deleted
+	deleted    deletedFilter // deleted selects which rows reads see with respect to soft deletes
+
+	filterable map[string]bool // filterable restricts the columns filters may use, when set with WithFilterable
+
+	versioned bool // versioned is set when the model embeds Versioned and its updates increment the version
+
+	changeHooks []ChangeHook // changeHooks are called with the changes made by writes, when added with WithChangeHook
 }
 
+// deletedFilter selects which rows the reads of a repository see with respect to soft deletes.
+type deletedFilter int
+
+const (
+	excludeDeleted deletedFilter = iota // excludeDeleted hides soft-deleted rows, the default
+	includeDeleted                      // includeDeleted sees soft-deleted rows along with the others
+	onlyDeleted                         // onlyDeleted sees soft-deleted rows only
+)
+
 // NewBaseRepository creates a new instance of the BaseRepository with the specified database connection and table name.
 // It returns a pointer to the created BaseRepository.
 // The type parameter T represents the model type that the repository will operate on.
@@ -35,38 +95,88 @@ func NewBaseRepository[T any](db *gorm.DB, tableName string) *BaseRepository[T]
 	repo := &BaseRepository[T]{
 		Db:        db,
 		tableName: tableName,
+		keyColumn: "id",
 	}
+	modelType := reflect.TypeOf(new(T)).Elem()
+	if field, ok := modelType.FieldByName(baseModelType.Name()); ok && field.Anonymous && field.Type == baseModelType {
+		repo.baseModel = field.Index
+	}
+	_, repo.softDelete = modelType.FieldByName("IsDeleted")
+	_, repo.versioned = modelType.FieldByName(versionField)
 
 	// Set table name
 	repo.Db = database.Db.Table(tableName)
 
 	return repo
 }
-func (r *BaseRepository[T]) Create(model *T) (*T, error) {
 
-	// Set the BaseModel fields in the input model
-	modelValue := reflect.ValueOf(model).Elem()
-	baseModelType := reflect.TypeOf(BaseModel{})
-	// Create a slice of reflect.StructField with the same length as the number of fields in baseModelType.
-	baseModelFields := make([]reflect.StructField, baseModelType.NumField())
+// WithPublicID configures the column holding the public identifier of the model, such as a ULID.
+// The column is used by FindByPublicID and replaces the internal ID in pagination cursors,
+// so the auto-increment ID never reaches clients.
+func (r *BaseRepository[T]) WithPublicID(column string) *BaseRepository[T] {
+	r.publicIDColumn = column
+	r.keyColumn = column
+	return r
+}
 
-	for i := 0; i < baseModelType.NumField(); i++ {
-		baseModelFields[i] = baseModelType.Field(i)
-	}
+// WithTx returns a copy of the repository running its queries inside the given transaction.
+// The repository itself is left unchanged and keeps running outside of it.
+func (r *BaseRepository[T]) WithTx(tx *Tx) *BaseRepository[T] {
+	bound := *r
+	bound.Db = tx.db.Table(r.tableName)
+	return &bound
+}
 
-	baseModelStructType := reflect.StructOf(baseModelFields)
+// WithContext returns a copy of the repository running its queries with the given context, so that they are
+// cancelled along with it, such as when the client of a request goes away or its deadline passes.
+func (r *BaseRepository[T]) WithContext(ctx context.Context) *BaseRepository[T] {
+	bound := *r
+	bound.Db = r.Db.WithContext(ctx)
+	return &bound
+}
 
-	for i := 0; i < modelValue.NumField(); i++ {
-		field := modelValue.Field(i)
-		fieldType := modelValue.Type().Field(i)
+// WithDeleted returns a copy of the repository whose reads also see soft-deleted rows,
+// such as when checking a unique value still held by a deleted row.
+func (r *BaseRepository[T]) WithDeleted() *BaseRepository[T] {
+	bound := *r
+	bound.deleted = includeDeleted
+	return &bound
+}
 
-		if fieldType.Anonymous && fieldType.Type == baseModelType {
-			field.Set(reflect.Zero(baseModelStructType))
-			break
-		}
+// OnlyDeleted returns a copy of the repository whose reads only see soft-deleted rows,
+// such as when listing what can be restored.
+func (r *BaseRepository[T]) OnlyDeleted() *BaseRepository[T] {
+	bound := *r
+	bound.deleted = onlyDeleted
+	return &bound
+}
+
+// deletedScope applies the soft delete filter of the repository to a read. A row is soft-deleted when
+// is_deleted is set; GORM's own deleted_at filter is turned off so that both columns can never disagree.
+// Models without BaseModel are never soft-deleted and are left unfiltered.
+func (r *BaseRepository[T]) deletedScope(db *gorm.DB) *gorm.DB {
+	if !r.softDelete {
+		return db
+	}
+	db = db.Unscoped()
+	switch r.deleted {
+	case includeDeleted:
+		return db
+	case onlyDeleted:
+		return db.Where("is_deleted = ?", true)
+	default:
+		return AllowNonDeletedRecords(db)
 	}
+}
+
+// Create inserts the model, resetting the BaseModel fields so that the database assigns them.
+func (r *BaseRepository[T]) Create(model *T) (*T, error) {
+	r.prepareCreate(model)
 
-	err := r.Db.Create(model).Error
+	session := r.Db.Session(&gorm.Session{})
+	err := r.track(session, ActionCreate, r.primaryKeyScope(model), func(tx *gorm.DB) error {
+		return tx.Create(model).Error
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -74,48 +184,118 @@ func (r *BaseRepository[T]) Create(model *T) (*T, error) {
 	return model, nil
 }
 
+// prepareCreate resets the BaseModel fields of a model about to be inserted, and starts versioned models at version 1.
+func (r *BaseRepository[T]) prepareCreate(model *T) {
+	modelValue := reflect.ValueOf(model).Elem()
+	if r.baseModel != nil {
+		modelValue.FieldByIndex(r.baseModel).Set(reflect.Zero(baseModelType))
+	}
+	if r.versioned {
+		modelValue.FieldByName(versionField).Set(reflect.ValueOf(Versioned{Version: 1}))
+	}
+}
+
+// Update applies update to the records matching filter. Like reads, it leaves soft-deleted records
+// untouched unless the repository was created with WithDeleted or OnlyDeleted.
+// The version of versioned models is incremented without being checked; use UpdateVersioned to check it.
 func (r *BaseRepository[T]) Update(filter any, update any) error {
+	update, err := r.versionedUpdate(update)
+	if err != nil {
+		return err
+	}
 	session := r.Db.Session(&gorm.Session{})
-	model := new(T)
-	return session.Model(model).Where(filter).Updates(update).Error
+	scope := func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(r.deletedScope).Where(filter)
+	}
+	return r.track(session, ActionUpdate, scope, func(tx *gorm.DB) error {
+		return tx.Model(new(T)).Scopes(scope).Updates(update).Error
+	})
 }
 
+// Delete soft deletes the record with the given ID, setting is_deleted and recording the time in deleted_at.
+// Deleting a record that does not exist or is already deleted does nothing.
 func (r *BaseRepository[T]) Delete(id uint64) error {
+	update, err := r.versionedUpdate(map[string]interface{}{
+		"deleted_at": time.Now(),
+		"is_deleted": true,
+	})
+	if err != nil {
+		return err
+	}
 	session := r.Db.Session(&gorm.Session{})
-	model := new(T)
+	scope := func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Where("id = ? AND is_deleted = ?", id, false)
+	}
+	return r.track(session, ActionDelete, scope, func(tx *gorm.DB) error {
+		return tx.Model(new(T)).Scopes(scope).Updates(update).Error
+	})
+}
 
-	err := session.First(model, id).Error
+// Restore reverts the soft delete of the record with the given ID.
+// It returns gorm.ErrRecordNotFound when no soft-deleted record has the given ID.
+func (r *BaseRepository[T]) Restore(id uint64) error {
+	update, err := r.versionedUpdate(map[string]interface{}{
+		"deleted_at": nil,
+		"is_deleted": false,
+	})
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil
-		}
 		return err
 	}
-
-	now := time.Now()
-	modelValue := reflect.ValueOf(model).Elem()
-
-	deletedAtField := modelValue.FieldByName("DeletedAt")
-	if deletedAtField.IsValid() && deletedAtField.CanSet() {
-		deletedAtField.Set(reflect.ValueOf(gorm.DeletedAt{Time: now}))
+	session := r.Db.Session(&gorm.Session{})
+	scope := func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Where("id = ? AND is_deleted = ?", id, true)
 	}
+	return r.track(session, ActionRestore, scope, func(tx *gorm.DB) error {
+		result := tx.Model(new(T)).Scopes(scope).Updates(update)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
 
-	isDeletedField := modelValue.FieldByName("IsDeleted")
-	if isDeletedField.IsValid() && isDeletedField.CanSet() {
-		isDeletedField.Set(reflect.ValueOf(true))
+// HardDelete permanently removes the record with the given ID, whether or not it is soft-deleted.
+func (r *BaseRepository[T]) HardDelete(id uint64) error {
+	session := r.Db.Session(&gorm.Session{})
+	scope := func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Where("id = ?", id)
 	}
-
-	return session.Save(model).Error
+	return r.track(session, ActionHardDelete, scope, func(tx *gorm.DB) error {
+		return tx.Scopes(scope).Delete(new(T)).Error
+	})
 }
 
 // FindByID retrieves a record from the database based on the given ID.
-// It uses the Unscoped method to include soft-deleted records.
+// Soft-deleted records are left out unless the repository was created with WithDeleted or OnlyDeleted.
 // The retrieved record is stored in the 'model' variable.
 // If an error occurs during the retrieval, it is returned.
 func (r *BaseRepository[T]) FindByID(id uint64) (*T, error) {
 	session := r.Db.Session(&gorm.Session{})
 	var model T
-	err := session.Scopes(AllowNonDeletedRecords).First(&model, id).Error
+	err := session.Scopes(r.deletedScope).First(&model, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// FindByPublicID retrieves a record by the public identifier column set with WithPublicID.
+// It returns nil without an error when no record matches.
+func (r *BaseRepository[T]) FindByPublicID(id any) (*T, error) {
+	if r.publicIDColumn == "" {
+		return nil, errors.New("public ID column is not configured for table " + r.tableName)
+	}
+
+	session := r.Db.Session(&gorm.Session{})
+	var model T
+	err := session.Scopes(r.deletedScope).Where(clause.Eq{Column: clause.Column{Name: r.publicIDColumn}, Value: id}).First(&model).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -138,7 +318,7 @@ func (r *BaseRepository[T]) FindAll(page, pageSize int) ([]T, error) {
 
 	var models []T
 
-	err := session.Scopes(paginateScope(page, pageSize), AllowNonDeletedRecords).Find(&models).Error
+	err := session.Scopes(paginateScope(page, pageSize), r.deletedScope).Find(&models).Error
 	if err != nil {
 		return nil, err
 	}
@@ -146,34 +326,32 @@ func (r *BaseRepository[T]) FindAll(page, pageSize int) ([]T, error) {
 	return models, nil
 }
 
-func (r *BaseRepository[T]) FindAllBy(conditions map[string]interface{}, page, pageSize int) ([]T, error) {
-	session := r.Db.Session(&gorm.Session{})
-	if pageSize <= 0 {
-		pageSize = defaultPageSize
+// FindAllBy retrieves a page of records matching the filter of the query, in the order of the query.
+// It returns ErrUnknownColumn when the query names a column that cannot be filtered or ordered on.
+func (r *BaseRepository[T]) FindAllBy(query Query) ([]T, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = defaultPageSize
 	}
 
+	session := r.Db.Session(&gorm.Session{})
 	var models []T
-	err := session.Scopes(paginateScope(page, pageSize), func(db *gorm.DB) *gorm.DB {
-		for key, value := range conditions {
-			db = db.Where(key, value)
-		}
-		return db.Unscoped()
-	}).Find(&models).Error
+	err := session.Scopes(r.deletedScope, r.FilterScope(query.Filter), r.orderScope(query.Sort), paginateScope(query.Page, query.PageSize)).
+		Find(&models).Error
 	if err != nil {
 		return nil, err
 	}
 	return models, nil
 }
 
-func (r *BaseRepository[T]) FindOneBy(conditions map[string]interface{}) (*T, error) {
+// FindOneBy retrieves the first record matching the filter.
+// It returns nil without an error when no record matches.
+func (r *BaseRepository[T]) FindOneBy(filter Filter) (*T, error) {
 	session := r.Db.Session(&gorm.Session{})
 	var model T
-	err := session.Scopes(func(db *gorm.DB) *gorm.DB {
-		for key, value := range conditions {
-			db = db.Where(key, value)
-		}
-		return db.Unscoped()
-	}).First(&model).Error
+	err := session.Scopes(r.deletedScope, r.FilterScope(filter)).First(&model).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -184,6 +362,111 @@ func (r *BaseRepository[T]) FindOneBy(conditions map[string]interface{}) (*T, er
 	return &model, nil
 }
 
+// FindPage retrieves a single page of models matching the request scopes.
+// It also counts the total number of matching models so callers can build page metadata.
+// The page size is clamped to MaxPageSize and results are always ordered by ID last so
+// that pages stay stable when the requested sort columns contain duplicate values.
+func (r *BaseRepository[T]) FindPage(req PageRequest) (*PageResult[T], error) {
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = defaultPageSize
+	}
+	if req.PageSize > MaxPageSize {
+		req.PageSize = MaxPageSize
+	}
+
+	scopes := append([]Scope{r.deletedScope}, req.Scopes...)
+
+	var total int64
+	err := r.Db.Session(&gorm.Session{}).Model(new(T)).Scopes(scopes...).Count(&total).Error
+	if err != nil {
+		return nil, err
+	}
+
+	models := []T{}
+	err = r.Db.Session(&gorm.Session{}).
+		Scopes(scopes...).
+		Scopes(sortScope(req.Sort), paginateScope(req.Page, req.PageSize)).
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	totalPages := int((total + int64(req.PageSize) - 1) / int64(req.PageSize))
+	return &PageResult[T]{
+		Items:      models,
+		Total:      total,
+		Page:       req.Page,
+		PageSize:   req.PageSize,
+		TotalPages: totalPages,
+	}, nil
+}
+
+// Count returns the number of models matching the given scopes.
+func (r *BaseRepository[T]) Count(scopes ...Scope) (int64, error) {
+	var total int64
+	err := r.Db.Session(&gorm.Session{}).Model(new(T)).Scopes(r.deletedScope).Scopes(scopes...).Count(&total).Error
+	return total, err
+}
+
+// FindInBatches walks through every model matching the given scopes in primary key order,
+// loading batchSize models at a time and passing each batch to fn. Only one batch is held in memory,
+// so it suits exports and other jobs over large tables. Returning an error from fn stops the walk.
+func (r *BaseRepository[T]) FindInBatches(scopes []Scope, batchSize int, fn func(batch []T) error) error {
+	if batchSize <= 0 {
+		batchSize = defaultPageSize
+	}
+
+	var models []T
+	return r.Db.Session(&gorm.Session{}).
+		Scopes(r.deletedScope).
+		Scopes(scopes...).
+		FindInBatches(&models, batchSize, func(tx *gorm.DB, batch int) error {
+			return fn(models)
+		}).Error
+}
+
+// sortScope orders the query by the given columns, followed by the primary key as a tie-breaker.
+// Column names are quoted by GORM, so they are never interpolated into the SQL as-is.
+func sortScope(sort []SortOrder) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		hasID := false
+		for _, order := range sort {
+			if order.Column == "id" {
+				hasID = true
+			}
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: order.Column}, Desc: order.Desc})
+		}
+		if !hasID {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}})
+		}
+		return db
+	}
+}
+
+// likeEscapeChar is the escape character used for LIKE patterns. A backslash is avoided
+// because its meaning inside string literals differs between MySQL, Postgres and SQLite.
+const likeEscapeChar = "!"
+
+// ContainsScope matches rows where any of the given columns contains term, ignoring case.
+// LIKE wildcards in term are escaped so user input is always matched literally.
+// The columns must come from code, never from user input.
+func ContainsScope(term string, columns ...string) Scope {
+	pattern := containsPattern(term)
+
+	return func(db *gorm.DB) *gorm.DB {
+		conditions := make([]string, len(columns))
+		args := make([]interface{}, len(columns))
+		for i, column := range columns {
+			conditions[i] = "LOWER(" + column + ") LIKE ? ESCAPE '" + likeEscapeChar + "'"
+			args[i] = pattern
+		}
+		return db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+}
+
 // paginateScope is a helper function that returns a closure function to be used as a scope in GORM queries for pagination.
 // It takes in two parameters: page and pageSize, which specify the current page number and the number of records per page, respectively.
 // The returned closure function takes a GORM DB instance as input and returns a modified DB instance with pagination applied.
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"backendService/internals/setup/database/databasetest"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// widget is the model stored by the repositories under test.
+type widget struct {
+	BaseModel
+	Code string
+	Name string
+	Rank int
+}
+
+// newTestRepository returns a repository of widgets backed by a migrated test database.
+func newTestRepository(t *testing.T) *BaseRepository[widget] {
+	t.Helper()
+	db := databasetest.Open(t)
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatalf("creating the widgets table: %v", err)
+	}
+	return NewBaseRepository[widget](db, "widgets")
+}
+
+// createWidgets creates a widget for each name, ranked in the order given.
+func createWidgets(t *testing.T, repo *BaseRepository[widget], names ...string) []*widget {
+	t.Helper()
+	widgets := make([]*widget, len(names))
+	for i, name := range names {
+		created, err := repo.Create(&widget{Name: name, Rank: i + 1})
+		if err != nil {
+			t.Fatalf("creating widget %s: %v", name, err)
+		}
+		widgets[i] = created
+	}
+	return widgets
+}
+
+// widgetNames returns the names of widgets, in order.
+func widgetNames(widgets []widget) []string {
+	names := make([]string, len(widgets))
+	for i, w := range widgets {
+		names[i] = w.Name
+	}
+	return names
+}
+
+func equalNames(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFindPage(t *testing.T) {
+	repo := newTestRepository(t)
+	createWidgets(t, repo, "b", "a", "b", "c", "a")
+
+	page, err := repo.FindPage(PageRequest{Page: 2, PageSize: 2, Sort: []SortOrder{{Column: "name", Desc: true}}})
+	if err != nil {
+		t.Fatalf("FindPage: %v", err)
+	}
+	if page.Total != 5 || page.TotalPages != 3 {
+		t.Errorf("got total %d on %d pages, want 5 on 3 pages", page.Total, page.TotalPages)
+	}
+	// Widgets sharing a name are ordered by ID, so the second page holds the later "b" and the first "a".
+	if names := widgetNames(page.Items); !equalNames(names, "b", "a") || page.Items[0].Rank != 3 || page.Items[1].Rank != 2 {
+		t.Errorf("got second page %v, want [b a] ranked 3 and 2", names)
+	}
+
+	page, err = repo.FindPage(PageRequest{Page: 9, PageSize: MaxPageSize + 1})
+	if err != nil {
+		t.Fatalf("FindPage past the last page: %v", err)
+	}
+	if page.PageSize != MaxPageSize || len(page.Items) != 0 || page.Total != 5 {
+		t.Errorf("got %d items of page size %d, want none with the page size clamped to %d", len(page.Items), page.PageSize, MaxPageSize)
+	}
+}
+
+func TestContainsScope(t *testing.T) {
+	repo := newTestRepository(t)
+	createWidgets(t, repo, "50% off", "500 off", "Half_Price", "Half price")
+
+	for term, want := range map[string][]string{
+		"0% ":     {"50% off"},
+		"f_p":     {"Half_Price"},
+		"HALF":    {"Half_Price", "Half price"},
+		"!":       {},
+		"0 OFF":   {"500 off"},
+		"nothing": {},
+	} {
+		page, err := repo.FindPage(PageRequest{Scopes: []Scope{ContainsScope(term, "name")}})
+		if err != nil {
+			t.Fatalf("FindPage containing %q: %v", term, err)
+		}
+		if names := widgetNames(page.Items); !equalNames(names, want...) {
+			t.Errorf("containing %q: got %v, want %v", term, names, want)
+		}
+	}
+}
+
+func TestFindByPublicID(t *testing.T) {
+	repo := newTestRepository(t)
+	if _, err := repo.FindByPublicID("w1"); err == nil {
+		t.Error("looking up a public ID without a public ID column: got no error")
+	}
+
+	repo.WithPublicID("code")
+	for _, code := range []string{"w1", "w2"} {
+		if _, err := repo.Create(&widget{Code: code, Name: "widget " + code}); err != nil {
+			t.Fatalf("creating widget %s: %v", code, err)
+		}
+	}
+
+	found, err := repo.FindByPublicID("w2")
+	if err != nil {
+		t.Fatalf("FindByPublicID: %v", err)
+	}
+	if found == nil || found.Name != "widget w2" {
+		t.Errorf("got %+v, want widget w2", found)
+	}
+	if found, err := repo.FindByPublicID("w3"); err != nil || found != nil {
+		t.Errorf("looking up an unknown public ID: got %+v, %v, want nil without an error", found, err)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	repo := newTestRepository(t)
+	widgets := createWidgets(t, repo, "kept", "deleted")
+	id := widgets[1].ID
+
+	if err := repo.Delete(id); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if found, err := repo.FindByID(id); err != nil || found != nil {
+		t.Errorf("finding a deleted widget: got %+v, %v, want nothing", found, err)
+	}
+	deleted, err := repo.OnlyDeleted().FindByID(id)
+	if err != nil || deleted == nil {
+		t.Fatalf("finding a deleted widget with OnlyDeleted: got %+v, %v", deleted, err)
+	}
+	if !deleted.IsDeleted || !deleted.DeletedAt.Valid {
+		t.Errorf("got is_deleted %v and deleted_at %v, want both set", deleted.IsDeleted, deleted.DeletedAt)
+	}
+	if all, err := repo.WithDeleted().FindAllBy(Query{}); err != nil || !equalNames(widgetNames(all), "kept", "deleted") {
+		t.Errorf("got %v, %v with WithDeleted, want both widgets", widgetNames(all), err)
+	}
+
+	// Updates leave deleted widgets alone, and deleting twice does nothing
+	if err := repo.Update(map[string]interface{}{"id": id}, map[string]interface{}{"name": "renamed"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repo.Delete(id); err != nil {
+		t.Fatalf("deleting twice: %v", err)
+	}
+	if again, _ := repo.OnlyDeleted().FindByID(id); again.Name != "deleted" || !again.DeletedAt.Time.Equal(deleted.DeletedAt.Time) {
+		t.Errorf("got %s deleted at %v, want it untouched", again.Name, again.DeletedAt.Time)
+	}
+
+	if err := repo.Restore(id); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	restored, err := repo.FindByID(id)
+	if err != nil || restored == nil || restored.IsDeleted || restored.DeletedAt.Valid {
+		t.Errorf("got %+v, %v after Restore, want the widget back", restored, err)
+	}
+	if err := repo.Restore(id); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("restoring a widget that is not deleted: got %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	if err := repo.HardDelete(id); err != nil {
+		t.Fatalf("HardDelete: %v", err)
+	}
+	if found, err := repo.WithDeleted().FindByID(id); err != nil || found != nil {
+		t.Errorf("finding a hard deleted widget: got %+v, %v, want nothing", found, err)
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	repo := newTestRepository(t)
+	widgets := createWidgets(t, repo, "kept", "old", "older", "recent", "restored")
+	for _, w := range widgets[1:] {
+		if err := repo.Delete(w.ID); err != nil {
+			t.Fatalf("deleting %s: %v", w.Name, err)
+		}
+	}
+	now := time.Now()
+	deletedAt := map[string]time.Time{"old": now.Add(-48 * time.Hour), "older": now.Add(-72 * time.Hour), "recent": now, "restored": now.Add(-72 * time.Hour)}
+	for _, w := range widgets[1:] {
+		if err := repo.Db.Session(&gorm.Session{}).Unscoped().Where("id = ?", w.ID).Update("deleted_at", deletedAt[w.Name]).Error; err != nil {
+			t.Fatalf("aging %s: %v", w.Name, err)
+		}
+	}
+	if err := repo.Restore(widgets[4].ID); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	var seen []uint64
+	record := func(tx *gorm.DB, ids []uint64) error {
+		seen = append(seen, ids...)
+		return nil
+	}
+	before := now.Add(-24 * time.Hour)
+	purged, err := repo.PurgeDeleted(before, 1, record)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDeleted: got %d, %v, want 1 widget purged", purged, err)
+	}
+	purged, err = repo.PurgeDeleted(before, 10, record)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDeleted: got %d, %v, want the last widget due purged", purged, err)
+	}
+	if len(seen) != 2 || seen[0] != widgets[1].ID || seen[1] != widgets[2].ID {
+		t.Errorf("got purge functions called with %v, want the old and older widgets", seen)
+	}
+	all, err := repo.WithDeleted().FindAllBy(Query{})
+	if err != nil || !equalNames(widgetNames(all), "kept", "recent", "restored") {
+		t.Errorf("got %v, %v, want the widgets not due for purging", widgetNames(all), err)
+	}
+
+	// A failing purge function keeps the widgets
+	if err := repo.Delete(widgets[0].ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	failing := func(tx *gorm.DB, ids []uint64) error { return errors.New("unavailable") }
+	if purged, err := repo.PurgeDeleted(now.Add(time.Hour), 10, failing); err == nil || purged != 0 {
+		t.Errorf("purging with a failing function: got %d, %v, want an error", purged, err)
+	}
+	if count, _ := repo.OnlyDeleted().Count(); count != 2 {
+		t.Errorf("got %d deleted widgets, want 2", count)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	repo := newTestRepository(t)
+	widgets := createWidgets(t, repo, "alpha")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.WithContext(ctx).FindByID(widgets[0].ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("reading with a canceled context: got %v, want context.Canceled", err)
+	}
+	if err := repo.WithContext(ctx).Update(map[string]interface{}{"id": widgets[0].ID}, map[string]interface{}{"name": "beta"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("updating with a canceled context: got %v, want context.Canceled", err)
+	}
+	// The repository itself keeps running without the context
+	if found, err := repo.FindByID(widgets[0].ID); err != nil || found.Name != "alpha" {
+		t.Errorf("got %+v, %v, want the widget unchanged", found, err)
+	}
+}
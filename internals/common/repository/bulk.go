@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// defaultBatchSize is the number of records inserted per statement by CreateMany and Upsert by default.
+const defaultBatchSize = 100
+
+// upsertPreservedColumns are left untouched by default when an upsert updates an existing record,
+// so that a soft-deleted record is updated but stays deleted.
+var upsertPreservedColumns = map[string]bool{"created_at": true, "deleted_at": true, "is_deleted": true, "version": true}
+
+// UpsertOptions configures Upsert.
+type UpsertOptions struct {
+	// ConflictColumns are the columns whose values identify an existing record. They must be covered by a unique index.
+	ConflictColumns []string
+	// UpdateColumns are the columns updated on an existing record. They default to every column but the primary key,
+	// the public ID, the conflict columns, the creation time and the soft delete columns. The update time is always updated.
+	UpdateColumns []string
+	// BatchSize is the number of records inserted per statement, defaulting to 100.
+	BatchSize int
+}
+
+// CreateMany inserts the models batchSize at a time, defaulting to 100, in a single transaction, so that
+// either all of them are created or none. Like Create, it resets their BaseModel fields, and the IDs
+// assigned by the database are set on the models. Change hooks are called once per batch.
+func (r *BaseRepository[T]) CreateMany(models []T, batchSize int) error {
+	if len(models) == 0 {
+		return nil
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	for i := range models {
+		r.prepareCreate(&models[i])
+	}
+
+	return r.Db.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		return inBatches(models, batchSize, func(batch []T) error {
+			created := make([]*T, len(batch))
+			for i := range batch {
+				created[i] = &batch[i]
+			}
+			return r.track(tx, ActionCreate, r.primaryKeyScope(created...), func(tx *gorm.DB) error {
+				return tx.Create(&batch).Error
+			})
+		})
+	})
+}
+
+// Upsert inserts the models, updating instead the records already holding the values of their conflict columns,
+// in a single transaction. Inserted records are prepared like by Create, and updated records of versioned models
+// have their version incremented. The IDs set on the models are only reliable for inserted records.
+// Change hooks are called once per batch.
+//
+// The statement suits the dialect: Postgres and SQLite update on a conflict on the conflict columns only, while
+// MySQL updates on a conflict on any unique index of the table, the conflict columns only narrowing down the
+// columns updated by default.
+func (r *BaseRepository[T]) Upsert(models []T, options UpsertOptions) error {
+	if len(models) == 0 {
+		return nil
+	}
+	onConflict, err := r.onConflict(options)
+	if err != nil {
+		return err
+	}
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	for i := range models {
+		r.prepareCreate(&models[i])
+	}
+
+	return r.Db.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		return inBatches(models, batchSize, func(batch []T) error {
+			return r.track(tx, ActionUpdate, r.conflictScope(batch, options.ConflictColumns), func(tx *gorm.DB) error {
+				return tx.Clauses(onConflict).Create(&batch).Error
+			})
+		})
+	})
+}
+
+// UpdateWhere applies update to the records matching filter and returns how many records were updated.
+// Unlike Update, the filter is checked against the columns of the model and may be built from client input.
+// Like reads, it leaves soft-deleted records untouched unless the repository was created with WithDeleted or
+// OnlyDeleted, and the version of versioned models is incremented. A zero filter, which would update every
+// record, is rejected with gorm.ErrMissingWhereClause. When the repository has change hooks, the records are
+// updated 100 at a time, by primary key, and the hooks are called once per batch.
+func (r *BaseRepository[T]) UpdateWhere(filter Filter, update any) (int64, error) {
+	if filter.IsZero() {
+		return 0, gorm.ErrMissingWhereClause
+	}
+	update, err := r.versionedUpdate(update)
+	if err != nil {
+		return 0, err
+	}
+
+	var updated int64
+	session := r.Db.Session(&gorm.Session{})
+	scope := func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(r.deletedScope, r.FilterScope(filter))
+	}
+	err = r.trackInBatches(session, ActionUpdate, scope, defaultBatchSize, func(tx *gorm.DB, batch Scope) error {
+		result := tx.Model(new(T)).Scopes(scope, batch).Updates(update)
+		updated += result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// inBatches calls fn with consecutive batches of at most batchSize of the models, stopping at the first error.
+// The batches share the array of models, so the changes fn makes to them are seen by the caller.
+func inBatches[T any](models []T, batchSize int, fn func(batch []T) error) error {
+	for start := 0; start < len(models); start += batchSize {
+		if err := fn(models[start:min(start+batchSize, len(models))]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// onConflict builds the conflict clause of an upsert, checking the columns of the options against the model.
+func (r *BaseRepository[T]) onConflict(options UpsertOptions) (clause.OnConflict, error) {
+	if len(options.ConflictColumns) == 0 {
+		return clause.OnConflict{}, errors.New("upsert into table " + r.tableName + " needs conflict columns")
+	}
+	modelSchema, err := r.schema()
+	if err != nil {
+		return clause.OnConflict{}, err
+	}
+
+	conflicting := make(map[string]bool, len(options.ConflictColumns))
+	onConflict := clause.OnConflict{}
+	for _, column := range options.ConflictColumns {
+		if _, ok := modelSchema.FieldsByDBName[column]; !ok {
+			return clause.OnConflict{}, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+		conflicting[column] = true
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+	}
+
+	updateColumns := options.UpdateColumns
+	if len(updateColumns) == 0 {
+		for _, column := range modelSchema.DBNames {
+			field := modelSchema.FieldsByDBName[column]
+			if !field.PrimaryKey && column != r.publicIDColumn && !conflicting[column] && !upsertPreservedColumns[column] {
+				updateColumns = append(updateColumns, column)
+			}
+		}
+	}
+	updatesTime := false
+	for _, column := range updateColumns {
+		if _, ok := modelSchema.FieldsByDBName[column]; !ok {
+			return clause.OnConflict{}, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+		if column == "version" && r.versioned {
+			continue
+		}
+		updatesTime = updatesTime || column == "updated_at"
+		onConflict.DoUpdates = append(onConflict.DoUpdates, clause.AssignmentColumns([]string{column})...)
+	}
+	if _, ok := modelSchema.FieldsByDBName["updated_at"]; ok && !updatesTime {
+		onConflict.DoUpdates = append(onConflict.DoUpdates, clause.AssignmentColumns([]string{"updated_at"})...)
+	}
+	if r.versioned {
+		// The version is qualified, as Postgres also sees the one of the excluded row
+		version := clause.Column{Table: clause.CurrentTable, Name: "version"}
+		onConflict.DoUpdates = append(onConflict.DoUpdates, clause.Assignment{Column: clause.Column{Name: "version"}, Value: gorm.Expr("? + 1", version)})
+	}
+	if len(onConflict.DoUpdates) == 0 {
+		onConflict.DoNothing = true
+	}
+	return onConflict, nil
+}
+
+// conflictScope selects the records holding the values of the conflict columns of any of the models.
+// Models with a NULL conflict column never conflict and are always inserted, so they are selected by
+// primary key once inserted instead.
+func (r *BaseRepository[T]) conflictScope(models []T, columns []string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(columns) == 0 {
+			return db.Where("1 = 0")
+		}
+		modelSchema, err := r.schema()
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+
+		var conditions []clause.Expression
+		var values, inserted []interface{}
+		for i := range models {
+			value := reflect.ValueOf(&models[i]).Elem()
+			equalities := make([]clause.Expression, 0, len(columns))
+			for _, column := range columns {
+				field, ok := modelSchema.FieldsByDBName[column]
+				if !ok {
+					db.AddError(fmt.Errorf("%w: %s", ErrUnknownColumn, column))
+					return db
+				}
+				columnValue, _ := field.ValueOf(db.Statement.Context, value)
+				if _, ok := formatValue(columnValue); !ok {
+					break
+				}
+				equalities = append(equalities, clause.Eq{Column: clause.Column{Name: column}, Value: columnValue})
+			}
+			if len(equalities) < len(columns) {
+				if id, zero := modelSchema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, value); !zero {
+					inserted = append(inserted, id)
+				}
+				continue
+			}
+			conditions = append(conditions, clause.And(equalities...))
+			values = append(values, equalities[0].(clause.Eq).Value)
+		}
+		if len(columns) == 1 && len(values) > 0 {
+			conditions = []clause.Expression{clause.IN{Column: clause.Column{Name: columns[0]}, Values: values}}
+		}
+		if len(inserted) > 0 {
+			conditions = append(conditions, clause.IN{Column: clause.PrimaryColumn, Values: inserted})
+		}
+		if len(conditions) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(clause.Or(conditions...))
+	}
+}
+
+// schema returns the parsed schema of the model.
+func (r *BaseRepository[T]) schema() (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: r.Db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"backendService/internals/setup/database/databasetest"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// part is the versioned model with a unique column upserted by the repositories under test.
+type part struct {
+	BaseModel
+	Versioned
+	Sku   string `gorm:"uniqueIndex"`
+	Name  string
+	Stock int
+}
+
+// newPartRepository returns a repository of parts backed by a migrated test database.
+func newPartRepository(t *testing.T) *BaseRepository[part] {
+	t.Helper()
+	db := databasetest.Open(t)
+	if err := db.AutoMigrate(&part{}); err != nil {
+		t.Fatalf("creating the parts table: %v", err)
+	}
+	return NewBaseRepository[part](db, "parts")
+}
+
+// findPart returns the part with the given SKU, deleted or not.
+func findPart(t *testing.T, repo *BaseRepository[part], sku string) *part {
+	t.Helper()
+	found, err := repo.WithDeleted().FindOneBy(Eq("sku", sku))
+	if err != nil {
+		t.Fatalf("finding part %s: %v", sku, err)
+	}
+	return found
+}
+
+func TestCreateMany(t *testing.T) {
+	repo := newTestRepository(t)
+	widgets := []widget{{Name: "alpha"}, {Name: "beta"}, {Name: "gamma"}}
+	widgets[0].ID = 42
+	widgets[0].IsDeleted = true
+
+	if err := repo.CreateMany(widgets, 2); err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+	for _, w := range widgets {
+		if w.ID == 0 || w.ID == 42 || w.IsDeleted {
+			t.Errorf("got %s with ID %d deleted %v, want a new record", w.Name, w.ID, w.IsDeleted)
+		}
+	}
+	all, err := repo.FindAllBy(Query{})
+	if err != nil || !equalNames(widgetNames(all), "alpha", "beta", "gamma") {
+		t.Errorf("got %v, %v, want every widget created", widgetNames(all), err)
+	}
+
+	if err := repo.CreateMany(nil, 0); err != nil {
+		t.Errorf("creating no widgets: got %v", err)
+	}
+}
+
+func TestCreateManyRollsBack(t *testing.T) {
+	repo := newPartRepository(t)
+	parts := []part{{Sku: "A1"}, {Sku: "B2"}, {Sku: "A1"}}
+
+	if err := repo.CreateMany(parts, 1); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Fatalf("creating parts with a duplicate SKU: got %v, want ErrDuplicatedKey", err)
+	}
+	if count, err := repo.WithDeleted().Count(); err != nil || count != 0 {
+		t.Errorf("got %d parts, %v, want none created", count, err)
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	repo := newPartRepository(t)
+	if err := repo.CreateMany([]part{{Sku: "A1", Name: "bolt", Stock: 1}, {Sku: "B2", Name: "nut", Stock: 2}}, 0); err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+	before := findPart(t, repo, "A1")
+	if err := repo.Delete(findPart(t, repo, "B2").ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	parts := []part{{Sku: "A1", Name: "hex bolt", Stock: 10}, {Sku: "B2", Name: "hex nut", Stock: 20}, {Sku: "C3", Name: "washer", Stock: 30}}
+	if err := repo.Upsert(parts, UpsertOptions{ConflictColumns: []string{"sku"}, BatchSize: 2}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	updated := findPart(t, repo, "A1")
+	if updated.ID != before.ID || updated.Name != "hex bolt" || updated.Stock != 10 {
+		t.Errorf("got %+v, want the existing part updated", updated)
+	}
+	if !updated.CreatedAt.Equal(before.CreatedAt) || !updated.UpdatedAt.After(before.UpdatedAt) {
+		t.Errorf("got created at %v and updated at %v, want created at %v and updated later", updated.CreatedAt, updated.UpdatedAt, before.CreatedAt)
+	}
+	if updated.Version != 2 {
+		t.Errorf("got version %d, want 2", updated.Version)
+	}
+	if deleted := findPart(t, repo, "B2"); !deleted.IsDeleted || deleted.Name != "hex nut" {
+		t.Errorf("got %s deleted %v, want the deleted part updated and still deleted", deleted.Name, deleted.IsDeleted)
+	}
+	if inserted := findPart(t, repo, "C3"); inserted.Version != 1 || inserted.Stock != 30 {
+		t.Errorf("got %+v, want the new part inserted", inserted)
+	}
+
+	// Only the update columns change on a conflict
+	err := repo.Upsert([]part{{Sku: "A1", Name: "ignored", Stock: 11}}, UpsertOptions{ConflictColumns: []string{"sku"}, UpdateColumns: []string{"stock"}})
+	if err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if updated := findPart(t, repo, "A1"); updated.Name != "hex bolt" || updated.Stock != 11 || updated.Version != 3 {
+		t.Errorf("got %+v, want only the stock updated", updated)
+	}
+}
+
+func TestUpsertOptions(t *testing.T) {
+	repo := newPartRepository(t)
+	parts := []part{{Sku: "A1"}}
+
+	if err := repo.Upsert(parts, UpsertOptions{}); err == nil {
+		t.Error("upserting without conflict columns: got no error")
+	}
+	if err := repo.Upsert(parts, UpsertOptions{ConflictColumns: []string{"serial"}}); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("upserting on an unknown conflict column: got %v, want ErrUnknownColumn", err)
+	}
+	if err := repo.Upsert(parts, UpsertOptions{ConflictColumns: []string{"sku"}, UpdateColumns: []string{"price"}}); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("upserting an unknown update column: got %v, want ErrUnknownColumn", err)
+	}
+}
+
+func TestUpdateWhere(t *testing.T) {
+	repo := newPartRepository(t)
+	if err := repo.CreateMany([]part{{Sku: "A1", Stock: 1}, {Sku: "B2", Stock: 1}, {Sku: "C3", Stock: 5}}, 0); err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+	if err := repo.Delete(findPart(t, repo, "B2").ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	updated, err := repo.UpdateWhere(Lte("stock", 1), map[string]interface{}{"stock": 0})
+	if err != nil {
+		t.Fatalf("UpdateWhere: %v", err)
+	}
+	if updated != 1 {
+		t.Errorf("got %d records updated, want 1", updated)
+	}
+	if a1 := findPart(t, repo, "A1"); a1.Stock != 0 || a1.Version != 2 {
+		t.Errorf("got stock %d at version %d, want 0 at version 2", a1.Stock, a1.Version)
+	}
+	if b2 := findPart(t, repo, "B2"); b2.Stock != 1 {
+		t.Errorf("got stock %d on the deleted part, want it untouched", b2.Stock)
+	}
+
+	if _, err := repo.UpdateWhere(Filter{}, map[string]interface{}{"stock": 0}); !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Errorf("updating with a zero filter: got %v, want ErrMissingWhereClause", err)
+	}
+	if _, err := repo.UpdateWhere(Eq("price", 1), map[string]interface{}{"stock": 0}); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("updating with an unknown column: got %v, want ErrUnknownColumn", err)
+	}
+	if c3 := findPart(t, repo, "C3"); c3.Stock != 5 {
+		t.Errorf("got stock %d, want the rejected updates to change nothing", c3.Stock)
+	}
+}
+
+func TestBulkWritesTrackChangesInBatches(t *testing.T) {
+	repo := newPartRepository(t)
+	var batches []string
+	repo.WithChangeHook(func(tx *gorm.DB, changes []Change) error {
+		for _, change := range changes {
+			if (change.Before == nil && change.Action != ActionCreate) || change.After == nil {
+				t.Errorf("got %s change of %s without its values", change.Action, change.EntityId)
+			}
+		}
+		batches = append(batches, changes[0].Action+":"+strconv.Itoa(len(changes)))
+		return nil
+	})
+	// wantBatches checks the batches the hook was called with since the previous check
+	wantBatches := func(write string, want ...string) {
+		t.Helper()
+		if !reflect.DeepEqual(batches, want) {
+			t.Errorf("%s: got batches %v, want %v", write, batches, want)
+		}
+		batches = nil
+	}
+
+	parts := make([]part, 250)
+	for i := range parts {
+		parts[i] = part{Sku: "P" + strconv.Itoa(i), Stock: 1}
+	}
+	if err := repo.CreateMany(parts, 0); err != nil {
+		t.Fatalf("CreateMany: %v", err)
+	}
+	wantBatches("CreateMany", "create:100", "create:100", "create:50")
+
+	updated, err := repo.UpdateWhere(Gte("stock", 1), map[string]interface{}{"stock": 0})
+	if err != nil || updated != 250 {
+		t.Fatalf("UpdateWhere: got %d, %v, want 250 records updated", updated, err)
+	}
+	wantBatches("UpdateWhere", "update:100", "update:100", "update:50")
+
+	upserted := []part{{Sku: "P0", Stock: 5}, {Sku: "P1", Stock: 5}, {Sku: "Q0", Stock: 5}}
+	if err := repo.Upsert(upserted, UpsertOptions{ConflictColumns: []string{"sku"}, BatchSize: 2}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	wantBatches("Upsert", "update:2", "create:1")
+}
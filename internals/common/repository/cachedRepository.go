@@ -0,0 +1,486 @@
+package repository
+
+import (
+	"backendService/internals/common/cache"
+	"backendService/internals/common/logger"
+	"backendService/internals/setup/config"
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tombstoneTTL is how long an evicted key keeps a tombstone, which stops loads that read the record before
+// the change from caching it afterwards. It is well above the time a load takes.
+const tombstoneTTL = 5 * time.Second
+
+// errLoadPanicked is handed to the callers waiting on a load that panicked.
+var errLoadPanicked = errors.New("loading the record panicked")
+
+// tombstone is the value of an evicted key. Encoded records are never empty, so it cannot be mistaken for one.
+var tombstone = []byte{}
+
+// CacheOptions configures the cache of a CachedRepository.
+type CacheOptions struct {
+	// TTL is how long a record stays cached after being read. Caching is off when it is zero or less.
+	TTL time.Duration
+	// UniqueKeys are the unique columns records can be looked up by with FindByUnique,
+	// besides the ID and the public ID which are always cached.
+	UniqueKeys []string
+	// UncachedColumns are left out of the cached records, such as secrets that must not be copied to the cache.
+	// Records found in the cache are completed with them from the database.
+	UncachedColumns []string
+}
+
+// CacheTTL returns the configured cache TTL of the given table, or zero when the table is not cached.
+func CacheTTL(tableName string) time.Duration {
+	return time.Duration(config.Config.RepositoryCache.TTLSeconds[tableName]) * time.Second
+}
+
+// CachedRepository decorates a BaseRepository with a read-through cache. Records found by ID, public ID
+// or one of the unique keys are kept in the cache, and the writes made through the repository drop the
+// cached copies of the records they change. Writes made without the repository must call BeginInvalidation.
+//
+// Concurrent loads of the same record by one instance share a single query, and TTLs are spread a little,
+// so a popular record expiring does not flood the database. Evicted keys hold a tombstone for a few seconds,
+// during which the record is not cached again, so that a load that read the record before it changed cannot
+// cache the stale copy. Reads that see soft-deleted records and reads inside a transaction always go to the database.
+type CachedRepository[T any] struct {
+	*BaseRepository[T]
+	cacheService *cache.CacheService
+	options      CacheOptions
+	loads        *loadGroup
+
+	ctx context.Context // ctx is the context of the cache calls, set along with the one of the queries by WithContext
+	tx  *Tx             // tx is the transaction the repository is bound to, whose commit invalidation waits for
+}
+
+// NewCachedRepository returns base decorated with a cache kept in cacheService.
+func NewCachedRepository[T any](base *BaseRepository[T], cacheService *cache.CacheService, options CacheOptions) *CachedRepository[T] {
+	return &CachedRepository[T]{
+		BaseRepository: base,
+		cacheService:   cacheService,
+		options:        options,
+		loads:          &loadGroup{loads: map[string]*load{}},
+		ctx:            context.Background(),
+	}
+}
+
+// WithTx returns a copy of the repository running its queries inside the given transaction.
+// Its reads skip the cache, and the cached records it changes are dropped once the transaction commits.
+func (r *CachedRepository[T]) WithTx(tx *Tx) *CachedRepository[T] {
+	bound := *r
+	bound.BaseRepository = r.BaseRepository.WithTx(tx)
+	bound.tx = tx
+	return &bound
+}
+
+// WithContext returns a copy of the repository running its queries and cache calls with the given context.
+func (r *CachedRepository[T]) WithContext(ctx context.Context) *CachedRepository[T] {
+	bound := *r
+	bound.BaseRepository = r.BaseRepository.WithContext(ctx)
+	bound.ctx = ctx
+	return &bound
+}
+
+// WithDeleted returns a copy of the repository whose reads also see soft-deleted rows, bypassing the cache.
+func (r *CachedRepository[T]) WithDeleted() *CachedRepository[T] {
+	bound := *r
+	bound.BaseRepository = r.BaseRepository.WithDeleted()
+	return &bound
+}
+
+// OnlyDeleted returns a copy of the repository whose reads only see soft-deleted rows, bypassing the cache.
+func (r *CachedRepository[T]) OnlyDeleted() *CachedRepository[T] {
+	bound := *r
+	bound.BaseRepository = r.BaseRepository.OnlyDeleted()
+	return &bound
+}
+
+// FindByID retrieves a record by its ID, from the cache when it is there.
+func (r *CachedRepository[T]) FindByID(id uint64) (*T, error) {
+	return r.cached("id", id, func() (*T, error) {
+		return r.BaseRepository.FindByID(id)
+	})
+}
+
+// FindByPublicID retrieves a record by its public ID, from the cache when it is there.
+func (r *CachedRepository[T]) FindByPublicID(id any) (*T, error) {
+	return r.cached(r.publicIDColumn, id, func() (*T, error) {
+		return r.BaseRepository.FindByPublicID(id)
+	})
+}
+
+// FindByUnique retrieves the record whose unique column holds value, from the cache when it is there.
+// The column must be one of the unique keys of the cache options. It returns nil without an error
+// when no record matches.
+func (r *CachedRepository[T]) FindByUnique(column string, value any) (*T, error) {
+	if !r.isUniqueKey(column) {
+		return nil, fmt.Errorf("%w: %s is not a unique key of table %s", ErrUnknownColumn, column, r.tableName)
+	}
+	return r.cached(column, value, func() (*T, error) {
+		var model T
+		err := r.Db.Session(&gorm.Session{}).Scopes(r.deletedScope).
+			Where(clause.Eq{Column: clause.Column{Name: column}, Value: value}).
+			First(&model).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &model, nil
+	})
+}
+
+// Create inserts the model, dropping any cached record still held under its keys.
+func (r *CachedRepository[T]) Create(model *T) (*T, error) {
+	created, err := r.BaseRepository.Create(model)
+	if err != nil {
+		return nil, err
+	}
+	r.forget(r.keysOf([]T{*created}))
+	return created, nil
+}
+
+// Update applies update to the records matching filter and drops their cached copies.
+func (r *CachedRepository[T]) Update(filter any, update any) error {
+	invalidate := r.BeginInvalidation(filter)
+	if err := r.BaseRepository.Update(filter, update); err != nil {
+		return err
+	}
+	invalidate()
+	return nil
+}
+
+// UpdateVersioned applies update to the records matching filter when they are still at the given version,
+// and drops their cached copies.
+func (r *CachedRepository[T]) UpdateVersioned(filter any, version uint64, update any) error {
+	invalidate := r.BeginInvalidation(filter)
+	if err := r.BaseRepository.UpdateVersioned(filter, version, update); err != nil {
+		return err
+	}
+	invalidate()
+	return nil
+}
+
+// Delete soft deletes the record with the given ID and drops its cached copies.
+func (r *CachedRepository[T]) Delete(id uint64) error {
+	invalidate := r.BeginInvalidation(map[string]interface{}{"id": id})
+	if err := r.BaseRepository.Delete(id); err != nil {
+		return err
+	}
+	invalidate()
+	return nil
+}
+
+// Restore reverts the soft delete of the record with the given ID and drops its cached copies.
+func (r *CachedRepository[T]) Restore(id uint64) error {
+	invalidate := r.BeginInvalidation(map[string]interface{}{"id": id})
+	if err := r.BaseRepository.Restore(id); err != nil {
+		return err
+	}
+	invalidate()
+	return nil
+}
+
+// HardDelete permanently removes the record with the given ID and drops its cached copies.
+func (r *CachedRepository[T]) HardDelete(id uint64) error {
+	invalidate := r.BeginInvalidation(map[string]interface{}{"id": id})
+	if err := r.BaseRepository.HardDelete(id); err != nil {
+		return err
+	}
+	invalidate()
+	return nil
+}
+
+// CreateMany inserts the models batchSize at a time, dropping any cached record still held under their keys.
+func (r *CachedRepository[T]) CreateMany(models []T, batchSize int) error {
+	if err := r.BaseRepository.CreateMany(models, batchSize); err != nil {
+		return err
+	}
+	r.forget(r.keysOf(models))
+	return nil
+}
+
+// Upsert inserts the models or updates the records they conflict with, and drops the cached copies of both.
+func (r *CachedRepository[T]) Upsert(models []T, options UpsertOptions) error {
+	invalidate := r.beginInvalidation(r.conflictScope(models, options.ConflictColumns))
+	if err := r.BaseRepository.Upsert(models, options); err != nil {
+		return err
+	}
+	invalidate()
+	return nil
+}
+
+// UpdateWhere applies update to the records matching filter, drops their cached copies,
+// and returns how many records were updated.
+func (r *CachedRepository[T]) UpdateWhere(filter Filter, update any) (int64, error) {
+	invalidate := r.beginInvalidation(r.FilterScope(filter))
+	updated, err := r.BaseRepository.UpdateWhere(filter, update)
+	if err != nil {
+		return 0, err
+	}
+	invalidate()
+	return updated, nil
+}
+
+// BeginInvalidation is called before changing the records matching filter without the repository, such as
+// in a transaction of a custom repository. It notes the keys the records are cached under, and the returned
+// function, called once the change is done, drops them along with the keys the records have after the change.
+func (r *CachedRepository[T]) BeginInvalidation(filter any) func() {
+	return r.beginInvalidation(func(db *gorm.DB) *gorm.DB {
+		return db.Where(filter)
+	})
+}
+
+// beginInvalidation is BeginInvalidation for the records selected by scope.
+func (r *CachedRepository[T]) beginInvalidation(scope Scope) func() {
+	if r.options.TTL <= 0 {
+		return func() {}
+	}
+	before := r.keysMatching(scope)
+	return func() {
+		r.evict(append(before, r.keysMatching(scope)...))
+	}
+}
+
+// cached returns the record cached under the key of column and value, or loads and caches it.
+// Concurrent loads of the same key share the query of the first one.
+func (r *CachedRepository[T]) cached(column string, value any, loadFn func() (*T, error)) (*T, error) {
+	if r.options.TTL <= 0 || r.tx != nil || r.deleted != excludeDeleted {
+		return loadFn()
+	}
+
+	key, ok := r.key(column, value)
+	if !ok {
+		return loadFn()
+	}
+	data, err := r.cacheService.GetBytes(r.ctx, key)
+	if err == nil && len(data) > 0 {
+		var model T
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&model); err != nil {
+			logger.Error("repository", "CachedRepository", "cached", "failed to decode cached record", key, err)
+		} else if err := r.loadUncached(&model); err == nil {
+			return &model, nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	} else if err != nil && err != redis.Nil {
+		// The cache is only a shortcut, so fall back to the database when it is unavailable
+		logger.Error("repository", "CachedRepository", "cached", "failed to read cached record", key, err)
+	}
+
+	loaded, err, shared := r.loads.do(key, func() (any, error) {
+		model, err := loadFn()
+		if err == nil && model != nil {
+			r.store(key, model)
+		}
+		return model, err
+	})
+	if err != nil && shared {
+		// The load belonged to another caller, whose context may have been cancelled
+		return loadFn()
+	}
+	model, _ := loaded.(*T)
+	if err != nil || model == nil {
+		return nil, err
+	}
+	// Callers sharing a load each get their own copy, which they may change
+	copied := *model
+	return &copied, nil
+}
+
+// loadUncached reads the uncached columns of a record found in the cache from the database.
+// It returns gorm.ErrRecordNotFound when the record no longer exists.
+func (r *CachedRepository[T]) loadUncached(model *T) error {
+	if len(r.options.UncachedColumns) == 0 {
+		return nil
+	}
+	// The primary key of the model narrows the query down to its record
+	return r.Db.Session(&gorm.Session{}).Select(r.options.UncachedColumns).Take(model).Error
+}
+
+// store caches the model under key, for the TTL of the repository give or take a tenth, without its
+// uncached columns. It leaves alone a key holding a record or a tombstone, as the record may have changed
+// since it was loaded.
+func (r *CachedRepository[T]) store(key string, model *T) {
+	cached := *model
+	if len(r.options.UncachedColumns) > 0 {
+		stmt := &gorm.Statement{DB: r.Db}
+		if err := stmt.Parse(new(T)); err != nil {
+			logger.Error("repository", "CachedRepository", "store", "failed to parse model", err)
+			return
+		}
+		value := reflect.ValueOf(&cached).Elem()
+		for _, column := range r.options.UncachedColumns {
+			if field := stmt.Schema.LookUpField(column); field != nil {
+				fieldValue := field.ReflectValueOf(r.Db.Statement.Context, value)
+				fieldValue.Set(reflect.Zero(fieldValue.Type()))
+			}
+		}
+	}
+
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(&cached); err != nil {
+		logger.Error("repository", "CachedRepository", "store", "failed to encode record", key, err)
+		return
+	}
+	ttl := r.options.TTL
+	if spread := int64(ttl / 10); spread > 0 {
+		ttl += time.Duration(rand.Int63n(2*spread) - spread)
+	}
+	if _, err := r.cacheService.SetBytesIfAbsent(r.ctx, key, data.Bytes(), ttl); err != nil {
+		logger.Error("repository", "CachedRepository", "store", "failed to cache record", key, err)
+	}
+}
+
+// evict replaces the given keys with tombstones, once the transaction of the repository commits if it has one.
+func (r *CachedRepository[T]) evict(keys []string) {
+	r.afterCommit(keys, func(ctx context.Context) error {
+		return r.cacheService.SetManyBytes(ctx, keys, tombstone, tombstoneTTL)
+	})
+}
+
+// forget drops the given keys from the cache, once the transaction of the repository commits if it has one.
+// It is used for new records, which no load can have read before, so they can be cached right away.
+func (r *CachedRepository[T]) forget(keys []string) {
+	r.afterCommit(keys, func(ctx context.Context) error {
+		return r.cacheService.Delete(ctx, keys...)
+	})
+}
+
+// afterCommit runs the invalidation of the given keys, once the transaction of the repository commits if it has one.
+// Invalidation goes on even when the context was cancelled, as the change it follows is already made.
+func (r *CachedRepository[T]) afterCommit(keys []string, invalidate func(ctx context.Context) error) {
+	if len(keys) == 0 {
+		return
+	}
+	ctx := context.WithoutCancel(r.ctx)
+	run := func() {
+		if err := invalidate(ctx); err != nil {
+			logger.Error("repository", "CachedRepository", "afterCommit", "failed to invalidate cached records", keys, err)
+		}
+	}
+	if r.tx != nil {
+		r.tx.AfterCommit(run)
+		return
+	}
+	run()
+}
+
+// keysMatching returns the keys the records selected by scope are cached under, whether they are deleted or not.
+func (r *CachedRepository[T]) keysMatching(scope Scope) []string {
+	var models []T
+	err := r.Db.Session(&gorm.Session{}).Unscoped().Model(new(T)).Select(r.keyColumns()).Scopes(scope).Find(&models).Error
+	if err != nil {
+		logger.Error("repository", "CachedRepository", "keysMatching", "failed to find the records to invalidate", err)
+		return nil
+	}
+	return r.keysOf(models)
+}
+
+// keysOf returns the keys the given records are cached under.
+func (r *CachedRepository[T]) keysOf(models []T) []string {
+	if r.options.TTL <= 0 {
+		return nil
+	}
+	stmt := &gorm.Statement{DB: r.Db}
+	if err := stmt.Parse(new(T)); err != nil {
+		logger.Error("repository", "CachedRepository", "keysOf", "failed to parse model", err)
+		return nil
+	}
+
+	var keys []string
+	for i := range models {
+		value := reflect.ValueOf(&models[i]).Elem()
+		for _, column := range r.keyColumns() {
+			field := stmt.Schema.LookUpField(column)
+			if field == nil {
+				continue
+			}
+			fieldValue, zero := field.ValueOf(r.Db.Statement.Context, value)
+			if zero {
+				continue
+			}
+			if key, ok := r.key(column, fieldValue); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// keyColumns returns the columns records are cached by.
+func (r *CachedRepository[T]) keyColumns() []string {
+	columns := []string{"id"}
+	if r.publicIDColumn != "" {
+		columns = append(columns, r.publicIDColumn)
+	}
+	return append(columns, r.options.UniqueKeys...)
+}
+
+// isUniqueKey reports whether column is one of the unique keys of the cache options.
+func (r *CachedRepository[T]) isUniqueKey(column string) bool {
+	for _, key := range r.options.UniqueKeys {
+		if key == column {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the cache key of the record whose column holds value. It reports false for a nil value,
+// which no record can be looked up by.
+func (r *CachedRepository[T]) key(column string, value any) (string, bool) {
+	formatted, ok := formatValue(value)
+	if !ok {
+		return "", false
+	}
+	return "repository:" + r.tableName + ":" + column + ":" + formatted, true
+}
+
+// load is a load of a record in progress, whose outcome is shared with the callers waiting for it.
+type load struct {
+	done  chan struct{}
+	model any
+	err   error
+}
+
+// loadGroup makes concurrent loads of the same key share the query of the first one.
+type loadGroup struct {
+	mu    sync.Mutex
+	loads map[string]*load
+}
+
+// do runs fn, unless a load of key is already in progress, in which case it waits for that load and
+// returns its outcome. shared reports whether the outcome is the one of another caller.
+func (g *loadGroup) do(key string, fn func() (any, error)) (model any, err error, shared bool) {
+	g.mu.Lock()
+	if inProgress, ok := g.loads[key]; ok {
+		g.mu.Unlock()
+		<-inProgress.done
+		return inProgress.model, inProgress.err, true
+	}
+	current := &load{done: make(chan struct{}), err: errLoadPanicked}
+	g.loads[key] = current
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.loads, key)
+		g.mu.Unlock()
+		close(current.done)
+	}()
+	current.model, current.err = fn()
+	return current.model, current.err, false
+}
@@ -0,0 +1,274 @@
+package repository
+
+import (
+	"backendService/internals/common/cache/cachetest"
+	appError "backendService/internals/common/errors"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newTestCachedRepository returns a cached repository of widgets, looked up by code, and the cache behind it.
+func newTestCachedRepository(t *testing.T, ttl time.Duration) (*CachedRepository[widget], *cachetest.Server) {
+	t.Helper()
+	cacheService, cacheServer := cachetest.NewCacheService(t)
+	return NewCachedRepository(newTestRepository(t), cacheService, CacheOptions{TTL: ttl, UniqueKeys: []string{"code"}}), cacheServer
+}
+
+// renameBehindCache renames a widget without the repository, so that its cached copy goes stale.
+func renameBehindCache(t *testing.T, repo *CachedRepository[widget], id uint64, name string) {
+	t.Helper()
+	if err := repo.Db.Session(&gorm.Session{}).Where("id = ?", id).Update("name", name).Error; err != nil {
+		t.Fatalf("renaming widget %d: %v", id, err)
+	}
+}
+
+// holdsRecord reports whether key holds a cached record, rather than nothing or a tombstone.
+func holdsRecord(cacheServer *cachetest.Server, key string) bool {
+	value, ok := cacheServer.Get(key)
+	return ok && value != ""
+}
+
+func TestCachedRepositoryReadThrough(t *testing.T) {
+	repo, cacheServer := newTestCachedRepository(t, time.Minute)
+	created, err := repo.Create(&widget{Name: "alpha", Code: "A1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	idKey := "repository:widgets:id:" + strconv.FormatUint(created.ID, 10)
+
+	if found, err := repo.FindByID(created.ID); err != nil || found.Name != "alpha" {
+		t.Fatalf("FindByID: got %+v, %v", found, err)
+	}
+	if !holdsRecord(cacheServer, idKey) {
+		t.Errorf("got keys %v, want %s cached", cacheServer.Keys(), idKey)
+	}
+
+	// Reads are served from the cache until the repository changes the widget
+	renameBehindCache(t, repo, created.ID, "stale")
+	if found, _ := repo.FindByID(created.ID); found.Name != "alpha" {
+		t.Errorf("got %s, want the cached alpha", found.Name)
+	}
+	if found, _ := repo.WithDeleted().FindByID(created.ID); found.Name != "stale" {
+		t.Errorf("got %s with WithDeleted, want the stored name", found.Name)
+	}
+	if err := repo.Update(map[string]interface{}{"id": created.ID}, map[string]interface{}{"rank": 2}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if found, _ := repo.FindByID(created.ID); found.Name != "stale" || found.Rank != 2 {
+		t.Errorf("got %s ranked %d after Update, want the stored widget", found.Name, found.Rank)
+	}
+
+	if err := repo.Delete(created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if found, err := repo.FindByID(created.ID); err != nil || found != nil {
+		t.Errorf("finding a deleted widget: got %+v, %v, want nothing", found, err)
+	}
+	if holdsRecord(cacheServer, idKey) {
+		t.Errorf("got %s cached after Delete, want it dropped", idKey)
+	}
+}
+
+func TestCachedRepositoryUniqueKeys(t *testing.T) {
+	repo, cacheServer := newTestCachedRepository(t, time.Minute)
+	created, err := repo.Create(&widget{Name: "alpha", Code: "A1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if found, err := repo.FindByUnique("code", "A1"); err != nil || found == nil || found.ID != created.ID {
+		t.Fatalf("FindByUnique: got %+v, %v", found, err)
+	}
+	if _, err := repo.FindByUnique("name", "alpha"); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("finding by a column that is not a unique key: got %v, want ErrUnknownColumn", err)
+	}
+	if found, err := repo.FindByUnique("code", "B2"); err != nil || found != nil {
+		t.Errorf("finding a missing code: got %+v, %v, want nothing", found, err)
+	}
+
+	// Changing the code drops the widget from under its old code, and any stale copy under the new one
+	renameBehindCache(t, repo, created.ID, "stale")
+	if err := repo.Update(map[string]interface{}{"id": created.ID}, map[string]interface{}{"code": "B2"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	for _, key := range []string{"repository:widgets:code:A1", "repository:widgets:code:B2"} {
+		if holdsRecord(cacheServer, key) {
+			t.Errorf("got %s cached after changing the code", key)
+		}
+	}
+	if found, _ := repo.FindByUnique("code", "A1"); found != nil {
+		t.Errorf("finding the old code: got %+v, want nothing", found)
+	}
+	if found, _ := repo.FindByUnique("code", "B2"); found == nil || found.Name != "stale" {
+		t.Errorf("finding the new code: got %+v, want the stored widget", found)
+	}
+}
+
+func TestCachedRepositoryTransactions(t *testing.T) {
+	repo, _ := newTestCachedRepository(t, time.Minute)
+	created, err := repo.Create(&widget{Name: "alpha"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	repo.FindByID(created.ID)
+	uow := NewUnitOfWork(repo.Db)
+
+	// A rolled back change keeps the cached copy, which is still accurate
+	uow.Do(context.Background(), func(tx *Tx) *appError.ApplicationError {
+		if err := repo.WithTx(tx).Update(map[string]interface{}{"id": created.ID}, map[string]interface{}{"name": "rolled back"}); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		if found, _ := repo.WithTx(tx).FindByID(created.ID); found.Name != "rolled back" {
+			t.Errorf("got %s within the transaction, want its own change", found.Name)
+		}
+		return appError.NewBadRequestError("invalid_widget", "invalid widget")
+	})
+	renameBehindCache(t, repo, created.ID, "stale")
+	if found, _ := repo.FindByID(created.ID); found.Name != "alpha" {
+		t.Errorf("got %s after a rollback, want the cached alpha", found.Name)
+	}
+
+	appErr := uow.Do(context.Background(), func(tx *Tx) *appError.ApplicationError {
+		if err := repo.WithTx(tx).Update(map[string]interface{}{"id": created.ID}, map[string]interface{}{"name": "committed"}); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		return nil
+	})
+	if appErr != nil {
+		t.Fatalf("Do: %s", appErr.Message)
+	}
+	if found, _ := repo.FindByID(created.ID); found.Name != "committed" {
+		t.Errorf("got %s after the commit, want committed", found.Name)
+	}
+}
+
+func TestCachedRepositoryStaleLoad(t *testing.T) {
+	repo, cacheServer := newTestCachedRepository(t, time.Minute)
+	created, err := repo.Create(&widget{Name: "alpha"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	idKey := "repository:widgets:id:" + strconv.FormatUint(created.ID, 10)
+
+	// A load reads the widget, which is then renamed before the load caches it
+	stale, err := repo.BaseRepository.FindByID(created.ID)
+	if err != nil {
+		t.Fatalf("FindByID: %v", err)
+	}
+	if err := repo.Update(map[string]interface{}{"id": created.ID}, map[string]interface{}{"name": "beta"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	repo.store(idKey, stale)
+
+	if holdsRecord(cacheServer, idKey) {
+		t.Errorf("got the stale widget cached over the tombstone of %s", idKey)
+	}
+	if found, _ := repo.FindByID(created.ID); found.Name != "beta" {
+		t.Errorf("got %s, want the renamed widget", found.Name)
+	}
+}
+
+func TestCachedRepositoryUncachedColumns(t *testing.T) {
+	cacheService, cacheServer := cachetest.NewCacheService(t)
+	repo := NewCachedRepository(newTestRepository(t), cacheService, CacheOptions{TTL: time.Minute, UncachedColumns: []string{"name"}})
+	created, err := repo.Create(&widget{Name: "secret name", Rank: 1})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	idKey := "repository:widgets:id:" + strconv.FormatUint(created.ID, 10)
+
+	if found, err := repo.FindByID(created.ID); err != nil || found.Name != "secret name" {
+		t.Fatalf("FindByID: got %+v, %v", found, err)
+	}
+	value, _ := cacheServer.Get(idKey)
+	if value == "" || strings.Contains(value, "secret name") {
+		t.Errorf("got %q cached, want the widget without its name", value)
+	}
+
+	// The name of a cached widget is read from the database, and the rest from the cache
+	renameBehindCache(t, repo, created.ID, "new name")
+	if err := repo.Db.Session(&gorm.Session{}).Where("id = ?", created.ID).Update("rank", 2).Error; err != nil {
+		t.Fatalf("ranking the widget: %v", err)
+	}
+	if found, _ := repo.FindByID(created.ID); found.Name != "new name" || found.Rank != 1 {
+		t.Errorf("got %s ranked %d, want the stored name and the cached rank 1", found.Name, found.Rank)
+	}
+
+	if err := repo.Db.Session(&gorm.Session{}).Unscoped().Delete(&widget{}, created.ID).Error; err != nil {
+		t.Fatalf("deleting the widget: %v", err)
+	}
+	if found, err := repo.FindByID(created.ID); err != nil || found != nil {
+		t.Errorf("finding a widget deleted behind the cache: got %+v, %v, want nothing", found, err)
+	}
+}
+
+func TestCachedRepositoryWithoutTTL(t *testing.T) {
+	repo, cacheServer := newTestCachedRepository(t, 0)
+	created, err := repo.Create(&widget{Name: "alpha", Code: "A1"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	repo.FindByID(created.ID)
+	repo.FindByUnique("code", "A1")
+	if keys := cacheServer.Keys(); len(keys) != 0 {
+		t.Errorf("got keys %v, want nothing cached", keys)
+	}
+	renameBehindCache(t, repo, created.ID, "beta")
+	if found, _ := repo.FindByID(created.ID); found.Name != "beta" {
+		t.Errorf("got %s, want the stored name", found.Name)
+	}
+}
+
+func TestLoadGroup(t *testing.T) {
+	group := &loadGroup{loads: map[string]*load{}}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	loads := 0
+
+	var wg sync.WaitGroup
+	results := make([]bool, 5)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _, results[0] = group.do("key", func() (any, error) {
+			loads++
+			close(started)
+			<-release
+			return "loaded", nil
+		})
+	}()
+	<-started
+	for i := 1; i < len(results); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var model any
+			model, _, results[i] = group.do("key", func() (any, error) {
+				loads++
+				return "loaded again", nil
+			})
+			if model != "loaded" {
+				t.Errorf("got %v, want the shared load", model)
+			}
+		}(i)
+	}
+	// Give the other callers time to wait on the load in progress
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads != 1 || results[0] {
+		t.Errorf("got %d loads, the first one shared %v, want a single load by the first caller", loads, results[0])
+	}
+	for i, shared := range results[1:] {
+		if !shared {
+			t.Errorf("caller %d did not share the load", i+1)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Actions of the changes made by the writes of a repository. Custom writes tracked with Track may name their own.
+const (
+	ActionCreate     = "create"
+	ActionUpdate     = "update"
+	ActionDelete     = "delete"
+	ActionRestore    = "restore"
+	ActionHardDelete = "hard_delete"
+	// ActionErase is the action of writes clearing personal data, whose values change hooks must not keep.
+	ActionErase = "erase"
+)
+
+// Change describes how a write changed a single record. Before and After map the columns of the record
+// to their values around the write; Before is nil for a created record and After for a removed one.
+type Change struct {
+	Table    string
+	Action   string
+	EntityId string // EntityId is the public ID of the record, or its internal ID when the model has none
+	Before   map[string]interface{}
+	After    map[string]interface{}
+}
+
+// ChangeHook is called with the changes made by a write of a repository. It runs in the transaction
+// of the write, so that a failing hook rolls the write back and no change goes unrecorded.
+type ChangeHook func(tx *gorm.DB, changes []Change) error
+
+// WithChangeHook adds a hook called with the changes made by every write of the repository,
+// such as recording them in a history. Writes of a repository with change hooks run in a transaction.
+func (r *BaseRepository[T]) WithChangeHook(hook ChangeHook) *BaseRepository[T] {
+	r.changeHooks = append(r.changeHooks, hook)
+	return r
+}
+
+// Track runs write, which changes the records matching filter without the repository, such as in a transaction
+// of a custom repository, and passes the changes it made to the change hooks of the repository. write runs on db,
+// or on a transaction of db when the repository has change hooks.
+func (r *BaseRepository[T]) Track(db *gorm.DB, action string, filter any, write func(tx *gorm.DB) error) error {
+	return r.track(db, action, func(db *gorm.DB) *gorm.DB {
+		return db.Where(filter)
+	}, write)
+}
+
+// track runs write, which changes the records selected by scope, and passes the changes it made to the change
+// hooks. The records are read before and after write; those selected beforehand are read again by primary key,
+// as write may change the columns scope selects them by.
+func (r *BaseRepository[T]) track(db *gorm.DB, action string, scope Scope, write func(tx *gorm.DB) error) error {
+	if len(r.changeHooks) == 0 {
+		return write(db)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		before, err := r.snapshot(tx, scope)
+		if err != nil {
+			return err
+		}
+		if err := write(tx); err != nil {
+			return err
+		}
+		after, err := r.snapshot(tx, scope)
+		if err != nil {
+			return err
+		}
+		if len(before) > 0 {
+			ids := make([]interface{}, len(before))
+			for i, row := range before {
+				ids[i] = row.id
+			}
+			known, err := r.snapshot(tx, func(db *gorm.DB) *gorm.DB {
+				return db.Where(clause.IN{Column: clause.PrimaryColumn, Values: ids})
+			})
+			if err != nil {
+				return err
+			}
+			after = append(known, after...)
+		}
+
+		changes := r.changes(action, before, after)
+		if len(changes) == 0 {
+			return nil
+		}
+		for _, hook := range r.changeHooks {
+			if err := hook(tx, changes); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// trackInBatches is track for writes that may change many records. The records selected by scope are written
+// batchSize at a time, in primary key order and in a single transaction, so that only the records of a batch are
+// held in memory. write is given the scope of the batch, and the change hooks are called once per batch.
+// Without change hooks, write runs once with the whole scope.
+func (r *BaseRepository[T]) trackInBatches(db *gorm.DB, action string, scope Scope, batchSize int, write func(tx *gorm.DB, batch Scope) error) error {
+	if len(r.changeHooks) == 0 {
+		return write(db, func(db *gorm.DB) *gorm.DB { return db })
+	}
+	modelSchema, err := r.schema()
+	if err != nil {
+		return err
+	}
+	if modelSchema.PrioritizedPrimaryField == nil {
+		return fmt.Errorf("table %s has no primary key to track changes by", r.tableName)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var models []T
+		return tx.Session(&gorm.Session{NewDB: true}).Table(r.tableName).Unscoped().
+			Select(modelSchema.PrioritizedPrimaryField.DBName).
+			Scopes(scope).
+			FindInBatches(&models, batchSize, func(_ *gorm.DB, _ int) error {
+				selected := make([]*T, len(models))
+				for i := range models {
+					selected[i] = &models[i]
+				}
+				batch := r.primaryKeyScope(selected...)
+				return r.track(tx, action, batch, func(tx *gorm.DB) error {
+					return write(tx, batch)
+				})
+			}).Error
+	})
+}
+
+// snapshotRow holds the columns of a record read by snapshot.
+type snapshotRow struct {
+	id       interface{}
+	entityId string
+	columns  map[string]interface{}
+}
+
+// snapshot reads the records selected by scope, deleted or not, in primary key order.
+func (r *BaseRepository[T]) snapshot(tx *gorm.DB, scope Scope) ([]snapshotRow, error) {
+	var models []T
+	err := tx.Session(&gorm.Session{NewDB: true}).Table(r.tableName).Unscoped().
+		Scopes(scope).
+		Order(clause.OrderByColumn{Column: clause.PrimaryColumn}).
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	primaryKey := stmt.Schema.PrioritizedPrimaryField
+	if primaryKey == nil {
+		return nil, fmt.Errorf("table %s has no primary key to track changes by", r.tableName)
+	}
+	entityField := primaryKey
+	if r.publicIDColumn != "" {
+		if field := stmt.Schema.LookUpField(r.publicIDColumn); field != nil {
+			entityField = field
+		}
+	}
+
+	rows := make([]snapshotRow, len(models))
+	for i := range models {
+		value := reflect.ValueOf(&models[i]).Elem()
+		columns := make(map[string]interface{}, len(stmt.Schema.DBNames))
+		for _, column := range stmt.Schema.DBNames {
+			columns[column], _ = stmt.Schema.FieldsByDBName[column].ValueOf(tx.Statement.Context, value)
+		}
+		id, _ := primaryKey.ValueOf(tx.Statement.Context, value)
+		entityId, _ := entityField.ValueOf(tx.Statement.Context, value)
+		rows[i] = snapshotRow{id: id, columns: columns}
+		rows[i].entityId, _ = formatValue(entityId)
+	}
+	return rows, nil
+}
+
+// changes pairs the records read before and after a write by primary key, in the order they were read.
+// A record read more than once after the write is only paired once, and a record only read after the write
+// was created by it, such as by an upsert, whatever the action of the write.
+func (r *BaseRepository[T]) changes(action string, before, after []snapshotRow) []Change {
+	afterById := make(map[interface{}]snapshotRow, len(after))
+	var afterOrder []snapshotRow
+	for _, row := range after {
+		if _, seen := afterById[row.id]; !seen {
+			afterById[row.id] = row
+			afterOrder = append(afterOrder, row)
+		}
+	}
+
+	changes := make([]Change, 0, len(afterById))
+	for _, row := range before {
+		change := Change{Table: r.tableName, Action: action, EntityId: row.entityId, Before: row.columns}
+		if changed, ok := afterById[row.id]; ok {
+			change.After = changed.columns
+			delete(afterById, row.id)
+		}
+		changes = append(changes, change)
+	}
+	for _, row := range afterOrder {
+		if _, ok := afterById[row.id]; ok {
+			changes = append(changes, Change{Table: r.tableName, Action: ActionCreate, EntityId: row.entityId, After: row.columns})
+		}
+	}
+	return changes
+}
+
+// primaryKeyScope selects models by their primary keys, read when the scope is applied, so that a scope taken
+// before the models are created selects nothing and selects the created records afterwards.
+func (r *BaseRepository[T]) primaryKeyScope(models ...*T) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(new(T)); err != nil {
+			db.AddError(err)
+			return db
+		}
+		var ids []interface{}
+		for _, model := range models {
+			id, zero := stmt.Schema.PrioritizedPrimaryField.ValueOf(db.Statement.Context, reflect.ValueOf(model).Elem())
+			if !zero {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(clause.IN{Column: clause.PrimaryColumn, Values: ids})
+	}
+}
+
+// formatValue formats a column value as a string, following pointers. It reports false for a nil value.
+func formatValue(value any) (string, bool) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "", false
+	}
+	return fmt.Sprintf("%v", v.Interface()), true
+}
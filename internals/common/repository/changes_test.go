@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// recordChanges adds a change hook to the repository keeping the changes it is called with.
+func recordChanges(repo *BaseRepository[widget]) *[]Change {
+	var changes []Change
+	repo.WithChangeHook(func(tx *gorm.DB, batch []Change) error {
+		changes = append(changes, batch...)
+		return nil
+	})
+	return &changes
+}
+
+func TestChangeHooks(t *testing.T) {
+	repo := newTestRepository(t).WithPublicID("code")
+	changes := recordChanges(repo)
+
+	created, err := repo.Create(&widget{Code: "A1", Name: "alpha"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.Update(map[string]interface{}{"code": "A1"}, map[string]interface{}{"code": "B2", "name": "beta"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repo.Delete(created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	// Writes changing nothing call no hook
+	if err := repo.Update(map[string]interface{}{"code": "missing"}, map[string]interface{}{"name": "gamma"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repo.HardDelete(created.ID); err != nil {
+		t.Fatalf("HardDelete: %v", err)
+	}
+
+	want := []struct {
+		action   string
+		entityId string
+		before   interface{}
+		after    interface{}
+	}{
+		{ActionCreate, "A1", nil, "alpha"},
+		{ActionUpdate, "A1", "alpha", "beta"},
+		{ActionDelete, "B2", "beta", "beta"},
+		{ActionHardDelete, "B2", "beta", nil},
+	}
+	if len(*changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(*changes), len(want), *changes)
+	}
+	for i, change := range *changes {
+		if change.Table != "widgets" || change.Action != want[i].action || change.EntityId != want[i].entityId {
+			t.Errorf("change %d: got %s %s of %s, want %s %s", i, change.Action, change.EntityId, change.Table, want[i].action, want[i].entityId)
+		}
+		if got := change.Before["name"]; (change.Before == nil) != (want[i].before == nil) || (change.Before != nil && got != want[i].before) {
+			t.Errorf("change %d: got name %v before, want %v", i, got, want[i].before)
+		}
+		if got := change.After["name"]; (change.After == nil) != (want[i].after == nil) || (change.After != nil && got != want[i].after) {
+			t.Errorf("change %d: got name %v after, want %v", i, got, want[i].after)
+		}
+	}
+	if deleted := (*changes)[2]; deleted.Before["is_deleted"] != false || deleted.After["is_deleted"] != true {
+		t.Errorf("got is_deleted %v then %v on Delete, want false then true", deleted.Before["is_deleted"], deleted.After["is_deleted"])
+	}
+}
+
+func TestChangeHookFailureRollsBack(t *testing.T) {
+	repo := newTestRepository(t)
+	widgets := createWidgets(t, repo, "alpha")
+	failure := errors.New("history unavailable")
+	repo.WithChangeHook(func(tx *gorm.DB, changes []Change) error {
+		return failure
+	})
+
+	if _, err := repo.Create(&widget{Name: "beta"}); !errors.Is(err, failure) {
+		t.Errorf("Create: got %v, want the error of the hook", err)
+	}
+	if err := repo.Update(map[string]interface{}{"id": widgets[0].ID}, map[string]interface{}{"name": "gamma"}); !errors.Is(err, failure) {
+		t.Errorf("Update: got %v, want the error of the hook", err)
+	}
+	all, err := repo.FindAllBy(Query{})
+	if err != nil || !equalNames(widgetNames(all), "alpha") {
+		t.Errorf("got %v, %v, want the writes rolled back", widgetNames(all), err)
+	}
+}
+
+func TestTrack(t *testing.T) {
+	repo := newTestRepository(t)
+	widgets := createWidgets(t, repo, "alpha", "beta")
+	changes := recordChanges(repo)
+
+	err := repo.Track(repo.Db.Session(&gorm.Session{}), "rename", map[string]interface{}{"id": widgets[1].ID}, func(tx *gorm.DB) error {
+		return tx.Model(&widget{}).Where("id = ?", widgets[1].ID).Update("name", "gamma").Error
+	})
+	if err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+	if len(*changes) != 1 {
+		t.Fatalf("got %d changes, want 1", len(*changes))
+	}
+	change := (*changes)[0]
+	if change.Action != "rename" || change.Before["name"] != "beta" || change.After["name"] != "gamma" {
+		t.Errorf("got %s from %v to %v, want rename from beta to gamma", change.Action, change.Before["name"], change.After["name"])
+	}
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"backendService/internals/setup/config"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded, its signature does not match,
+// or it was issued for a different sort order than the one requested.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	cursorNext = "next"
+	cursorPrev = "prev"
+)
+
+// CursorRequest describes a keyset paginated query.
+// Rows are ordered by SortColumn and then by the repository key column, so the sort column
+// should be a non-null column. An empty Cursor fetches the first page.
+type CursorRequest struct {
+	Cursor     string
+	Limit      int
+	SortColumn string
+	Desc       bool
+	Scopes     []Scope
+}
+
+// CursorResult holds a single page of models fetched with keyset pagination.
+// NextCursor and PrevCursor are empty when there is no next or previous page.
+type CursorResult[T any] struct {
+	Items      []T
+	Limit      int
+	NextCursor string
+	PrevCursor string
+}
+
+// cursorPayload is the content of an opaque cursor. It records the sort order the cursor was issued
+// for and the values of the sort and key columns of the row the cursor points at.
+type cursorPayload struct {
+	Column    string          `json:"c"`
+	Desc      bool            `json:"d"`
+	Direction string          `json:"dir"`
+	Value     json.RawMessage `json:"v"`
+	Key       json.RawMessage `json:"k"`
+}
+
+// FindByCursor retrieves a page of models using keyset pagination.
+// Unlike FindPage, the cost of a query does not grow with the page number and rows inserted
+// or deleted between requests do not shift the pages. The returned cursors are signed, so a
+// tampered cursor is rejected with ErrInvalidCursor.
+func (r *BaseRepository[T]) FindByCursor(req CursorRequest) (*CursorResult[T], error) {
+	if req.Limit <= 0 {
+		req.Limit = defaultPageSize
+	}
+	if req.Limit > MaxPageSize {
+		req.Limit = MaxPageSize
+	}
+	if req.SortColumn == "" {
+		req.SortColumn = r.keyColumn
+	}
+
+	stmt := &gorm.Statement{DB: r.Db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, err
+	}
+	sortField := stmt.Schema.LookUpField(req.SortColumn)
+	keyField := stmt.Schema.LookUpField(r.keyColumn)
+	if sortField == nil || keyField == nil {
+		return nil, errors.New("unknown cursor column")
+	}
+
+	direction := cursorNext
+	session := r.Db.Session(&gorm.Session{}).Scopes(r.deletedScope).Scopes(req.Scopes...)
+
+	var cursorSortValue, cursorKeyValue interface{}
+	if req.Cursor != "" {
+		payload, err := decodeCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if payload.Column != req.SortColumn || payload.Desc != req.Desc {
+			return nil, ErrInvalidCursor
+		}
+
+		sortValue := reflect.New(sortField.FieldType)
+		keyValue := reflect.New(keyField.FieldType)
+		if json.Unmarshal(payload.Value, sortValue.Interface()) != nil || json.Unmarshal(payload.Key, keyValue.Interface()) != nil {
+			return nil, ErrInvalidCursor
+		}
+
+		direction = payload.Direction
+		cursorSortValue, cursorKeyValue = sortValue.Elem().Interface(), keyValue.Elem().Interface()
+	}
+
+	// A previous page is fetched by walking the index backwards and reversing the rows afterwards.
+	desc := req.Desc != (direction == cursorPrev)
+	if req.Cursor != "" {
+		session = session.Scopes(keysetScope(sortField.DBName, keyField.DBName, cursorSortValue, cursorKeyValue, desc))
+	}
+
+	models := []T{}
+	err := session.
+		Order(clause.OrderByColumn{Column: clause.Column{Name: sortField.DBName}, Desc: desc}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: keyField.DBName}, Desc: desc}).
+		Limit(req.Limit + 1).
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(models) > req.Limit
+	if hasMore {
+		models = models[:req.Limit]
+	}
+	if direction == cursorPrev {
+		for i, j := 0, len(models)-1; i < j; i, j = i+1, j-1 {
+			models[i], models[j] = models[j], models[i]
+		}
+	}
+
+	result := &CursorResult[T]{Items: models, Limit: req.Limit}
+	if len(models) == 0 {
+		return result, nil
+	}
+
+	// Coming from a cursor means there is a page on the side we came from.
+	hasNext := (direction == cursorNext && hasMore) || (direction == cursorPrev && req.Cursor != "")
+	hasPrev := (direction == cursorPrev && hasMore) || (direction == cursorNext && req.Cursor != "")
+
+	ctx := r.Db.Statement.Context
+	if hasNext {
+		last := reflect.ValueOf(&models[len(models)-1]).Elem()
+		sortValue, _ := sortField.ValueOf(ctx, last)
+		keyValue, _ := keyField.ValueOf(ctx, last)
+		if result.NextCursor, err = encodeCursor(req.SortColumn, req.Desc, cursorNext, sortValue, keyValue); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		first := reflect.ValueOf(&models[0]).Elem()
+		sortValue, _ := sortField.ValueOf(ctx, first)
+		keyValue, _ := keyField.ValueOf(ctx, first)
+		if result.PrevCursor, err = encodeCursor(req.SortColumn, req.Desc, cursorPrev, sortValue, keyValue); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// keysetScope selects the rows that come after the (sortValue, keyValue) position in the given order.
+func keysetScope(sortColumn, keyColumn string, sortValue, keyValue interface{}, desc bool) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		operator := " > ?"
+		if desc {
+			operator = " < ?"
+		}
+		sort := db.Statement.Quote(sortColumn)
+		key := db.Statement.Quote(keyColumn)
+		if sortColumn == keyColumn {
+			return db.Where(key+operator, keyValue)
+		}
+		return db.Where("("+sort+operator+" OR ("+sort+" = ? AND "+key+operator+"))", sortValue, sortValue, keyValue)
+	}
+}
+
+// encodeCursor serializes and signs a cursor pointing at the row with the given sort and key values.
+func encodeCursor(column string, desc bool, direction string, sortValue, keyValue interface{}) (string, error) {
+	value, err := json.Marshal(sortValue)
+	if err != nil {
+		return "", err
+	}
+	key, err := json.Marshal(keyValue)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(cursorPayload{Column: column, Desc: desc, Direction: direction, Value: value, Key: key})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(signCursor(encoded)), nil
+}
+
+// decodeCursor verifies the signature of a cursor and returns its payload.
+func decodeCursor(cursor string) (*cursorPayload, error) {
+	encoded, signature, found := strings.Cut(cursor, ".")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+	providedSignature, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(providedSignature, signCursor(encoded)) {
+		return nil, ErrInvalidCursor
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if payload.Direction != cursorNext && payload.Direction != cursorPrev {
+		return nil, ErrInvalidCursor
+	}
+	return &payload, nil
+}
+
+// signCursor computes the HMAC of an encoded cursor payload with the application secret key.
+func signCursor(encoded string) []byte {
+	mac := hmac.New(sha256.New, []byte(config.Config.App.SecretKey))
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"backendService/internals/setup/config"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// setSecretKey signs the cursors issued until the end of the test with key.
+func setSecretKey(t *testing.T, key string) {
+	t.Helper()
+	previous := config.Config
+	config.Config.App.SecretKey = key
+	t.Cleanup(func() { config.Config = previous })
+}
+
+func TestFindByCursor(t *testing.T) {
+	setSecretKey(t, "test_secret_key")
+	repo := newTestRepository(t)
+	createWidgets(t, repo, "b", "a", "b", "c", "a")
+	request := CursorRequest{Limit: 2, SortColumn: "name"}
+
+	var pages [][]string
+	var cursors []string
+	for cursor := ""; ; {
+		request.Cursor = cursor
+		page, err := repo.FindByCursor(request)
+		if err != nil {
+			t.Fatalf("FindByCursor page %d: %v", len(pages)+1, err)
+		}
+		if (cursor == "") != (page.PrevCursor == "") {
+			t.Errorf("page %d: got previous cursor %q", len(pages)+1, page.PrevCursor)
+		}
+		pages = append(pages, widgetNames(page.Items))
+		cursors = append(cursors, page.PrevCursor)
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(pages) != 3 || !equalNames(pages[0], "a", "a") || !equalNames(pages[1], "b", "b") || !equalNames(pages[2], "c") {
+		t.Fatalf("walking forward: got pages %v, want [[a a] [b b] [c]]", pages)
+	}
+
+	// Going back from the last page returns the same rows as going forward did.
+	request.Cursor = cursors[2]
+	page, err := repo.FindByCursor(request)
+	if err != nil {
+		t.Fatalf("FindByCursor backwards: %v", err)
+	}
+	if names := widgetNames(page.Items); !equalNames(names, "b", "b") || page.Items[0].Rank != 1 || page.NextCursor == "" || page.PrevCursor == "" {
+		t.Errorf("going back: got %v with cursors %q and %q, want [b b] in ID order with both cursors", names, page.PrevCursor, page.NextCursor)
+	}
+
+	request.Desc = true
+	request.Cursor = ""
+	page, err = repo.FindByCursor(request)
+	if err != nil {
+		t.Fatalf("FindByCursor descending: %v", err)
+	}
+	if names := widgetNames(page.Items); !equalNames(names, "c", "b") || page.Items[1].Rank != 3 {
+		t.Errorf("descending: got %v, want [c b] with the later b first", names)
+	}
+}
+
+func TestFindByCursorRejectsInvalidCursors(t *testing.T) {
+	setSecretKey(t, "test_secret_key")
+	repo := newTestRepository(t)
+	createWidgets(t, repo, "a", "b", "c")
+
+	page, err := repo.FindByCursor(CursorRequest{Limit: 1, SortColumn: "name"})
+	if err != nil {
+		t.Fatalf("FindByCursor: %v", err)
+	}
+	cursor := page.NextCursor
+	encoded, signature, _ := strings.Cut(cursor, ".")
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("decoding cursor: %v", err)
+	}
+	tampered := base64.RawURLEncoding.EncodeToString(bytes.Replace(raw, []byte(`"a"`), []byte(`"b"`), 1)) + "." + signature
+
+	valid, err := encodeCursor("name", false, cursorNext, "a", 1)
+	if err != nil {
+		t.Fatalf("encodeCursor: %v", err)
+	}
+	setSecretKey(t, "another_secret_key")
+	forged, err := encodeCursor("name", false, cursorNext, "a", 1)
+	if err != nil {
+		t.Fatalf("encodeCursor: %v", err)
+	}
+	setSecretKey(t, "test_secret_key")
+
+	for name, request := range map[string]CursorRequest{
+		"tampered":         {Cursor: tampered, SortColumn: "name"},
+		"unsigned":         {Cursor: encoded, SortColumn: "name"},
+		"signed elsewhere": {Cursor: forged, SortColumn: "name"},
+		"garbage":          {Cursor: "not.a-cursor", SortColumn: "name"},
+		"other column":     {Cursor: cursor, SortColumn: "rank"},
+		"other direction":  {Cursor: cursor, SortColumn: "name", Desc: true},
+	} {
+		if _, err := repo.FindByCursor(request); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s cursor: got %v, want ErrInvalidCursor", name, err)
+		}
+	}
+
+	if _, err := repo.FindByCursor(CursorRequest{Cursor: valid, SortColumn: "name"}); err != nil {
+		t.Errorf("cursor signed with the current key: got %v", err)
+	}
+}
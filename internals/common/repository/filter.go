@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUnknownColumn is returned when a filter or an ordering names a column the model does not have,
+// or one that is not filterable.
+var ErrUnknownColumn = errors.New("unknown column")
+
+// Operator is the comparison a filter condition applies to a column.
+type Operator string
+
+// Operators supported by filter conditions.
+const (
+	OpEq      Operator = "eq"
+	OpNe      Operator = "ne"
+	OpGte     Operator = "gte"
+	OpLte     Operator = "lte"
+	OpIn      Operator = "in"
+	OpLike    Operator = "like"
+	OpBetween Operator = "between"
+	OpIsNull  Operator = "isnull"
+)
+
+// Filter is a condition on the columns of a model, built with Eq, Ne, Gte, Lte, In, Like, Contains, Between,
+// IsNull and NotNull, and grouped with And and Or. Column names are checked against the model and
+// quoted, and values are always bound as parameters, so neither ends up in the SQL as-is.
+// The zero Filter matches every row.
+type Filter struct {
+	column     string
+	operator   Operator
+	values     []interface{}
+	ignoreCase bool // ignoreCase compares lowercase values, for Contains
+
+	group   string // group is "AND" or "OR" when the filter combines other filters
+	filters []Filter
+}
+
+// Eq matches rows where column equals value.
+func Eq(column string, value interface{}) Filter {
+	return Filter{column: column, operator: OpEq, values: []interface{}{value}}
+}
+
+// Ne matches rows where column differs from value. Rows where column is NULL never match.
+func Ne(column string, value interface{}) Filter {
+	return Filter{column: column, operator: OpNe, values: []interface{}{value}}
+}
+
+// Gte matches rows where column is greater than or equal to value.
+func Gte(column string, value interface{}) Filter {
+	return Filter{column: column, operator: OpGte, values: []interface{}{value}}
+}
+
+// Lte matches rows where column is less than or equal to value.
+func Lte(column string, value interface{}) Filter {
+	return Filter{column: column, operator: OpLte, values: []interface{}{value}}
+}
+
+// In matches rows where column equals any of the values, given as a slice. An empty slice matches nothing.
+func In(column string, values interface{}) Filter {
+	return Filter{column: column, operator: OpIn, values: []interface{}{values}}
+}
+
+// Like matches rows where column matches pattern, in which % and _ are wildcards.
+func Like(column string, pattern string) Filter {
+	return Filter{column: column, operator: OpLike, values: []interface{}{pattern}}
+}
+
+// Contains matches rows where column contains term, ignoring case. Unlike Like, wildcards in term
+// are matched literally, so it is safe to use with user input.
+func Contains(column string, term string) Filter {
+	return Filter{column: column, operator: OpLike, values: []interface{}{containsPattern(term)}, ignoreCase: true}
+}
+
+// Between matches rows where column lies between from and to, both included.
+func Between(column string, from interface{}, to interface{}) Filter {
+	return Filter{column: column, operator: OpBetween, values: []interface{}{from, to}}
+}
+
+// IsNull matches rows where column is NULL.
+func IsNull(column string) Filter {
+	return Filter{column: column, operator: OpIsNull, values: []interface{}{true}}
+}
+
+// NotNull matches rows where column is not NULL.
+func NotNull(column string) Filter {
+	return Filter{column: column, operator: OpIsNull, values: []interface{}{false}}
+}
+
+// And matches rows matching every one of the filters. Zero filters are left out.
+func And(filters ...Filter) Filter {
+	return group("AND", filters)
+}
+
+// Or matches rows matching any of the filters. Zero filters are left out.
+func Or(filters ...Filter) Filter {
+	return group("OR", filters)
+}
+
+func group(operator string, filters []Filter) Filter {
+	kept := make([]Filter, 0, len(filters))
+	for _, filter := range filters {
+		if !filter.IsZero() {
+			kept = append(kept, filter)
+		}
+	}
+	if len(kept) == 1 {
+		return kept[0]
+	}
+	return Filter{group: operator, filters: kept}
+}
+
+// IsZero reports whether the filter has no condition and matches every row.
+func (f Filter) IsZero() bool {
+	return f.operator == "" && len(f.filters) == 0
+}
+
+// Query describes a filtered read: the rows matching Filter, ordered by Sort, one page at a time.
+// A zero PageSize reads the default page size.
+type Query struct {
+	Filter   Filter
+	Sort     []SortOrder
+	Page     int
+	PageSize int
+}
+
+// WithFilterable restricts the columns filters and orderings may use to the given ones, such as to keep
+// secrets out of reach. Without it, every column of the model can be used.
+func (r *BaseRepository[T]) WithFilterable(columns ...string) *BaseRepository[T] {
+	r.filterable = make(map[string]bool, len(columns))
+	for _, column := range columns {
+		r.filterable[column] = true
+	}
+	return r
+}
+
+// FilterScope returns a scope applying the filter to a query. A filter naming a column the model does not
+// have, or one left out by WithFilterable, makes the query fail with ErrUnknownColumn.
+func (r *BaseRepository[T]) FilterScope(filter Filter) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.IsZero() {
+			return db
+		}
+		expression, err := r.filterExpression(filter)
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+		return db.Where(expression)
+	}
+}
+
+// filterExpression turns the filter into a clause expression, checking the columns it names.
+func (r *BaseRepository[T]) filterExpression(filter Filter) (clause.Expression, error) {
+	if filter.group != "" {
+		expressions := make([]clause.Expression, len(filter.filters))
+		for i, child := range filter.filters {
+			expression, err := r.filterExpression(child)
+			if err != nil {
+				return nil, err
+			}
+			expressions[i] = expression
+		}
+		if filter.group == "OR" {
+			return clause.Or(expressions...), nil
+		}
+		return clause.And(expressions...), nil
+	}
+
+	column, err := r.filterColumn(filter.column)
+	if err != nil {
+		return nil, err
+	}
+	switch filter.operator {
+	case OpEq:
+		return clause.Eq{Column: column, Value: filter.values[0]}, nil
+	case OpNe:
+		return clause.Neq{Column: column, Value: filter.values[0]}, nil
+	case OpGte:
+		return clause.Gte{Column: column, Value: filter.values[0]}, nil
+	case OpLte:
+		return clause.Lte{Column: column, Value: filter.values[0]}, nil
+	case OpIn:
+		values := reflect.ValueOf(filter.values[0])
+		if values.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("in filter on %s expects a slice", filter.column)
+		}
+		in := clause.IN{Column: column, Values: make([]interface{}, values.Len())}
+		for i := range in.Values {
+			in.Values[i] = values.Index(i).Interface()
+		}
+		return in, nil
+	case OpLike:
+		if filter.ignoreCase {
+			return clause.Expr{SQL: "LOWER(?) LIKE ? ESCAPE '" + likeEscapeChar + "'", Vars: []interface{}{column, filter.values[0]}}, nil
+		}
+		return clause.Like{Column: column, Value: filter.values[0]}, nil
+	case OpBetween:
+		return clause.Expr{SQL: "? BETWEEN ? AND ?", Vars: []interface{}{column, filter.values[0], filter.values[1]}}, nil
+	case OpIsNull:
+		if filter.values[0] == true {
+			return clause.Expr{SQL: "? IS NULL", Vars: []interface{}{column}}, nil
+		}
+		return clause.Expr{SQL: "? IS NOT NULL", Vars: []interface{}{column}}, nil
+	default:
+		return nil, fmt.Errorf("unknown filter operator %q", filter.operator)
+	}
+}
+
+// filterColumn checks that the model has the column and that it may be filtered on,
+// and returns it qualified with the table of the query.
+func (r *BaseRepository[T]) filterColumn(name string) (clause.Column, error) {
+	stmt := &gorm.Statement{DB: r.Db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return clause.Column{}, err
+	}
+	if _, ok := stmt.Schema.FieldsByDBName[name]; !ok || (r.filterable != nil && !r.filterable[name]) {
+		return clause.Column{}, fmt.Errorf("%w: %s", ErrUnknownColumn, name)
+	}
+	return clause.Column{Table: clause.CurrentTable, Name: name}, nil
+}
+
+// orderScope orders a query by the given columns after checking them like filter columns.
+func (r *BaseRepository[T]) orderScope(sort []SortOrder) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, order := range sort {
+			column, err := r.filterColumn(order.Column)
+			if err != nil {
+				db.AddError(err)
+				return db
+			}
+			db = db.Order(clause.OrderByColumn{Column: column, Desc: order.Desc})
+		}
+		return db
+	}
+}
+
+// containsPattern turns a search term into a lowercase LIKE pattern matching it literally anywhere in a value.
+func containsPattern(term string) string {
+	replacer := strings.NewReplacer(likeEscapeChar, likeEscapeChar+likeEscapeChar, "%", likeEscapeChar+"%", "_", likeEscapeChar+"_")
+	return "%" + strings.ToLower(replacer.Replace(term)) + "%"
+}
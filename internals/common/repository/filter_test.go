@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	repo := newTestRepository(t)
+	widgets := createWidgets(t, repo, "alpha", "beta", "gamma", "delta", "100%_sure")
+	if err := repo.Update(map[string]interface{}{"id": widgets[0].ID}, map[string]interface{}{"code": "A1"}); err != nil {
+		t.Fatalf("setting a code: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"zero", Filter{}, []string{"alpha", "beta", "gamma", "delta", "100%_sure"}},
+		{"eq", Eq("name", "beta"), []string{"beta"}},
+		{"ne", Ne("name", "beta"), []string{"alpha", "gamma", "delta", "100%_sure"}},
+		{"gte", Gte("rank", 4), []string{"delta", "100%_sure"}},
+		{"lte", Lte("rank", 2), []string{"alpha", "beta"}},
+		{"in", In("name", []string{"gamma", "alpha", "omega"}), []string{"alpha", "gamma"}},
+		{"empty in", In("name", []string{}), nil},
+		{"like", Like("name", "%ta"), []string{"beta", "delta"}},
+		{"like wildcard", Like("name", "_eta"), []string{"beta"}},
+		{"contains", Contains("name", "ELT"), []string{"delta"}},
+		{"contains wildcards literally", Contains("name", "%_"), []string{"100%_sure"}},
+		{"contains an underscore literally", Contains("name", "_"), []string{"100%_sure"}},
+		{"between", Between("rank", 2, 3), []string{"beta", "gamma"}},
+		{"not null", And(NotNull("code"), Ne("code", "")), []string{"alpha"}},
+		{"and", And(Gte("rank", 2), Lte("rank", 4), Ne("name", "gamma")), []string{"beta", "delta"}},
+		{"or", Or(Eq("name", "alpha"), Eq("rank", 5)), []string{"alpha", "100%_sure"}},
+		{"nested", Or(Eq("name", "alpha"), And(Gte("rank", 3), Like("name", "%a"))), []string{"alpha", "gamma", "delta"}},
+		{"groups of zero filters", And(Filter{}, Or(Filter{}, Eq("name", "beta"))), []string{"beta"}},
+	}
+	for _, test := range tests {
+		found, err := repo.FindAllBy(Query{Filter: test.filter, Sort: []SortOrder{{Column: "rank"}}})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if names := widgetNames(found); !equalNames(names, test.want...) {
+			t.Errorf("%s: got %v, want %v", test.name, names, test.want)
+		}
+	}
+}
+
+func TestFiltersRejectUnknownColumns(t *testing.T) {
+	repo := newTestRepository(t)
+	createWidgets(t, repo, "alpha")
+
+	tests := []struct {
+		name  string
+		query Query
+	}{
+		{"missing column", Query{Filter: Eq("color", "red")}},
+		{"injected column", Query{Filter: Eq("name = name OR 1", 1)}},
+		{"nested missing column", Query{Filter: Or(Eq("name", "alpha"), And(Eq("rank", 1), IsNull("color")))}},
+		{"missing sort column", Query{Sort: []SortOrder{{Column: "color"}}}},
+	}
+	for _, test := range tests {
+		if _, err := repo.FindAllBy(test.query); !errors.Is(err, ErrUnknownColumn) {
+			t.Errorf("%s: got %v, want ErrUnknownColumn", test.name, err)
+		}
+	}
+
+	// Only the filterable columns can be used once they are set
+	repo.WithFilterable("name")
+	if found, err := repo.FindOneBy(Eq("name", "alpha")); err != nil || found == nil {
+		t.Errorf("filtering on a filterable column: got %+v, %v", found, err)
+	}
+	if _, err := repo.FindOneBy(Eq("code", "")); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("filtering on a column that is not filterable: got %v, want ErrUnknownColumn", err)
+	}
+	if _, err := repo.FindAllBy(Query{Sort: []SortOrder{{Column: "rank"}}}); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("ordering on a column that is not filterable: got %v, want ErrUnknownColumn", err)
+	}
+	if _, err := repo.FindAllBy(Query{Filter: In("name", "alpha")}); err == nil {
+		t.Error("filtering with In on a single value: got no error")
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"backendService/internals/setup/config"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PurgeFunc removes the records referring to rows about to be purged, given their internal IDs.
+// It runs in the transaction deleting the rows, so that nothing is left pointing at them.
+type PurgeFunc func(tx *gorm.DB, ids []uint64) error
+
+// PurgeDeleted permanently removes up to limit records soft-deleted at or before deletedBefore, oldest first,
+// after running fns in the same transaction. Records soft-deleted before deleted_at was recorded are aged by
+// their last update instead. It returns how many records were removed, which is less than limit once every
+// record due has been purged.
+func (r *BaseRepository[T]) PurgeDeleted(deletedBefore time.Time, limit int, fns ...PurgeFunc) (int64, error) {
+	if !r.softDelete {
+		return 0, nil
+	}
+
+	var ids []uint64
+	err := r.Db.Session(&gorm.Session{}).Unscoped().Model(new(T)).
+		Where("is_deleted = ? AND COALESCE(deleted_at, updated_at) <= ?", true, deletedBefore).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	var purged int64
+	err = r.Db.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		for _, fn := range fns {
+			if err := fn(tx, ids); err != nil {
+				return err
+			}
+		}
+		// A record restored in the meantime is kept
+		scope := func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped().Where("id IN ? AND is_deleted = ?", ids, true)
+		}
+		return r.track(tx, ActionHardDelete, scope, func(tx *gorm.DB) error {
+			result := tx.Scopes(scope).Delete(new(T))
+			purged = result.RowsAffected
+			return result.Error
+		})
+	})
+	return purged, err
+}
+
+// PurgeRetention returns how long soft-deleted records are kept before being purged, defaulting to ninety days.
+func PurgeRetention() time.Duration {
+	if config.Config.SoftDelete.RetentionDays <= 0 {
+		return 90 * 24 * time.Hour
+	}
+	return time.Duration(config.Config.SoftDelete.RetentionDays) * 24 * time.Hour
+}
+
+// PurgeInterval returns how often soft-deleted records due for purging are looked for, defaulting to one hour.
+func PurgeInterval() time.Duration {
+	if config.Config.SoftDelete.PurgeIntervalMinutes <= 0 {
+		return time.Hour
+	}
+	return time.Duration(config.Config.SoftDelete.PurgeIntervalMinutes) * time.Minute
+}
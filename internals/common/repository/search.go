@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"html"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxSearchTerms bounds the number of words of a search query.
+	maxSearchTerms = 8
+	// highlightStart and highlightEnd wrap the matched part of highlighted values.
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+)
+
+// ErrSearchNotConfigured is returned by Search when the repository was not set up with WithSearch.
+var ErrSearchNotConfigured = errors.New("search is not configured for this repository")
+
+// SearchHit is a model matching a search, with its relevance and highlighted values.
+// Rank is higher for better matches, but its scale depends on the database and only orders hits.
+// Highlights holds the searchable columns whose value matched, HTML escaped, with the matched
+// parts wrapped in <mark> tags.
+type SearchHit[T any] struct {
+	Item       T
+	Rank       float64
+	Highlights map[string]string
+}
+
+// searchIndex describes the columns searched by a repository and whether the database can search them natively.
+type searchIndex struct {
+	columns []string
+	native  bool
+}
+
+// WithSearch enables full-text search over the given text columns, using the search engine of the database:
+// a tsvector GIN index on Postgres, a FULLTEXT index on MySQL and an FTS5 table on SQLite.
+// The index is created by a migration over the same columns, and CheckSearchIndex must be called to use it.
+func (r *BaseRepository[T]) WithSearch(columns ...string) *BaseRepository[T] {
+	r.search = &searchIndex{columns: columns}
+	return r
+}
+
+// CheckSearchIndex checks that the full-text index configured with WithSearch exists, so that searches use it.
+// When it does not, search falls back to slower, unranked LIKE matching and the reason is returned.
+// The index is only ever created by migrations, never by the application.
+func (r *BaseRepository[T]) CheckSearchIndex() error {
+	if r.search == nil {
+		return ErrSearchNotConfigured
+	}
+	db := r.Db.Session(&gorm.Session{NewDB: true})
+
+	var exists bool
+	switch db.Dialector.Name() {
+	case "postgres", "mysql":
+		exists = db.Migrator().HasIndex(r.tableName, r.searchIndexName())
+	case "sqlite":
+		exists = db.Migrator().HasTable(r.searchTableName())
+	default:
+		return errors.New("full-text search is not supported on " + db.Dialector.Name())
+	}
+	r.search.native = exists
+	if !exists {
+		return errors.New("the full-text index of table " + r.tableName + " does not exist, run the migrate up command")
+	}
+	return nil
+}
+
+// Search returns a page of models matching every word of term, best matches first.
+// Words match the start of words in the searchable columns, so partial names, emails and numbers are found.
+// The request scopes narrow down the models searched, and its sort order is ignored.
+func (r *BaseRepository[T]) Search(term string, req PageRequest) (*PageResult[SearchHit[T]], error) {
+	if r.search == nil {
+		return nil, ErrSearchNotConfigured
+	}
+	pageSize := req.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+	page := req.Page
+	if page <= 0 {
+		page = 1
+	}
+	result := &PageResult[SearchHit[T]]{Items: []SearchHit[T]{}, Page: page, PageSize: pageSize}
+
+	terms := searchTerms(term)
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	match, rank, rankable := r.searchQuery(terms)
+	db := r.Db.Session(&gorm.Session{}).Scopes(r.deletedScope).Scopes(req.Scopes...)
+
+	if err := db.Model(new(T)).Scopes(match).Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+	result.TotalPages = int((result.Total + int64(pageSize) - 1) / int64(pageSize))
+	if result.Total == 0 {
+		return result, nil
+	}
+
+	// Ranking only needs the IDs and the searchable values, the models are loaded afterwards
+	columns := make([]string, len(r.search.columns))
+	for i, column := range r.search.columns {
+		columns[i] = r.tableName + "." + column
+	}
+	rows, err := db.Model(new(T)).Scopes(rankable).
+		Select(r.tableName + ".id, " + rank + " AS search_rank, " + strings.Join(columns, ", ")).
+		Order("search_rank DESC").Order(r.tableName + ".id").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type rankedRow struct {
+		id         uint64
+		rank       float64
+		highlights map[string]string
+	}
+	var hits []rankedRow
+	for rows.Next() {
+		var id uint64
+		var rank sql.NullFloat64
+		values := make([]sql.NullString, len(r.search.columns))
+		dest := []interface{}{&id, &rank}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		highlights := map[string]string{}
+		for i, value := range values {
+			if highlighted, ok := highlight(value.String, terms); ok {
+				highlights[r.search.columns[i]] = highlighted
+			}
+		}
+		hits = append(hits, rankedRow{id: id, rank: rank.Float64, highlights: highlights})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return result, nil
+	}
+
+	ids := make([]uint64, len(hits))
+	for i, row := range hits {
+		ids[i] = row.id
+	}
+	var models []T
+	if err := r.Db.Session(&gorm.Session{}).Find(&models, ids).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint64]T, len(models))
+	for _, model := range models {
+		byID[reflect.ValueOf(model).FieldByName("ID").Uint()] = model
+	}
+	for _, row := range hits {
+		model, ok := byID[row.id]
+		if !ok {
+			// Deleted between the two queries
+			continue
+		}
+		result.Items = append(result.Items, SearchHit[T]{Item: model, Rank: row.rank, Highlights: row.highlights})
+	}
+	return result, nil
+}
+
+// searchQuery returns, for the database in use, the scope keeping the rows matching every term,
+// the SQL expression ranking them, and the scope selecting the matching rows in a way the ranking
+// expression can be evaluated on.
+func (r *BaseRepository[T]) searchQuery(terms []string) (match Scope, rank string, rankable Scope) {
+	if !r.search.native {
+		match = func(db *gorm.DB) *gorm.DB {
+			for _, term := range terms {
+				db = db.Scopes(ContainsScope(term, r.search.columns...))
+			}
+			return db
+		}
+		return match, "0", match
+	}
+
+	switch r.Db.Dialector.Name() {
+	case "postgres":
+		prefixes := make([]string, len(terms))
+		for i, term := range terms {
+			prefixes[i] = term + ":*"
+		}
+		query := strings.Join(prefixes, " & ")
+		match = func(db *gorm.DB) *gorm.DB {
+			return db.Where(r.postgresDocument()+" @@ to_tsquery('simple', ?)", query)
+		}
+		rank = "ts_rank(" + r.postgresDocument() + ", to_tsquery('simple', '" + query + "'))"
+		return match, rank, match
+
+	case "mysql":
+		prefixes := make([]string, len(terms))
+		for i, term := range terms {
+			prefixes[i] = "+" + term + "*"
+		}
+		query := strings.Join(prefixes, " ")
+		against := "MATCH (" + strings.Join(r.search.columns, ", ") + ") AGAINST ('" + query + "' IN BOOLEAN MODE)"
+		match = func(db *gorm.DB) *gorm.DB {
+			return db.Where(against)
+		}
+		return match, against, match
+
+	default:
+		ftsTable := r.searchTableName()
+		prefixes := make([]string, len(terms))
+		for i, term := range terms {
+			prefixes[i] = term + "*"
+		}
+		query := strings.Join(prefixes, " ")
+		match = func(db *gorm.DB) *gorm.DB {
+			return db.Where(r.tableName+".id IN (SELECT rowid FROM "+ftsTable+" WHERE "+ftsTable+" MATCH ?)", query)
+		}
+		// bm25 can only be evaluated when the FTS5 table is part of the query
+		rankable = func(db *gorm.DB) *gorm.DB {
+			return db.Joins("JOIN "+ftsTable+" ON "+ftsTable+".rowid = "+r.tableName+".id AND "+ftsTable+" MATCH ?", query)
+		}
+		// bm25 is lower for better matches, so it is negated to rank them first
+		return match, "-bm25(" + ftsTable + ")", rankable
+	}
+}
+
+// postgresDocument returns the tsvector expression indexed on Postgres. Email separators are replaced
+// with spaces, so each part of an address can be searched on its own.
+func (r *BaseRepository[T]) postgresDocument() string {
+	values := make([]string, len(r.search.columns))
+	for i, column := range r.search.columns {
+		values[i] = "COALESCE(" + r.tableName + "." + column + ", '')"
+	}
+	return "to_tsvector('simple', translate(" + strings.Join(values, " || ' ' || ") + ", '@._-+', '     '))"
+}
+
+func (r *BaseRepository[T]) searchIndexName() string {
+	return "idx_" + r.tableName + "_search"
+}
+
+func (r *BaseRepository[T]) searchTableName() string {
+	return r.tableName + "_search"
+}
+
+// searchTerms splits a search query into lower case words of letters and digits. Everything else separates
+// words, so the terms can be embedded in the query syntax of every database without escaping.
+func searchTerms(query string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, word := range strings.FieldsFunc(strings.ToLower(query), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	}) {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// highlight HTML escapes value and marks the start of every word beginning with one of the terms.
+// It reports false when no word matched. Highlighting is done here rather than by the database,
+// so that it behaves the same on every database and the values are always escaped.
+func highlight(value string, terms []string) (string, bool) {
+	var builder strings.Builder
+	matched := false
+	runes := []rune(value)
+	for start := 0; start < len(runes); {
+		end := start
+		isWord := unicode.IsLetter(runes[start]) || unicode.IsDigit(runes[start])
+		for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) == isWord {
+			end++
+		}
+		segment := string(runes[start:end])
+		start = end
+
+		if !isWord {
+			builder.WriteString(html.EscapeString(segment))
+			continue
+		}
+		longest := 0
+		lower := strings.ToLower(segment)
+		for _, term := range terms {
+			if strings.HasPrefix(lower, term) && len([]rune(term)) > longest {
+				longest = len([]rune(term))
+			}
+		}
+		if longest == 0 {
+			builder.WriteString(html.EscapeString(segment))
+			continue
+		}
+		matched = true
+		word := []rune(segment)
+		builder.WriteString(highlightStart + html.EscapeString(string(word[:longest])) + highlightEnd + html.EscapeString(string(word[longest:])))
+	}
+	return builder.String(), matched
+}
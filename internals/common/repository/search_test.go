@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSearchTerms(t *testing.T) {
+	tests := map[string][]string{
+		"Ada Lovelace":            {"ada", "lovelace"},
+		"ada@example.com":         {"ada", "example", "com"},
+		"  O'Brien  o'brien ":     {"o", "brien"},
+		"Zoë 42":                  {"zoë", "42"},
+		"'; DROP TABLE users; --": {"drop", "table", "users"},
+		"a b c d e f g h i j":     {"a", "b", "c", "d", "e", "f", "g", "h"},
+		"*:& +-":                  nil,
+	}
+	for query, want := range tests {
+		if got := searchTerms(query); !reflect.DeepEqual(got, want) {
+			t.Errorf("searchTerms(%q): got %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		value   string
+		terms   []string
+		want    string
+		matched bool
+	}{
+		{"Ada Lovelace", []string{"love"}, "Ada <mark>Love</mark>lace", true},
+		{"ada.lovelace@example.com", []string{"ada", "ex"}, "<mark>ada</mark>.lovelace@<mark>ex</mark>ample.com", true},
+		{"Adam", []string{"a", "ada"}, "<mark>Ada</mark>m", true},
+		{"<script>ada</script>", []string{"ada"}, "&lt;script&gt;<mark>ada</mark>&lt;/script&gt;", true},
+		{"Zoë Zoëlle", []string{"zoë"}, "<mark>Zoë</mark> <mark>Zoë</mark>lle", true},
+		{"Grace & Hopper", []string{"ada"}, "Grace &amp; Hopper", false},
+		{"", []string{"ada"}, "", false},
+	}
+	for _, test := range tests {
+		got, matched := highlight(test.value, test.terms)
+		if got != test.want || matched != test.matched {
+			t.Errorf("highlight(%q, %q): got %q, %v, want %q, %v", test.value, test.terms, got, matched, test.want, test.matched)
+		}
+	}
+}
+
+func TestSearchWithoutIndex(t *testing.T) {
+	repo := newTestRepository(t)
+	if _, err := repo.Search("widget", PageRequest{}); !errors.Is(err, ErrSearchNotConfigured) {
+		t.Errorf("searching without WithSearch: got %v, want ErrSearchNotConfigured", err)
+	}
+
+	// The widgets table has no full-text index, so search falls back to LIKE matching.
+	repo.WithSearch("name")
+	if err := repo.CheckSearchIndex(); err == nil {
+		t.Error("checking a missing index: got no error")
+	}
+	createWidgets(t, repo, "Blue widget", "Red widget", "Blue gadget", "100% blue")
+
+	page, err := repo.Search("widget BLUE", PageRequest{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if page.Total != 1 || len(page.Items) != 1 || page.Items[0].Item.Name != "Blue widget" {
+		t.Fatalf("got %+v, want only the blue widget", page)
+	}
+	if got := page.Items[0].Highlights["name"]; got != "<mark>Blue</mark> <mark>widget</mark>" {
+		t.Errorf("got highlight %q", got)
+	}
+
+	// Punctuation only separates words, so it never reaches the query as a wildcard.
+	page, err = repo.Search("%", PageRequest{})
+	if err != nil || page.Total != 0 {
+		t.Errorf("searching for %%: got %+v, %v, want nothing", page, err)
+	}
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/logger"
+
+	"gorm.io/gorm"
+)
+
+// UnitOfWork runs functions inside database transactions, so that the changes they make
+// through repositories bound to the transaction are committed or rolled back together.
+type UnitOfWork struct {
+	db *gorm.DB
+}
+
+// Tx is a transaction in progress, handed to the functions run by a UnitOfWork.
+// Repositories are bound to it with WithTx.
+type Tx struct {
+	db          *gorm.DB
+	afterCommit *[]func() // afterCommit holds the functions registered with AfterCommit, shared with savepoints
+}
+
+// NewUnitOfWork creates a UnitOfWork running transactions on the given database connection.
+func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// Do runs fn inside a new transaction bound to ctx. The transaction is committed when fn returns nil, and rolled
+// back when fn returns an error or panics, in which case the panic is propagated after the rollback. It is also
+// rolled back when ctx is done before the transaction commits.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(tx *Tx) *appError.ApplicationError) *appError.ApplicationError {
+	var afterCommit []func()
+	if appErr := run(u.db.Session(&gorm.Session{NewDB: true, Context: ctx}), &afterCommit, fn); appErr != nil {
+		return appErr
+	}
+	for _, f := range afterCommit {
+		f()
+	}
+	return nil
+}
+
+// Do runs fn inside a savepoint of the transaction. When fn returns an error or panics, only the changes
+// made by fn are rolled back, and the caller decides whether the rest of the transaction goes on.
+func (tx *Tx) Do(fn func(tx *Tx) *appError.ApplicationError) *appError.ApplicationError {
+	return run(tx.db, tx.afterCommit, fn)
+}
+
+// AfterCommit registers f to run once the transaction is committed, such as dropping cached copies of
+// the records it changed. f does not run when the transaction is rolled back, but still runs when only
+// the savepoint it was registered in is rolled back, so it must be harmless when nothing changed.
+func (tx *Tx) AfterCommit(f func()) {
+	*tx.afterCommit = append(*tx.afterCommit, f)
+}
+
+// DB returns the transaction as a GORM database, for queries not covered by a repository.
+func (tx *Tx) DB() *gorm.DB {
+	return tx.db
+}
+
+// rollbackError carries the application error that made a transaction roll back through GORM,
+// which only knows about plain errors.
+type rollbackError struct {
+	appErr *appError.ApplicationError
+}
+
+func (e *rollbackError) Error() string {
+	return e.appErr.Message
+}
+
+// run runs fn in a transaction of db, which GORM turns into a savepoint when db is already a transaction.
+func run(db *gorm.DB, afterCommit *[]func(), fn func(tx *Tx) *appError.ApplicationError) *appError.ApplicationError {
+	err := db.Transaction(func(gormTx *gorm.DB) error {
+		if appErr := fn(&Tx{db: gormTx, afterCommit: afterCommit}); appErr != nil {
+			return &rollbackError{appErr: appErr}
+		}
+		return nil
+	})
+	if err == nil {
+		return nil
+	}
+	if rollback, ok := err.(*rollbackError); ok {
+		return rollback.appErr
+	}
+	logger.Error("repository", "UnitOfWork", "run", "transaction failed", err)
+	return appError.NewApplicationError("internal_error", "failed to complete the transaction")
+}
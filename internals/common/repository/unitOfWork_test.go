@@ -0,0 +1,138 @@
+package repository
+
+import (
+	appError "backendService/internals/common/errors"
+	"context"
+	"testing"
+)
+
+// countWidgets returns the number of widgets stored, failing the test on errors.
+func countWidgets(t *testing.T, repo *BaseRepository[widget]) int64 {
+	t.Helper()
+	count, err := repo.Count()
+	if err != nil {
+		t.Fatalf("counting widgets: %v", err)
+	}
+	return count
+}
+
+func TestUnitOfWork(t *testing.T) {
+	repo := newTestRepository(t)
+	uow := NewUnitOfWork(repo.Db)
+	ctx := context.Background()
+	var committed []string
+
+	appErr := uow.Do(ctx, func(tx *Tx) *appError.ApplicationError {
+		if _, err := repo.WithTx(tx).Create(&widget{Name: "committed"}); err != nil {
+			t.Fatalf("creating a widget: %v", err)
+		}
+		tx.AfterCommit(func() { committed = append(committed, "first") })
+		return nil
+	})
+	if appErr != nil {
+		t.Fatalf("Do: %s", appErr.Message)
+	}
+	if count := countWidgets(t, repo); count != 1 || len(committed) != 1 {
+		t.Errorf("got %d widgets and after commit functions %v, want 1 widget and [first]", count, committed)
+	}
+
+	// The error returned by the function is returned as is, and nothing it did is kept
+	failure := appError.NewBadRequestError("invalid_widget", "invalid widget")
+	appErr = uow.Do(ctx, func(tx *Tx) *appError.ApplicationError {
+		if _, err := repo.WithTx(tx).Create(&widget{Name: "rolled back"}); err != nil {
+			t.Fatalf("creating a widget: %v", err)
+		}
+		tx.AfterCommit(func() { committed = append(committed, "second") })
+		return failure
+	})
+	if appErr != failure {
+		t.Errorf("got %v, want the error of the function", appErr)
+	}
+	if count := countWidgets(t, repo); count != 1 || len(committed) != 1 {
+		t.Errorf("got %d widgets and after commit functions %v after a rollback, want 1 widget and [first]", count, committed)
+	}
+}
+
+func TestUnitOfWorkPanic(t *testing.T) {
+	repo := newTestRepository(t)
+	uow := NewUnitOfWork(repo.Db)
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "boom" {
+				t.Errorf("got panic %v, want boom", recovered)
+			}
+		}()
+		uow.Do(context.Background(), func(tx *Tx) *appError.ApplicationError {
+			if _, err := repo.WithTx(tx).Create(&widget{Name: "rolled back"}); err != nil {
+				t.Fatalf("creating a widget: %v", err)
+			}
+			panic("boom")
+		})
+	}()
+	if count := countWidgets(t, repo); count != 0 {
+		t.Errorf("got %d widgets after a panic, want 0", count)
+	}
+}
+
+func TestUnitOfWorkSavepoint(t *testing.T) {
+	repo := newTestRepository(t)
+	uow := NewUnitOfWork(repo.Db)
+	var committed []string
+
+	appErr := uow.Do(context.Background(), func(tx *Tx) *appError.ApplicationError {
+		widgets := repo.WithTx(tx)
+		if _, err := widgets.Create(&widget{Name: "outer"}); err != nil {
+			t.Fatalf("creating a widget: %v", err)
+		}
+		savepointErr := tx.Do(func(tx *Tx) *appError.ApplicationError {
+			if _, err := repo.WithTx(tx).Create(&widget{Name: "inner"}); err != nil {
+				t.Fatalf("creating a widget: %v", err)
+			}
+			tx.AfterCommit(func() { committed = append(committed, "inner") })
+			return appError.NewBadRequestError("invalid_widget", "invalid widget")
+		})
+		if savepointErr == nil || savepointErr.ErrorCode != "invalid_widget" {
+			t.Errorf("got savepoint error %v, want invalid_widget", savepointErr)
+		}
+		// The transaction goes on after the savepoint is rolled back
+		if count := countWidgets(t, widgets); count != 1 {
+			t.Errorf("got %d widgets after the savepoint rollback, want 1", count)
+		}
+		return nil
+	})
+	if appErr != nil {
+		t.Fatalf("Do: %s", appErr.Message)
+	}
+	all, err := repo.FindAllBy(Query{})
+	if err != nil {
+		t.Fatalf("FindAllBy: %v", err)
+	}
+	if names := widgetNames(all); !equalNames(names, "outer") {
+		t.Errorf("got widgets %v, want [outer]", names)
+	}
+	// Functions registered in a rolled back savepoint still run once the transaction commits
+	if len(committed) != 1 {
+		t.Errorf("got after commit functions %v, want [inner]", committed)
+	}
+}
+
+func TestUnitOfWorkCanceledContext(t *testing.T) {
+	repo := newTestRepository(t)
+	uow := NewUnitOfWork(repo.Db)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	appErr := uow.Do(ctx, func(tx *Tx) *appError.ApplicationError {
+		if _, err := repo.WithTx(tx).Create(&widget{Name: "rolled back"}); err != nil {
+			t.Fatalf("creating a widget: %v", err)
+		}
+		cancel()
+		return nil
+	})
+	if appErr == nil || appErr.ErrorCode != "internal_error" {
+		t.Errorf("got %v, want internal_error", appErr)
+	}
+	if count := countWidgets(t, repo); count != 0 {
+		t.Errorf("got %d widgets after the context was canceled, want 0", count)
+	}
+}
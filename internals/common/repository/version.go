@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+// ErrVersionConflict is returned by UpdateVersioned when the record is no longer at the expected version,
+// because another update got there first or the record was deleted in the meantime.
+var ErrVersionConflict = errors.New("record was modified concurrently")
+
+// Versioned is embedded next to BaseModel by models opting in to optimistic locking.
+// Their version starts at 1 and is incremented by every update made through the repository,
+// so a client can tell whether the record changed since it read it.
+type Versioned struct {
+	Version uint64 `gorm:"not null;default:1" json:"version"`
+}
+
+// versionField is the name of the embedded Versioned struct within a model.
+var versionField = reflect.TypeOf(Versioned{}).Name()
+
+// IncrementVersion adds the increment of the version to update and returns it, for updates of versioned
+// models made without the repository, such as within a transaction of a custom repository.
+func IncrementVersion(update map[string]interface{}) map[string]interface{} {
+	update["version"] = gorm.Expr("version + 1")
+	return update
+}
+
+// UpdateVersioned applies update to the records matching filter, like Update, but only when they are still
+// at the given version. It returns ErrVersionConflict when no record was updated.
+func (r *BaseRepository[T]) UpdateVersioned(filter any, version uint64, update any) error {
+	if !r.versioned {
+		return errors.New("table " + r.tableName + " is not versioned")
+	}
+	update, err := r.versionedUpdate(update)
+	if err != nil {
+		return err
+	}
+
+	session := r.Db.Session(&gorm.Session{})
+	scope := func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(r.deletedScope).Where(filter).Where("version = ?", version)
+	}
+	return r.track(session, ActionUpdate, scope, func(tx *gorm.DB) error {
+		result := tx.Model(new(T)).Scopes(scope).Updates(update)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return nil
+	})
+}
+
+// versionedUpdate adds the version increment to an update of a versioned model, leaving update untouched.
+// Updates of versioned models must be given as a map, as the increment cannot be expressed in a struct.
+func (r *BaseRepository[T]) versionedUpdate(update any) (any, error) {
+	if !r.versioned {
+		return update, nil
+	}
+	values, ok := update.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("updates of versioned table " + r.tableName + " must be given as a map")
+	}
+	versioned := make(map[string]interface{}, len(values)+1)
+	for column, value := range values {
+		versioned[column] = value
+	}
+	return IncrementVersion(versioned), nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"backendService/internals/setup/database/databasetest"
+	"errors"
+	"testing"
+)
+
+// gadget is the versioned model stored by the repositories under test.
+type gadget struct {
+	BaseModel
+	Versioned
+	Name string
+}
+
+// newVersionedRepository returns a repository of gadgets backed by a migrated test database.
+func newVersionedRepository(t *testing.T) *BaseRepository[gadget] {
+	t.Helper()
+	db := databasetest.Open(t)
+	if err := db.AutoMigrate(&gadget{}, &widget{}); err != nil {
+		t.Fatalf("creating the gadgets table: %v", err)
+	}
+	return NewBaseRepository[gadget](db, "gadgets")
+}
+
+func TestUpdateVersioned(t *testing.T) {
+	repo := newVersionedRepository(t)
+	created, err := repo.Create(&gadget{Name: "alpha", Versioned: Versioned{Version: 7}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.Version != 1 {
+		t.Errorf("got version %d after Create, want 1", created.Version)
+	}
+	filter := map[string]interface{}{"id": created.ID}
+
+	if err := repo.UpdateVersioned(filter, 1, map[string]interface{}{"name": "beta"}); err != nil {
+		t.Fatalf("UpdateVersioned: %v", err)
+	}
+	// The version the client read is now stale
+	if err := repo.UpdateVersioned(filter, 1, map[string]interface{}{"name": "gamma"}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("updating a stale version: got %v, want ErrVersionConflict", err)
+	}
+	// Updates without a version still increment it
+	if err := repo.Update(filter, map[string]interface{}{"name": "delta"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	found, err := repo.FindByID(created.ID)
+	if err != nil || found.Name != "delta" || found.Version != 3 {
+		t.Errorf("got %+v, %v, want delta at version 3", found, err)
+	}
+
+	if err := repo.Update(filter, gadget{Name: "epsilon"}); err == nil {
+		t.Error("updating a versioned model with a struct: got no error")
+	}
+	if err := repo.Delete(created.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.UpdateVersioned(filter, 4, map[string]interface{}{"name": "zeta"}); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("updating a deleted gadget: got %v, want ErrVersionConflict", err)
+	}
+
+	widgets := NewBaseRepository[widget](repo.Db, "widgets")
+	if err := widgets.UpdateVersioned(map[string]interface{}{"id": 1}, 1, map[string]interface{}{"name": "alpha"}); err == nil {
+		t.Error("updating an unversioned model with UpdateVersioned: got no error")
+	}
+}
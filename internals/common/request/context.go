@@ -0,0 +1,39 @@
+package request
+
+import "context"
+
+type contextKey int
+
+const (
+	idKey contextKey = iota
+	actorKey
+)
+
+// Actor is the user on whose behalf a request runs.
+type Actor struct {
+	UserId         string
+	ImpersonatorId string // ImpersonatorId is the staff member impersonating the user, if any
+}
+
+// WithID returns a copy of ctx carrying the ID of the request it serves.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey, id)
+}
+
+// ID returns the ID of the request ctx serves, or an empty string outside of a request.
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(idKey).(string)
+	return id
+}
+
+// WithActor returns a copy of ctx carrying the actor of the request it serves.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// CurrentActor returns the actor of the request ctx serves. It reports false for unauthenticated
+// requests and for work done outside of a request, such as background jobs.
+func CurrentActor(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorKey).(Actor)
+	return actor, ok
+}
@@ -4,6 +4,8 @@ import (
 	"backendService/internals/common/errors"
 	"backendService/internals/common/logger"
 	"backendService/internals/setup/server"
+	"context"
+	stdErrors "errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -21,8 +23,27 @@ type BaseRouter struct {
 }
 
 type Response struct {
-	Data    any    `json:"data"`
-	Message string `json:"message"`
+	Data       any    `json:"data"`
+	Message    string `json:"message"`
+	Meta       any    `json:"meta,omitempty"` // Meta carries additional information about the data, such as pagination.
+	StatusCode int    `json:"-"`              // StatusCode overrides the default 200 status of a success response when set.
+	ETag       string `json:"-"`              // ETag is sent as the ETag header of a success response when set, see VersionETag.
+}
+
+// Pagination describes the page metadata returned alongside paginated data.
+type Pagination struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"pageSize"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"totalPages"`
+}
+
+// CursorPagination describes the page metadata returned alongside keyset paginated data.
+// NextCursor and PrevCursor are omitted when there is no next or previous page.
+type CursorPagination struct {
+	PageSize   int    `json:"pageSize"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
 }
 
 // HandlerFunc represents a handler function for processing HTTP requests
@@ -43,7 +64,7 @@ func (br *BaseRouter) Handle(method, path string, handlers ...HandlerFunc) {
 	wrappedLastHandler := handleWrapper(lastHandler)
 	ginMiddlewares := make([]gin.HandlerFunc, len(middlewares))
 	for i, mw := range middlewares {
-		ginMiddlewares[i] = handleWrapper(mw)
+		ginMiddlewares[i] = middlewareWrapper(mw)
 	}
 	br.group.Handle(method, path, append(ginMiddlewares, wrappedLastHandler)...)
 }
@@ -54,6 +75,26 @@ func getMiddlewaresAndLastHandler(handlers []HandlerFunc) ([]HandlerFunc, Handle
 	return handlers[:lastIndex], handlers[lastIndex]
 }
 
+// middlewareWrapper wraps a middleware handler function so that it either aborts the request with an
+// error response or passes control to the next handler. The response returned by a middleware is ignored.
+func middlewareWrapper(handler HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				formatErrorResponse(c, http.StatusInternalServerError, err)
+				c.Abort()
+			}
+		}()
+		_, err := handler(c)
+		if err != nil {
+			formatErrorResponse(c, http.StatusInternalServerError, timeoutError(c, err))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
 // handleWrapper wraps the final handler function with Gin middleware for error handling and response formatting
 func handleWrapper(handler HandlerFunc) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -63,14 +104,41 @@ func handleWrapper(handler HandlerFunc) gin.HandlerFunc {
 			}
 		}()
 		data, err := handler(c)
+		if c.Writer.Written() {
+			// The handler streamed its own response, such as a file download
+			if err != nil {
+				logger.Error("router", "baseRouter", "handleWrapper", "error after response was written", err.Message)
+			}
+			return
+		}
 		if err != nil {
-			formatErrorResponse(c, http.StatusInternalServerError, err)
+			formatErrorResponse(c, http.StatusInternalServerError, timeoutError(c, err))
 		} else {
-			formatSuccessResponse(c, http.StatusOK, data.Data, data.Message)
+			statusCode := http.StatusOK
+			if data.StatusCode != 0 {
+				statusCode = data.StatusCode
+			}
+			formatSuccessResponse(c, statusCode, data)
 		}
 	}
 }
 
+// timeoutError replaces the error of a handler whose request ran out of time, as the handler failed
+// because its database or cache work was cancelled rather than for the reason it reports.
+func timeoutError(c *gin.Context, err *errors.ApplicationError) *errors.ApplicationError {
+	if stdErrors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		return errors.NewApplicationError("request_timeout", "the request took too long to complete", http.StatusGatewayTimeout)
+	}
+	return err
+}
+
+// DetachDeadline lifts the deadline of the request, for the handlers whose work is expected to outlast it,
+// such as bulk imports. The context keeps its values but is no longer cancelled, neither by the deadline
+// nor when the client goes away, so that the work is not left half done.
+func DetachDeadline(c *gin.Context) {
+	c.Request = c.Request.WithContext(context.WithoutCancel(c.Request.Context()))
+}
+
 // formatErrorResponse formats and sends an error response
 func formatErrorResponse(c *gin.Context, statusCode int, err interface{}) {
 
@@ -139,13 +207,25 @@ func formatErrorResponse(c *gin.Context, statusCode int, err interface{}) {
 }
 
 // formatSuccessResponse formats and sends a success response
-func formatSuccessResponse(c *gin.Context, statusCode int, data interface{}, message string) {
-	c.JSON(statusCode, gin.H{
+func formatSuccessResponse(c *gin.Context, statusCode int, response Response) {
+	// A 204 No Content response must not carry a body
+	if statusCode == http.StatusNoContent {
+		c.Status(statusCode)
+		return
+	}
+	if response.ETag != "" {
+		c.Header("ETag", response.ETag)
+	}
+	body := gin.H{
 		"success":   true,
-		"data":      data,
+		"data":      response.Data,
 		"timestamp": time.Now().Format(time.RFC3339),
-		"message":   message,
-	})
+		"message":   response.Message,
+	}
+	if response.Meta != nil {
+		body["meta"] = response.Meta
+	}
+	c.JSON(statusCode, body)
 }
 
 // Group creates a new router group relative to the current router's path and applies provided handlers to it
@@ -0,0 +1,90 @@
+package router
+
+import (
+	"backendService/internals/common/errors"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serve registers the handlers on a new router and returns the status and error code of a GET request to them.
+// The request context is given the deadline, unless it is zero.
+func serve(t *testing.T, deadline time.Time, handlers ...HandlerFunc) (int, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	if !deadline.IsZero() {
+		engine.Use(func(c *gin.Context) {
+			ctx, cancel := context.WithDeadline(c.Request.Context(), deadline)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+			c.Next()
+		})
+	}
+	NewBaseRouter("test", engine).GET("/", handlers...)
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	var body struct {
+		Error struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding the response %q: %v", recorder.Body.String(), err)
+	}
+	return recorder.Code, body.Error.ErrorCode
+}
+
+func TestTimeoutError(t *testing.T) {
+	failing := func(c *gin.Context) (Response, *errors.ApplicationError) {
+		<-c.Request.Context().Done()
+		return Response{}, errors.NewApplicationError("internal_error", "failed to retrieve user")
+	}
+	passing := func(c *gin.Context) (Response, *errors.ApplicationError) {
+		return Response{}, nil
+	}
+	notFound := func(c *gin.Context) (Response, *errors.ApplicationError) {
+		return Response{}, errors.NewNotFoundError("user_not_found", "user not found")
+	}
+	expired := time.Now().Add(-time.Second)
+
+	tests := []struct {
+		name       string
+		deadline   time.Time
+		handlers   []HandlerFunc
+		wantStatus int
+		wantCode   string
+	}{
+		{"handler past the deadline", expired, []HandlerFunc{failing}, http.StatusGatewayTimeout, "request_timeout"},
+		{"middleware past the deadline", expired, []HandlerFunc{failing, passing}, http.StatusGatewayTimeout, "request_timeout"},
+		{"handler within the deadline", time.Now().Add(time.Minute), []HandlerFunc{notFound}, http.StatusNotFound, "user_not_found"},
+		{"handler without a deadline", time.Time{}, []HandlerFunc{notFound}, http.StatusNotFound, "user_not_found"},
+	}
+	for _, test := range tests {
+		status, code := serve(t, test.deadline, test.handlers...)
+		if status != test.wantStatus || code != test.wantCode {
+			t.Errorf("%s: got %d %s, want %d %s", test.name, status, code, test.wantStatus, test.wantCode)
+		}
+	}
+}
+
+func TestDetachDeadline(t *testing.T) {
+	detached := func(c *gin.Context) (Response, *errors.ApplicationError) {
+		DetachDeadline(c)
+		if _, ok := c.Request.Context().Deadline(); ok || c.Request.Context().Err() != nil {
+			return Response{}, errors.NewApplicationError("still_bound", "the context still has a deadline")
+		}
+		return Response{}, errors.NewNotFoundError("user_not_found", "user not found")
+	}
+
+	// The error of the handler is reported as is, even though the deadline of the request passed
+	if status, code := serve(t, time.Now().Add(-time.Second), detached); status != http.StatusNotFound || code != "user_not_found" {
+		t.Errorf("got %d %s, want %d user_not_found", status, code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,33 @@
+package router
+
+import (
+	"backendService/internals/common/errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VersionETag returns the entity tag of a record at the given version, to be set as the ETag of a response.
+func VersionETag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// IfMatchVersion returns the version named by the If-Match header of the request, as set by VersionETag,
+// so that an update only applies to the version the client read. It returns nil when the header is missing
+// or is *, which matches any version. Weak and multiple entity tags are rejected, as they cannot name a version.
+func IfMatchVersion(c *gin.Context) (*uint64, *errors.ApplicationError) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	tag, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		tag, ok = strings.CutSuffix(tag, `"`)
+	}
+	version, err := strconv.ParseUint(tag, 10, 64)
+	if !ok || err != nil {
+		return nil, errors.NewBadRequestError("invalid_if_match", "If-Match must be a single entity tag returned in an ETag header")
+	}
+	return &version, nil
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"backendService/internals/common/errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    uint64
+		isNil   bool
+		invalid bool
+	}{
+		{header: "", isNil: true},
+		{header: "*", isNil: true},
+		{header: VersionETag(7), want: 7},
+		{header: ` "12" `, want: 12},
+		{header: "7", invalid: true},
+		{header: `W/"7"`, invalid: true},
+		{header: `"7", "8"`, invalid: true},
+		{header: `"-1"`, invalid: true},
+		{header: `"seven"`, invalid: true},
+	}
+	for _, test := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPatch, "/", nil)
+		if test.header != "" {
+			c.Request.Header.Set("If-Match", test.header)
+		}
+		version, appErr := IfMatchVersion(c)
+		switch {
+		case test.invalid:
+			if appErr == nil || appErr.ErrorCode != "invalid_if_match" {
+				t.Errorf("If-Match %s: got %v, %v, want invalid_if_match", test.header, version, appErr)
+			}
+		case test.isNil:
+			if version != nil || appErr != nil {
+				t.Errorf("If-Match %q: got %v, %v, want no version", test.header, version, appErr)
+			}
+		default:
+			if appErr != nil || version == nil || *version != test.want {
+				t.Errorf("If-Match %s: got %v, %v, want %d", test.header, version, appErr, test.want)
+			}
+		}
+	}
+}
+
+func TestETagHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	NewBaseRouter("test", engine).GET("/", func(c *gin.Context) (Response, *errors.ApplicationError) {
+		return Response{Data: "widget", ETag: VersionETag(3)}, nil
+	})
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := recorder.Header().Get("ETag"); got != `"3"` {
+		t.Errorf("got ETag %s, want \"3\"", got)
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage stores files in a directory of the local filesystem.
+// Files are served by the application itself, see StorageRouter.
+type LocalStorage struct {
+	directory string
+	publicURL string
+}
+
+// NewLocalStorage creates a new LocalStorage keeping files under directory and serving them from publicURL.
+func NewLocalStorage(directory string, publicURL string) *LocalStorage {
+	if directory == "" {
+		directory = filepath.Join("storage", "files")
+	}
+	return &LocalStorage{directory: directory, publicURL: publicURL}
+}
+
+// Put writes the file to a temporary name first and renames it into place,
+// so readers never see a partially written file.
+func (ls *LocalStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Get opens the file stored under key. The local filesystem keeps no metadata,
+// so the content type is derived from the extension of the key.
+func (ls *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	path, err := ls.path(key)
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", err
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return file, contentType, nil
+}
+
+func (ls *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (ls *LocalStorage) URL(key string) string {
+	return joinURL(ls.publicURL, key)
+}
+
+// path returns the filesystem path of the file stored under key.
+func (ls *LocalStorage) path(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(ls.directory, filepath.FromSlash(key)), nil
+}
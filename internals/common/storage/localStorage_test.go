@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	directory := t.TempDir()
+	ls := NewLocalStorage(directory, "/api/v1/files/")
+	ctx := context.Background()
+
+	if err := ls.Put(ctx, "avatars/user/avatar.jpg", []byte("first"), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := ls.Put(ctx, "avatars/user/avatar.jpg", []byte("second"), "image/jpeg"); err != nil {
+		t.Fatalf("Put replacing a file: %v", err)
+	}
+
+	file, contentType, err := ls.Get(ctx, "avatars/user/avatar.jpg")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "second" || contentType != "image/jpeg" {
+		t.Errorf("got %q of type %s, want the second upload of type image/jpeg", data, contentType)
+	}
+
+	// Temporary upload files never stay behind.
+	entries, _ := os.ReadDir(filepath.Join(directory, "avatars", "user"))
+	if len(entries) != 1 {
+		t.Errorf("got %d files in the directory, want only the stored file", len(entries))
+	}
+
+	if url := ls.URL("avatars/user/avatar.jpg"); url != "/api/v1/files/avatars/user/avatar.jpg" {
+		t.Errorf("got URL %s", url)
+	}
+
+	if err := ls.Delete(ctx, "avatars/user/avatar.jpg"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := ls.Get(ctx, "avatars/user/avatar.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("getting a deleted file: got %v, want ErrNotFound", err)
+	}
+	if err := ls.Delete(ctx, "avatars/user/avatar.jpg"); err != nil {
+		t.Errorf("deleting a missing file: got %v", err)
+	}
+}
+
+func TestLocalStorageRejectsInvalidKeys(t *testing.T) {
+	ls := NewLocalStorage(t.TempDir(), "/files")
+	ctx := context.Background()
+
+	for _, key := range []string{"", "/etc/passwd", "../outside", "avatars/../../outside", "avatars//file", "avatars/./file", `avatars\file`} {
+		if err := ls.Put(ctx, key, []byte("data"), "text/plain"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q): got %v, want ErrInvalidKey", key, err)
+		}
+		if _, _, err := ls.Get(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Get(%q): got %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
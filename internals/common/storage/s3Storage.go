@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"backendService/internals/setup/config"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Storage stores files in a bucket of Amazon S3 or any S3 compatible server, such as MinIO.
+// Requests are signed with Signature Version 4, so no SDK is needed. Pointing the endpoint at a
+// local S3 compatible server with path style addressing is enough to run it without AWS.
+type S3Storage struct {
+	endpoint     *url.URL
+	bucket       string
+	usePathStyle bool
+	publicURL    string
+	signer       *sigV4Signer
+	client       *http.Client
+}
+
+// s3Error is the error document returned by S3 for failed requests.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// NewS3Storage creates a new S3Storage from the given configuration.
+// Files are served from publicURL when it is set, and straight from the bucket otherwise.
+func NewS3Storage(s3Config config.S3StorageConfig, publicURL string) *S3Storage {
+	endpoint, err := url.Parse(s3Config.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		endpoint = &url.URL{Scheme: "https", Host: "s3.amazonaws.com"}
+	}
+	region := s3Config.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &S3Storage{
+		endpoint:     endpoint,
+		bucket:       s3Config.Bucket,
+		usePathStyle: s3Config.UsePathStyle,
+		publicURL:    publicURL,
+		signer: &sigV4Signer{
+			accessKeyID:     s3Config.AccessKeyID,
+			secretAccessKey: s3Config.SecretAccessKey,
+			region:          region,
+			service:         "s3",
+		},
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(req, hashHex(data))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, string, error) {
+	if err := validateKey(key); err != nil {
+		return nil, "", err
+	}
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, "", err
+	}
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3Storage) URL(key string) string {
+	if s.publicURL != "" {
+		return joinURL(s.publicURL, key)
+	}
+	return s.objectURL(key).String()
+}
+
+// objectURL returns the URL of the object stored under key, with either path style or virtual hosted addressing.
+func (s *S3Storage) objectURL(key string) *url.URL {
+	objectURL := *s.endpoint
+	path := "/" + key
+	if s.usePathStyle {
+		path = "/" + s.bucket + path
+	} else {
+		objectURL.Host = s.bucket + "." + objectURL.Host
+	}
+	objectURL.Path = strings.TrimSuffix(s.endpoint.Path, "/") + path
+	objectURL.RawPath = escapePath(objectURL.Path)
+	return &objectURL
+}
+
+// newRequest creates a request for the object stored under key.
+func (s *S3Storage) newRequest(ctx context.Context, method string, key string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	return http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), reader)
+}
+
+// do signs and sends the request. Responses other than 2xx are turned into errors,
+// with ErrNotFound for missing objects.
+func (s *S3Storage) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.signer.sign(req, payloadHash, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	var s3Err s3Error
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if xml.Unmarshal(body, &s3Err) != nil || s3Err.Code == "" {
+		s3Err.Code = resp.Status
+	}
+	return nil, fmt.Errorf("s3 %s %s failed: %s %s", req.Method, req.URL.Path, s3Err.Code, s3Err.Message)
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"backendService/internals/setup/config"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is an S3 compatible server keeping objects in memory, addressed in path style.
+type fakeS3 struct {
+	mu           sync.Mutex
+	objects      map[string]string
+	contentTypes map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), sigV4Algorithm+" Credential=access-key/") {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+		return
+	}
+
+	path := r.URL.Path
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Content-Sha256") != hashHex(body) {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `<Error><Code>XAmzContentSHA256Mismatch</Code></Error>`)
+			return
+		}
+		f.objects[path] = string(body)
+		f.contentTypes[path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		object, ok := f.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.contentTypes[path])
+		io.WriteString(w, object)
+	case http.MethodDelete:
+		if _, ok := f.objects[path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	server := &fakeS3{objects: map[string]string{}, contentTypes: map[string]string{}}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	s3 := NewS3Storage(config.S3StorageConfig{
+		Endpoint:        httpServer.URL,
+		Bucket:          "bucket",
+		UsePathStyle:    true,
+		AccessKeyID:     "access-key",
+		SecretAccessKey: "secret-key",
+	}, "")
+	ctx := context.Background()
+
+	if err := s3.Put(ctx, "avatars/user/a b.jpg", []byte("avatar"), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, ok := server.objects["/bucket/avatars/user/a b.jpg"]; !ok {
+		t.Fatalf("got objects %v, want the object stored under the bucket", server.objects)
+	}
+
+	file, contentType, err := s3.Get(ctx, "avatars/user/a b.jpg")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "avatar" || contentType != "image/jpeg" {
+		t.Errorf("got %q of type %s, want the stored avatar", data, contentType)
+	}
+	if url := s3.URL("avatars/user/a b.jpg"); url != httpServer.URL+"/bucket/avatars/user/a%20b.jpg" {
+		t.Errorf("got URL %s", url)
+	}
+
+	if err := s3.Delete(ctx, "avatars/user/a b.jpg"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, _, err := s3.Get(ctx, "avatars/user/a b.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("getting a deleted object: got %v, want ErrNotFound", err)
+	}
+	if err := s3.Delete(ctx, "avatars/user/a b.jpg"); err != nil {
+		t.Errorf("deleting a missing object: got %v", err)
+	}
+	if err := s3.Put(ctx, "../outside", []byte("data"), ""); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("putting an invalid key: got %v, want ErrInvalidKey", err)
+	}
+
+	denied := NewS3Storage(config.S3StorageConfig{Endpoint: httpServer.URL, Bucket: "bucket", UsePathStyle: true, AccessKeyID: "other"}, "https://cdn.example.com")
+	if err := denied.Put(ctx, "file.txt", []byte("data"), ""); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("putting with other credentials: got %v, want the S3 error code", err)
+	}
+	if url := denied.URL("file.txt"); url != "https://cdn.example.com/file.txt" {
+		t.Errorf("got URL %s, want the public URL", url)
+	}
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+	sigV4DateTime  = "20060102T150405Z"
+	// emptyPayloadHash is the SHA-256 hash of an empty body.
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// sigV4Signer signs requests with AWS Signature Version 4, as described in
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html
+type sigV4Signer struct {
+	accessKeyID     string
+	secretAccessKey string
+	region          string
+	service         string
+}
+
+// sign adds the X-Amz-Date, X-Amz-Content-Sha256 and Authorization headers to the request.
+// payloadHash is the hex encoded SHA-256 hash of the body. Every header already set on the request
+// is signed, so headers must not be changed afterwards. The request path must already be escaped
+// with escapePath, as S3 expects keys to be encoded exactly once.
+func (s *sigV4Signer) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format(sigV4DateTime)
+	scope := amzDate[:8] + "/" + s.region + "/" + s.service + "/aws4_request"
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalHeaders, signedHeaders := canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req),
+		canonicalQuery(req),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, hashHex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), amzDate[:8])
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", sigV4Algorithm+" Credential="+s.accessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+// canonicalURI returns the escaped path of the request, or "/" for an empty path.
+func canonicalURI(req *http.Request) string {
+	path := req.URL.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// canonicalQuery returns the query parameters sorted by name and value, each of them escaped.
+func canonicalQuery(req *http.Request) string {
+	var pairs []string
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// canonicalHeaders returns the canonical header block and the list of signed header names.
+// The host header is always signed, along with every header set on the request.
+func canonicalHeaders(req *http.Request) (string, string) {
+	headers := map[string]string{"host": req.URL.Host}
+	if req.Host != "" {
+		headers["host"] = req.Host
+	}
+	for name, values := range req.Header {
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var block strings.Builder
+	for _, name := range names {
+		block.WriteString(name + ":" + headers[name] + "\n")
+	}
+	return block.String(), strings.Join(names, ";")
+}
+
+// escapePath escapes every segment of a slash separated path the way SigV4 expects.
+func escapePath(path string) string {
+	return uriEncode(path, false)
+}
+
+// uriEncode percent-encodes every byte except the unreserved characters of RFC 3986.
+// Slashes are only encoded when encodeSlash is set.
+func uriEncode(value string, encodeSlash bool) string {
+	const hexDigits = "0123456789ABCDEF"
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			encoded.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			encoded.WriteByte('%')
+			encoded.WriteByte(hexDigits[c>>4])
+			encoded.WriteByte(hexDigits[c&15])
+		}
+	}
+	return encoded.String()
+}
+
+// hashHex returns the hex encoded SHA-256 hash of data.
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
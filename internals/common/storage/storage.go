@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"backendService/internals/common/logger"
+	"backendService/internals/setup/config"
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Storage drivers that can be configured.
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+var (
+	// ErrNotFound is returned when no file is stored under the requested key.
+	ErrNotFound = errors.New("file not found")
+	// ErrInvalidKey is returned for keys that are empty, absolute or try to leave the storage root.
+	ErrInvalidKey = errors.New("invalid file key")
+)
+
+// Storage stores files under slash separated keys, e.g. "avatars/<userId>/<id>.jpg".
+// Files are public once stored: URL returns where clients can fetch them.
+type Storage interface {
+	// Put stores data under key, replacing any existing file.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens the file stored under key and returns its content type.
+	// The caller must close the returned reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, string, error)
+	// Delete removes the file stored under key. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of the file stored under key.
+	URL(key string) string
+}
+
+// Store is the storage backend selected in the configuration.
+var Store Storage
+
+// InitializeStorage sets up Store with the configured storage driver.
+func InitializeStorage() {
+	storageConfig := config.Config.Storage
+	switch storageConfig.Driver {
+	case DriverS3:
+		Store = NewS3Storage(storageConfig.S3, storageConfig.PublicURL)
+	default:
+		Store = NewLocalStorage(storageConfig.Local.Directory, storageConfig.PublicURL)
+	}
+	logger.Info("storage", "Storage", "InitializeStorage", "using storage driver", storageConfig.Driver)
+}
+
+// validateKey checks that a key is a clean relative path, so it cannot escape the storage root.
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// joinURL appends a key to a base URL.
+func joinURL(base string, key string) string {
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"backendService/internals/common/errors"
+	"backendService/internals/common/logger"
+	"backendService/internals/common/router"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StorageRouter serves stored files through the application, which is how files of the local
+// storage backend reach clients. The default public URL of the storage points at these routes.
+type StorageRouter struct {
+	storage Storage
+}
+
+func (sr *StorageRouter) SetupRoutes(app *gin.Engine) {
+
+	router := router.NewBaseRouter("StorageRouter", app)
+
+	filesRouter := router.Group("api/v1/files")
+	{
+		filesRouter.GET("/*key", sr.GetFile)
+	}
+}
+
+// GetFile streams the file stored under the key in the path.
+// Keys are never reused for different content, so clients may cache files for long.
+func (sr *StorageRouter) GetFile(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	file, contentType, err := sr.storage.Get(c.Request.Context(), key)
+	if err != nil {
+		if err == ErrNotFound || err == ErrInvalidKey {
+			return router.Response{}, errors.NewNotFoundError("file_not_found", "file not found")
+		}
+		logger.Error("storage", "StorageRouter", "GetFile", "failed to open file", key, err)
+		return router.Response{}, errors.NewInternalServerError("failed to retrieve file", err)
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", contentType)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	if _, err := io.Copy(c.Writer, file); err != nil {
+		logger.Error("storage", "StorageRouter", "GetFile", "failed to send file", key, err)
+	}
+	return router.Response{}, nil
+}
+
+func NewStorageRouter(storage Storage) *StorageRouter {
+	return &StorageRouter{storage: storage}
+}
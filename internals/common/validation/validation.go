@@ -0,0 +1,32 @@
+// Package validation builds the validators of request bodies and imported rows.
+package validation
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// New returns a validator with the rules of the application registered next to the built-in ones:
+//
+//   - maxbytes=n: a string is at most n bytes long. Unlike max, which counts characters,
+//     it bounds the encoded size, as needed for passwords given to bcrypt.
+func New() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterValidation("maxbytes", maxBytes)
+	return validate
+}
+
+// maxBytes implements the maxbytes rule.
+func maxBytes(fl validator.FieldLevel) bool {
+	limit, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		panic("validation: invalid maxbytes parameter " + fl.Param())
+	}
+	field := fl.Field()
+	if field.Kind() != reflect.String {
+		panic("validation: maxbytes only applies to strings, not " + field.Kind().String())
+	}
+	return len(field.String()) <= limit
+}
@@ -0,0 +1,30 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaxBytes(t *testing.T) {
+	type body struct {
+		Password string `validate:"maxbytes=72"`
+	}
+	validate := New()
+
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"72 bytes", strings.Repeat("a", 72), true},
+		{"73 bytes", strings.Repeat("a", 73), false},
+		// 36 two-byte characters fit, 37 pass max=72 but are 74 bytes long
+		{"36 two-byte characters", strings.Repeat("é", 36), true},
+		{"37 two-byte characters", strings.Repeat("é", 37), false},
+	}
+	for _, test := range tests {
+		if err := validate.Struct(body{Password: test.password}); (err == nil) != test.valid {
+			t.Errorf("%s: got %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+}
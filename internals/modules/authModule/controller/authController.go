@@ -27,7 +27,7 @@ func (ac *AuthController) SendOtp(c *gin.Context) (router.Response, *errors.Appl
 		return router.Response{}, err
 	}
 
-	_, err = ac.authService.SendOtp(sendOtpData)
+	_, err = ac.authService.SendOtp(c.Request.Context(), sendOtpData)
 
 	if err != nil {
 		return router.Response{}, err
@@ -44,12 +44,12 @@ func (ac *AuthController) VerifyOtp(c *gin.Context) (router.Response, *errors.Ap
 		return router.Response{}, err
 	}
 
-	_, err = ac.authService.VerifyOtp(signUpData)
+	login, err := ac.authService.VerifyOtp(c.Request.Context(), signUpData)
 
 	if err != nil {
 		return router.Response{}, err
 	}
 
-	return router.Response{Message: "OTP verified successfully"}, nil
+	return router.Response{Data: login, Message: "OTP verified successfully"}, nil
 
 }
@@ -1,12 +1,30 @@
 package authModule
 
+import (
+	"backendService/internals/modules/organizationModule/organizationService"
+	"backendService/internals/modules/userModule/userRepository"
+	"time"
+)
+
 type OtpVerifyBody struct {
 	Mobile *string `json:"mobile,omitempty" validate:"omitempty,len=10"` // Mobile should be 10 characters long if present
 	Email  *string `json:"email,omitempty" validate:"omitempty,email"`   // Email should be a valid email address if present
 	OTP    string  `json:"otp" validate:"required,len=6"`                // OTP is required and should be 6 characters long
+	// InvitationToken accepts an invitation to join an organization sent to the mobile or email, once verified
+	InvitationToken *string `json:"invitationToken,omitempty" validate:"omitempty,max=100"`
 }
 
 type OtpSendBody struct {
 	Mobile *string `json:"mobile,omitempty" validate:"omitempty,len=10"` // Mobile should be 10 characters long if present
 	Email  *string `json:"email,omitempty" validate:"omitempty,email"`   // Email should be a valid email address if present
 }
+
+// LoginResponse is returned once a user has authenticated.
+// AccessToken must be sent as a bearer token in the Authorization header of subsequent requests.
+type LoginResponse struct {
+	AccessToken string               `json:"accessToken"`
+	ExpiresAt   time.Time            `json:"expiresAt"`
+	User        *userRepository.User `json:"user"`
+	// Organization is the organization joined by accepting the invitation sent with the request, if any
+	Organization *organizationService.UserOrganization `json:"organization,omitempty"`
+}
@@ -1,10 +1,12 @@
 package authModule
 
 import (
+	"backendService/internals/common/auth"
 	"backendService/internals/common/cache"
 	authController "backendService/internals/modules/authModule/controller"
 	authRoutes "backendService/internals/modules/authModule/routes"
 	authService "backendService/internals/modules/authModule/service"
+	"backendService/internals/modules/organizationModule"
 	"backendService/internals/modules/userModule"
 )
 
@@ -15,7 +17,8 @@ var (
 
 func Initialize() {
 	otpService := authService.NewOtpService(cache.Cache)
-	authService := authService.NewAuthService(*userModule.UserService, *otpService)
+	sessionStore := auth.NewSessionStore(&cache.Cache)
+	authService := authService.NewAuthService(*userModule.UserService, *otpService, sessionStore, organizationModule.OrganizationService)
 	authController := authController.NewAuthController(*authService)
 	authRouter := authRoutes.NewAuthRoutes(authController)
 
@@ -1,26 +1,34 @@
 package authService
 
 import (
+	"backendService/internals/common/auth"
 	"backendService/internals/common/errors"
+	"backendService/internals/common/logger"
 	authModule "backendService/internals/modules/authModule/dto"
+	"backendService/internals/modules/organizationModule/organizationService"
+	"backendService/internals/modules/userModule/userRepository"
 	"backendService/internals/modules/userModule/userService"
+	"context"
 	"strconv"
 )
 
 type AuthService struct {
-	userService *userService.UserService
-	otpService  *OtpService
+	userService         *userService.UserService
+	otpService          *OtpService
+	sessionStore        *auth.SessionStore
+	organizationService *organizationService.OrganizationService
 }
 
-// NewAuthService creates a new instance of AuthService with the provided UserService and OtpService.
-// The returned AuthService will use the given services to handle user and OTP operations.
-func NewAuthService(userService userService.UserService, otpService OtpService) *AuthService {
-	return &AuthService{userService: &userService, otpService: &otpService}
+// NewAuthService creates a new instance of AuthService with the provided UserService, OtpService, SessionStore
+// and OrganizationService. The returned AuthService will use the given services to handle user, OTP, session
+// and invitation operations.
+func NewAuthService(userService userService.UserService, otpService OtpService, sessionStore *auth.SessionStore, organizationService *organizationService.OrganizationService) *AuthService {
+	return &AuthService{userService: &userService, otpService: &otpService, sessionStore: sessionStore, organizationService: organizationService}
 }
 
 // SendOtp sends an OTP (One-Time Password) to the provided mobile or email address.
 // It returns true if the OTP was successfully sent, or an ApplicationError if there was an error sending the OTP.
-func (as *AuthService) SendOtp(sendOtpData authModule.OtpSendBody) (any, *errors.ApplicationError) {
+func (as *AuthService) SendOtp(ctx context.Context, sendOtpData authModule.OtpSendBody) (any, *errors.ApplicationError) {
 	if sendOtpData.Mobile == nil && sendOtpData.Email == nil {
 		return nil, errors.NewBadRequestError("missing_data", "mobile or email is required")
 	}
@@ -36,7 +44,7 @@ func (as *AuthService) SendOtp(sendOtpData authModule.OtpSendBody) (any, *errors
 		Recipient: recipient,
 	}
 
-	_, err := as.otpService.SendOtp(otpSendRequest)
+	_, err := as.otpService.SendOtp(ctx, otpSendRequest)
 	if err != nil {
 		return nil, err
 	}
@@ -44,32 +52,70 @@ func (as *AuthService) SendOtp(sendOtpData authModule.OtpSendBody) (any, *errors
 	return true, nil
 }
 
-// VerifyOtp verifies the provided OTP for the given mobile or email address.
-// It returns true if the OTP is valid, or an ApplicationError if the OTP is invalid or other errors occur.
-func (as *AuthService) VerifyOtp(verifyOtpData authModule.OtpVerifyBody) (any, *errors.ApplicationError) {
+// VerifyOtp verifies the provided OTP for the given mobile or email address and signs the user in.
+// A user is created for the mobile or email when none exists yet. When an invitation token is sent along,
+// the invitation must have been sent to the mobile or email, and it is accepted once the user is signed in.
+// It returns the access token of the new session, or an ApplicationError if the OTP is invalid or other errors occur.
+func (as *AuthService) VerifyOtp(ctx context.Context, verifyOtpData authModule.OtpVerifyBody) (*authModule.LoginResponse, *errors.ApplicationError) {
 
 	if verifyOtpData.Mobile == nil && verifyOtpData.Email == nil {
 		return nil, errors.NewBadRequestError("missing_data", "mobile or email is required")
 	}
 
 	var recipient string
+	kind := userRepository.ContactEmail
 	if verifyOtpData.Mobile != nil {
 		recipient = *verifyOtpData.Mobile
+		kind = userRepository.ContactMobile
 	} else {
 		recipient = *verifyOtpData.Email
 	}
 
+	// Checked before the OTP is used up, so that a wrong invitation does not cost the user their code
+	if verifyOtpData.InvitationToken != nil {
+		if err := as.organizationService.CheckInvitation(ctx, *verifyOtpData.InvitationToken, kind, recipient); err != nil {
+			return nil, err
+		}
+	}
+
 	otp, _ := strconv.Atoi(verifyOtpData.OTP)
 	otpVerifyRequest := VerifyOtpRequest{
 		Key: recipient,
 		Otp: otp,
 	}
 
-	_, err := as.otpService.VerifyOtp(otpVerifyRequest)
+	_, err := as.otpService.VerifyOtp(ctx, otpVerifyRequest)
 	if err != nil {
 		return nil, err
 	}
 
-	return true, nil
+	// The mobile takes precedence, matching the recipient the OTP was verified for
+	email := verifyOtpData.Email
+	if verifyOtpData.Mobile != nil {
+		email = nil
+	}
+	user, err := as.userService.FindOrCreateByContact(ctx, email, verifyOtpData.Mobile)
+	if err != nil {
+		return nil, err
+	}
+
+	token, session, sessionErr := as.sessionStore.Create(ctx, user.UserId.String(), user.Role)
+	if sessionErr != nil {
+		logger.Error("Auth", "AuthService", "VerifyOtp", "failed to create session", sessionErr)
+		return nil, errors.NewInternalServerError("failed to create session", sessionErr)
+	}
+
+	login := &authModule.LoginResponse{AccessToken: token, ExpiresAt: session.ExpiresAt, User: user}
+	if verifyOtpData.InvitationToken != nil {
+		// The user is signed in by now, so a failure, such as the invitation being used concurrently,
+		// leaves the invitation out of the response rather than failing the sign in
+		organization, err := as.organizationService.AcceptInvitationForUser(ctx, user, *verifyOtpData.InvitationToken)
+		if err != nil {
+			logger.Error("Auth", "AuthService", "VerifyOtp", "failed to accept invitation", err.Message)
+		}
+		login.Organization = organization
+	}
+
+	return login, nil
 
 }
@@ -30,7 +30,7 @@ func NewOtpService(cacheService cache.CacheService) *OtpService {
 	return &OtpService{cacheService: cacheService}
 }
 
-func (os *OtpService) SendOtp(req OtpSendRequest) (bool, *errors.ApplicationError) {
+func (os *OtpService) SendOtp(ctx context.Context, req OtpSendRequest) (bool, *errors.ApplicationError) {
 	// Generate OTP
 	length := 6 // Specify the desired length of the OTP
 	otp := generateOtp(length)
@@ -39,7 +39,7 @@ func (os *OtpService) SendOtp(req OtpSendRequest) (bool, *errors.ApplicationErro
 	recipient := req.Recipient
 
 	// Save OTP in cache for a specific time frame
-	os.saveOtpInCache(recipient, otp)
+	os.saveOtpInCache(ctx, recipient, otp)
 
 	// Send OTP to the user
 	err := os.sendOtpToUser(recipient, otp)
@@ -52,7 +52,7 @@ func (os *OtpService) SendOtp(req OtpSendRequest) (bool, *errors.ApplicationErro
 	return true, nil
 }
 
-func (os *OtpService) VerifyOtp(verifyOtpData VerifyOtpRequest) (bool, *errors.ApplicationError) {
+func (os *OtpService) VerifyOtp(ctx context.Context, verifyOtpData VerifyOtpRequest) (bool, *errors.ApplicationError) {
 	// Extract necessary data from the request
 	key := verifyOtpData.Key
 	userProvidedOtp := verifyOtpData.Otp
@@ -62,7 +62,7 @@ func (os *OtpService) VerifyOtp(verifyOtpData VerifyOtpRequest) (bool, *errors.A
 
 	// Fetch the OTP from the cache service
 	var storedOtp int
-	err := os.cacheService.Get(context.Background(), cacheKey, &storedOtp)
+	err := os.cacheService.Get(ctx, cacheKey, &storedOtp)
 	if err != nil {
 		if err == redis.Nil {
 			// OTP not found in the cache
@@ -77,7 +77,7 @@ func (os *OtpService) VerifyOtp(verifyOtpData VerifyOtpRequest) (bool, *errors.A
 	if userProvidedOtp == storedOtp {
 		// OTP is correct
 		// Remove the OTP from the cache to prevent reuse
-		err := os.cacheService.Delete(context.Background(), cacheKey)
+		err := os.cacheService.Delete(ctx, cacheKey)
 		if err != nil {
 			// Handle cache deletion error
 			logger.Error("Auth", "OtpService", "VerifyOtp", "failed to delete OTP from cache", err)
@@ -104,7 +104,7 @@ func generateOtp(length int) int {
 	return otp
 }
 
-func (os *OtpService) saveOtpInCache(key string, value int) {
+func (os *OtpService) saveOtpInCache(ctx context.Context, key string, value int) {
 	// Set the expiration time for the OTP (e.g., 5 minutes)
 	expiration := time.Minute * 5
 
@@ -112,7 +112,7 @@ func (os *OtpService) saveOtpInCache(key string, value int) {
 	cacheKey := "otp:" + key
 
 	// Save the OTP in the cache using the cacheService
-	err := os.cacheService.Set(context.Background(), cacheKey, value, expiration)
+	err := os.cacheService.Set(ctx, cacheKey, value, expiration)
 	if err != nil {
 		// Handle the error appropriately (e.g., log the error)
 		logger.Error("Auth", "OtpService", "saveOtpInCache", "failed to save OTP in cache", err)
@@ -0,0 +1,47 @@
+package organizationModule
+
+import (
+	"backendService/internals/common/audit"
+	"backendService/internals/common/auth"
+	"backendService/internals/common/cache"
+	"backendService/internals/common/jobs"
+	"backendService/internals/common/notification"
+	"backendService/internals/common/privacy"
+	baseRepository "backendService/internals/common/repository"
+	"backendService/internals/modules/organizationModule/organizationController"
+	repository "backendService/internals/modules/organizationModule/organizationRepository"
+	"backendService/internals/modules/organizationModule/organizationService"
+	organizationModule "backendService/internals/modules/organizationModule/routes"
+	"backendService/internals/modules/userModule"
+	"backendService/internals/setup/server"
+)
+
+var (
+	OrganizationRouter *organizationModule.OrganizationRouter
+	// OrganizationService lets other modules accept invitations, such as when signing in
+	OrganizationService *organizationService.OrganizationService
+)
+
+// Initialize wires the organization module. It depends on the user module, which must be initialized first.
+func Initialize() {
+
+	sessionStore := auth.NewSessionStore(&cache.Cache)
+	organizationRepository := repository.NewOrganizationRepository(server.Server.Db, &cache.Cache)
+	auditService := audit.NewAuditService(server.Server.Db)
+	organizationService := organizationService.NewOrganizationService(organizationRepository, userModule.UserService, notification.NewLogNotifier(), auditService)
+	organizationController := organizationController.NewOrganizationController(organizationService)
+	organizationRouter := organizationModule.NewOrganizationRouter(organizationController, sessionStore)
+
+	// Personal data held by the organization module, for data exports and erasures
+	organizationService.RegisterPersonalData(privacy.DefaultRegistry)
+
+	// Memberships follow their user when duplicate accounts are merged
+	userModule.UserMergeService.RegisterMergeHandler("memberships", organizationRepository.MergeMemberships)
+
+	// Deleted organizations are purged for good once the retention window has passed
+	jobs.Schedule("organization_purge", baseRepository.PurgeInterval(), organizationService.PurgeDeletedOrganizations)
+
+	// Export
+	OrganizationService = organizationService
+	OrganizationRouter = organizationRouter
+}
@@ -0,0 +1,234 @@
+package organizationController
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"backendService/internals/common/auth"
+	controllers "backendService/internals/common/controller"
+	"backendService/internals/common/errors"
+	"backendService/internals/common/logger"
+	"backendService/internals/common/router"
+	"backendService/internals/modules/organizationModule/organizationModule"
+	repository "backendService/internals/modules/organizationModule/organizationRepository"
+	"backendService/internals/modules/organizationModule/organizationService"
+)
+
+const (
+	organizationContextKey = "organization"
+	membershipContextKey   = "membership"
+)
+
+type OrganizationController struct {
+	controllers.BaseController
+	organizationService *organizationService.OrganizationService
+}
+
+// NewOrganizationController creates a new instance of OrganizationController with the provided organizationService dependency.
+func NewOrganizationController(organizationService *organizationService.OrganizationService) *OrganizationController {
+	return &OrganizationController{organizationService: organizationService}
+}
+
+// RequireOrganizationRole returns a middleware that only lets through members of the organization in the :orgId
+// path parameter having at least the given role. It must be registered after Authenticate. The organization
+// and the membership are stored in the request context and can be read with CurrentOrganization and CurrentMembership.
+func (oc *OrganizationController) RequireOrganizationRole(minimumRole string) router.HandlerFunc {
+	return func(c *gin.Context) (router.Response, *errors.ApplicationError) {
+		session := auth.CurrentSession(c)
+		if session == nil {
+			return router.Response{}, errors.NewUnauthorizedError("missing_token", "authorization token is required")
+		}
+
+		organization, membership, err := oc.organizationService.Authorize(c.Request.Context(), c.Param("orgId"), session.UserId, minimumRole)
+		if err != nil {
+			return router.Response{}, err
+		}
+
+		c.Set(organizationContextKey, organization)
+		c.Set(membershipContextKey, membership)
+		return router.Response{}, nil
+	}
+}
+
+// CurrentOrganization returns the organization stored in the request context by RequireOrganizationRole.
+func CurrentOrganization(c *gin.Context) *repository.Organization {
+	value, _ := c.Get(organizationContextKey)
+	organization, _ := value.(*repository.Organization)
+	return organization
+}
+
+// CurrentMembership returns the membership of the authenticated user stored in the request context by RequireOrganizationRole.
+func CurrentMembership(c *gin.Context) *repository.Membership {
+	value, _ := c.Get(membershipContextKey)
+	membership, _ := value.(*repository.Membership)
+	return membership
+}
+
+// CreateOrganization creates an organization owned by the authenticated user.
+func (oc *OrganizationController) CreateOrganization(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var body organizationModule.CreateOrganizationBody
+	_, err := oc.TransformAndValidate(c, &body)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	organization, err := oc.organizationService.CreateOrganization(c.Request.Context(), auth.CurrentSession(c).UserId, body)
+	if err != nil {
+		logger.Error("controller", "organization_controller", "CreateOrganization", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: organization, Message: "Organization created successfully", StatusCode: http.StatusCreated}, nil
+}
+
+// GetMyOrganizations retrieves the organizations the authenticated user is a member of.
+func (oc *OrganizationController) GetMyOrganizations(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	organizations, err := oc.organizationService.ListMyOrganizations(c.Request.Context(), auth.CurrentSession(c).UserId)
+	if err != nil {
+		logger.Error("controller", "organization_controller", "GetMyOrganizations", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: organizations, Message: "Organizations retrieved successfully"}, nil
+}
+
+// GetOrganization retrieves an organization the authenticated user is a member of.
+func (oc *OrganizationController) GetOrganization(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	organization := oc.organizationService.GetOrganization(CurrentOrganization(c), CurrentMembership(c))
+	return router.Response{Data: organization, Message: "Organization retrieved successfully", ETag: router.VersionETag(organization.Version)}, nil
+}
+
+// UpdateOrganization partially updates an organization. An If-Match header holding the ETag of a previous
+// response makes the update fail with a conflict when the organization changed since.
+func (oc *OrganizationController) UpdateOrganization(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var body organizationModule.UpdateOrganizationBody
+	_, err := oc.TransformAndValidate(c, &body)
+	if err != nil {
+		return router.Response{}, err
+	}
+	version, err := router.IfMatchVersion(c)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	organization, err := oc.organizationService.UpdateOrganization(c.Request.Context(), auth.CurrentSession(c).UserId, CurrentOrganization(c), CurrentMembership(c), body, version)
+	if err != nil {
+		logger.Error("controller", "organization_controller", "UpdateOrganization", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: organization, Message: "Organization updated successfully", ETag: router.VersionETag(organization.Version)}, nil
+}
+
+// DeleteOrganization soft deletes an organization and responds with 204 No Content.
+// The user must have signed in recently.
+func (oc *OrganizationController) DeleteOrganization(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	session := auth.CurrentSession(c)
+	if err := auth.RequireRecentAuthentication(session); err != nil {
+		return router.Response{}, err
+	}
+
+	err := oc.organizationService.DeleteOrganization(c.Request.Context(), session.UserId, CurrentOrganization(c))
+	if err != nil {
+		logger.Error("controller", "organization_controller", "DeleteOrganization", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{StatusCode: http.StatusNoContent}, nil
+}
+
+// GetMembers retrieves the members of an organization.
+func (oc *OrganizationController) GetMembers(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	members, err := oc.organizationService.ListMembers(c.Request.Context(), CurrentOrganization(c))
+	if err != nil {
+		logger.Error("controller", "organization_controller", "GetMembers", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: members, Message: "Members retrieved successfully"}, nil
+}
+
+// UpdateMember changes the role of a member of an organization.
+func (oc *OrganizationController) UpdateMember(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var body organizationModule.UpdateMemberBody
+	_, err := oc.TransformAndValidate(c, &body)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	member, err := oc.organizationService.UpdateMemberRole(c.Request.Context(), auth.CurrentSession(c).UserId, CurrentOrganization(c), CurrentMembership(c), c.Param("userId"), body)
+	if err != nil {
+		logger.Error("controller", "organization_controller", "UpdateMember", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: member, Message: "Member updated successfully"}, nil
+}
+
+// RemoveMember removes a member from an organization and responds with 204 No Content.
+// Members can remove themselves to leave the organization.
+func (oc *OrganizationController) RemoveMember(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	err := oc.organizationService.RemoveMember(c.Request.Context(), auth.CurrentSession(c).UserId, CurrentOrganization(c), CurrentMembership(c), c.Param("userId"))
+	if err != nil {
+		logger.Error("controller", "organization_controller", "RemoveMember", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{StatusCode: http.StatusNoContent}, nil
+}
+
+// InviteMember invites someone to join an organization by email or mobile.
+func (oc *OrganizationController) InviteMember(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var body organizationModule.InviteMemberBody
+	_, err := oc.TransformAndValidate(c, &body)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	invitation, err := oc.organizationService.InviteMember(c.Request.Context(), auth.CurrentSession(c).UserId, CurrentOrganization(c), CurrentMembership(c), body)
+	if err != nil {
+		logger.Error("controller", "organization_controller", "InviteMember", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: invitation, Message: "Invitation sent successfully", StatusCode: http.StatusCreated}, nil
+}
+
+// GetInvitations retrieves the pending invitations of an organization.
+func (oc *OrganizationController) GetInvitations(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	invitations, err := oc.organizationService.ListInvitations(c.Request.Context(), CurrentOrganization(c))
+	if err != nil {
+		logger.Error("controller", "organization_controller", "GetInvitations", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: invitations, Message: "Invitations retrieved successfully"}, nil
+}
+
+// RevokeInvitation revokes a pending invitation of an organization and responds with 204 No Content.
+func (oc *OrganizationController) RevokeInvitation(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	err := oc.organizationService.RevokeInvitation(c.Request.Context(), auth.CurrentSession(c).UserId, CurrentOrganization(c), CurrentMembership(c), c.Param("invitationId"))
+	if err != nil {
+		logger.Error("controller", "organization_controller", "RevokeInvitation", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{StatusCode: http.StatusNoContent}, nil
+}
+
+// GetInvitation retrieves the invitation sent with the token in the path, without requiring authentication,
+// so that the invitee can see what they are invited to before signing in.
+func (oc *OrganizationController) GetInvitation(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	invitation, err := oc.organizationService.GetInvitation(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		return router.Response{}, err
+	}
+	return router.Response{Data: invitation, Message: "Invitation retrieved successfully"}, nil
+}
+
+// AcceptInvitation makes the authenticated user a member of the organization they were invited to.
+func (oc *OrganizationController) AcceptInvitation(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var body organizationModule.AcceptInvitationBody
+	_, err := oc.TransformAndValidate(c, &body)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	organization, err := oc.organizationService.AcceptInvitation(c.Request.Context(), auth.CurrentSession(c).UserId, body.Token)
+	if err != nil {
+		logger.Error("controller", "organization_controller", "AcceptInvitation", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: organization, Message: "Invitation accepted successfully"}, nil
+}
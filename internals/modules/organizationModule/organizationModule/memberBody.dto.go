@@ -0,0 +1,19 @@
+package organizationModule
+
+// InviteMemberBody represents the request body for inviting someone to an organization.
+// Exactly one of email and mobile must be set.
+type InviteMemberBody struct {
+	Email  *string `json:"email,omitempty" validate:"omitempty,email,max=100"` // Email should be a valid email address if present
+	Mobile *string `json:"mobile,omitempty" validate:"omitempty,len=10"`       // Mobile should be 10 characters long if present
+	Role   string  `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// UpdateMemberBody represents the request body for changing the role of a member.
+type UpdateMemberBody struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// AcceptInvitationBody represents the request body for accepting an invitation with the token it was sent with.
+type AcceptInvitationBody struct {
+	Token string `json:"token" validate:"required,max=100"`
+}
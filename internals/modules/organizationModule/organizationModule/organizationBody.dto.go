@@ -0,0 +1,11 @@
+package organizationModule
+
+// CreateOrganizationBody represents the request body for creating an organization.
+type CreateOrganizationBody struct {
+	Name string `json:"name" validate:"required,min=2,max=100"`
+}
+
+// UpdateOrganizationBody represents the request body for partially updating an organization.
+type UpdateOrganizationBody struct {
+	Name *string `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+}
@@ -0,0 +1,140 @@
+package organizationRepository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"gorm.io/gorm"
+)
+
+// ErrInvitationNotFound is returned when an invitation does not exist or was already used.
+var ErrInvitationNotFound = errors.New("invitation not found")
+
+// ErrAlreadyMember is returned when accepting an invitation of a user who is already a member of the organization.
+var ErrAlreadyMember = errors.New("user is already a member of the organization")
+
+// Invitation invites whoever owns an email or mobile to join an organization with a role.
+// An organization has at most one pending invitation per contact, and only a hash of the token is stored.
+// Invitations are deleted once accepted, revoked or replaced.
+type Invitation struct {
+	ID        uint64    `json:"-" gorm:"primary_key"`
+	CreatedAt time.Time `json:"createdAt" gorm:"not null"`
+	UpdatedAt time.Time `json:"-" gorm:"not null"`
+
+	InvitationId   ulid.ULID     `json:"invitationId" gorm:"uniqueIndex"`
+	OrganizationID uint64        `json:"-" gorm:"index;not null"`
+	Organization   *Organization `json:"organization,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	Email          *string       `json:"email,omitempty"`
+	Mobile         *string       `json:"mobile,omitempty"`
+	Role           string        `json:"role" gorm:"not null"`
+	TokenHash      string        `json:"-" gorm:"uniqueIndex;not null"`
+	InvitedByID    uint64        `json:"-" gorm:"not null"`
+	ExpiresAt      time.Time     `json:"expiresAt" gorm:"index;not null"`
+}
+
+// IsExpired reports whether the invitation can no longer be accepted.
+func (i *Invitation) IsExpired() bool {
+	return time.Now().After(i.ExpiresAt)
+}
+
+// invitationsTable returns a session on the invitations table.
+func (r *OrganizationRepository) invitationsTable() *gorm.DB {
+	return r.invitations.Db.Session(&gorm.Session{})
+}
+
+// SaveInvitation records an invitation, replacing any earlier invitation of the same contact to the organization.
+// Expired invitations of the organization are cleaned up along the way.
+func (r *OrganizationRepository) SaveInvitation(invitation *Invitation) error {
+	return r.invitationsTable().Transaction(func(tx *gorm.DB) error {
+		stale := tx.Where("organization_id = ?", invitation.OrganizationID)
+		switch {
+		case invitation.Email != nil:
+			stale = stale.Where(tx.Where("email = ?", *invitation.Email).Or("expires_at <= ?", time.Now()))
+		case invitation.Mobile != nil:
+			stale = stale.Where(tx.Where("mobile = ?", *invitation.Mobile).Or("expires_at <= ?", time.Now()))
+		}
+		if err := stale.Delete(&Invitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("Organization").Create(invitation).Error
+	})
+}
+
+// FindInvitationByTokenHash returns the invitation with the given token hash, with its organization loaded.
+// It returns nil without an error when no invitation matches.
+func (r *OrganizationRepository) FindInvitationByTokenHash(tokenHash string) (*Invitation, error) {
+	var invitation Invitation
+	err := r.invitationsTable().Preload("Organization").Where("token_hash = ?", tokenHash).First(&invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// FindInvitation returns the invitation of the organization with the given public ID.
+// It returns nil without an error when no invitation matches.
+func (r *OrganizationRepository) FindInvitation(organizationID uint64, invitationId ulid.ULID) (*Invitation, error) {
+	var invitation Invitation
+	err := r.invitationsTable().Where("organization_id = ? AND invitation_id = ?", organizationID, invitationId).First(&invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// FindPendingInvitations returns the invitations of the organization that have not expired, newest first.
+func (r *OrganizationRepository) FindPendingInvitations(organizationID uint64) ([]Invitation, error) {
+	var invitations []Invitation
+	err := r.invitationsTable().
+		Where("organization_id = ? AND expires_at > ?", organizationID, time.Now()).
+		Order("id DESC").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+// DeleteInvitation removes an invitation.
+func (r *OrganizationRepository) DeleteInvitation(invitation *Invitation) error {
+	return r.invitationsTable().Delete(&Invitation{}, invitation.ID).Error
+}
+
+// AcceptInvitation consumes the invitation and makes the user with the given internal ID a member of its
+// organization, in a single transaction. It returns ErrInvitationNotFound when the invitation was already
+// used concurrently, and ErrAlreadyMember when the user is already a member.
+func (r *OrganizationRepository) AcceptInvitation(invitation *Invitation, userID uint64) (*Membership, error) {
+	membership := &Membership{
+		OrganizationID: invitation.OrganizationID,
+		UserID:         userID,
+		Role:           invitation.Role,
+	}
+	err := r.invitationsTable().Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Invitation{}, invitation.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationNotFound
+		}
+
+		memberships := tx.Session(&gorm.Session{NewDB: true}).Table("memberships")
+		var existing int64
+		err := memberships.Model(&Membership{}).Where("organization_id = ? AND user_id = ?", invitation.OrganizationID, userID).Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrAlreadyMember
+		}
+		return memberships.Omit("Organization", "User").Create(membership).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return membership, nil
+}
@@ -0,0 +1,245 @@
+package organizationRepository
+
+import (
+	"backendService/internals/common/cache"
+	"backendService/internals/common/repository"
+	userRepository "backendService/internals/modules/userModule/userRepository"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Roles a user can have within an organization, from the most to the least privileged.
+// Owners manage the organization itself and its owners, admins manage members and invitations,
+// and members can only see the organization and its members.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// roleRanks orders the organization roles, higher ranks include the permissions of lower ones.
+var roleRanks = map[string]int{
+	RoleOwner:  3,
+	RoleAdmin:  2,
+	RoleMember: 1,
+}
+
+// RoleAtLeast reports whether role grants at least the permissions of minimum.
+func RoleAtLeast(role string, minimum string) bool {
+	return roleRanks[role] >= roleRanks[minimum]
+}
+
+// ErrLastOwner is returned when a change would leave an organization without an owner.
+var ErrLastOwner = errors.New("an organization must keep at least one owner")
+
+// Organization is a group of users, such as a customer company, sharing access to the product.
+type Organization struct {
+	repository.BaseModel
+	repository.Versioned
+
+	OrganizationId ulid.ULID `json:"organizationId" gorm:"uniqueIndex"`
+	Name           string    `json:"name" gorm:"not null"`
+}
+
+// Membership grants a user a role within an organization. A user has at most one membership per organization.
+// Memberships live and die with their organization and user, so they are never soft-deleted.
+type Membership struct {
+	ID        uint64    `json:"-" gorm:"primary_key"`
+	CreatedAt time.Time `json:"joinedAt" gorm:"not null"`
+	UpdatedAt time.Time `json:"-" gorm:"not null"`
+
+	OrganizationID uint64               `json:"-" gorm:"uniqueIndex:idx_membership;not null"`
+	Organization   *Organization        `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	UserID         uint64               `json:"-" gorm:"uniqueIndex:idx_membership;index;not null"`
+	User           *userRepository.User `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Role           string               `json:"role" gorm:"not null"`
+}
+
+type OrganizationRepository struct {
+	*repository.CachedRepository[Organization]
+	memberships *repository.BaseRepository[Membership]
+	invitations *repository.BaseRepository[Invitation]
+}
+
+// NewOrganizationRepository creates a new instance of OrganizationRepository. Organizations are cached
+// in cacheService for the TTL configured for the organizations table.
+func NewOrganizationRepository(db *gorm.DB, cacheService *cache.CacheService) *OrganizationRepository {
+	organizations := repository.NewBaseRepository[Organization](db, "organizations").WithPublicID("organization_id")
+	return &OrganizationRepository{
+		CachedRepository: repository.NewCachedRepository(organizations, cacheService, repository.CacheOptions{TTL: repository.CacheTTL("organizations")}),
+		memberships:      repository.NewBaseRepository[Membership](db, "memberships"),
+		invitations:      repository.NewBaseRepository[Invitation](db, "invitations"),
+	}
+}
+
+// WithContext returns a copy of the repository running its queries with the given context.
+func (r *OrganizationRepository) WithContext(ctx context.Context) *OrganizationRepository {
+	return &OrganizationRepository{
+		CachedRepository: r.CachedRepository.WithContext(ctx),
+		memberships:      r.memberships.WithContext(ctx),
+		invitations:      r.invitations.WithContext(ctx),
+	}
+}
+
+// CreateWithOwner creates the organization and makes the user with the given internal ID its owner,
+// in a single transaction.
+func (r *OrganizationRepository) CreateWithOwner(organization *Organization, ownerID uint64) error {
+	return r.Db.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(organization).Error; err != nil {
+			return err
+		}
+		return tx.Session(&gorm.Session{NewDB: true}).Table("memberships").Omit("Organization", "User").Create(&Membership{
+			OrganizationID: organization.ID,
+			UserID:         ownerID,
+			Role:           RoleOwner,
+		}).Error
+	})
+}
+
+// membershipsTable returns a session on the memberships table.
+func (r *OrganizationRepository) membershipsTable() *gorm.DB {
+	return r.memberships.Db.Session(&gorm.Session{})
+}
+
+// FindMembership returns the membership of the user in the organization, given their internal IDs.
+// It returns nil without an error when the user is not a member.
+func (r *OrganizationRepository) FindMembership(organizationID uint64, userID uint64) (*Membership, error) {
+	var membership Membership
+	err := r.membershipsTable().Where("organization_id = ? AND user_id = ?", organizationID, userID).First(&membership).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// FindMembershipsByUser returns the memberships of the user in organizations that are not deleted,
+// with their organization loaded, oldest first.
+func (r *OrganizationRepository) FindMembershipsByUser(userID uint64) ([]Membership, error) {
+	var memberships []Membership
+	db := r.membershipsTable()
+	organizations := db.Session(&gorm.Session{NewDB: true}).Table("organizations").Select("id").Where("is_deleted = ?", false)
+	err := db.Preload("Organization").
+		Where("user_id = ? AND organization_id IN (?)", userID, organizations).
+		Order("id").
+		Find(&memberships).Error
+	return memberships, err
+}
+
+// FindMembers returns the memberships of the organization with their user loaded, oldest first.
+// Memberships of deleted users are left out.
+func (r *OrganizationRepository) FindMembers(organizationID uint64) ([]Membership, error) {
+	var memberships []Membership
+	db := r.membershipsTable()
+	users := db.Session(&gorm.Session{NewDB: true}).Table("users").Select("id").Where("is_deleted = ?", false)
+	err := db.Preload("User").
+		Where("organization_id = ? AND user_id IN (?)", organizationID, users).
+		Order("id").
+		Find(&memberships).Error
+	return memberships, err
+}
+
+// HasMemberWithContact reports whether a non-deleted user with the given email or mobile is a member of the
+// organization. kind is userRepository.ContactEmail or userRepository.ContactMobile.
+func (r *OrganizationRepository) HasMemberWithContact(organizationID uint64, kind string, value string) (bool, error) {
+	var count int64
+	db := r.membershipsTable()
+	users := db.Session(&gorm.Session{NewDB: true}).Table("users").Select("id").
+		Where(clause.Eq{Column: clause.Column{Name: kind}, Value: value}).
+		Where("is_deleted = ?", false)
+	err := db.Model(&Membership{}).Where("organization_id = ? AND user_id IN (?)", organizationID, users).Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateMembershipRole changes the role of a membership. It returns ErrLastOwner when the membership
+// is the last owner of its organization and would lose that role.
+func (r *OrganizationRepository) UpdateMembershipRole(membership *Membership, role string) error {
+	return r.membershipsTable().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Membership{}).Where("id = ?", membership.ID).Update("role", role).Error; err != nil {
+			return err
+		}
+		return ensureOwner(tx, membership.OrganizationID)
+	})
+}
+
+// DeleteMembership removes a user from an organization. It returns ErrLastOwner when the membership
+// is the last owner of its organization.
+func (r *OrganizationRepository) DeleteMembership(membership *Membership) error {
+	return r.membershipsTable().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&Membership{}, membership.ID).Error; err != nil {
+			return err
+		}
+		return ensureOwner(tx, membership.OrganizationID)
+	})
+}
+
+// PurgeDeleted permanently removes up to limit organizations soft-deleted at or before deletedBefore,
+// together with their memberships and invitations. It returns how many organizations were removed.
+func (r *OrganizationRepository) PurgeDeleted(deletedBefore time.Time, limit int) (int64, error) {
+	return r.BaseRepository.PurgeDeleted(deletedBefore, limit, func(tx *gorm.DB, ids []uint64) error {
+		err := tx.Session(&gorm.Session{NewDB: true}).Table("invitations").Where("organization_id IN ?", ids).Delete(&Invitation{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Session(&gorm.Session{NewDB: true}).Table("memberships").Where("organization_id IN ?", ids).Delete(&Membership{}).Error
+	})
+}
+
+// DeleteMembershipsByUser removes the user with the given internal ID from every organization.
+func (r *OrganizationRepository) DeleteMembershipsByUser(userID uint64) error {
+	return r.membershipsTable().Where("user_id = ?", userID).Delete(&Membership{}).Error
+}
+
+// MergeMemberships moves the memberships of the merged user to the surviving user, given their internal IDs,
+// as a userRepository.MergeFunc. Where both users are members of the same organization, the surviving user
+// keeps the higher of the two roles.
+func (r *OrganizationRepository) MergeMemberships(tx *gorm.DB, survivorID uint64, mergedID uint64) error {
+	memberships := tx.Table("memberships")
+	var merged []Membership
+	if err := memberships.Session(&gorm.Session{}).Where("user_id = ?", mergedID).Find(&merged).Error; err != nil {
+		return err
+	}
+	for _, membership := range merged {
+		var existing Membership
+		err := memberships.Session(&gorm.Session{}).Where("organization_id = ? AND user_id = ?", membership.OrganizationID, survivorID).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = memberships.Session(&gorm.Session{}).Model(&Membership{}).Where("id = ?", membership.ID).Update("user_id", survivorID).Error
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if roleRanks[membership.Role] > roleRanks[existing.Role] {
+			if err := memberships.Session(&gorm.Session{}).Model(&Membership{}).Where("id = ?", existing.ID).Update("role", membership.Role).Error; err != nil {
+				return err
+			}
+		}
+		if err := memberships.Session(&gorm.Session{}).Delete(&Membership{}, membership.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureOwner returns ErrLastOwner when the organization has no owner left, rolling back the transaction.
+func ensureOwner(tx *gorm.DB, organizationID uint64) error {
+	var owners int64
+	err := tx.Model(&Membership{}).Where("organization_id = ? AND role = ?", organizationID, RoleOwner).Count(&owners).Error
+	if err != nil {
+		return err
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package organizationService
+
+import (
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/logger"
+	"backendService/internals/modules/organizationModule/organizationModule"
+	repository "backendService/internals/modules/organizationModule/organizationRepository"
+	userRepository "backendService/internals/modules/userModule/userRepository"
+	"backendService/internals/setup/config"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// InviteMember invites the owner of an email or mobile to join the organization with a role, and sends them
+// a link to accept the invitation. Inviting the same contact again replaces the earlier invitation.
+// Only owners can invite owners.
+func (ors *OrganizationService) InviteMember(ctx context.Context, actorId string, organization *repository.Organization, actor *repository.Membership, body organizationModule.InviteMemberBody) (*repository.Invitation, *appError.ApplicationError) {
+	if (body.Email == nil) == (body.Mobile == nil) {
+		return nil, appError.NewBadRequestError("missing_data", "either email or mobile is required")
+	}
+	if appErr := canManage(actor, body.Role, body.Role); appErr != nil {
+		return nil, appErr
+	}
+	kind, value := userRepository.ContactMobile, body.Mobile
+	if body.Email != nil {
+		kind, value = userRepository.ContactEmail, body.Email
+	}
+
+	member, err := ors.organizationRepository.WithContext(ctx).HasMemberWithContact(organization.ID, kind, *value)
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve members")
+	}
+	if member {
+		return nil, appError.NewApplicationError("already_member", "the user with this "+kind+" is already a member", http.StatusConflict)
+	}
+
+	token, err := generateInvitationToken()
+	if err != nil {
+		return nil, appError.NewInternalServerError("failed to generate invitation token", err)
+	}
+	inviter, appErr := ors.userService.GetUserByID(ctx, actorId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	invitation := &repository.Invitation{
+		InvitationId:   ulid.Make(),
+		OrganizationID: organization.ID,
+		Email:          body.Email,
+		Mobile:         body.Mobile,
+		Role:           body.Role,
+		TokenHash:      hashInvitationToken(token),
+		InvitedByID:    inviter.ID,
+		ExpiresAt:      time.Now().Add(invitationTTL()),
+	}
+	if err := ors.organizationRepository.WithContext(ctx).SaveInvitation(invitation); err != nil {
+		logger.Error("service", "organization_service", "InviteMember", "failed to save invitation", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to save invitation")
+	}
+
+	message := "You have been invited to join " + organization.Name + " as " + body.Role + ". Accept the invitation at " +
+		config.Config.Organizations.InvitationURL + token + " within " + fmt.Sprint(invitationTTL().Round(time.Hour)) + "."
+	if err := ors.notifier.Notify(ctx, *value, message); err != nil {
+		logger.Error("service", "organization_service", "InviteMember", "failed to send invitation", err)
+		if err := ors.organizationRepository.WithContext(ctx).DeleteInvitation(invitation); err != nil {
+			logger.Error("service", "organization_service", "InviteMember", "failed to drop unsent invitation", err)
+		}
+		return nil, appError.NewBadRequestError("failed_to_send_invitation", "failed to send invitation")
+	}
+	ors.recordAudit(ctx, "organization.member_invited", actorId, organization, map[string]interface{}{
+		"invitationId": invitation.InvitationId.String(), "role": invitation.Role,
+	})
+
+	return invitation, nil
+}
+
+// ListInvitations returns the invitations of the organization that can still be accepted, newest first.
+func (ors *OrganizationService) ListInvitations(ctx context.Context, organization *repository.Organization) ([]repository.Invitation, *appError.ApplicationError) {
+	invitations, err := ors.organizationRepository.WithContext(ctx).FindPendingInvitations(organization.ID)
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve invitations")
+	}
+	return invitations, nil
+}
+
+// RevokeInvitation deletes an invitation of the organization, so that it can no longer be accepted.
+// Only owners can revoke invitations to become an owner.
+func (ors *OrganizationService) RevokeInvitation(ctx context.Context, actorId string, organization *repository.Organization, actor *repository.Membership, id string) *appError.ApplicationError {
+	invitationId, err := ulid.ParseStrict(id)
+	if err != nil {
+		return appError.NewBadRequestError("invalid_id", "invalid invitation ID")
+	}
+	invitation, err := ors.organizationRepository.WithContext(ctx).FindInvitation(organization.ID, invitationId)
+	if err != nil {
+		return appError.NewApplicationError("internal_error", "failed to retrieve invitation")
+	}
+	if invitation == nil {
+		return appError.NewNotFoundError("invitation_not_found", "invitation not found")
+	}
+	if appErr := canManage(actor, invitation.Role, invitation.Role); appErr != nil {
+		return appErr
+	}
+
+	if err := ors.organizationRepository.WithContext(ctx).DeleteInvitation(invitation); err != nil {
+		return appError.NewApplicationError("internal_error", "failed to revoke invitation")
+	}
+	ors.recordAudit(ctx, "organization.invitation_revoked", actorId, organization, map[string]interface{}{"invitationId": id})
+
+	return nil
+}
+
+// GetInvitation returns the invitation sent with the token, with its organization, so that the invitee
+// can see what they are invited to before signing in.
+func (ors *OrganizationService) GetInvitation(ctx context.Context, token string) (*repository.Invitation, *appError.ApplicationError) {
+	return ors.findInvitation(ctx, token)
+}
+
+// CheckInvitation verifies that the invitation sent with the token can be accepted by whoever owns the given
+// email or mobile, without accepting it. kind is userRepository.ContactEmail or userRepository.ContactMobile.
+func (ors *OrganizationService) CheckInvitation(ctx context.Context, token string, kind string, value string) *appError.ApplicationError {
+	invitation, appErr := ors.findInvitation(ctx, token)
+	if appErr != nil {
+		return appErr
+	}
+	if !invitationMatches(invitation, kind, value) {
+		return invitationMismatchError(invitation)
+	}
+	return nil
+}
+
+// AcceptInvitation makes the user identified by userId a member of the organization the token invites to.
+// The invitation must have been sent to a verified email or mobile of the user.
+func (ors *OrganizationService) AcceptInvitation(ctx context.Context, userId string, token string) (*UserOrganization, *appError.ApplicationError) {
+	user, appErr := ors.userService.GetUserByID(ctx, userId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return ors.AcceptInvitationForUser(ctx, user, token)
+}
+
+// AcceptInvitationForUser is AcceptInvitation for an already loaded user, such as one who just signed in.
+func (ors *OrganizationService) AcceptInvitationForUser(ctx context.Context, user *userRepository.User, token string) (*UserOrganization, *appError.ApplicationError) {
+	invitation, appErr := ors.findInvitation(ctx, token)
+	if appErr != nil {
+		return nil, appErr
+	}
+	verified := (user.IsEmailVerified && user.Email != nil && invitationMatches(invitation, userRepository.ContactEmail, *user.Email)) ||
+		(user.IsMobileVerified && user.Mobile != nil && invitationMatches(invitation, userRepository.ContactMobile, *user.Mobile))
+	if !verified {
+		return nil, invitationMismatchError(invitation)
+	}
+
+	membership, err := ors.organizationRepository.WithContext(ctx).AcceptInvitation(invitation, user.ID)
+	if err != nil {
+		switch err {
+		case repository.ErrInvitationNotFound:
+			return nil, appError.NewNotFoundError("invitation_not_found", "invitation not found or already used")
+		case repository.ErrAlreadyMember:
+			return nil, appError.NewApplicationError("already_member", "you are already a member of this organization", http.StatusConflict)
+		}
+		logger.Error("service", "organization_service", "AcceptInvitation", "failed to accept invitation", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to accept invitation")
+	}
+	ors.recordAudit(ctx, "organization.invitation_accepted", user.UserId.String(), invitation.Organization, map[string]interface{}{
+		"invitationId": invitation.InvitationId.String(), "role": membership.Role,
+	})
+
+	return &UserOrganization{Organization: invitation.Organization, Role: membership.Role, JoinedAt: membership.CreatedAt}, nil
+}
+
+// findInvitation retrieves the invitation sent with the token, provided it can still be accepted.
+func (ors *OrganizationService) findInvitation(ctx context.Context, token string) (*repository.Invitation, *appError.ApplicationError) {
+	invitation, err := ors.organizationRepository.WithContext(ctx).FindInvitationByTokenHash(hashInvitationToken(token))
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve invitation")
+	}
+	if invitation == nil || invitation.Organization == nil || invitation.Organization.IsDeleted {
+		return nil, appError.NewNotFoundError("invitation_not_found", "invitation not found or already used")
+	}
+	if invitation.IsExpired() {
+		return nil, appError.NewApplicationError("invitation_expired", "invitation has expired, please ask for a new one", http.StatusGone)
+	}
+	return invitation, nil
+}
+
+// invitationMatches reports whether the invitation was sent to the given email or mobile.
+func invitationMatches(invitation *repository.Invitation, kind string, value string) bool {
+	if kind == userRepository.ContactEmail {
+		return invitation.Email != nil && *invitation.Email == value
+	}
+	return invitation.Mobile != nil && *invitation.Mobile == value
+}
+
+// invitationMismatchError is returned when an invitation is used by someone it was not sent to.
+func invitationMismatchError(invitation *repository.Invitation) *appError.ApplicationError {
+	kind := userRepository.ContactMobile
+	if invitation.Email != nil {
+		kind = userRepository.ContactEmail
+	}
+	return appError.NewApplicationError("invitation_mismatch", "this invitation was sent to another "+kind, http.StatusForbidden)
+}
+
+// generateInvitationToken returns a random URL safe token to send with an invitation.
+func generateInvitationToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashInvitationToken hashes an invitation token for storage. Tokens are random and long enough
+// that a plain hash is not open to brute force.
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// invitationTTL returns how long invitations can be accepted, defaulting to three days.
+func invitationTTL() time.Duration {
+	if config.Config.Organizations.InvitationTTLHours <= 0 {
+		return 72 * time.Hour
+	}
+	return time.Duration(config.Config.Organizations.InvitationTTLHours) * time.Hour
+}
@@ -0,0 +1,183 @@
+package organizationService
+
+import (
+	"backendService/internals/modules/organizationModule/organizationModule"
+	repository "backendService/internals/modules/organizationModule/organizationRepository"
+	userRepository "backendService/internals/modules/userModule/userRepository"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInviteMember(t *testing.T) {
+	env := newTestEnvironment(t)
+	ctx := context.Background()
+	ada := createTestUser(t, env, "Ada", "ada@example.com")
+	grace := createTestUser(t, env, "Grace", "grace@example.com")
+	organization, owner := createTestOrganization(t, env, ada)
+	admin := addMember(t, env, organization, ada, grace, repository.RoleAdmin)
+
+	tests := []struct {
+		name  string
+		actor *repository.Membership
+		body  organizationModule.InviteMemberBody
+		want  string
+	}{
+		{"neither email nor mobile", owner, organizationModule.InviteMemberBody{Role: repository.RoleMember}, "missing_data"},
+		{"both email and mobile", owner, organizationModule.InviteMemberBody{Email: stringPointer("alan@example.com"), Mobile: stringPointer("5550100000"), Role: repository.RoleMember}, "missing_data"},
+		{"an existing member", owner, organizationModule.InviteMemberBody{Email: grace.Email, Role: repository.RoleMember}, "already_member"},
+		{"an admin inviting an owner", admin, organizationModule.InviteMemberBody{Email: stringPointer("alan@example.com"), Role: repository.RoleOwner}, "forbidden"},
+	}
+	for _, test := range tests {
+		_, appErr := env.organizationService.InviteMember(ctx, "", organization, test.actor, test.body)
+		if appErr == nil || appErr.ErrorCode != test.want {
+			t.Errorf("inviting %s: got %v, want %s", test.name, appErr, test.want)
+		}
+	}
+
+	body := organizationModule.InviteMemberBody{Email: stringPointer("alan@example.com"), Role: repository.RoleMember}
+	if _, appErr := env.organizationService.InviteMember(ctx, grace.UserId.String(), organization, admin, body); appErr != nil {
+		t.Fatalf("InviteMember: %s", appErr.Message)
+	}
+	first := env.notifier.sentToken(t, "alan@example.com")
+
+	// Inviting the same contact again replaces the earlier invitation
+	body.Role = repository.RoleAdmin
+	invitation, appErr := env.organizationService.InviteMember(ctx, grace.UserId.String(), organization, admin, body)
+	if appErr != nil {
+		t.Fatalf("InviteMember: %s", appErr.Message)
+	}
+	second := env.notifier.sentToken(t, "alan@example.com")
+	if first == second {
+		t.Fatal("inviting again sent the same token")
+	}
+	if _, appErr := env.organizationService.GetInvitation(ctx, first); appErr == nil || appErr.ErrorCode != "invitation_not_found" {
+		t.Errorf("getting the replaced invitation: got %v, want invitation_not_found", appErr)
+	}
+	found, appErr := env.organizationService.GetInvitation(ctx, second)
+	if appErr != nil || found.InvitationId != invitation.InvitationId || found.Organization.Name != organization.Name {
+		t.Errorf("getting the invitation: got %+v, %v, want it with its organization", found, appErr)
+	}
+
+	invitations, appErr := env.organizationService.ListInvitations(ctx, organization)
+	if appErr != nil {
+		t.Fatalf("ListInvitations: %s", appErr.Message)
+	}
+	if len(invitations) != 1 || invitations[0].Role != repository.RoleAdmin {
+		t.Errorf("got invitations %+v, want only the admin invitation", invitations)
+	}
+
+	// The token is only ever sent, never stored
+	var stored repository.Invitation
+	if err := env.db.Where("invitation_id = ?", invitation.InvitationId).First(&stored).Error; err != nil {
+		t.Fatalf("reading the invitation: %v", err)
+	}
+	if stored.TokenHash == second || stored.TokenHash != hashInvitationToken(second) {
+		t.Errorf("got token hash %q, want the hash of the token", stored.TokenHash)
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	env := newTestEnvironment(t)
+	ctx := context.Background()
+	ada := createTestUser(t, env, "Ada", "ada@example.com")
+	grace := createTestUser(t, env, "Grace", "grace@example.com")
+	organization, owner := createTestOrganization(t, env, ada)
+	invite := func(email string) string {
+		t.Helper()
+		body := organizationModule.InviteMemberBody{Email: stringPointer(email), Role: repository.RoleAdmin}
+		if _, appErr := env.organizationService.InviteMember(ctx, ada.UserId.String(), organization, owner, body); appErr != nil {
+			t.Fatalf("InviteMember: %s", appErr.Message)
+		}
+		return env.notifier.sentToken(t, email)
+	}
+	token := invite("grace@example.com")
+
+	if appErr := env.organizationService.CheckInvitation(ctx, token, userRepository.ContactEmail, "alan@example.com"); appErr == nil || appErr.ErrorCode != "invitation_mismatch" {
+		t.Errorf("checking with another email: got %v, want invitation_mismatch", appErr)
+	}
+	if appErr := env.organizationService.CheckInvitation(ctx, token, userRepository.ContactEmail, "grace@example.com"); appErr != nil {
+		t.Errorf("CheckInvitation: %s", appErr.Message)
+	}
+	if _, appErr := env.organizationService.AcceptInvitation(ctx, ada.UserId.String(), token); appErr == nil || appErr.ErrorCode != "invitation_mismatch" {
+		t.Errorf("accepting someone else's invitation: got %v, want invitation_mismatch", appErr)
+	}
+
+	// The invitation must go to a verified contact of the user
+	unverified := *grace
+	unverified.IsEmailVerified = false
+	if _, appErr := env.organizationService.AcceptInvitationForUser(ctx, &unverified, token); appErr == nil || appErr.ErrorCode != "invitation_mismatch" {
+		t.Errorf("accepting with an unverified email: got %v, want invitation_mismatch", appErr)
+	}
+
+	joined, appErr := env.organizationService.AcceptInvitation(ctx, grace.UserId.String(), token)
+	if appErr != nil {
+		t.Fatalf("AcceptInvitation: %s", appErr.Message)
+	}
+	if joined.OrganizationId != organization.OrganizationId || joined.Role != repository.RoleAdmin {
+		t.Errorf("got %+v, want an admin of the organization", joined)
+	}
+	if _, appErr := env.organizationService.AcceptInvitation(ctx, grace.UserId.String(), token); appErr == nil || appErr.ErrorCode != "invitation_not_found" {
+		t.Errorf("accepting an invitation twice: got %v, want invitation_not_found", appErr)
+	}
+
+	// Users who joined by other means since being invited cannot join twice
+	if err := env.db.Exec("DELETE FROM memberships WHERE user_id = ?", grace.ID).Error; err != nil {
+		t.Fatalf("removing grace: %v", err)
+	}
+	token = invite("grace@example.com")
+	err := env.db.Table("memberships").Omit("Organization", "User").Create(&repository.Membership{
+		OrganizationID: organization.ID, UserID: grace.ID, Role: repository.RoleMember,
+	}).Error
+	if err != nil {
+		t.Fatalf("adding grace back: %v", err)
+	}
+	if _, appErr := env.organizationService.AcceptInvitation(ctx, grace.UserId.String(), token); appErr == nil || appErr.ErrorCode != "already_member" {
+		t.Errorf("accepting as a member: got %v, want already_member", appErr)
+	}
+
+	token = invite("alan@example.com")
+	if err := env.db.Model(&repository.Invitation{}).Where("email = ?", "alan@example.com").Update("expires_at", time.Now().Add(-time.Minute)).Error; err != nil {
+		t.Fatalf("expiring the invitation: %v", err)
+	}
+	if _, appErr := env.organizationService.GetInvitation(ctx, token); appErr == nil || appErr.ErrorCode != "invitation_expired" {
+		t.Errorf("getting an expired invitation: got %v, want invitation_expired", appErr)
+	}
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	env := newTestEnvironment(t)
+	ctx := context.Background()
+	ada := createTestUser(t, env, "Ada", "ada@example.com")
+	grace := createTestUser(t, env, "Grace", "grace@example.com")
+	organization, owner := createTestOrganization(t, env, ada)
+	admin := addMember(t, env, organization, ada, grace, repository.RoleAdmin)
+
+	body := organizationModule.InviteMemberBody{Email: stringPointer("alan@example.com"), Role: repository.RoleOwner}
+	invitation, appErr := env.organizationService.InviteMember(ctx, ada.UserId.String(), organization, owner, body)
+	if appErr != nil {
+		t.Fatalf("InviteMember: %s", appErr.Message)
+	}
+	token := env.notifier.sentToken(t, "alan@example.com")
+	if !strings.Contains(env.notifier.messages["alan@example.com"][0], organization.Name) {
+		t.Errorf("got invitation %q, want it to name the organization", env.notifier.messages["alan@example.com"][0])
+	}
+
+	id := invitation.InvitationId.String()
+	if appErr := env.organizationService.RevokeInvitation(ctx, grace.UserId.String(), organization, admin, id); appErr == nil || appErr.ErrorCode != "forbidden" {
+		t.Errorf("an admin revoking an owner invitation: got %v, want forbidden", appErr)
+	}
+	if appErr := env.organizationService.RevokeInvitation(ctx, ada.UserId.String(), organization, owner, "not-an-id"); appErr == nil || appErr.ErrorCode != "invalid_id" {
+		t.Errorf("revoking an invalid ID: got %v, want invalid_id", appErr)
+	}
+	if appErr := env.organizationService.RevokeInvitation(ctx, ada.UserId.String(), organization, owner, id); appErr != nil {
+		t.Fatalf("RevokeInvitation: %s", appErr.Message)
+	}
+	if appErr := env.organizationService.RevokeInvitation(ctx, ada.UserId.String(), organization, owner, id); appErr == nil || appErr.ErrorCode != "invitation_not_found" {
+		t.Errorf("revoking twice: got %v, want invitation_not_found", appErr)
+	}
+	if _, appErr := env.organizationService.GetInvitation(ctx, token); appErr == nil || appErr.ErrorCode != "invitation_not_found" {
+		t.Errorf("getting a revoked invitation: got %v, want invitation_not_found", appErr)
+	}
+}
@@ -0,0 +1,348 @@
+package organizationService
+
+import (
+	"backendService/internals/common/audit"
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/logger"
+	"backendService/internals/common/notification"
+	"backendService/internals/common/privacy"
+	baseRepository "backendService/internals/common/repository"
+	"backendService/internals/modules/organizationModule/organizationModule"
+	repository "backendService/internals/modules/organizationModule/organizationRepository"
+	"backendService/internals/modules/userModule/userService"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// UserOrganization is an organization as seen by one of its members.
+type UserOrganization struct {
+	*repository.Organization
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// Member is a user as seen by the other members of an organization. Only what members need
+// to recognize each other is exposed, never the full user.
+type Member struct {
+	UserId             ulid.ULID `json:"userId"`
+	FirstName          string    `json:"firstName"`
+	LastName           string    `json:"lastName"`
+	Email              *string   `json:"email"`
+	AvatarThumbnailURL *string   `json:"avatarThumbnailUrl"`
+	Role               string    `json:"role"`
+	JoinedAt           time.Time `json:"joinedAt"`
+}
+
+// OrganizationService manages organizations, their members and the invitations to join them.
+// Access to an organization is decided by the role of the user's membership, see Authorize.
+type OrganizationService struct {
+	organizationRepository *repository.OrganizationRepository
+	userService            *userService.UserService
+	notifier               notification.Notifier
+	auditService           *audit.AuditService
+}
+
+// NewOrganizationService creates a new instance of OrganizationService.
+func NewOrganizationService(organizationRepository *repository.OrganizationRepository, userService *userService.UserService, notifier notification.Notifier, auditService *audit.AuditService) *OrganizationService {
+	return &OrganizationService{
+		organizationRepository: organizationRepository,
+		userService:            userService,
+		notifier:               notifier,
+		auditService:           auditService,
+	}
+}
+
+// Authorize returns the organization identified by organizationId and the membership of the user identified by userId,
+// provided the membership has at least the given role. Organizations the user is not a member of are reported
+// as not found, so that their existence is not disclosed.
+func (ors *OrganizationService) Authorize(ctx context.Context, organizationId string, userId string, minimumRole string) (*repository.Organization, *repository.Membership, *appError.ApplicationError) {
+	organization, appErr := ors.findOrganization(ctx, organizationId)
+	if appErr != nil {
+		return nil, nil, appErr
+	}
+	user, appErr := ors.userService.GetUserByID(ctx, userId)
+	if appErr != nil {
+		return nil, nil, appErr
+	}
+	membership, err := ors.organizationRepository.WithContext(ctx).FindMembership(organization.ID, user.ID)
+	if err != nil {
+		return nil, nil, appError.NewApplicationError("internal_error", "failed to retrieve membership")
+	}
+	if membership == nil {
+		return nil, nil, appError.NewNotFoundError("organization_not_found", "organization not found")
+	}
+	if !repository.RoleAtLeast(membership.Role, minimumRole) {
+		return nil, nil, appError.NewApplicationError("forbidden", "this action requires the "+minimumRole+" role in the organization", http.StatusForbidden)
+	}
+	return organization, membership, nil
+}
+
+// CreateOrganization creates an organization owned by the user identified by userId.
+func (ors *OrganizationService) CreateOrganization(ctx context.Context, userId string, body organizationModule.CreateOrganizationBody) (*UserOrganization, *appError.ApplicationError) {
+	user, appErr := ors.userService.GetUserByID(ctx, userId)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	organization := &repository.Organization{OrganizationId: ulid.Make(), Name: body.Name}
+	if err := ors.organizationRepository.WithContext(ctx).CreateWithOwner(organization, user.ID); err != nil {
+		logger.Error("service", "organization_service", "CreateOrganization", "failed to create organization", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to create organization")
+	}
+	ors.recordAudit(ctx, "organization.created", userId, organization, map[string]interface{}{"name": organization.Name})
+
+	return &UserOrganization{Organization: organization, Role: repository.RoleOwner, JoinedAt: organization.CreatedAt}, nil
+}
+
+// ListMyOrganizations returns the organizations the user identified by userId is a member of, with their role.
+func (ors *OrganizationService) ListMyOrganizations(ctx context.Context, userId string) ([]UserOrganization, *appError.ApplicationError) {
+	user, appErr := ors.userService.GetUserByID(ctx, userId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	memberships, err := ors.organizationRepository.WithContext(ctx).FindMembershipsByUser(user.ID)
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve organizations")
+	}
+
+	organizations := make([]UserOrganization, 0, len(memberships))
+	for _, membership := range memberships {
+		if membership.Organization == nil {
+			continue
+		}
+		organizations = append(organizations, UserOrganization{Organization: membership.Organization, Role: membership.Role, JoinedAt: membership.CreatedAt})
+	}
+	return organizations, nil
+}
+
+// GetOrganization returns the organization as seen by the member.
+func (ors *OrganizationService) GetOrganization(organization *repository.Organization, membership *repository.Membership) *UserOrganization {
+	return &UserOrganization{Organization: organization, Role: membership.Role, JoinedAt: membership.CreatedAt}
+}
+
+// UpdateOrganization partially updates the organization. When version is set, the organization is only
+// updated if it is still at that version. Either way the update fails with a conflict when the organization
+// changes between being read and being updated.
+func (ors *OrganizationService) UpdateOrganization(ctx context.Context, actorId string, organization *repository.Organization, membership *repository.Membership, body organizationModule.UpdateOrganizationBody, version *uint64) (*UserOrganization, *appError.ApplicationError) {
+	if body.Name == nil {
+		return nil, appError.NewBadRequestError("missing_data", "no fields to update")
+	}
+	if version != nil && *version != organization.Version {
+		return nil, versionConflictError()
+	}
+
+	err := ors.organizationRepository.WithContext(ctx).UpdateVersioned(map[string]interface{}{"id": organization.ID}, organization.Version, map[string]interface{}{"name": *body.Name})
+	if errors.Is(err, baseRepository.ErrVersionConflict) {
+		return nil, versionConflictError()
+	}
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to update organization")
+	}
+	ors.recordAudit(ctx, "organization.updated", actorId, organization, map[string]interface{}{"name": *body.Name, "previousName": organization.Name})
+	organization.Name = *body.Name
+	organization.Version++
+
+	return ors.GetOrganization(organization, membership), nil
+}
+
+// versionConflictError is returned when an organization changed since the client or the service read it.
+func versionConflictError() *appError.ApplicationError {
+	return appError.NewApplicationError("version_conflict", "the organization was modified in the meantime, fetch it again and retry", http.StatusConflict)
+}
+
+// DeleteOrganization soft deletes the organization. Its members lose access to it, and its pending
+// invitations can no longer be accepted.
+func (ors *OrganizationService) DeleteOrganization(ctx context.Context, actorId string, organization *repository.Organization) *appError.ApplicationError {
+	if err := ors.organizationRepository.WithContext(ctx).Delete(organization.ID); err != nil {
+		return appError.NewApplicationError("internal_error", "failed to delete organization")
+	}
+	ors.recordAudit(ctx, "organization.deleted", actorId, organization, nil)
+	return nil
+}
+
+// purgeBatchSize is how many deleted organizations are purged per transaction.
+const purgeBatchSize = 100
+
+// PurgeDeletedOrganizations permanently removes the organizations soft-deleted longer ago than the retention
+// window, with their memberships and invitations. It is meant to run periodically with jobs.Schedule.
+func (ors *OrganizationService) PurgeDeletedOrganizations(ctx context.Context) error {
+	deletedBefore := time.Now().Add(-baseRepository.PurgeRetention())
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		purged, err := ors.organizationRepository.WithContext(ctx).PurgeDeleted(deletedBefore, purgeBatchSize)
+		if err != nil {
+			return err
+		}
+		if purged > 0 {
+			logger.Info("service", "organization_service", "PurgeDeletedOrganizations", "purged deleted organizations", purged)
+		}
+		if purged < purgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// ListMembers returns the members of the organization, oldest first.
+func (ors *OrganizationService) ListMembers(ctx context.Context, organization *repository.Organization) ([]Member, *appError.ApplicationError) {
+	memberships, err := ors.organizationRepository.WithContext(ctx).FindMembers(organization.ID)
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve members")
+	}
+
+	members := make([]Member, 0, len(memberships))
+	for i := range memberships {
+		if memberships[i].User == nil {
+			continue
+		}
+		members = append(members, newMember(&memberships[i]))
+	}
+	return members, nil
+}
+
+// UpdateMemberRole changes the role of the member identified by userId. Only owners can grant or take away
+// the owner role, and the last owner cannot be demoted.
+func (ors *OrganizationService) UpdateMemberRole(ctx context.Context, actorId string, organization *repository.Organization, actor *repository.Membership, userId string, body organizationModule.UpdateMemberBody) (*Member, *appError.ApplicationError) {
+	target, appErr := ors.findMember(ctx, organization, userId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if appErr := canManage(actor, target.Role, body.Role); appErr != nil {
+		return nil, appErr
+	}
+
+	if target.Role != body.Role {
+		if err := ors.organizationRepository.WithContext(ctx).UpdateMembershipRole(target, body.Role); err != nil {
+			if err == repository.ErrLastOwner {
+				return nil, appError.NewApplicationError("last_owner", "the organization must keep at least one owner", http.StatusConflict)
+			}
+			return nil, appError.NewApplicationError("internal_error", "failed to update member")
+		}
+		ors.recordAudit(ctx, "organization.member_role_changed", actorId, organization, map[string]interface{}{
+			"userId": userId, "role": body.Role, "previousRole": target.Role,
+		})
+		target.Role = body.Role
+	}
+
+	member := newMember(target)
+	return &member, nil
+}
+
+// RemoveMember removes the member identified by userId from the organization. Members can always leave
+// on their own, otherwise the same rules as for role changes apply. The last owner cannot leave.
+func (ors *OrganizationService) RemoveMember(ctx context.Context, actorId string, organization *repository.Organization, actor *repository.Membership, userId string) *appError.ApplicationError {
+	target, appErr := ors.findMember(ctx, organization, userId)
+	if appErr != nil {
+		return appErr
+	}
+	if target.ID != actor.ID {
+		if appErr := canManage(actor, target.Role, target.Role); appErr != nil {
+			return appErr
+		}
+	}
+
+	if err := ors.organizationRepository.WithContext(ctx).DeleteMembership(target); err != nil {
+		if err == repository.ErrLastOwner {
+			return appError.NewApplicationError("last_owner", "the organization must keep at least one owner", http.StatusConflict)
+		}
+		return appError.NewApplicationError("internal_error", "failed to remove member")
+	}
+	ors.recordAudit(ctx, "organization.member_removed", actorId, organization, map[string]interface{}{"userId": userId, "role": target.Role})
+
+	return nil
+}
+
+// RegisterPersonalData registers the memberships of users with the registry. Erasing a user removes them
+// from every organization.
+func (ors *OrganizationService) RegisterPersonalData(registry *privacy.Registry) {
+	registry.RegisterSection("organizations", func(ctx context.Context, subject privacy.Subject) (interface{}, error) {
+		memberships, err := ors.organizationRepository.WithContext(ctx).FindMembershipsByUser(subject.ID)
+		if err != nil || len(memberships) == 0 {
+			return nil, err
+		}
+		organizations := make([]UserOrganization, 0, len(memberships))
+		for _, membership := range memberships {
+			organizations = append(organizations, UserOrganization{Organization: membership.Organization, Role: membership.Role, JoinedAt: membership.CreatedAt})
+		}
+		return organizations, nil
+	})
+
+	registry.RegisterEraser("organizations", func(ctx context.Context, subject privacy.Subject) error {
+		return ors.organizationRepository.WithContext(ctx).DeleteMembershipsByUser(subject.ID)
+	})
+}
+
+// canManage checks that the actor may change a membership from one role to another.
+// Admins manage admins and members, and only owners manage owners.
+func canManage(actor *repository.Membership, fromRole string, toRole string) *appError.ApplicationError {
+	if !repository.RoleAtLeast(actor.Role, repository.RoleAdmin) {
+		return appError.NewApplicationError("forbidden", "this action requires the admin role in the organization", http.StatusForbidden)
+	}
+	if (fromRole == repository.RoleOwner || toRole == repository.RoleOwner) && actor.Role != repository.RoleOwner {
+		return appError.NewApplicationError("forbidden", "only owners can manage owners", http.StatusForbidden)
+	}
+	return nil
+}
+
+// findOrganization retrieves a non-deleted organization by its public ID.
+func (ors *OrganizationService) findOrganization(ctx context.Context, id string) (*repository.Organization, *appError.ApplicationError) {
+	organizationId, err := ulid.ParseStrict(id)
+	if err != nil {
+		return nil, appError.NewBadRequestError("invalid_id", "invalid organization ID")
+	}
+	organization, err := ors.organizationRepository.WithContext(ctx).FindByPublicID(organizationId)
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve organization")
+	}
+	if organization == nil {
+		return nil, appError.NewNotFoundError("organization_not_found", "organization not found")
+	}
+	return organization, nil
+}
+
+// findMember retrieves the membership in the organization of the user identified by userId, with the user loaded.
+func (ors *OrganizationService) findMember(ctx context.Context, organization *repository.Organization, userId string) (*repository.Membership, *appError.ApplicationError) {
+	user, appErr := ors.userService.GetUserByID(ctx, userId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	membership, err := ors.organizationRepository.WithContext(ctx).FindMembership(organization.ID, user.ID)
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve member")
+	}
+	if membership == nil {
+		return nil, appError.NewNotFoundError("member_not_found", "member not found")
+	}
+	membership.User = user
+	return membership, nil
+}
+
+// newMember builds the member view of a membership with its user loaded.
+func newMember(membership *repository.Membership) Member {
+	user := membership.User
+	return Member{
+		UserId:             user.UserId,
+		FirstName:          user.FirstName,
+		LastName:           user.LastName,
+		Email:              user.Email,
+		AvatarThumbnailURL: user.AvatarThumbnailURL,
+		Role:               membership.Role,
+		JoinedAt:           membership.CreatedAt,
+	}
+}
+
+// recordAudit records an action on the organization in the audit log.
+func (ors *OrganizationService) recordAudit(ctx context.Context, action string, actorId string, organization *repository.Organization, details map[string]interface{}) {
+	ors.auditService.Record(ctx, audit.Entry{
+		Action:     action,
+		ActorId:    actorId,
+		TargetType: "organization",
+		TargetId:   organization.OrganizationId.String(),
+		Details:    details,
+	})
+}
@@ -0,0 +1,294 @@
+package organizationService
+
+import (
+	"backendService/internals/common/audit"
+	"backendService/internals/common/auth"
+	"backendService/internals/common/cache/cachetest"
+	"backendService/internals/common/history"
+	"backendService/internals/common/jobs"
+	"backendService/internals/common/privacy"
+	baseRepository "backendService/internals/common/repository"
+	"backendService/internals/common/storage"
+	"backendService/internals/modules/organizationModule/organizationModule"
+	repository "backendService/internals/modules/organizationModule/organizationRepository"
+	"backendService/internals/modules/userModule/userModule"
+	userRepository "backendService/internals/modules/userModule/userRepository"
+	"backendService/internals/modules/userModule/userService"
+	"backendService/internals/setup/config"
+	"backendService/internals/setup/database/databasetest"
+	"context"
+	"regexp"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// testInvitationURL is the invitation page configured for the tests, the tokens sent are appended to it.
+const testInvitationURL = "https://app.example.com/invitations/"
+
+// recordingNotifier keeps the messages sent to every recipient.
+type recordingNotifier struct {
+	mu       sync.Mutex
+	messages map[string][]string
+}
+
+func (rn *recordingNotifier) Notify(ctx context.Context, recipient string, message string) error {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	rn.messages[recipient] = append(rn.messages[recipient], message)
+	return nil
+}
+
+var invitationTokenPattern = regexp.MustCompile(regexp.QuoteMeta(testInvitationURL) + `([A-Za-z0-9_-]+)`)
+
+// sentToken returns the invitation token last sent to the recipient.
+func (rn *recordingNotifier) sentToken(t *testing.T, recipient string) string {
+	t.Helper()
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	messages := rn.messages[recipient]
+	if len(messages) == 0 {
+		t.Fatalf("no invitation was sent to %s", recipient)
+	}
+	match := invitationTokenPattern.FindStringSubmatch(messages[len(messages)-1])
+	if match == nil {
+		t.Fatalf("no invitation token in %q", messages[len(messages)-1])
+	}
+	return match[1]
+}
+
+// testEnvironment holds the dependencies shared by the services under test.
+type testEnvironment struct {
+	db                  *gorm.DB
+	userRepository      *userRepository.UserRepository
+	userService         *userService.UserService
+	notifier            *recordingNotifier
+	organizationService *OrganizationService
+}
+
+// newTestEnvironment sets up a migrated test database, an in-memory cache and an OrganizationService using them.
+func newTestEnvironment(t *testing.T) *testEnvironment {
+	t.Helper()
+	previous := config.Config
+	config.Config.App.SecretKey = "test_secret_key"
+	config.Config.Organizations.InvitationURL = testInvitationURL
+	t.Cleanup(func() { config.Config = previous })
+
+	db := databasetest.Open(t)
+	cacheService, _ := cachetest.NewCacheService(t)
+	auditService := audit.NewAuditService(db)
+	env := &testEnvironment{
+		db:             db,
+		userRepository: userRepository.NewUserRepository(db, cacheService),
+		notifier:       &recordingNotifier{messages: map[string][]string{}},
+	}
+	env.userService = userService.NewUserService(
+		env.userRepository,
+		auth.NewSessionStore(cacheService),
+		auditService,
+		history.NewHistoryService(db),
+		jobs.NewJobStore(cacheService),
+		storage.NewLocalStorage(t.TempDir(), "/files"),
+		privacy.NewRegistry(),
+		baseRepository.NewUnitOfWork(db),
+	)
+	env.organizationService = NewOrganizationService(repository.NewOrganizationRepository(db, cacheService), env.userService, env.notifier, auditService)
+	return env
+}
+
+// createTestUser creates a user with a verified email.
+func createTestUser(t *testing.T, env *testEnvironment, firstName string, email string) *userRepository.User {
+	t.Helper()
+	user, appErr := env.userService.CreateUser(context.Background(), userModule.CreateUserBody{
+		FirstName: firstName,
+		LastName:  "Tester",
+		Email:     email,
+		Password:  "correct horse battery staple",
+	})
+	if appErr != nil {
+		t.Fatalf("creating user %s: %s", email, appErr.Message)
+	}
+	if err := env.userRepository.Update(map[string]interface{}{"id": user.ID}, map[string]interface{}{"is_email_verified": true}); err != nil {
+		t.Fatalf("verifying the email of %s: %v", email, err)
+	}
+	user.IsEmailVerified = true
+	return user
+}
+
+// createTestOrganization creates an organization owned by the user, and returns it with the owner's membership.
+func createTestOrganization(t *testing.T, env *testEnvironment, owner *userRepository.User) (*repository.Organization, *repository.Membership) {
+	t.Helper()
+	ctx := context.Background()
+	created, appErr := env.organizationService.CreateOrganization(ctx, owner.UserId.String(), organizationModule.CreateOrganizationBody{Name: "Analytical Engines"})
+	if appErr != nil {
+		t.Fatalf("CreateOrganization: %s", appErr.Message)
+	}
+	return authorize(t, env, created.Organization, owner, repository.RoleOwner)
+}
+
+// authorize returns the organization and the membership of the user, who must have at least the given role.
+func authorize(t *testing.T, env *testEnvironment, organization *repository.Organization, user *userRepository.User, role string) (*repository.Organization, *repository.Membership) {
+	t.Helper()
+	organization, membership, appErr := env.organizationService.Authorize(context.Background(), organization.OrganizationId.String(), user.UserId.String(), role)
+	if appErr != nil {
+		t.Fatalf("authorizing %s as %s: %s", *user.Email, role, appErr.Message)
+	}
+	return organization, membership
+}
+
+// addMember invites the user to the organization with the role and accepts the invitation on their behalf.
+func addMember(t *testing.T, env *testEnvironment, organization *repository.Organization, owner *userRepository.User, user *userRepository.User, role string) *repository.Membership {
+	t.Helper()
+	ctx := context.Background()
+	_, ownerMembership := authorize(t, env, organization, owner, repository.RoleOwner)
+	_, appErr := env.organizationService.InviteMember(ctx, owner.UserId.String(), organization, ownerMembership, organizationModule.InviteMemberBody{Email: user.Email, Role: role})
+	if appErr != nil {
+		t.Fatalf("inviting %s: %s", *user.Email, appErr.Message)
+	}
+	if _, appErr := env.organizationService.AcceptInvitation(ctx, user.UserId.String(), env.notifier.sentToken(t, *user.Email)); appErr != nil {
+		t.Fatalf("accepting the invitation of %s: %s", *user.Email, appErr.Message)
+	}
+	_, membership := authorize(t, env, organization, user, role)
+	return membership
+}
+
+func TestCreateOrganization(t *testing.T) {
+	env := newTestEnvironment(t)
+	ctx := context.Background()
+	ada := createTestUser(t, env, "Ada", "ada@example.com")
+	grace := createTestUser(t, env, "Grace", "grace@example.com")
+	organization, membership := createTestOrganization(t, env, ada)
+
+	if membership.Role != repository.RoleOwner {
+		t.Errorf("got role %s, want owner", membership.Role)
+	}
+	organizations, appErr := env.organizationService.ListMyOrganizations(ctx, ada.UserId.String())
+	if appErr != nil {
+		t.Fatalf("ListMyOrganizations: %s", appErr.Message)
+	}
+	if len(organizations) != 1 || organizations[0].OrganizationId != organization.OrganizationId || organizations[0].Role != repository.RoleOwner {
+		t.Errorf("got organizations %+v, want only the created one, as owner", organizations)
+	}
+
+	// Organizations are hidden from non-members
+	_, _, appErr = env.organizationService.Authorize(ctx, organization.OrganizationId.String(), grace.UserId.String(), repository.RoleMember)
+	if appErr == nil || appErr.ErrorCode != "organization_not_found" {
+		t.Errorf("authorizing a non-member: got %v, want organization_not_found", appErr)
+	}
+	_, _, appErr = env.organizationService.Authorize(ctx, "not-an-id", ada.UserId.String(), repository.RoleMember)
+	if appErr == nil || appErr.ErrorCode != "invalid_id" {
+		t.Errorf("authorizing with an invalid ID: got %v, want invalid_id", appErr)
+	}
+}
+
+func TestUpdateOrganization(t *testing.T) {
+	env := newTestEnvironment(t)
+	ctx := context.Background()
+	ada := createTestUser(t, env, "Ada", "ada@example.com")
+	organization, membership := createTestOrganization(t, env, ada)
+	version := organization.Version
+
+	name := "Difference Engines"
+	updated, appErr := env.organizationService.UpdateOrganization(ctx, ada.UserId.String(), organization, membership, organizationModule.UpdateOrganizationBody{Name: &name}, &version)
+	if appErr != nil {
+		t.Fatalf("UpdateOrganization: %s", appErr.Message)
+	}
+	if updated.Name != name || updated.Version != version+1 {
+		t.Errorf("got %s at version %d, want %s at version %d", updated.Name, updated.Version, name, version+1)
+	}
+
+	// The client still holds the version it read before the update
+	stale, _ := authorize(t, env, organization, ada, repository.RoleOwner)
+	_, appErr = env.organizationService.UpdateOrganization(ctx, ada.UserId.String(), stale, membership, organizationModule.UpdateOrganizationBody{Name: &name}, &version)
+	if appErr == nil || appErr.ErrorCode != "version_conflict" {
+		t.Errorf("updating a stale version: got %v, want version_conflict", appErr)
+	}
+	_, appErr = env.organizationService.UpdateOrganization(ctx, ada.UserId.String(), stale, membership, organizationModule.UpdateOrganizationBody{}, nil)
+	if appErr == nil || appErr.ErrorCode != "missing_data" {
+		t.Errorf("updating nothing: got %v, want missing_data", appErr)
+	}
+}
+
+func TestMemberRoles(t *testing.T) {
+	env := newTestEnvironment(t)
+	ctx := context.Background()
+	ada := createTestUser(t, env, "Ada", "ada@example.com")
+	grace := createTestUser(t, env, "Grace", "grace@example.com")
+	alan := createTestUser(t, env, "Alan", "alan@example.com")
+	organization, owner := createTestOrganization(t, env, ada)
+	admin := addMember(t, env, organization, ada, grace, repository.RoleAdmin)
+	member := addMember(t, env, organization, ada, alan, repository.RoleMember)
+
+	members, appErr := env.organizationService.ListMembers(ctx, organization)
+	if appErr != nil {
+		t.Fatalf("ListMembers: %s", appErr.Message)
+	}
+	if len(members) != 3 || members[0].UserId != ada.UserId || members[2].Role != repository.RoleMember {
+		t.Errorf("got members %+v, want ada, grace and alan, oldest first", members)
+	}
+
+	tests := []struct {
+		name  string
+		actor *repository.Membership
+		user  *userRepository.User
+		role  string
+		want  string
+	}{
+		{"a member managing members", member, alan, repository.RoleAdmin, "forbidden"},
+		{"an admin granting the owner role", admin, alan, repository.RoleOwner, "forbidden"},
+		{"an admin demoting the owner", admin, ada, repository.RoleAdmin, "forbidden"},
+		{"the last owner demoting themselves", owner, ada, repository.RoleAdmin, "last_owner"},
+	}
+	for _, test := range tests {
+		_, appErr := env.organizationService.UpdateMemberRole(ctx, "", organization, test.actor, test.user.UserId.String(), organizationModule.UpdateMemberBody{Role: test.role})
+		if appErr == nil || appErr.ErrorCode != test.want {
+			t.Errorf("%s: got %v, want %s", test.name, appErr, test.want)
+		}
+	}
+
+	promoted, appErr := env.organizationService.UpdateMemberRole(ctx, grace.UserId.String(), organization, admin, alan.UserId.String(), organizationModule.UpdateMemberBody{Role: repository.RoleAdmin})
+	if appErr != nil || promoted.Role != repository.RoleAdmin {
+		t.Fatalf("promoting alan: got %+v, %v, want admin", promoted, appErr)
+	}
+	if appErr := env.organizationService.RemoveMember(ctx, ada.UserId.String(), organization, owner, ada.UserId.String()); appErr == nil || appErr.ErrorCode != "last_owner" {
+		t.Errorf("the last owner leaving: got %v, want last_owner", appErr)
+	}
+
+	// Members can always leave, even without the role to remove others
+	_, alanMembership := authorize(t, env, organization, alan, repository.RoleMember)
+	if appErr := env.organizationService.RemoveMember(ctx, alan.UserId.String(), organization, alanMembership, alan.UserId.String()); appErr != nil {
+		t.Fatalf("alan leaving: %s", appErr.Message)
+	}
+	_, _, appErr = env.organizationService.Authorize(ctx, organization.OrganizationId.String(), alan.UserId.String(), repository.RoleMember)
+	if appErr == nil || appErr.ErrorCode != "organization_not_found" {
+		t.Errorf("authorizing a former member: got %v, want organization_not_found", appErr)
+	}
+}
+
+func TestDeleteOrganization(t *testing.T) {
+	env := newTestEnvironment(t)
+	ctx := context.Background()
+	ada := createTestUser(t, env, "Ada", "ada@example.com")
+	organization, owner := createTestOrganization(t, env, ada)
+	_, appErr := env.organizationService.InviteMember(ctx, ada.UserId.String(), organization, owner, organizationModule.InviteMemberBody{Email: stringPointer("grace@example.com"), Role: repository.RoleMember})
+	if appErr != nil {
+		t.Fatalf("InviteMember: %s", appErr.Message)
+	}
+	token := env.notifier.sentToken(t, "grace@example.com")
+
+	if appErr := env.organizationService.DeleteOrganization(ctx, ada.UserId.String(), organization); appErr != nil {
+		t.Fatalf("DeleteOrganization: %s", appErr.Message)
+	}
+	_, _, appErr = env.organizationService.Authorize(ctx, organization.OrganizationId.String(), ada.UserId.String(), repository.RoleMember)
+	if appErr == nil || appErr.ErrorCode != "organization_not_found" {
+		t.Errorf("authorizing in a deleted organization: got %v, want organization_not_found", appErr)
+	}
+	if _, appErr := env.organizationService.GetInvitation(ctx, token); appErr == nil || appErr.ErrorCode != "invitation_not_found" {
+		t.Errorf("getting an invitation to a deleted organization: got %v, want invitation_not_found", appErr)
+	}
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
@@ -0,0 +1,57 @@
+package organizationModule
+
+import (
+	"backendService/internals/common/auth"
+	"backendService/internals/common/router"
+	"backendService/internals/modules/organizationModule/organizationController"
+	repository "backendService/internals/modules/organizationModule/organizationRepository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationRouter struct {
+	organizationController *organizationController.OrganizationController
+	sessionStore           *auth.SessionStore
+}
+
+func (or *OrganizationRouter) SetupRoutes(app *gin.Engine) {
+
+	router := router.NewBaseRouter("OrganizationRouter", app)
+
+	organizationRouter := router.Group("api/v1/organizations")
+	{
+		authenticate := auth.Authenticate(or.sessionStore)
+		organizationRouter.POST("/", authenticate, or.organizationController.CreateOrganization)
+		organizationRouter.GET("/", authenticate, or.organizationController.GetMyOrganizations)
+
+		// Invitations, looked up by the token sent to the invitee
+		organizationRouter.GET("/invitations/:token", or.organizationController.GetInvitation)
+		organizationRouter.POST("/invitations/accept", authenticate, or.organizationController.AcceptInvitation)
+
+		// Members of the organization
+		requireMember := or.organizationController.RequireOrganizationRole(repository.RoleMember)
+		organizationRouter.GET("/:orgId", authenticate, requireMember, or.organizationController.GetOrganization)
+		organizationRouter.GET("/:orgId/members", authenticate, requireMember, or.organizationController.GetMembers)
+		organizationRouter.DELETE("/:orgId/members/:userId", authenticate, requireMember, or.organizationController.RemoveMember)
+
+		// Admins of the organization
+		requireAdmin := or.organizationController.RequireOrganizationRole(repository.RoleAdmin)
+		organizationRouter.PATCH("/:orgId", authenticate, requireAdmin, or.organizationController.UpdateOrganization)
+		organizationRouter.PATCH("/:orgId/members/:userId", authenticate, requireAdmin, or.organizationController.UpdateMember)
+		organizationRouter.POST("/:orgId/invitations", authenticate, requireAdmin, or.organizationController.InviteMember)
+		organizationRouter.GET("/:orgId/invitations", authenticate, requireAdmin, or.organizationController.GetInvitations)
+		organizationRouter.DELETE("/:orgId/invitations/:invitationId", authenticate, requireAdmin, or.organizationController.RevokeInvitation)
+
+		// Owners of the organization
+		requireOwner := or.organizationController.RequireOrganizationRole(repository.RoleOwner)
+		organizationRouter.DELETE("/:orgId", authenticate, requireOwner, or.organizationController.DeleteOrganization)
+
+	}
+}
+
+func NewOrganizationRouter(organizationController *organizationController.OrganizationController, sessionStore *auth.SessionStore) *OrganizationRouter {
+	return &OrganizationRouter{
+		organizationController: organizationController,
+		sessionStore:           sessionStore,
+	}
+}
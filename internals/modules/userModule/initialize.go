@@ -1,6 +1,15 @@
 package userModule
 
 import (
+	"backendService/internals/common/audit"
+	"backendService/internals/common/auth"
+	"backendService/internals/common/cache"
+	"backendService/internals/common/history"
+	"backendService/internals/common/jobs"
+	"backendService/internals/common/notification"
+	"backendService/internals/common/privacy"
+	baseRepository "backendService/internals/common/repository"
+	"backendService/internals/common/storage"
 	userModule "backendService/internals/modules/userModule/routes"
 	"backendService/internals/modules/userModule/userController"
 	repository "backendService/internals/modules/userModule/userRepository"
@@ -11,16 +20,40 @@ import (
 var (
 	UserRouter  *userModule.UserRouter
 	UserService *userService.UserService
+	// UserSettingsService lets other modules read user preferences
+	UserSettingsService *userService.UserSettingsService
+	// UserMergeService lets other modules move their records when duplicate accounts are merged
+	UserMergeService *userService.UserMergeService
 )
 
 func Initialize() {
 
-	userRepository := repository.NewUserRepository(server.Server.Db)
-	userService := userService.NewUserService(userRepository)
-	userController := userController.NewUserController(userService)
-	userRouter := userModule.NewUserRouter(userController)
+	sessionStore := auth.NewSessionStore(&cache.Cache)
+	userRepository := repository.NewUserRepository(server.Server.Db, &cache.Cache)
+	auditService := audit.NewAuditService(server.Server.Db)
+	historyService := history.NewHistoryService(server.Server.Db)
+	jobStore := jobs.NewJobStore(&cache.Cache)
+	userSettingsRepository := repository.NewUserSettingsRepository(server.Server.Db)
+	userSettingsService := userService.NewUserSettingsService(userRepository, userSettingsRepository, &cache.Cache, auditService)
+	contactChangeService := userService.NewContactChangeService(userRepository, notification.NewLogNotifier(), auditService)
+	userMergeService := userService.NewUserMergeService(userRepository, userSettingsRepository, sessionStore, &cache.Cache, notification.NewLogNotifier(), auditService)
+	erasureInterval := userService.ErasureInterval()
+	userService := userService.NewUserService(userRepository, sessionStore, auditService, historyService, jobStore, storage.Store, privacy.DefaultRegistry, baseRepository.NewUnitOfWork(server.Server.Db))
+	userController := userController.NewUserController(userService, userSettingsService, contactChangeService, userMergeService)
+	userRouter := userModule.NewUserRouter(userController, sessionStore)
+
+	// Personal data held by the user module, for data exports and erasures
+	userService.RegisterPersonalData(privacy.DefaultRegistry)
+	userSettingsService.RegisterPersonalData(privacy.DefaultRegistry)
+	contactChangeService.RegisterPersonalData(privacy.DefaultRegistry)
+	userMergeService.RegisterPersonalData(privacy.DefaultRegistry)
+	auditService.RegisterPersonalData(privacy.DefaultRegistry)
+	historyService.RegisterPersonalData(privacy.DefaultRegistry)
+	jobs.Schedule("user_erasure", erasureInterval, userService.EraseDueUsers)
 
 	// Export
 	UserService = userService
+	UserSettingsService = userSettingsService
+	UserMergeService = userMergeService
 	UserRouter = userRouter
 }
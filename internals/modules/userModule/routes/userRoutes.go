@@ -1,14 +1,17 @@
 package userModule
 
 import (
+	"backendService/internals/common/auth"
 	"backendService/internals/common/router"
 	"backendService/internals/modules/userModule/userController"
+	repository "backendService/internals/modules/userModule/userRepository"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserRouter struct {
 	userController *userController.UserController
+	sessionStore   *auth.SessionStore
 }
 
 func (ur *UserRouter) SetupRoutes(app *gin.Engine) {
@@ -17,16 +20,57 @@ func (ur *UserRouter) SetupRoutes(app *gin.Engine) {
 
 	userRouter := router.Group("api/v1/user")
 	{
+		authenticate := auth.Authenticate(ur.sessionStore)
+		requireAdmin := auth.RequireRole(repository.RoleAdmin)
+		requireStaff := auth.RequireRole(repository.RoleAdmin, repository.RoleSupport)
 
-		userRouter.GET("/:id", ur.userController.GetUser)
-		userRouter.GET("/", ur.userController.GetAllUsers)
-		userRouter.POST("/", ur.userController.CreateUser)
+		userRouter.GET("/me", authenticate, ur.userController.GetMe)
+		userRouter.PATCH("/me", authenticate, ur.userController.UpdateMe)
+		userRouter.DELETE("/me", authenticate, ur.userController.DeleteMe)
+		userRouter.POST("/me/contact-change", authenticate, ur.userController.RequestContactChange)
+		userRouter.POST("/me/contact-change/verify", authenticate, ur.userController.VerifyContactChange)
+		userRouter.DELETE("/me/contact-change/:kind", authenticate, ur.userController.CancelContactChange)
+		userRouter.POST("/me/merge", authenticate, ur.userController.RequestMerge)
+		userRouter.POST("/me/merge/verify", authenticate, ur.userController.VerifyMerge)
+		userRouter.DELETE("/me/merge", authenticate, ur.userController.CancelMerge)
+		userRouter.GET("/me/settings", authenticate, ur.userController.GetMySettings)
+		userRouter.PATCH("/me/settings", authenticate, ur.userController.UpdateMySettings)
+		userRouter.PUT("/me/avatar", authenticate, ur.userController.UploadMyAvatar)
+		userRouter.DELETE("/me/avatar", authenticate, ur.userController.DeleteMyAvatar)
+		userRouter.POST("/me/data-export", authenticate, ur.userController.RequestDataExport)
+		userRouter.GET("/me/data-export/:jobId", authenticate, ur.userController.GetDataExportJob)
+		userRouter.GET("/me/data-export/:jobId/download", authenticate, ur.userController.DownloadDataExport)
+		userRouter.POST("/me/erasure", authenticate, ur.userController.RequestErasure)
+		userRouter.DELETE("/me/erasure", authenticate, ur.userController.CancelErasure)
+
+		userRouter.GET("/:id", authenticate, requireStaff, ur.userController.GetUser)
+		userRouter.GET("/", authenticate, requireStaff, ur.userController.GetAllUsers)
+		userRouter.POST("/", authenticate, requireAdmin, ur.userController.CreateUser)
+		userRouter.PATCH("/:id", authenticate, requireAdmin, ur.userController.UpdateUser)
+		userRouter.DELETE("/:id", authenticate, requireAdmin, ur.userController.DeleteUser)
+
+		// Admin only
+		userRouter.POST("/import", authenticate, requireAdmin, ur.userController.ImportUsers)
+		userRouter.GET("/export", authenticate, requireAdmin, ur.userController.ExportUsers)
+		userRouter.GET("/export/jobs/:jobId", authenticate, requireAdmin, ur.userController.GetExportJob)
+		userRouter.GET("/export/jobs/:jobId/download", authenticate, requireAdmin, ur.userController.DownloadExport)
+		userRouter.POST("/:id/deactivate", authenticate, requireAdmin, ur.userController.DeactivateUser)
+		userRouter.POST("/:id/activate", authenticate, requireAdmin, ur.userController.ActivateUser)
+		userRouter.POST("/:id/restore", authenticate, requireAdmin, ur.userController.RestoreUser)
+		userRouter.DELETE("/:id/purge", authenticate, requireAdmin, ur.userController.PurgeUser)
+		userRouter.POST("/:id/merge", authenticate, requireAdmin, ur.userController.MergeUser)
+
+		// Staff only
+		userRouter.GET("/search", authenticate, requireStaff, ur.userController.SearchUsers)
+		userRouter.POST("/:id/impersonate", authenticate, requireStaff, ur.userController.ImpersonateUser)
+		userRouter.GET("/:id/history", authenticate, requireStaff, ur.userController.GetUserHistory)
 
 	}
 }
 
-func NewUserRouter(userController *userController.UserController) *UserRouter {
+func NewUserRouter(userController *userController.UserController, sessionStore *auth.SessionStore) *UserRouter {
 	return &UserRouter{
 		userController: userController,
+		sessionStore:   sessionStore,
 	}
 }
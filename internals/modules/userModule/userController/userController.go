@@ -1,8 +1,14 @@
 package userController
 
 import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 
+	"backendService/internals/common/auth"
 	controllers "backendService/internals/common/controller"
 	"backendService/internals/common/errors"
 	"backendService/internals/common/logger"
@@ -13,28 +19,30 @@ import (
 
 type UserController struct {
 	controllers.BaseController
-	userService *userService.UserService
+	userService          *userService.UserService
+	userSettingsService  *userService.UserSettingsService
+	contactChangeService *userService.ContactChangeService
+	userMergeService     *userService.UserMergeService
 }
 
-// NewUserController creates a new instance of User_Controller with a provided userService
-// dependency.
-func NewUserController(userService *userService.UserService) *UserController {
-	return &UserController{userService: userService}
+// NewUserController creates a new instance of User_Controller with provided userService,
+// userSettingsService, contactChangeService and userMergeService dependencies.
+func NewUserController(userService *userService.UserService, userSettingsService *userService.UserSettingsService, contactChangeService *userService.ContactChangeService, userMergeService *userService.UserMergeService) *UserController {
+	return &UserController{userService: userService, userSettingsService: userSettingsService, contactChangeService: contactChangeService, userMergeService: userMergeService}
 }
 
 // GetUser retrieves a user from the database.
 func (uc *UserController) GetUser(c *gin.Context) (router.Response, *errors.ApplicationError) {
 	id := c.Param("id")
-	user, err := uc.userService.GetUserByID(id)
+	user, err := uc.userService.GetUserByID(c.Request.Context(), id)
 	if err != nil {
 		logger.Error("controller", "user_controller", "GetUser", err.Message)
 		return router.Response{}, err
 	}
-	message := "User retrieved successfully"
 	if user == nil {
-		message = "User not found"
+		return router.Response{Data: user, Message: "User not found"}, nil
 	}
-	return router.Response{Data: user, Message: message}, nil
+	return router.Response{Data: user, Message: "User retrieved successfully", ETag: router.VersionETag(user.Version)}, nil
 }
 
 // CreateUser handles the creation of a user. It reads the request body, parses it into a CreateUserData struct,
@@ -50,7 +58,7 @@ func (uc *UserController) CreateUser(c *gin.Context) (router.Response, *errors.A
 		return router.Response{}, err
 	}
 
-	user, err := uc.userService.CreateUser(createData)
+	user, err := uc.userService.CreateUser(c.Request.Context(), createData)
 	if err != nil {
 		logger.Error("controller", "user_controller", "CreateUser", err.Message)
 		return router.Response{}, err
@@ -59,11 +67,583 @@ func (uc *UserController) CreateUser(c *gin.Context) (router.Response, *errors.A
 	return router.Response{Data: user, Message: "User created successfully"}, nil
 }
 
-// GetAllUsers retrieves all users from the database.
+// GetAllUsers retrieves a page of users, applying the filters and sorting from the query string.
+// Pages are addressed either by number or by cursor, depending on the query.
 func (uc *UserController) GetAllUsers(c *gin.Context) (router.Response, *errors.ApplicationError) {
-	users, err := uc.userService.GetUsers()
+	var query userModule.ListUsersQuery
+	_, err := uc.TransformAndValidateQuery(c, &query)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	if query.UsesCursor() {
+		page, err := uc.userService.GetUsersByCursor(c.Request.Context(), query)
+		if err != nil {
+			return router.Response{}, err
+		}
+		meta := router.CursorPagination{
+			PageSize:   page.Limit,
+			NextCursor: page.NextCursor,
+			PrevCursor: page.PrevCursor,
+		}
+		return router.Response{Data: page.Items, Message: "Users retrieved successfully", Meta: meta}, nil
+	}
+
+	page, err := uc.userService.GetUsers(c.Request.Context(), query)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	meta := router.Pagination{
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		Total:      page.Total,
+		TotalPages: page.TotalPages,
+	}
+	return router.Response{Data: page.Items, Message: "Users retrieved successfully", Meta: meta}, nil
+}
+
+// SearchUsers returns a page of users matching a full-text search, best matches first.
+func (uc *UserController) SearchUsers(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var query userModule.SearchUsersQuery
+	_, err := uc.TransformAndValidateQuery(c, &query)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	page, err := uc.userService.SearchUsers(c.Request.Context(), query)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	meta := router.Pagination{
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		Total:      page.Total,
+		TotalPages: page.TotalPages,
+	}
+	return router.Response{Data: page.Items, Message: "Users retrieved successfully", Meta: meta}, nil
+}
+
+// UpdateUser partially updates a user. Only the fields present in the request body are changed.
+// An If-Match header holding the ETag of a previous response makes the update fail with a conflict
+// when the user changed since.
+func (uc *UserController) UpdateUser(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var updateData userModule.UpdateUserBody
+	_, err := uc.TransformAndValidate(c, &updateData)
+	if err != nil {
+		return router.Response{}, err
+	}
+	version, err := router.IfMatchVersion(c)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	user, err := uc.userService.UpdateUser(c.Request.Context(), c.Param("id"), updateData, version)
+	if err != nil {
+		logger.Error("controller", "user_controller", "UpdateUser", err.Message)
+		return router.Response{}, err
+	}
+
+	return router.Response{Data: user, Message: "User updated successfully", ETag: router.VersionETag(user.Version)}, nil
+}
+
+// DeleteUser soft-deletes a user and responds with 204 No Content.
+func (uc *UserController) DeleteUser(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	err := uc.userService.DeleteUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		logger.Error("controller", "user_controller", "DeleteUser", err.Message)
+		return router.Response{}, err
+	}
+
+	return router.Response{StatusCode: http.StatusNoContent}, nil
+}
+
+// GetMe retrieves the profile of the authenticated user.
+func (uc *UserController) GetMe(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	user, err := uc.userService.GetUserByID(c.Request.Context(), auth.CurrentSession(c).UserId)
+	if err != nil {
+		logger.Error("controller", "user_controller", "GetMe", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: user, Message: "User retrieved successfully", ETag: router.VersionETag(user.Version)}, nil
+}
+
+// UpdateMe partially updates the profile of the authenticated user.
+// The request body is the same as for UpdateUser; the email and mobile are changed through /me/contact-change.
+// Like UpdateUser, it honours an If-Match header.
+func (uc *UserController) UpdateMe(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var updateData userModule.UpdateUserBody
+	_, err := uc.TransformAndValidate(c, &updateData)
+	if err != nil {
+		return router.Response{}, err
+	}
+	version, err := router.IfMatchVersion(c)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	user, err := uc.userService.UpdateUser(c.Request.Context(), auth.CurrentSession(c).UserId, updateData, version)
+	if err != nil {
+		logger.Error("controller", "user_controller", "UpdateMe", err.Message)
+		return router.Response{}, err
+	}
+
+	return router.Response{Data: user, Message: "User updated successfully", ETag: router.VersionETag(user.Version)}, nil
+}
+
+// DeleteMe soft-deletes the authenticated user and responds with 204 No Content.
+// The user must have signed in recently to delete their own account.
+func (uc *UserController) DeleteMe(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	session := auth.CurrentSession(c)
+	if err := auth.RequireRecentAuthentication(session); err != nil {
+		return router.Response{}, err
+	}
+
+	err := uc.userService.DeleteUser(c.Request.Context(), session.UserId)
+	if err != nil {
+		logger.Error("controller", "user_controller", "DeleteMe", err.Message)
+		return router.Response{}, err
+	}
+
+	return router.Response{StatusCode: http.StatusNoContent}, nil
+}
+
+// RequestContactChange starts changing the email or mobile of the authenticated user by sending
+// a verification code to the new contact. The user must have signed in recently.
+func (uc *UserController) RequestContactChange(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	session := auth.CurrentSession(c)
+	if err := auth.RequireRecentAuthentication(session); err != nil {
+		return router.Response{}, err
+	}
+
+	var body userModule.ContactChangeBody
+	_, err := uc.TransformAndValidate(c, &body)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	pending, err := uc.contactChangeService.RequestChange(c.Request.Context(), session.UserId, body)
+	if err != nil {
+		logger.Error("controller", "user_controller", "RequestContactChange", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: pending, Message: "Verification code sent successfully", StatusCode: http.StatusAccepted}, nil
+}
+
+// VerifyContactChange completes a contact change of the authenticated user with the code sent to the new contact.
+func (uc *UserController) VerifyContactChange(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var body userModule.VerifyContactChangeBody
+	_, err := uc.TransformAndValidate(c, &body)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	user, err := uc.contactChangeService.VerifyChange(c.Request.Context(), auth.CurrentSession(c).UserId, body)
+	if err != nil {
+		logger.Error("controller", "user_controller", "VerifyContactChange", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: user, Message: "Contact changed successfully"}, nil
+}
+
+// CancelContactChange drops a pending contact change of the authenticated user and responds with 204 No Content.
+func (uc *UserController) CancelContactChange(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	err := uc.contactChangeService.CancelChange(c.Request.Context(), auth.CurrentSession(c).UserId, c.Param("kind"))
+	if err != nil {
+		return router.Response{}, err
+	}
+	return router.Response{StatusCode: http.StatusNoContent}, nil
+}
+
+// RequestMerge starts merging another account of the authenticated user, identified by its email or mobile,
+// into their account by sending a verification code to that email or mobile. The user must have signed in recently.
+func (uc *UserController) RequestMerge(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	session := auth.CurrentSession(c)
+	if err := auth.RequireRecentAuthentication(session); err != nil {
+		return router.Response{}, err
+	}
+
+	var body userModule.MergeAccountBody
+	_, err := uc.TransformAndValidate(c, &body)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	pending, err := uc.userMergeService.RequestMerge(c.Request.Context(), session.UserId, body)
+	if err != nil {
+		logger.Error("controller", "user_controller", "RequestMerge", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: pending, Message: "Verification code sent successfully", StatusCode: http.StatusAccepted}, nil
+}
+
+// VerifyMerge completes an account merge of the authenticated user with the code sent to the other account.
+func (uc *UserController) VerifyMerge(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var body userModule.VerifyMergeBody
+	_, err := uc.TransformAndValidate(c, &body)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	user, err := uc.userMergeService.VerifyMerge(c.Request.Context(), auth.CurrentSession(c).UserId, body)
+	if err != nil {
+		logger.Error("controller", "user_controller", "VerifyMerge", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: user, Message: "Accounts merged successfully"}, nil
+}
+
+// CancelMerge drops the pending account merge of the authenticated user and responds with 204 No Content.
+func (uc *UserController) CancelMerge(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	err := uc.userMergeService.CancelMerge(c.Request.Context(), auth.CurrentSession(c).UserId)
+	if err != nil {
+		return router.Response{}, err
+	}
+	return router.Response{StatusCode: http.StatusNoContent}, nil
+}
+
+// GetMySettings retrieves the settings of the authenticated user.
+func (uc *UserController) GetMySettings(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	settings, err := uc.userSettingsService.GetSettings(c.Request.Context(), auth.CurrentSession(c).UserId)
+	if err != nil {
+		logger.Error("controller", "user_controller", "GetMySettings", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: settings, Message: "Settings retrieved successfully"}, nil
+}
+
+// UpdateMySettings partially updates the settings of the authenticated user.
+func (uc *UserController) UpdateMySettings(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var updateData userModule.UpdateSettingsBody
+	_, err := uc.TransformAndValidate(c, &updateData)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	settings, err := uc.userSettingsService.UpdateSettings(c.Request.Context(), auth.CurrentSession(c).UserId, updateData)
+	if err != nil {
+		logger.Error("controller", "user_controller", "UpdateMySettings", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: settings, Message: "Settings updated successfully"}, nil
+}
+
+// UploadMyAvatar replaces the avatar of the authenticated user.
+// The image is either sent as the raw request body or as the "file" part of a multipart form.
+func (uc *UserController) UploadMyAvatar(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	// Leave room for the multipart envelope, the service enforces the exact limit on the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, userService.MaxAvatarBytes+64<<10)
+	source, _, err := uploadedFile(c)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	user, err := uc.userService.UploadAvatar(c.Request.Context(), auth.CurrentSession(c).UserId, source)
+	if err != nil {
+		logger.Error("controller", "user_controller", "UploadMyAvatar", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: user, Message: "Avatar uploaded successfully"}, nil
+}
+
+// DeleteMyAvatar removes the avatar of the authenticated user.
+func (uc *UserController) DeleteMyAvatar(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	user, err := uc.userService.DeleteAvatar(c.Request.Context(), auth.CurrentSession(c).UserId)
+	if err != nil {
+		logger.Error("controller", "user_controller", "DeleteMyAvatar", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: user, Message: "Avatar removed successfully"}, nil
+}
+
+// RequestDataExport starts collecting the personal data held about the authenticated user into an archive.
+// The user must have signed in recently.
+func (uc *UserController) RequestDataExport(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	session := auth.CurrentSession(c)
+	if err := auth.RequireRecentAuthentication(session); err != nil {
+		return router.Response{}, err
+	}
+
+	job, err := uc.userService.RequestDataExport(c.Request.Context(), session.UserId)
+	if err != nil {
+		logger.Error("controller", "user_controller", "RequestDataExport", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: job, Message: "Data export started successfully", StatusCode: http.StatusAccepted}, nil
+}
+
+// GetDataExportJob returns the status of a data export of the authenticated user.
+func (uc *UserController) GetDataExportJob(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	job, err := uc.userService.GetDataExportJob(c.Request.Context(), auth.CurrentSession(c).UserId, c.Param("jobId"))
+	if err != nil {
+		return router.Response{}, err
+	}
+	return router.Response{Data: job, Message: "Data export retrieved successfully"}, nil
+}
+
+// DownloadDataExport sends the archive produced by a completed data export of the authenticated user.
+func (uc *UserController) DownloadDataExport(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	job, path, err := uc.userService.GetDataExportFile(c.Request.Context(), auth.CurrentSession(c).UserId, c.Param("jobId"))
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	c.Header("Content-Type", job.ContentType)
+	c.FileAttachment(path, job.FileName)
+	return router.Response{}, nil
+}
+
+// RequestErasure schedules the erasure of the personal data of the authenticated user after the grace period.
+// The user must have signed in recently.
+func (uc *UserController) RequestErasure(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	session := auth.CurrentSession(c)
+	if err := auth.RequireRecentAuthentication(session); err != nil {
+		return router.Response{}, err
+	}
+
+	user, err := uc.userService.RequestErasure(c.Request.Context(), session.UserId)
+	if err != nil {
+		logger.Error("controller", "user_controller", "RequestErasure", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: user, Message: "Erasure scheduled successfully", StatusCode: http.StatusAccepted}, nil
+}
+
+// CancelErasure cancels the scheduled erasure of the authenticated user.
+func (uc *UserController) CancelErasure(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	user, err := uc.userService.CancelErasure(c.Request.Context(), auth.CurrentSession(c).UserId)
+	if err != nil {
+		logger.Error("controller", "user_controller", "CancelErasure", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: user, Message: "Erasure cancelled successfully"}, nil
+}
+
+// DeactivateUser deactivates a user and signs them out of every session.
+func (uc *UserController) DeactivateUser(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	user, err := uc.userService.SetUserActive(c.Request.Context(), auth.CurrentSession(c).UserId, c.Param("id"), false)
+	if err != nil {
+		logger.Error("controller", "user_controller", "DeactivateUser", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: user, Message: "User deactivated successfully"}, nil
+}
+
+// ActivateUser reactivates a deactivated user.
+func (uc *UserController) ActivateUser(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	user, err := uc.userService.SetUserActive(c.Request.Context(), auth.CurrentSession(c).UserId, c.Param("id"), true)
+	if err != nil {
+		logger.Error("controller", "user_controller", "ActivateUser", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: user, Message: "User activated successfully"}, nil
+}
+
+// RestoreUser restores a soft-deleted user.
+func (uc *UserController) RestoreUser(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	user, err := uc.userService.RestoreUser(c.Request.Context(), auth.CurrentSession(c).UserId, c.Param("id"))
+	if err != nil {
+		logger.Error("controller", "user_controller", "RestoreUser", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: user, Message: "User restored successfully"}, nil
+}
+
+// PurgeUser permanently removes a user and responds with 204 No Content.
+func (uc *UserController) PurgeUser(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	err := uc.userService.PurgeUser(c.Request.Context(), auth.CurrentSession(c).UserId, c.Param("id"))
+	if err != nil {
+		logger.Error("controller", "user_controller", "PurgeUser", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{StatusCode: http.StatusNoContent}, nil
+}
+
+// GetUserHistory lists the changes made to a user, newest first, with who made them.
+func (uc *UserController) GetUserHistory(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var query userModule.UserHistoryQuery
+	_, err := uc.TransformAndValidateQuery(c, &query)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	page, err := uc.userService.GetUserHistory(c.Request.Context(), c.Param("id"), query)
+	if err != nil {
+		logger.Error("controller", "user_controller", "GetUserHistory", err.Message)
+		return router.Response{}, err
+	}
+
+	meta := router.Pagination{
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		Total:      page.Total,
+		TotalPages: page.TotalPages,
+	}
+	return router.Response{Data: page.Items, Message: "User history retrieved successfully", Meta: meta}, nil
+}
+
+// ImpersonateUser issues an impersonation token that lets the staff member act as the user.
+func (uc *UserController) ImpersonateUser(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var impersonateData userModule.ImpersonateUserBody
+	_, err := uc.TransformAndValidate(c, &impersonateData)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	token, err := uc.userService.ImpersonateUser(c.Request.Context(), auth.CurrentSession(c).UserId, c.Param("id"), impersonateData.Reason)
+	if err != nil {
+		logger.Error("controller", "user_controller", "ImpersonateUser", err.Message)
+		return router.Response{}, err
+	}
+	return router.Response{Data: token, Message: "Impersonation token issued successfully"}, nil
+}
+
+// maxImportBytes is the largest upload accepted by ImportUsers.
+const maxImportBytes = 50 << 20
+
+// ImportUsers creates users in bulk from a CSV or NDJSON upload and reports the rows that were rejected.
+// The file is either sent as the raw request body or as the "file" part of a multipart form,
+// and is streamed rather than loaded into memory. Importing a large file takes longer than the deadline
+// of a request, so the import is not bound by it.
+func (uc *UserController) ImportUsers(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	router.DetachDeadline(c)
+
+	var query userModule.ImportUsersQuery
+	_, err := uc.TransformAndValidateQuery(c, &query)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	source, contentType, err := uploadedFile(c)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	format := query.Format
+	if format == "" {
+		format = importFormat(contentType)
+	}
+
+	report, err := uc.userService.ImportUsers(c.Request.Context(), auth.CurrentSession(c).UserId, source, format, query.DryRun)
+	if err != nil {
+		logger.Error("controller", "user_controller", "ImportUsers", err.Message)
+		return router.Response{}, err
+	}
+
+	message := "Users imported successfully"
+	if query.DryRun {
+		message = "Import validated successfully"
+	}
+	return router.Response{Data: report, Message: message}, nil
+}
+
+// ExportUsers exports the users matching the listing filters as CSV, NDJSON or XLSX.
+// Small exports are streamed in the response as they are read from the database. Large exports
+// are handed to a background job and answered with 202 and the job, to be downloaded once completed.
+func (uc *UserController) ExportUsers(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var query userModule.ExportUsersQuery
+	_, err := uc.TransformAndValidateQuery(c, &query)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	userExport, err := uc.userService.ExportUsers(c.Request.Context(), auth.CurrentSession(c).UserId, query)
+	if err != nil {
+		logger.Error("controller", "user_controller", "ExportUsers", err.Message)
+		return router.Response{}, err
+	}
+	if userExport.Job != nil {
+		return router.Response{Data: userExport.Job, Message: "Export started successfully", StatusCode: http.StatusAccepted}, nil
+	}
+
+	c.Header("Content-Type", userExport.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": userExport.FileName}))
+	if err := userExport.Write(c.Writer); err != nil {
+		// Nothing was sent yet, so the error can still be answered as JSON
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		return router.Response{}, err
+	}
+	return router.Response{}, nil
+}
+
+// GetExportJob returns the status of a background export started by the current user.
+func (uc *UserController) GetExportJob(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	job, err := uc.userService.GetExportJob(c.Request.Context(), auth.CurrentSession(c).UserId, c.Param("jobId"))
+	if err != nil {
+		return router.Response{}, err
+	}
+	return router.Response{Data: job, Message: "Export retrieved successfully"}, nil
+}
+
+// DownloadExport sends the file produced by a completed background export of the current user.
+func (uc *UserController) DownloadExport(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	job, path, err := uc.userService.GetExportFile(c.Request.Context(), auth.CurrentSession(c).UserId, c.Param("jobId"))
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	c.Header("Content-Type", job.ContentType)
+	c.FileAttachment(path, job.FileName)
+	return router.Response{}, nil
+}
+
+// uploadedFile returns a stream over the uploaded file and its content type.
+// For multipart requests the "file" part is used, otherwise the request body itself.
+func uploadedFile(c *gin.Context) (io.Reader, string, *errors.ApplicationError) {
+	contentType := c.ContentType()
+	if contentType != "multipart/form-data" {
+		return c.Request.Body, contentType, nil
+	}
+
+	multipartReader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, "", errors.NewBadRequestError("invalid_file", "invalid multipart body")
+	}
+	for {
+		part, err := multipartReader.NextPart()
+		if err != nil {
+			return nil, "", errors.NewBadRequestError("missing_file", "file is required")
+		}
+		if part.FormName() == "file" {
+			partContentType := part.Header.Get("Content-Type")
+			if strings.HasSuffix(strings.ToLower(part.FileName()), ".ndjson") || strings.HasSuffix(strings.ToLower(part.FileName()), ".jsonl") {
+				partContentType = "application/x-ndjson"
+			} else if strings.HasSuffix(strings.ToLower(part.FileName()), ".csv") {
+				partContentType = "text/csv"
+			}
+			return part, partContentType, nil
+		}
+	}
+}
+
+// importFormat maps the content type of an upload to an import format.
+func importFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return userService.ImportFormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return userService.ImportFormatNDJSON
+	default:
+		return ""
+	}
+}
+
+// MergeUser merges a duplicate account into the user identified by the id path parameter on behalf of an admin.
+func (uc *UserController) MergeUser(c *gin.Context) (router.Response, *errors.ApplicationError) {
+	var body userModule.AdminMergeBody
+	_, err := uc.TransformAndValidate(c, &body)
+	if err != nil {
+		return router.Response{}, err
+	}
+
+	user, err := uc.userMergeService.MergeUsers(c.Request.Context(), auth.CurrentSession(c).UserId, c.Param("id"), body)
 	if err != nil {
+		logger.Error("controller", "user_controller", "MergeUser", err.Message)
 		return router.Response{}, err
 	}
-	return router.Response{Data: users, Message: "Users retrieved successfully"}, nil
+	return router.Response{Data: user, Message: "Accounts merged successfully"}, nil
 }
@@ -0,0 +1,15 @@
+package userModule
+
+// ContactChangeBody represents the request body for starting a change of email or mobile.
+// Exactly one of email and mobile must be set.
+type ContactChangeBody struct {
+	Email  *string `json:"email,omitempty" validate:"omitempty,email,max=100"` // Email should be a valid email address if present
+	Mobile *string `json:"mobile,omitempty" validate:"omitempty,len=10"`       // Mobile should be 10 characters long if present
+}
+
+// VerifyContactChangeBody represents the request body for confirming a change of email or mobile
+// with the code sent to the new contact.
+type VerifyContactChangeBody struct {
+	Kind string `json:"kind" validate:"required,oneof=email mobile"`
+	OTP  string `json:"otp" validate:"required,len=6,numeric"`
+}
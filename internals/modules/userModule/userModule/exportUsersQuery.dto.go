@@ -0,0 +1,12 @@
+package userModule
+
+// ExportUsersQuery represents the query parameters accepted when exporting users.
+// It takes the same filters as listing users. Sensitive columns are redacted unless includePii is set,
+// and async forces the export to run as a background job whatever its size.
+type ExportUsersQuery struct {
+	Format     string `form:"format" validate:"omitempty,oneof=csv ndjson xlsx"`
+	IncludePII bool   `form:"includePii"`
+	Async      bool   `form:"async"`
+
+	UserFilters
+}
@@ -0,0 +1,7 @@
+package userModule
+
+// ImpersonateUserBody represents the request body for impersonating a user.
+// The reason is required and is kept in the audit log.
+type ImpersonateUserBody struct {
+	Reason string `json:"reason" validate:"required,min=5,max=500"`
+}
@@ -0,0 +1,8 @@
+package userModule
+
+// ImportUsersQuery represents the query parameters accepted when importing users.
+// When format is omitted it is derived from the content type of the upload.
+type ImportUsersQuery struct {
+	Format string `form:"format" validate:"omitempty,oneof=csv ndjson"`
+	DryRun bool   `form:"dryRun"`
+}
@@ -0,0 +1,20 @@
+package userModule
+
+// ListUsersQuery represents the query parameters accepted when listing users.
+// Sort is a comma separated list of fields with an optional direction, e.g. "createdAt:desc,firstName".
+// Pages are addressed by number by default. Setting pagination to "cursor", or passing a cursor,
+// switches to keyset pagination where pageSize is the page limit and sort accepts a single field.
+type ListUsersQuery struct {
+	Page       int    `form:"page" validate:"omitempty,min=1"`
+	PageSize   int    `form:"pageSize" validate:"omitempty,min=1,max=100"`
+	Sort       string `form:"sort" validate:"omitempty,max=200"`
+	Pagination string `form:"pagination" validate:"omitempty,oneof=offset cursor"`
+	Cursor     string `form:"cursor" validate:"omitempty,max=1000"`
+
+	UserFilters
+}
+
+// UsesCursor reports whether the query asks for keyset pagination.
+func (q ListUsersQuery) UsesCursor() bool {
+	return q.Pagination == "cursor" || q.Cursor != ""
+}
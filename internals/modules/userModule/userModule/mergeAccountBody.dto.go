@@ -0,0 +1,21 @@
+package userModule
+
+// MergeAccountBody represents the request body for starting the merge of another account, identified by
+// its email or mobile, into the authenticated user's account. Exactly one of email and mobile must be set.
+type MergeAccountBody struct {
+	Email  *string `json:"email,omitempty" validate:"omitempty,email,max=100"` // Email should be a valid email address if present
+	Mobile *string `json:"mobile,omitempty" validate:"omitempty,len=10"`       // Mobile should be 10 characters long if present
+}
+
+// VerifyMergeBody represents the request body for completing an account merge with the code sent to
+// the other account's email or mobile.
+type VerifyMergeBody struct {
+	OTP string `json:"otp" validate:"required,len=6,numeric"`
+}
+
+// AdminMergeBody represents the request body for an admin merging a duplicate account into another.
+// The reason is kept in the audit log, e.g. how the admin confirmed that both accounts belong to the same person.
+type AdminMergeBody struct {
+	UserId string `json:"userId" validate:"required,len=26"`
+	Reason string `json:"reason" validate:"required,min=5,max=500"`
+}
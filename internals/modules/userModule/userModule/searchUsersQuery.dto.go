@@ -0,0 +1,12 @@
+package userModule
+
+// SearchUsersQuery represents the query parameters accepted when searching users.
+// Q is matched against the start of the words of the names, username, email and mobile of users,
+// and every word of Q must match. Results are ordered by relevance.
+type SearchUsersQuery struct {
+	Q        string `form:"q" validate:"required,min=2,max=100"`
+	Page     int    `form:"page" validate:"omitempty,min=1"`
+	PageSize int    `form:"pageSize" validate:"omitempty,min=1,max=100"`
+
+	UserFilters
+}
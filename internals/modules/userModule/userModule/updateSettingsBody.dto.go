@@ -0,0 +1,25 @@
+package userModule
+
+// UpdateSettingsBody represents the request body for partially updating the settings of a user.
+// Fields that are omitted keep their current value. Locale is a BCP 47 language tag such as "en-GB",
+// and timezone an IANA time zone name such as "Europe/London".
+type UpdateSettingsBody struct {
+	Locale           *string                  `json:"locale" validate:"omitempty,max=35,bcp47_language_tag"`
+	Timezone         *string                  `json:"timezone" validate:"omitempty,max=64,timezone"`
+	Notifications    *UpdateNotificationsBody `json:"notifications"`
+	MarketingConsent *bool                    `json:"marketingConsent"`
+}
+
+// UpdateNotificationsBody represents the notification channels to turn on or off.
+type UpdateNotificationsBody struct {
+	Email *bool `json:"email"`
+	SMS   *bool `json:"sms"`
+	Push  *bool `json:"push"`
+}
+
+// IsEmpty reports whether the body does not change any setting.
+func (b UpdateSettingsBody) IsEmpty() bool {
+	notificationsEmpty := b.Notifications == nil ||
+		(b.Notifications.Email == nil && b.Notifications.SMS == nil && b.Notifications.Push == nil)
+	return b.Locale == nil && b.Timezone == nil && notificationsEmpty && b.MarketingConsent == nil
+}
@@ -0,0 +1,17 @@
+package userModule
+
+// UpdateUserBody represents the request body for partially updating a user.
+// Every field is optional; only the fields present in the request are changed.
+// Fields that are present follow the same validation rules as CreateUserBody.
+// The email and mobile are not part of it: they only change through the contact change flow,
+// which verifies the new contact and notifies the old one.
+type UpdateUserBody struct {
+	FirstName *string `json:"firstName,omitempty" validate:"omitempty,min=2,max=50"`
+	LastName  *string `json:"lastName,omitempty" validate:"omitempty,min=2,max=50"`
+	DOB       *string `json:"dob,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// IsEmpty reports whether the body does not carry any field to update.
+func (b UpdateUserBody) IsEmpty() bool {
+	return b.FirstName == nil && b.LastName == nil && b.DOB == nil
+}
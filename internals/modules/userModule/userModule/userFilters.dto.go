@@ -0,0 +1,15 @@
+package userModule
+
+import "time"
+
+// UserFilters represents the query parameters that narrow down which users are listed or exported.
+// Timestamps are expected in RFC 3339 format.
+type UserFilters struct {
+	IsActive         *bool      `form:"isActive"`
+	IsEmailVerified  *bool      `form:"isEmailVerified"`
+	IsMobileVerified *bool      `form:"isMobileVerified"`
+	CreatedAfter     *time.Time `form:"createdAfter" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore    *time.Time `form:"createdBefore" time_format:"2006-01-02T15:04:05Z07:00"`
+	Email            string     `form:"email" validate:"omitempty,max=100"`
+	Name             string     `form:"name" validate:"omitempty,max=100"`
+}
@@ -0,0 +1,7 @@
+package userModule
+
+// UserHistoryQuery represents the query parameters accepted when listing the changes made to a user.
+type UserHistoryQuery struct {
+	Page     int `form:"page" validate:"omitempty,min=1"`
+	PageSize int `form:"pageSize" validate:"omitempty,min=1,max=100"`
+}
@@ -0,0 +1,152 @@
+package userRepository
+
+import (
+	"backendService/internals/common/repository"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Contacts a user can change. They double as the names of the user columns holding them.
+const (
+	ContactEmail  = "email"
+	ContactMobile = "mobile"
+)
+
+// ErrContactTaken is returned when an email or mobile is already used by another user,
+// or reserved by another user's pending contact change.
+var ErrContactTaken = errors.New("contact is already in use")
+
+// PendingContactChange is a new email or mobile a user asked to switch to, awaiting verification.
+// A user has at most one pending change per kind of contact, and the unique index on the value
+// reserves it so that no other user can claim it while the change is pending.
+// Only a hash of the verification code is stored.
+type PendingContactChange struct {
+	ID        uint64    `gorm:"primary_key"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+
+	UserID    uint64    `gorm:"uniqueIndex:idx_pending_contact_user;not null"`
+	User      *User     `gorm:"constraint:OnDelete:CASCADE"`
+	Kind      string    `gorm:"uniqueIndex:idx_pending_contact_user;uniqueIndex:idx_pending_contact_value;not null"`
+	Value     string    `gorm:"uniqueIndex:idx_pending_contact_value;not null"`
+	CodeHash  string    `gorm:"not null"`
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+// IsExpired reports whether the change can no longer be verified.
+func (c *PendingContactChange) IsExpired() bool {
+	return time.Now().After(c.ExpiresAt)
+}
+
+// pendingContactChanges returns a session on the pending contact changes table.
+func (r *UserRepository) pendingContactChanges() *gorm.DB {
+	return r.contactChanges.Db.Session(&gorm.Session{})
+}
+
+// SavePendingContactChange records a pending change, replacing any earlier pending change of the same
+// kind by the user. It returns ErrContactTaken when the value is in use by another user or reserved
+// by another user's pending change.
+func (r *UserRepository) SavePendingContactChange(change *PendingContactChange) error {
+	return r.pendingContactChanges().Transaction(func(tx *gorm.DB) error {
+		if err := contactTaken(tx, change.Kind, change.Value, change.UserID); err != nil {
+			return err
+		}
+
+		// Expired changes by anyone no longer hold the value, and the user's own earlier change is replaced
+		err := tx.Where("kind = ? AND (user_id = ? OR (value = ? AND expires_at <= ?))", change.Kind, change.UserID, change.Value, time.Now()).
+			Delete(&PendingContactChange{}).Error
+		if err != nil {
+			return err
+		}
+
+		var reserved int64
+		if err := tx.Model(&PendingContactChange{}).Where("kind = ? AND value = ?", change.Kind, change.Value).Count(&reserved).Error; err != nil {
+			return err
+		}
+		if reserved > 0 {
+			return ErrContactTaken
+		}
+		return tx.Omit("User").Create(change).Error
+	})
+}
+
+// FindPendingContactChange returns the pending change of the given kind by the user.
+// It returns nil without an error when there is none.
+func (r *UserRepository) FindPendingContactChange(userID uint64, kind string) (*PendingContactChange, error) {
+	var change PendingContactChange
+	err := r.pendingContactChanges().Where("user_id = ? AND kind = ?", userID, kind).First(&change).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &change, nil
+}
+
+// IncrementContactChangeAttempts records a failed attempt at verifying the change.
+func (r *UserRepository) IncrementContactChangeAttempts(change *PendingContactChange) error {
+	change.Attempts++
+	return r.pendingContactChanges().Model(change).Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// DeletePendingContactChange removes a pending change.
+func (r *UserRepository) DeletePendingContactChange(change *PendingContactChange) error {
+	return r.pendingContactChanges().Delete(change).Error
+}
+
+// DeletePendingContactChanges removes every pending change of the user.
+func (r *UserRepository) DeletePendingContactChanges(userID uint64) error {
+	return r.pendingContactChanges().Where("user_id = ?", userID).Delete(&PendingContactChange{}).Error
+}
+
+// ConfirmContactChange swaps the verified contact into the user and removes the pending change,
+// in a single transaction. Uniqueness is checked again, as another user may have signed up with
+// the contact while the change was pending. It returns ErrContactTaken in that case.
+func (r *UserRepository) ConfirmContactChange(change *PendingContactChange) error {
+	invalidate := r.BeginInvalidation(map[string]interface{}{"id": change.UserID})
+	err := r.Db.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		if err := contactTaken(tx, change.Kind, change.Value, change.UserID); err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{change.Kind: change.Value}
+		if change.Kind == ContactEmail {
+			updates["is_email_verified"] = true
+			updates["email_verified_at"] = time.Now()
+		} else {
+			updates["is_mobile_verified"] = true
+		}
+		err := r.Track(tx, repository.ActionUpdate, map[string]interface{}{"id": change.UserID}, func(tx *gorm.DB) error {
+			return tx.Model(&User{}).Where("id = ?", change.UserID).Updates(repository.IncrementVersion(updates)).Error
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Table("pending_contact_changes").Delete(&PendingContactChange{}, change.ID).Error
+	})
+	if err != nil {
+		return err
+	}
+	invalidate()
+	return nil
+}
+
+// contactTaken returns ErrContactTaken when a user other than excludeID uses the contact,
+// including soft-deleted users since they still hold their unique values.
+func contactTaken(tx *gorm.DB, kind string, value string, excludeID uint64) error {
+	var count int64
+	err := tx.Session(&gorm.Session{NewDB: true}).Table("users").
+		Where(kind+" = ? AND id <> ?", value, excludeID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrContactTaken
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package userRepository
+
+import (
+	"backendService/internals/common/repository"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// actionMerge is the action recorded in the history of both users of a merge.
+const actionMerge = "merge"
+
+// ErrMergeConflict is returned when one of the users being merged was deleted or merged in the meantime.
+var ErrMergeConflict = errors.New("user was deleted or merged concurrently")
+
+// mergeReleasedColumns are the columns the merged user gives up when the surviving user takes them over,
+// either because they are unique or because they point to files the surviving user now owns.
+var mergeReleasedColumns = []string{"email", "mobile", "username", "avatar_key", "avatar_url", "avatar_thumbnail_url"}
+
+// MergeFunc moves the records held about the merged user to the surviving user, given their internal IDs.
+// It runs within the merge transaction, tx not being bound to any table.
+type MergeFunc func(tx *gorm.DB, survivorID uint64, mergedID uint64) error
+
+// MergeUsers merges the user merged into survivor in a single transaction. The surviving user takes over
+// the columns in updates, which the merged user releases first. The merged user is then soft-deleted and
+// remembers which user it was merged into, and fns move the records of other tables. It returns
+// ErrMergeConflict when either user was deleted or merged in the meantime.
+func (r *UserRepository) MergeUsers(survivor *User, merged *User, updates map[string]interface{}, fns ...MergeFunc) error {
+	filter := map[string]interface{}{"id": []uint64{survivor.ID, merged.ID}}
+	invalidate := r.BeginInvalidation(filter)
+	err := r.Db.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		err := r.Track(tx, actionMerge, filter, func(tx *gorm.DB) error {
+			now := time.Now()
+			released := map[string]interface{}{
+				"merged_into_id": survivor.ID,
+				"is_deleted":     true,
+				"deleted_at":     now,
+			}
+			for _, column := range mergeReleasedColumns {
+				if _, ok := updates[column]; ok {
+					released[column] = nil
+				}
+			}
+			result := tx.Unscoped().Model(&User{}).Where("id = ? AND is_deleted = ?", merged.ID, false).Updates(repository.IncrementVersion(released))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrMergeConflict
+			}
+
+			// Updating the timestamp makes sure a surviving user deleted in the meantime is noticed
+			updates["updated_at"] = now
+			result = tx.Unscoped().Model(&User{}).Where("id = ? AND is_deleted = ?", survivor.ID, false).Updates(repository.IncrementVersion(updates))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrMergeConflict
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Pending contact changes of the merged user are abandoned, the surviving user can start them again
+		err = tx.Session(&gorm.Session{NewDB: true}).Table("pending_contact_changes").Where("user_id = ?", merged.ID).Delete(&PendingContactChange{}).Error
+		if err != nil {
+			return err
+		}
+		for _, fn := range fns {
+			if err := fn(tx.Session(&gorm.Session{NewDB: true}), survivor.ID, merged.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	invalidate()
+	return nil
+}
+
+// FindMergedInto returns the users that were merged into the user with the given internal ID.
+func (r *UserRepository) FindMergedInto(survivorID uint64) ([]User, error) {
+	var users []User
+	err := r.Db.Session(&gorm.Session{}).Unscoped().Where("merged_into_id = ?", survivorID).Order("id").Find(&users).Error
+	return users, err
+}
@@ -1,22 +1,35 @@
 package userRepository
 
 import (
+	"backendService/internals/common/cache"
+	"backendService/internals/common/date"
+	"backendService/internals/common/history"
+	"backendService/internals/common/logger"
 	"backendService/internals/common/repository"
+	"context"
 	"time"
 
 	"github.com/oklog/ulid/v2"
 	"gorm.io/gorm"
 )
 
+// Roles a user can have. Staff roles grant access to the admin endpoints.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
 // UserRepository represents a repository for managing user data.
 type User struct {
 	repository.BaseModel
+	repository.Versioned
 
 	UserId           ulid.ULID  `json:"userId" gorm:"uniqueIndex"`
 	Email            *string    `json:"email" gorm:"uniqueIndex"`
 	Username         *string    `json:"username" gorm:"uniqueIndex"`
-	DOB              *time.Time `json:"dob,omitempty" gorm:"type:timestamp"`
-	Password         *string    `json:"password"`
+	DOB              *date.Date `json:"dob,omitempty" gorm:"type:date"`
+	Password         *string    `json:"-"`
 	FirstName        string     `json:"firstName"`
 	LastName         string     `json:"lastName"`
 	IsEmailVerified  bool       `json:"isEmailVerified" gorm:"type:boolean"`
@@ -25,17 +38,158 @@ type User struct {
 	Mobile           *string    `json:"mobile" gorm:"uniqueIndex"`
 	IsMobileVerified bool       `json:"isMobileVerified" gorm:"type:boolean"`
 	AuthProvider     string     `json:"authProvider"`
+	Role             string     `json:"role" gorm:"not null;default:user"`
+
+	// AvatarKey is the storage key prefix of the user's avatar files
+	AvatarKey          *string `json:"-"`
+	AvatarURL          *string `json:"avatarUrl"`
+	AvatarThumbnailURL *string `json:"avatarThumbnailUrl"`
+
+	// ErasureScheduledAt is when the user's personal data will be erased, as the user asked for
+	ErasureScheduledAt *time.Time `json:"erasureScheduledAt,omitempty" gorm:"type:timestamp;index"`
+	// ErasedAt is when the user's personal data was erased. The anonymized row is kept for the records referring to it
+	ErasedAt *time.Time `json:"erasedAt,omitempty" gorm:"type:timestamp"`
+	// MergedIntoID is the internal ID of the user this duplicate account was merged into
+	MergedIntoID *uint64 `json:"-" gorm:"index"`
+}
+
+// UserPage is a single page of users returned by a paginated query.
+type UserPage = repository.PageResult[User]
+
+// UserCursorPage is a single page of users returned by a keyset paginated query.
+type UserCursorPage = repository.CursorResult[User]
+
+// UserSearchHit is a user matching a search.
+type UserSearchHit = repository.SearchHit[User]
+
+// UserSearchPage is a single page of users matching a search, best matches first.
+type UserSearchPage = repository.PageResult[UserSearchHit]
+
+// searchColumns are the columns searched by the user search.
+var searchColumns = []string{"first_name", "last_name", "username", "email", "mobile"}
+
+// filterableColumns are the columns users can be filtered and ordered on. Secrets such as the password
+// are left out so that no filter can probe them.
+var filterableColumns = []string{
+	"id", "user_id", "email", "mobile", "username", "first_name", "last_name", "role",
+	"is_active", "is_email_verified", "is_mobile_verified", "created_at", "updated_at",
 }
+
+// uniqueKeys are the unique columns users are cached by, besides their ID and public ID.
+var uniqueKeys = []string{"email", "mobile", "username"}
+
 type UserRepository struct {
-	*repository.BaseRepository[User]
+	*repository.CachedRepository[User]
+	contactChanges *repository.BaseRepository[PendingContactChange]
+}
+
+// NewUserRepository creates a new instance of UserRepository. Users are cached in cacheService
+// for the TTL configured for the users table.
+func NewUserRepository(db *gorm.DB, cacheService *cache.CacheService) *UserRepository {
+	users := repository.NewBaseRepository[User](db, "users").WithPublicID("user_id").WithSearch(searchColumns...).WithFilterable(filterableColumns...).
+		WithChangeHook(history.Recorder("password", "merged_into_id"))
+	userRepository := &UserRepository{
+		CachedRepository: repository.NewCachedRepository(users, cacheService, repository.CacheOptions{
+			TTL:        repository.CacheTTL("users"),
+			UniqueKeys: uniqueKeys,
+			// The password hash and the storage key of the avatar files are not copied to the cache
+			UncachedColumns: []string{"password", "avatar_key"},
+		}),
+		contactChanges: repository.NewBaseRepository[PendingContactChange](db, "pending_contact_changes"),
+	}
+	if err := userRepository.CheckSearchIndex(); err != nil {
+		logger.Error("repository", "UserRepository", "NewUserRepository", "full-text search unavailable, falling back to LIKE matching", err)
+	}
+	return userRepository
+}
+
+// WithTx returns a copy of the repository running its queries inside the given transaction.
+func (r *UserRepository) WithTx(tx *repository.Tx) *UserRepository {
+	return &UserRepository{
+		CachedRepository: r.CachedRepository.WithTx(tx),
+		contactChanges:   r.contactChanges.WithTx(tx),
+	}
 }
 
-// NewUserRepository creates a new instance of UserRepository.
-func NewUserRepository(db *gorm.DB) *UserRepository {
-	db.Migrator().AutoMigrate(&User{})
+// WithContext returns a copy of the repository running its queries with the given context.
+func (r *UserRepository) WithContext(ctx context.Context) *UserRepository {
 	return &UserRepository{
-		BaseRepository: repository.NewBaseRepository[User](db, "users"),
+		CachedRepository: r.CachedRepository.WithContext(ctx),
+		contactChanges:   r.contactChanges.WithContext(ctx),
+	}
+}
+
+// FindExistingValues returns which of the given values are already used in column by any user,
+// including soft-deleted users since they still hold their unique values.
+// The column must come from code, never from user input.
+func (r *UserRepository) FindExistingValues(column string, values []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(values))
+	if len(values) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	err := r.Db.Session(&gorm.Session{}).Unscoped().Model(&User{}).Where(column+" IN ?", values).Pluck(column, &found).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range found {
+		existing[value] = true
+	}
+	return existing, nil
+}
+
+// FindDueForErasure returns up to limit users whose erasure was scheduled at or before the given time
+// and who are not erased yet, including soft-deleted users.
+func (r *UserRepository) FindDueForErasure(before time.Time, limit int) ([]User, error) {
+	var users []User
+	err := r.Db.Session(&gorm.Session{}).Unscoped().
+		Where("erasure_scheduled_at <= ? AND erased_at IS NULL", before).
+		Order("erasure_scheduled_at").
+		Limit(limit).
+		Find(&users).Error
+	return users, err
+}
+
+// Anonymize clears every piece of personal data of the user with the given ID, and soft-deletes them.
+// The row itself is kept, so records referring to the user stay valid. It reports false without an error
+// when the user was already erased, e.g. by another instance of the server.
+func (r *UserRepository) Anonymize(id uint64) (bool, error) {
+	filter := map[string]interface{}{"id": id}
+	invalidate := r.BeginInvalidation(filter)
+	now := time.Now()
+	var erased bool
+	err := r.Track(r.Db.Session(&gorm.Session{}), repository.ActionErase, filter, func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&User{}).
+			Where("id = ? AND erased_at IS NULL", id).
+			Updates(repository.IncrementVersion(map[string]interface{}{
+				"email":                nil,
+				"username":             nil,
+				"mobile":               nil,
+				"password":             nil,
+				"dob":                  nil,
+				"first_name":           "",
+				"last_name":            "",
+				"is_email_verified":    false,
+				"email_verified_at":    nil,
+				"is_mobile_verified":   false,
+				"is_active":            false,
+				"avatar_key":           nil,
+				"avatar_url":           nil,
+				"avatar_thumbnail_url": nil,
+				"erasure_scheduled_at": nil,
+				"erased_at":            now,
+				"deleted_at":           gorm.Expr("COALESCE(deleted_at, ?)", now),
+				"is_deleted":           true,
+			}))
+		erased = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		return false, err
 	}
+	invalidate()
+	return erased, nil
 }
 
 // func (r *User_Repository) GetTableName() string {
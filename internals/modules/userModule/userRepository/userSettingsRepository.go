@@ -0,0 +1,80 @@
+package userRepository
+
+import (
+	"backendService/internals/common/repository"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NotificationPreferences holds the channels a user agreed to be notified on.
+type NotificationPreferences struct {
+	Email bool `json:"email"`
+	SMS   bool `json:"sms"`
+	Push  bool `json:"push"`
+}
+
+// UserSettings holds the preferences of a user.
+// Settings are only stored once a user changes them, until then the defaults apply.
+// MarketingConsentAt records when the user last granted or withdrew marketing consent.
+// Settings live and die with their user, so unlike other models they are never soft-deleted.
+type UserSettings struct {
+	ID        uint64    `json:"-" gorm:"primary_key"`
+	CreatedAt time.Time `json:"-" gorm:"not null"`
+	UpdatedAt time.Time `json:"-" gorm:"not null"`
+
+	UserID             uint64                  `json:"-" gorm:"uniqueIndex;not null"`
+	User               *User                   `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Locale             string                  `json:"locale" gorm:"not null"`
+	Timezone           string                  `json:"timezone" gorm:"not null"`
+	Notifications      NotificationPreferences `json:"notifications" gorm:"embedded;embeddedPrefix:notify_"`
+	MarketingConsent   bool                    `json:"marketingConsent" gorm:"type:boolean"`
+	MarketingConsentAt *time.Time              `json:"marketingConsentAt" gorm:"type:timestamp"`
+}
+
+type UserSettingsRepository struct {
+	*repository.BaseRepository[UserSettings]
+}
+
+// NewUserSettingsRepository creates a new instance of UserSettingsRepository.
+func NewUserSettingsRepository(db *gorm.DB) *UserSettingsRepository {
+	return &UserSettingsRepository{
+		BaseRepository: repository.NewBaseRepository[UserSettings](db, "user_settings"),
+	}
+}
+
+// WithContext returns a copy of the repository running its queries with the given context.
+func (r *UserSettingsRepository) WithContext(ctx context.Context) *UserSettingsRepository {
+	return &UserSettingsRepository{BaseRepository: r.BaseRepository.WithContext(ctx)}
+}
+
+// FindByUserID returns the stored settings of the user with the given internal ID.
+// It returns nil without an error when the user never changed their settings.
+func (r *UserSettingsRepository) FindByUserID(userID uint64) (*UserSettings, error) {
+	return r.FindOneBy(repository.Eq("user_id", userID))
+}
+
+// Save inserts the settings, or updates every column of them when they are already stored.
+func (r *UserSettingsRepository) Save(settings *UserSettings) error {
+	return r.Db.Session(&gorm.Session{}).Omit("User").Save(settings).Error
+}
+
+// DeleteByUserID removes the stored settings of the user with the given internal ID, if any.
+func (r *UserSettingsRepository) DeleteByUserID(userID uint64) error {
+	return r.Db.Session(&gorm.Session{}).Where("user_id = ?", userID).Delete(&UserSettings{}).Error
+}
+
+// MergeUsers is a MergeFunc moving the stored settings of the merged user to the surviving user,
+// unless the surviving user has settings of their own, which then win.
+func (r *UserSettingsRepository) MergeUsers(tx *gorm.DB, survivorID uint64, mergedID uint64) error {
+	settings := tx.Table("user_settings")
+	var existing int64
+	if err := settings.Session(&gorm.Session{}).Model(&UserSettings{}).Where("user_id = ?", survivorID).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return settings.Session(&gorm.Session{}).Where("user_id = ?", mergedID).Delete(&UserSettings{}).Error
+	}
+	return settings.Session(&gorm.Session{}).Model(&UserSettings{}).Where("user_id = ?", mergedID).Update("user_id", survivorID).Error
+}
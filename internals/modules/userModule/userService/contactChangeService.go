@@ -0,0 +1,236 @@
+package userService
+
+import (
+	"backendService/internals/common/audit"
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/logger"
+	"backendService/internals/common/notification"
+	"backendService/internals/common/privacy"
+	"backendService/internals/modules/userModule/userModule"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"backendService/internals/setup/config"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+const (
+	// maxContactChangeAttempts is how many wrong codes are accepted before the pending change is dropped.
+	maxContactChangeAttempts = 5
+	// contactChangeResendInterval is how long a user must wait before asking for a new code.
+	contactChangeResendInterval = time.Minute
+)
+
+// ContactChangeRequest describes a contact change awaiting verification.
+type ContactChangeRequest struct {
+	Kind      string    `json:"kind"`
+	Value     string    `json:"value"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ContactChangeService changes the email or mobile of users. The new contact is only swapped in once
+// the user proves they own it with a code sent to it, and the old contact is told about the change.
+// Until then the user keeps their current contact, and the new one is reserved for them.
+type ContactChangeService struct {
+	userRepository *repository.UserRepository
+	notifier       notification.Notifier
+	auditService   *audit.AuditService
+}
+
+// NewContactChangeService creates a new instance of ContactChangeService.
+func NewContactChangeService(userRepository *repository.UserRepository, notifier notification.Notifier, auditService *audit.AuditService) *ContactChangeService {
+	return &ContactChangeService{userRepository: userRepository, notifier: notifier, auditService: auditService}
+}
+
+// RequestChange starts changing the email or mobile of the user identified by id,
+// and sends a verification code to the new contact. Requesting again replaces the pending change.
+func (ccs *ContactChangeService) RequestChange(ctx context.Context, id string, body userModule.ContactChangeBody) (*ContactChangeRequest, *appError.ApplicationError) {
+	if (body.Email == nil) == (body.Mobile == nil) {
+		return nil, appError.NewBadRequestError("missing_data", "either email or mobile is required")
+	}
+
+	user, appErr := findUserByPublicID(ccs.userRepository.WithContext(ctx), id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	kind, value, current := repository.ContactMobile, body.Mobile, user.Mobile
+	if body.Email != nil {
+		kind, value, current = repository.ContactEmail, body.Email, user.Email
+	}
+	if current != nil && *current == *value {
+		return nil, appError.NewBadRequestError("contact_unchanged", "the new "+kind+" is the same as the current one")
+	}
+
+	previous, err := ccs.userRepository.WithContext(ctx).FindPendingContactChange(user.ID, kind)
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve pending change")
+	}
+	if previous != nil && time.Since(previous.CreatedAt) < contactChangeResendInterval {
+		return nil, appError.NewApplicationError("too_many_requests", "please wait before requesting another code", http.StatusTooManyRequests)
+	}
+
+	code, err := generateVerificationCode()
+	if err != nil {
+		return nil, appError.NewInternalServerError("failed to generate verification code", err)
+	}
+	change := &repository.PendingContactChange{
+		UserID:    user.ID,
+		Kind:      kind,
+		Value:     *value,
+		CodeHash:  hashVerificationCode(user.ID, kind, *value, code),
+		ExpiresAt: time.Now().Add(contactChangeTTL()),
+	}
+	if err := ccs.userRepository.WithContext(ctx).SavePendingContactChange(change); err != nil {
+		if err == repository.ErrContactTaken {
+			return nil, appError.NewApplicationError("user_exists", "user with this "+kind+" already exists", http.StatusConflict)
+		}
+		logger.Error("service", "contact_change_service", "RequestChange", "failed to save pending change", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to save pending change")
+	}
+
+	message := "Your verification code is " + code + ". It expires in " + fmt.Sprint(contactChangeTTL().Round(time.Minute)) + "."
+	if err := ccs.notifier.Notify(ctx, *value, message); err != nil {
+		logger.Error("service", "contact_change_service", "RequestChange", "failed to send verification code", err)
+		return nil, appError.NewBadRequestError("failed_to_send_otp", "failed to send verification code")
+	}
+
+	return &ContactChangeRequest{Kind: change.Kind, Value: change.Value, ExpiresAt: change.ExpiresAt}, nil
+}
+
+// VerifyChange completes the pending change of the given kind with the code sent to the new contact.
+// The new contact replaces the old one and is marked as verified, and the old contact is notified.
+// After too many wrong codes the pending change is dropped and must be requested again.
+func (ccs *ContactChangeService) VerifyChange(ctx context.Context, id string, body userModule.VerifyContactChangeBody) (*repository.User, *appError.ApplicationError) {
+	user, appErr := findUserByPublicID(ccs.userRepository.WithContext(ctx), id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	change, err := ccs.userRepository.WithContext(ctx).FindPendingContactChange(user.ID, body.Kind)
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve pending change")
+	}
+	if change == nil || change.IsExpired() {
+		return nil, appError.NewBadRequestError("otp_not_found", "no pending "+body.Kind+" change, please request a new code")
+	}
+
+	expected := hashVerificationCode(user.ID, change.Kind, change.Value, body.OTP)
+	if !hmac.Equal([]byte(expected), []byte(change.CodeHash)) {
+		if err := ccs.userRepository.WithContext(ctx).IncrementContactChangeAttempts(change); err != nil {
+			logger.Error("service", "contact_change_service", "VerifyChange", "failed to record attempt", err)
+		}
+		if change.Attempts >= maxContactChangeAttempts {
+			if err := ccs.userRepository.WithContext(ctx).DeletePendingContactChange(change); err != nil {
+				logger.Error("service", "contact_change_service", "VerifyChange", "failed to drop pending change", err)
+			}
+			return nil, appError.NewBadRequestError("otp_attempts_exceeded", "too many incorrect codes, please request a new code")
+		}
+		return nil, appError.NewBadRequestError("otp_incorrect", "OTP is incorrect")
+	}
+
+	if err := ccs.userRepository.WithContext(ctx).ConfirmContactChange(change); err != nil {
+		if err == repository.ErrContactTaken {
+			return nil, appError.NewApplicationError("user_exists", "user with this "+change.Kind+" already exists", http.StatusConflict)
+		}
+		logger.Error("service", "contact_change_service", "VerifyChange", "failed to confirm change", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to update user")
+	}
+
+	previous := user.Mobile
+	redact := redactMobile
+	if change.Kind == repository.ContactEmail {
+		previous, redact = user.Email, redactEmail
+	}
+	if previous != nil {
+		message := fmt.Sprintf("The %s of your account was changed to %v. If you did not make this change, please contact support.", change.Kind, redact(change.Value))
+		if err := ccs.notifier.Notify(ctx, *previous, message); err != nil {
+			logger.Error("service", "contact_change_service", "VerifyChange", "failed to notify previous contact", err)
+		}
+	}
+	if err := ccs.auditService.Record(ctx, audit.Entry{
+		Action:     "user." + change.Kind + "_changed",
+		ActorId:    id,
+		TargetType: "user",
+		TargetId:   id,
+		Details:    map[string]interface{}{"previous": previous, "current": change.Value},
+	}); err != nil {
+		logger.Error("service", "contact_change_service", "VerifyChange", "failed to record audit log", err)
+	}
+
+	return findUserByPublicID(ccs.userRepository.WithContext(ctx), id)
+}
+
+// CancelChange drops the pending change of the given kind, releasing the reserved contact.
+func (ccs *ContactChangeService) CancelChange(ctx context.Context, id string, kind string) *appError.ApplicationError {
+	if kind != repository.ContactEmail && kind != repository.ContactMobile {
+		return appError.NewBadRequestError("invalid_kind", "kind must be email or mobile")
+	}
+	user, appErr := findUserByPublicID(ccs.userRepository.WithContext(ctx), id)
+	if appErr != nil {
+		return appErr
+	}
+	change, err := ccs.userRepository.WithContext(ctx).FindPendingContactChange(user.ID, kind)
+	if err != nil {
+		return appError.NewApplicationError("internal_error", "failed to retrieve pending change")
+	}
+	if change == nil {
+		return appError.NewNotFoundError("change_not_found", "no pending "+kind+" change")
+	}
+	if err := ccs.userRepository.WithContext(ctx).DeletePendingContactChange(change); err != nil {
+		return appError.NewApplicationError("internal_error", "failed to cancel pending change")
+	}
+	return nil
+}
+
+// RegisterPersonalData registers the pending contact changes with the personal data registry.
+func (ccs *ContactChangeService) RegisterPersonalData(registry *privacy.Registry) {
+	registry.RegisterSection("pending_contact_changes", func(ctx context.Context, subject privacy.Subject) (interface{}, error) {
+		var pending []ContactChangeRequest
+		for _, kind := range []string{repository.ContactEmail, repository.ContactMobile} {
+			change, err := ccs.userRepository.WithContext(ctx).FindPendingContactChange(subject.ID, kind)
+			if err != nil {
+				return nil, err
+			}
+			if change != nil {
+				pending = append(pending, ContactChangeRequest{Kind: change.Kind, Value: change.Value, ExpiresAt: change.ExpiresAt})
+			}
+		}
+		if len(pending) == 0 {
+			return nil, nil
+		}
+		return pending, nil
+	})
+	registry.RegisterEraser("pending_contact_changes", func(ctx context.Context, subject privacy.Subject) error {
+		return ccs.userRepository.WithContext(ctx).DeletePendingContactChanges(subject.ID)
+	})
+}
+
+// generateVerificationCode returns a random six digit code.
+func generateVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashVerificationCode binds a code to the user and the contact it was sent to, so a leaked hash
+// cannot be checked offline without the application secret key.
+func hashVerificationCode(userID uint64, kind string, value string, code string) string {
+	mac := hmac.New(sha256.New, []byte(config.Config.App.SecretKey))
+	fmt.Fprintf(mac, "%d:%s:%s:%s", userID, kind, value, code)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// contactChangeTTL returns how long a verification code stays valid, defaulting to fifteen minutes.
+func contactChangeTTL() time.Duration {
+	if config.Config.Auth.ContactChangeTTLMinutes <= 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(config.Config.Auth.ContactChangeTTLMinutes) * time.Minute
+}
@@ -0,0 +1,180 @@
+package userService
+
+import (
+	"backendService/internals/modules/userModule/userModule"
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingNotifier keeps the messages sent to every recipient.
+type recordingNotifier struct {
+	mu       sync.Mutex
+	messages map[string][]string
+}
+
+func newRecordingNotifier() *recordingNotifier {
+	return &recordingNotifier{messages: map[string][]string{}}
+}
+
+func (rn *recordingNotifier) Notify(ctx context.Context, recipient string, message string) error {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	rn.messages[recipient] = append(rn.messages[recipient], message)
+	return nil
+}
+
+// last returns the last message sent to the recipient.
+func (rn *recordingNotifier) last(recipient string) string {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	messages := rn.messages[recipient]
+	if len(messages) == 0 {
+		return ""
+	}
+	return messages[len(messages)-1]
+}
+
+var verificationCodePattern = regexp.MustCompile(`\b\d{6}\b`)
+
+// sentCode returns the verification code last sent to the recipient.
+func (rn *recordingNotifier) sentCode(t *testing.T, recipient string) string {
+	t.Helper()
+	code := verificationCodePattern.FindString(rn.last(recipient))
+	if code == "" {
+		t.Fatalf("no verification code was sent to %s", recipient)
+	}
+	return code
+}
+
+// wrongCode returns a code that differs from code.
+func wrongCode(code string) string {
+	if code == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
+
+func newTestContactChangeService(t *testing.T) (*ContactChangeService, *recordingNotifier, *testEnvironment) {
+	t.Helper()
+	env := newTestEnvironment(t)
+	notifier := newRecordingNotifier()
+	return NewContactChangeService(env.userRepository, notifier, env.auditService), notifier, env
+}
+
+func TestChangeEmail(t *testing.T) {
+	ccs, notifier, env := newTestContactChangeService(t)
+	ctx := context.Background()
+	user := createTestUser(t, env.userService, "Ada", "Lovelace", "ada@example.com")
+	id := user.UserId.String()
+
+	request, appErr := ccs.RequestChange(ctx, id, userModule.ContactChangeBody{Email: stringPointer("countess@example.com")})
+	if appErr != nil {
+		t.Fatalf("RequestChange: %s", appErr.Message)
+	}
+	if request.Kind != "email" || request.Value != "countess@example.com" {
+		t.Errorf("got request %+v", request)
+	}
+	code := notifier.sentCode(t, "countess@example.com")
+
+	// The user keeps their email until the new one is verified.
+	if found, _ := env.userService.GetUserByID(ctx, id); *found.Email != "ada@example.com" {
+		t.Errorf("got email %s before verification, want it unchanged", *found.Email)
+	}
+	if _, appErr := ccs.VerifyChange(ctx, id, userModule.VerifyContactChangeBody{Kind: "email", OTP: wrongCode(code)}); appErr == nil || appErr.ErrorCode != "otp_incorrect" {
+		t.Errorf("verifying a wrong code: got %v, want otp_incorrect", appErr)
+	}
+
+	changed, appErr := ccs.VerifyChange(ctx, id, userModule.VerifyContactChangeBody{Kind: "email", OTP: code})
+	if appErr != nil {
+		t.Fatalf("VerifyChange: %s", appErr.Message)
+	}
+	if *changed.Email != "countess@example.com" || !changed.IsEmailVerified || changed.Version != user.Version+1 {
+		t.Errorf("got email %s verified %v at version %d, want the new email verified", *changed.Email, changed.IsEmailVerified, changed.Version)
+	}
+	if notice := notifier.last("ada@example.com"); !strings.Contains(notice, "c***@example.com") || strings.Contains(notice, "countess@") {
+		t.Errorf("got notice %q to the previous email, want the new email redacted", notice)
+	}
+	if actions := auditActions(t, env.userService, user); len(actions) != 1 || actions[0] != "user.email_changed" {
+		t.Errorf("got audit log %v, want [user.email_changed]", actions)
+	}
+	if _, appErr := ccs.VerifyChange(ctx, id, userModule.VerifyContactChangeBody{Kind: "email", OTP: code}); appErr == nil || appErr.ErrorCode != "otp_not_found" {
+		t.Errorf("verifying the code again: got %v, want otp_not_found", appErr)
+	}
+}
+
+func TestRequestChangeReservesContact(t *testing.T) {
+	ccs, _, env := newTestContactChangeService(t)
+	ctx := context.Background()
+	ada := createTestUser(t, env.userService, "Ada", "Lovelace", "ada@example.com")
+	grace := createTestUser(t, env.userService, "Grace", "Hopper", "grace@example.com")
+
+	tests := []struct {
+		name string
+		id   string
+		body userModule.ContactChangeBody
+		code string
+	}{
+		{"neither contact", ada.UserId.String(), userModule.ContactChangeBody{}, "missing_data"},
+		{"both contacts", ada.UserId.String(), userModule.ContactChangeBody{Email: stringPointer("a@example.com"), Mobile: stringPointer("5550000000")}, "missing_data"},
+		{"the current email", ada.UserId.String(), userModule.ContactChangeBody{Email: stringPointer("ada@example.com")}, "contact_unchanged"},
+		{"the email of another user", ada.UserId.String(), userModule.ContactChangeBody{Email: stringPointer("grace@example.com")}, "user_exists"},
+	}
+	for _, test := range tests {
+		if _, appErr := ccs.RequestChange(ctx, test.id, test.body); appErr == nil || appErr.ErrorCode != test.code {
+			t.Errorf("requesting %s: got %v, want %s", test.name, appErr, test.code)
+		}
+	}
+
+	if _, appErr := ccs.RequestChange(ctx, ada.UserId.String(), userModule.ContactChangeBody{Mobile: stringPointer("5550000000")}); appErr != nil {
+		t.Fatalf("RequestChange: %s", appErr.Message)
+	}
+	if _, appErr := ccs.RequestChange(ctx, grace.UserId.String(), userModule.ContactChangeBody{Mobile: stringPointer("5550000000")}); appErr == nil || appErr.ErrorCode != "user_exists" {
+		t.Errorf("requesting a mobile reserved by another user: got %v, want user_exists", appErr)
+	}
+	if _, appErr := ccs.RequestChange(ctx, ada.UserId.String(), userModule.ContactChangeBody{Mobile: stringPointer("5550000001")}); appErr == nil || appErr.ErrorCode != "too_many_requests" {
+		t.Errorf("requesting another code right away: got %v, want too_many_requests", appErr)
+	}
+
+	// Cancelling releases the reserved mobile.
+	if appErr := ccs.CancelChange(ctx, ada.UserId.String(), "mobile"); appErr != nil {
+		t.Fatalf("CancelChange: %s", appErr.Message)
+	}
+	if appErr := ccs.CancelChange(ctx, ada.UserId.String(), "mobile"); appErr == nil || appErr.ErrorCode != "change_not_found" {
+		t.Errorf("cancelling again: got %v, want change_not_found", appErr)
+	}
+	if _, appErr := ccs.RequestChange(ctx, grace.UserId.String(), userModule.ContactChangeBody{Mobile: stringPointer("5550000000")}); appErr != nil {
+		t.Errorf("requesting a released mobile: %s", appErr.Message)
+	}
+}
+
+func TestVerifyChangeLimitsAttempts(t *testing.T) {
+	ccs, notifier, env := newTestContactChangeService(t)
+	ctx := context.Background()
+	user := createTestUser(t, env.userService, "Ada", "Lovelace", "ada@example.com")
+	id := user.UserId.String()
+
+	if _, appErr := ccs.RequestChange(ctx, id, userModule.ContactChangeBody{Mobile: stringPointer("5550000000")}); appErr != nil {
+		t.Fatalf("RequestChange: %s", appErr.Message)
+	}
+	code := notifier.sentCode(t, "5550000000")
+
+	for attempt := 1; attempt <= maxContactChangeAttempts; attempt++ {
+		want := "otp_incorrect"
+		if attempt == maxContactChangeAttempts {
+			want = "otp_attempts_exceeded"
+		}
+		if _, appErr := ccs.VerifyChange(ctx, id, userModule.VerifyContactChangeBody{Kind: "mobile", OTP: wrongCode(code)}); appErr == nil || appErr.ErrorCode != want {
+			t.Errorf("attempt %d: got %v, want %s", attempt, appErr, want)
+		}
+	}
+	// The pending change is dropped, so even the right code no longer works.
+	if _, appErr := ccs.VerifyChange(ctx, id, userModule.VerifyContactChangeBody{Kind: "mobile", OTP: code}); appErr == nil || appErr.ErrorCode != "otp_not_found" {
+		t.Errorf("verifying after too many attempts: got %v, want otp_not_found", appErr)
+	}
+	if found, _ := env.userService.GetUserByID(ctx, id); found.Mobile != nil {
+		t.Errorf("got mobile %s, want none", *found.Mobile)
+	}
+}
@@ -0,0 +1,182 @@
+package userService
+
+import (
+	"backendService/internals/common/audit"
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/history"
+	"backendService/internals/common/logger"
+	baseRepository "backendService/internals/common/repository"
+	"backendService/internals/modules/userModule/userModule"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"backendService/internals/setup/config"
+	"context"
+	"net/http"
+	"time"
+)
+
+// ImpersonationToken is an access token that lets a staff member act as another user.
+// Impersonation is always true so that clients can clearly flag the session.
+type ImpersonationToken struct {
+	AccessToken   string           `json:"accessToken"`
+	ExpiresAt     time.Time        `json:"expiresAt"`
+	Impersonation bool             `json:"impersonation"`
+	User          *repository.User `json:"user"`
+}
+
+// SetUserActive activates or deactivates the user identified by id on behalf of the actor.
+// Deactivating a user signs them out of every session and prevents them from signing in again.
+func (us *UserService) SetUserActive(ctx context.Context, actorId string, id string, active bool) (*repository.User, *appError.ApplicationError) {
+	user, appErr := us.GetUserByID(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if !active && user.UserId.String() == actorId {
+		return nil, appError.NewBadRequestError("invalid_action", "you cannot deactivate your own account")
+	}
+
+	if user.IsActive != active {
+		err := us.userRepository.WithContext(ctx).Update(Filter{"id": user.ID}, Filter{"is_active": active})
+		if err != nil {
+			return nil, appError.NewApplicationError("internal_error", "failed to update user")
+		}
+		user.IsActive = active
+	}
+
+	action := "user.activated"
+	if !active {
+		action = "user.deactivated"
+		if err := us.sessionStore.RevokeAll(ctx, user.UserId.String()); err != nil {
+			logger.Error("service", "UserService", "SetUserActive", "failed to revoke sessions", err)
+			return nil, appError.NewInternalServerError("failed to revoke sessions", err)
+		}
+	}
+	us.recordAudit(ctx, action, actorId, user, nil)
+
+	return user, nil
+}
+
+// RestoreUser reverts the soft delete of the user identified by id on behalf of the actor.
+func (us *UserService) RestoreUser(ctx context.Context, actorId string, id string) (*repository.User, *appError.ApplicationError) {
+	user, appErr := us.findUserIncludingDeleted(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if !user.IsDeleted {
+		return nil, appError.NewApplicationError("user_not_deleted", "user is not deleted", http.StatusConflict)
+	}
+	if user.ErasedAt != nil {
+		return nil, appError.NewApplicationError("user_erased", "the personal data of this user was erased", http.StatusConflict)
+	}
+	if user.MergedIntoID != nil {
+		return nil, appError.NewApplicationError("user_merged", "this user was merged into another user", http.StatusConflict)
+	}
+
+	if err := us.userRepository.WithContext(ctx).Restore(user.ID); err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to restore user")
+	}
+	us.recordAudit(ctx, "user.restored", actorId, user, nil)
+
+	return us.GetUserByID(ctx, id)
+}
+
+// PurgeUser permanently removes the user identified by id on behalf of the actor, whether or not the user
+// is soft-deleted. The user is signed out of every session and their avatar is removed from storage.
+func (us *UserService) PurgeUser(ctx context.Context, actorId string, id string) *appError.ApplicationError {
+	user, appErr := us.findUserIncludingDeleted(ctx, id)
+	if appErr != nil {
+		return appErr
+	}
+	if user.UserId.String() == actorId {
+		return appError.NewBadRequestError("invalid_action", "you cannot purge your own account")
+	}
+
+	if err := us.userRepository.WithContext(ctx).HardDelete(user.ID); err != nil {
+		return appError.NewApplicationError("internal_error", "failed to purge user")
+	}
+	if err := us.sessionStore.RevokeAll(ctx, user.UserId.String()); err != nil {
+		logger.Error("service", "UserService", "PurgeUser", "failed to revoke sessions", err)
+	}
+	us.deleteAvatarFiles(ctx, user.AvatarKey)
+	us.recordAudit(ctx, "user.purged", actorId, user, nil)
+
+	return nil
+}
+
+// GetUserHistory returns a page of the changes made to the user identified by id, newest first,
+// including the changes made before the user was deleted.
+func (us *UserService) GetUserHistory(ctx context.Context, id string, query userModule.UserHistoryQuery) (*history.ChangePage, *appError.ApplicationError) {
+	user, appErr := us.findUserIncludingDeleted(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	page, err := us.historyService.FindByEntity(ctx, "users", user.UserId.String(), query.Page, query.PageSize)
+	if err != nil {
+		logger.Error("service", "UserService", "GetUserHistory", "failed to retrieve history", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve user history")
+	}
+	return page, nil
+}
+
+// ImpersonateUser issues a short-lived access token with which the actor can act as the user identified by id.
+// Staff accounts cannot be impersonated. The token is revoked along with the user's other sessions.
+func (us *UserService) ImpersonateUser(ctx context.Context, actorId string, id string, reason string) (*ImpersonationToken, *appError.ApplicationError) {
+	user, appErr := us.GetUserByID(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if user.Role != repository.RoleUser {
+		return nil, appError.NewApplicationError("forbidden", "staff accounts cannot be impersonated", http.StatusForbidden)
+	}
+	if !user.IsActive {
+		return nil, appError.NewApplicationError("account_inactive", "this account has been deactivated", http.StatusForbidden)
+	}
+
+	ttl := time.Duration(config.Config.Auth.ImpersonationTTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	token, session, err := us.sessionStore.CreateImpersonation(ctx, user.UserId.String(), user.Role, actorId, ttl)
+	if err != nil {
+		logger.Error("service", "UserService", "ImpersonateUser", "failed to create session", err)
+		return nil, appError.NewInternalServerError("failed to create session", err)
+	}
+	us.recordAudit(ctx, "user.impersonated", actorId, user, map[string]interface{}{
+		"reason":    reason,
+		"sessionId": session.ID,
+		"expiresAt": session.ExpiresAt,
+	})
+
+	return &ImpersonationToken{
+		AccessToken:   token,
+		ExpiresAt:     session.ExpiresAt,
+		Impersonation: true,
+		User:          user,
+	}, nil
+}
+
+// findUserIncludingDeleted retrieves the user identified by id, including soft-deleted users.
+func (us *UserService) findUserIncludingDeleted(ctx context.Context, id string) (*repository.User, *appError.ApplicationError) {
+	userId, appErr := parseUserId(id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	user, err := us.userRepository.WithContext(ctx).WithDeleted().FindOneBy(baseRepository.Eq("user_id", userId))
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve user")
+	}
+	if user == nil {
+		return nil, appError.NewNotFoundError("user_not_found", "user not found")
+	}
+	return user, nil
+}
+
+// recordAudit records an administrative action on a user in the audit log.
+func (us *UserService) recordAudit(ctx context.Context, action string, actorId string, user *repository.User, details map[string]interface{}) {
+	us.auditService.Record(ctx, audit.Entry{
+		Action:     action,
+		ActorId:    actorId,
+		TargetType: "user",
+		TargetId:   user.UserId.String(),
+		Details:    details,
+	})
+}
@@ -0,0 +1,152 @@
+package userService
+
+import (
+	repository "backendService/internals/modules/userModule/userRepository"
+	"context"
+	"testing"
+)
+
+// auditActions returns the audit log actions performed by or on the user, oldest first.
+func auditActions(t *testing.T, us *UserService, user *repository.User) []string {
+	t.Helper()
+	logs, err := us.auditService.FindByUser(context.Background(), user.UserId.String())
+	if err != nil {
+		t.Fatalf("reading the audit log: %v", err)
+	}
+	actions := make([]string, len(logs))
+	for i, log := range logs {
+		actions[i] = log.Action
+	}
+	return actions
+}
+
+// setRole gives the user the role directly in the database.
+func setRole(t *testing.T, us *UserService, user *repository.User, role string) {
+	t.Helper()
+	if err := us.userRepository.Update(Filter{"id": user.ID}, Filter{"role": role}); err != nil {
+		t.Fatalf("setting the role of %s: %v", user.UserId, err)
+	}
+	user.Role = role
+}
+
+func TestSetUserActive(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	admin := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	user := createTestUser(t, us, "Grace", "Hopper", "grace@example.com")
+	token, _, err := us.sessionStore.Create(ctx, user.UserId.String(), user.Role)
+	if err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+
+	if _, appErr := us.SetUserActive(ctx, admin.UserId.String(), admin.UserId.String(), false); appErr == nil || appErr.ErrorCode != "invalid_action" {
+		t.Errorf("deactivating yourself: got %v, want invalid_action", appErr)
+	}
+
+	deactivated, appErr := us.SetUserActive(ctx, admin.UserId.String(), user.UserId.String(), false)
+	if appErr != nil {
+		t.Fatalf("SetUserActive: %s", appErr.Message)
+	}
+	if deactivated.IsActive {
+		t.Error("got an active user, want it deactivated")
+	}
+	if session, err := us.sessionStore.Get(ctx, token); err != nil || session != nil {
+		t.Errorf("session of the deactivated user: got %v, %v, want it revoked", session, err)
+	}
+
+	activated, appErr := us.SetUserActive(ctx, admin.UserId.String(), user.UserId.String(), true)
+	if appErr != nil {
+		t.Fatalf("SetUserActive: %s", appErr.Message)
+	}
+	if found, _ := us.GetUserByID(ctx, user.UserId.String()); !activated.IsActive || !found.IsActive {
+		t.Error("got an inactive user, want it activated again")
+	}
+	if actions := auditActions(t, us, user); len(actions) != 2 || actions[0] != "user.deactivated" || actions[1] != "user.activated" {
+		t.Errorf("got audit log %v, want [user.deactivated user.activated]", actions)
+	}
+}
+
+func TestRestoreUser(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	admin := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	user := createTestUser(t, us, "Grace", "Hopper", "grace@example.com")
+
+	if _, appErr := us.RestoreUser(ctx, admin.UserId.String(), user.UserId.String()); appErr == nil || appErr.ErrorCode != "user_not_deleted" {
+		t.Errorf("restoring a user that is not deleted: got %v, want user_not_deleted", appErr)
+	}
+
+	if appErr := us.DeleteUser(ctx, user.UserId.String()); appErr != nil {
+		t.Fatalf("DeleteUser: %s", appErr.Message)
+	}
+	restored, appErr := us.RestoreUser(ctx, admin.UserId.String(), user.UserId.String())
+	if appErr != nil {
+		t.Fatalf("RestoreUser: %s", appErr.Message)
+	}
+	if restored.IsDeleted || restored.DeletedAt.Valid {
+		t.Errorf("got deleted %v at %v, want the user restored", restored.IsDeleted, restored.DeletedAt)
+	}
+	if _, appErr := us.GetUserByID(ctx, user.UserId.String()); appErr != nil {
+		t.Errorf("getting the restored user: %s", appErr.Message)
+	}
+}
+
+func TestPurgeUser(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	admin := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	user := createTestUser(t, us, "Grace", "Hopper", "grace@example.com")
+
+	if appErr := us.PurgeUser(ctx, admin.UserId.String(), admin.UserId.String()); appErr == nil || appErr.ErrorCode != "invalid_action" {
+		t.Errorf("purging yourself: got %v, want invalid_action", appErr)
+	}
+
+	if appErr := us.DeleteUser(ctx, user.UserId.String()); appErr != nil {
+		t.Fatalf("DeleteUser: %s", appErr.Message)
+	}
+	if appErr := us.PurgeUser(ctx, admin.UserId.String(), user.UserId.String()); appErr != nil {
+		t.Fatalf("PurgeUser: %s", appErr.Message)
+	}
+	if _, appErr := us.findUserIncludingDeleted(ctx, user.UserId.String()); appErr == nil || appErr.ErrorCode != "user_not_found" {
+		t.Errorf("finding the purged user among deleted users: got %v, want user_not_found", appErr)
+	}
+}
+
+func TestImpersonateUser(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	admin := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	setRole(t, us, admin, repository.RoleAdmin)
+	support := createTestUser(t, us, "Alan", "Turing", "alan@example.com")
+	setRole(t, us, support, repository.RoleSupport)
+	user := createTestUser(t, us, "Grace", "Hopper", "grace@example.com")
+
+	if _, appErr := us.ImpersonateUser(ctx, admin.UserId.String(), support.UserId.String(), "ticket 42"); appErr == nil || appErr.ErrorCode != "forbidden" {
+		t.Errorf("impersonating staff: got %v, want forbidden", appErr)
+	}
+
+	impersonation, appErr := us.ImpersonateUser(ctx, admin.UserId.String(), user.UserId.String(), "ticket 42")
+	if appErr != nil {
+		t.Fatalf("ImpersonateUser: %s", appErr.Message)
+	}
+	session, err := us.sessionStore.Get(ctx, impersonation.AccessToken)
+	if err != nil || session == nil {
+		t.Fatalf("getting the impersonation session: %v, %v", session, err)
+	}
+	if !impersonation.Impersonation || session.UserId != user.UserId.String() || session.ImpersonatorId != admin.UserId.String() {
+		t.Errorf("got session %+v, want %s impersonating %s", session, admin.UserId, user.UserId)
+	}
+	if actions := auditActions(t, us, user); len(actions) != 1 || actions[0] != "user.impersonated" {
+		t.Errorf("got audit log %v, want [user.impersonated]", actions)
+	}
+
+	if _, appErr := us.SetUserActive(ctx, admin.UserId.String(), user.UserId.String(), false); appErr != nil {
+		t.Fatalf("SetUserActive: %s", appErr.Message)
+	}
+	if session, err := us.sessionStore.Get(ctx, impersonation.AccessToken); err != nil || session != nil {
+		t.Errorf("impersonation of a deactivated user: got %v, %v, want it revoked", session, err)
+	}
+	if _, appErr := us.ImpersonateUser(ctx, admin.UserId.String(), user.UserId.String(), "ticket 42"); appErr == nil || appErr.ErrorCode != "account_inactive" {
+		t.Errorf("impersonating an inactive user: got %v, want account_inactive", appErr)
+	}
+}
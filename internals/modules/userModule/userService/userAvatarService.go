@@ -0,0 +1,151 @@
+package userService
+
+import (
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/imaging"
+	"backendService/internals/common/logger"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"bytes"
+	"context"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	// MaxAvatarBytes is the largest avatar upload accepted.
+	MaxAvatarBytes = 5 << 20
+	// maxAvatarDimension bounds the width and height of avatar uploads, so a small but highly
+	// compressed file cannot make the server decode a huge image.
+	maxAvatarDimension = 4096
+	avatarQuality      = 85
+)
+
+// avatarContentTypes lists the image types accepted as avatars, all of which the standard library decodes.
+var avatarContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// avatarSizes lists the square renditions stored for every avatar, as a key suffix and a size in pixels.
+var avatarSizes = []struct {
+	suffix string
+	size   int
+}{
+	{suffix: "256", size: 256},
+	{suffix: "64", size: 64},
+}
+
+// UploadAvatar replaces the avatar of the user identified by id with the uploaded image.
+// The type of the image is sniffed from its content rather than trusted from the request.
+// The image is cropped to a square and stored as a 256 pixel avatar and a 64 pixel thumbnail.
+func (us *UserService) UploadAvatar(ctx context.Context, id string, source io.Reader) (*repository.User, *appError.ApplicationError) {
+	user, appErr := us.GetUserByID(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	data, err := io.ReadAll(io.LimitReader(source, MaxAvatarBytes+1))
+	if err != nil {
+		return nil, appError.NewBadRequestError("invalid_file", "failed to read the uploaded file")
+	}
+	if len(data) > MaxAvatarBytes {
+		return nil, appError.NewApplicationError("file_too_large", "avatar must not be larger than 5 MB", http.StatusRequestEntityTooLarge)
+	}
+	if len(data) == 0 {
+		return nil, appError.NewBadRequestError("missing_file", "file is required")
+	}
+	if !avatarContentTypes[http.DetectContentType(data)] {
+		return nil, appError.NewApplicationError("unsupported_media_type", "avatar must be a JPEG, PNG or GIF image", http.StatusUnsupportedMediaType)
+	}
+
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, appError.NewBadRequestError("invalid_image", "the uploaded image is corrupted")
+	}
+	if imageConfig.Width > maxAvatarDimension || imageConfig.Height > maxAvatarDimension {
+		return nil, appError.NewBadRequestError("invalid_image", "avatar must not be larger than 4096x4096 pixels")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, appError.NewBadRequestError("invalid_image", "the uploaded image is corrupted")
+	}
+
+	// Every upload gets new keys, so stale copies cached by clients are never served for the new avatar
+	key := "avatars/" + user.UserId.String() + "/" + ulid.Make().String()
+	square := imaging.SquareCrop(img)
+	urls := make([]string, len(avatarSizes))
+	for i, avatarSize := range avatarSizes {
+		var encoded bytes.Buffer
+		if err := jpeg.Encode(&encoded, imaging.Thumbnail(square, avatarSize.size), &jpeg.Options{Quality: avatarQuality}); err != nil {
+			return nil, appError.NewInternalServerError("failed to process avatar", err)
+		}
+		fileKey := avatarFileKey(key, avatarSize.suffix)
+		if err := us.fileStorage.Put(ctx, fileKey, encoded.Bytes(), "image/jpeg"); err != nil {
+			logger.Error("service", "user_service", "UploadAvatar", "failed to store avatar", err)
+			us.deleteAvatarFiles(ctx, &key)
+			return nil, appError.NewInternalServerError("failed to store avatar", err)
+		}
+		urls[i] = us.fileStorage.URL(fileKey)
+	}
+
+	err = us.userRepository.WithContext(ctx).Update(Filter{"id": user.ID}, Filter{
+		"avatar_key":           key,
+		"avatar_url":           urls[0],
+		"avatar_thumbnail_url": urls[1],
+	})
+	if err != nil {
+		us.deleteAvatarFiles(ctx, &key)
+		return nil, appError.NewApplicationError("internal_error", "failed to update user")
+	}
+	us.deleteAvatarFiles(ctx, user.AvatarKey)
+
+	return us.GetUserByID(ctx, id)
+}
+
+// DeleteAvatar removes the avatar of the user identified by id.
+func (us *UserService) DeleteAvatar(ctx context.Context, id string) (*repository.User, *appError.ApplicationError) {
+	user, appErr := us.GetUserByID(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if user.AvatarKey == nil {
+		return user, nil
+	}
+
+	err := us.userRepository.WithContext(ctx).Update(Filter{"id": user.ID}, Filter{
+		"avatar_key":           nil,
+		"avatar_url":           nil,
+		"avatar_thumbnail_url": nil,
+	})
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to update user")
+	}
+	us.deleteAvatarFiles(ctx, user.AvatarKey)
+
+	return us.GetUserByID(ctx, id)
+}
+
+// deleteAvatarFiles removes every rendition of the avatar stored under key.
+// Failures are only logged, as an orphaned file does no harm besides using storage.
+func (us *UserService) deleteAvatarFiles(ctx context.Context, key *string) {
+	if key == nil {
+		return
+	}
+	for _, avatarSize := range avatarSizes {
+		if err := us.fileStorage.Delete(ctx, avatarFileKey(*key, avatarSize.suffix)); err != nil {
+			logger.Error("service", "user_service", "deleteAvatarFiles", "failed to delete avatar", *key, err)
+		}
+	}
+}
+
+// avatarFileKey returns the storage key of one rendition of an avatar.
+func avatarFileKey(key string, suffix string) string {
+	return key + "-" + suffix + ".jpg"
+}
@@ -0,0 +1,116 @@
+package userService
+
+import (
+	"backendService/internals/common/storage"
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// testImage returns a PNG image of the given size.
+func testImage(t *testing.T, width int, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		t.Fatalf("encoding the test image: %v", err)
+	}
+	return encoded.Bytes()
+}
+
+// storedImageSize returns the size of the JPEG image stored under key.
+func storedImageSize(t *testing.T, us *UserService, key string) (int, int, error) {
+	t.Helper()
+	file, _, err := us.fileStorage.Get(context.Background(), key)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+	config, err := jpeg.DecodeConfig(file)
+	if err != nil {
+		t.Fatalf("decoding the stored avatar %s: %v", key, err)
+	}
+	return config.Width, config.Height, nil
+}
+
+func TestUploadAvatar(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	user := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+
+	updated, appErr := us.UploadAvatar(ctx, user.UserId.String(), bytes.NewReader(testImage(t, 600, 400)))
+	if appErr != nil {
+		t.Fatalf("UploadAvatar: %s", appErr.Message)
+	}
+	if updated.AvatarKey == nil || updated.AvatarURL == nil || updated.AvatarThumbnailURL == nil {
+		t.Fatalf("got user %+v, want the avatar set", updated)
+	}
+	key := *updated.AvatarKey
+	if !strings.HasPrefix(key, "avatars/"+user.UserId.String()+"/") || *updated.AvatarURL != "/files/"+key+"-256.jpg" {
+		t.Errorf("got avatar %s at %s", key, *updated.AvatarURL)
+	}
+	for suffix, size := range map[string]int{"256": 256, "64": 64} {
+		width, height, err := storedImageSize(t, us, avatarFileKey(key, suffix))
+		if err != nil || width != size || height != size {
+			t.Errorf("avatar %s: got %dx%d, %v, want a %dx%d square", suffix, width, height, err, size, size)
+		}
+	}
+
+	// A new upload replaces the files of the previous avatar.
+	replaced, appErr := us.UploadAvatar(ctx, user.UserId.String(), bytes.NewReader(testImage(t, 64, 64)))
+	if appErr != nil {
+		t.Fatalf("UploadAvatar replacing the avatar: %s", appErr.Message)
+	}
+	if *replaced.AvatarKey == key {
+		t.Error("got the same key for a new avatar, want a new one")
+	}
+	if _, _, err := storedImageSize(t, us, avatarFileKey(key, "256")); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("previous avatar: got %v, want it deleted", err)
+	}
+
+	deleted, appErr := us.DeleteAvatar(ctx, user.UserId.String())
+	if appErr != nil {
+		t.Fatalf("DeleteAvatar: %s", appErr.Message)
+	}
+	if deleted.AvatarKey != nil || deleted.AvatarURL != nil || deleted.AvatarThumbnailURL != nil {
+		t.Errorf("got user %+v, want the avatar cleared", deleted)
+	}
+	if _, _, err := storedImageSize(t, us, avatarFileKey(*replaced.AvatarKey, "64")); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("deleted avatar: got %v, want it deleted", err)
+	}
+}
+
+func TestUploadAvatarRejectsInvalidFiles(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	user := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	valid := testImage(t, 16, 16)
+
+	for name, test := range map[string]struct {
+		data []byte
+		code string
+	}{
+		"empty":     {nil, "missing_file"},
+		"text":      {[]byte("<html>not an image</html>"), "unsupported_media_type"},
+		"truncated": {valid[:len(valid)/2], "invalid_image"},
+		"too large": {bytes.Repeat([]byte{0}, MaxAvatarBytes+1), "file_too_large"},
+	} {
+		if _, appErr := us.UploadAvatar(ctx, user.UserId.String(), bytes.NewReader(test.data)); appErr == nil || appErr.ErrorCode != test.code {
+			t.Errorf("uploading a %s file: got %v, want %s", name, appErr, test.code)
+		}
+	}
+	if found, _ := us.GetUserByID(ctx, user.UserId.String()); found.AvatarKey != nil {
+		t.Error("got an avatar after rejected uploads, want none")
+	}
+}
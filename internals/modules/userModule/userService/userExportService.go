@@ -0,0 +1,253 @@
+package userService
+
+import (
+	"backendService/internals/common/audit"
+	"backendService/internals/common/date"
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/export"
+	"backendService/internals/common/jobs"
+	"backendService/internals/common/logger"
+	"backendService/internals/modules/userModule/userModule"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"backendService/internals/setup/config"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// JobTypeUserExport is the type of the background jobs exporting users.
+	JobTypeUserExport = "user_export"
+	exportBatchSize   = 500
+)
+
+// exportColumn describes a column of the user export.
+// Sensitive columns hold personal data and go through redact unless the export includes PII.
+type exportColumn struct {
+	name      string
+	sensitive bool
+	value     func(user *repository.User) interface{}
+	redact    func(value interface{}) interface{}
+}
+
+// userExportColumns lists the exported columns in order. Passwords are never exported.
+var userExportColumns = []exportColumn{
+	{name: "userId", value: func(u *repository.User) interface{} { return u.UserId.String() }},
+	{name: "firstName", value: func(u *repository.User) interface{} { return u.FirstName }},
+	{name: "lastName", value: func(u *repository.User) interface{} { return u.LastName }},
+	{name: "email", sensitive: true, value: func(u *repository.User) interface{} { return stringValue(u.Email) }, redact: redactEmail},
+	{name: "mobile", sensitive: true, value: func(u *repository.User) interface{} { return stringValue(u.Mobile) }, redact: redactMobile},
+	{name: "dob", sensitive: true, value: func(u *repository.User) interface{} { return dateValue(u.DOB) }, redact: redactAll},
+	{name: "isActive", value: func(u *repository.User) interface{} { return u.IsActive }},
+	{name: "isEmailVerified", value: func(u *repository.User) interface{} { return u.IsEmailVerified }},
+	{name: "isMobileVerified", value: func(u *repository.User) interface{} { return u.IsMobileVerified }},
+	{name: "authProvider", value: func(u *repository.User) interface{} { return u.AuthProvider }},
+	{name: "role", value: func(u *repository.User) interface{} { return u.Role }},
+	{name: "createdAt", value: func(u *repository.User) interface{} { return u.CreatedAt }},
+	{name: "updatedAt", value: func(u *repository.User) interface{} { return u.UpdatedAt }},
+}
+
+// UserExport is the outcome of ExportUsers. Small exports are streamed by the caller through Write,
+// while large ones run in the background and are described by Job.
+type UserExport struct {
+	Job         *jobs.Job
+	FileName    string
+	ContentType string
+	Write       func(w io.Writer) *appError.ApplicationError
+}
+
+// ExportUsers exports the users matching the filters in the query.
+// Exports with more rows than the configured limit, or explicitly asked to run asynchronously,
+// are written to a file by a background job that the actor can download once it completes.
+func (us *UserService) ExportUsers(ctx context.Context, actorId string, query userModule.ExportUsersQuery) (*UserExport, *appError.ApplicationError) {
+	format := query.Format
+	if format == "" {
+		format = export.FormatCSV
+	}
+	fileName := "users-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	contentType := export.ContentType(format)
+
+	async := query.Async
+	if !async {
+		total, err := us.userRepository.WithContext(ctx).Count(us.userFilterScopes(query.UserFilters)...)
+		if err != nil {
+			logger.Error("service", "user_service", "ExportUsers", "failed to count users", err)
+			return nil, appError.NewInternalServerError("failed to export users", err)
+		}
+		async = total > int64(config.Config.Export.MaxSyncRows)
+	}
+
+	if !async {
+		return &UserExport{
+			FileName:    fileName,
+			ContentType: contentType,
+			Write: func(w io.Writer) *appError.ApplicationError {
+				rows, err := us.writeUserExport(ctx, w, format, query, nil)
+				if err != nil {
+					logger.Error("service", "user_service", "ExportUsers", "failed to write export", err)
+					return appError.NewInternalServerError("failed to export users", err)
+				}
+				us.recordExportAudit(ctx, actorId, format, query, rows, "")
+				return nil
+			},
+		}, nil
+	}
+
+	job, err := us.jobStore.StartFileJob(ctx, JobTypeUserExport, actorId, fileName, contentType,
+		func(ctx context.Context, w io.Writer, progress func(processed int64)) error {
+			rows, err := us.writeUserExport(ctx, w, format, query, progress)
+			if err != nil {
+				return err
+			}
+			us.recordExportAudit(ctx, actorId, format, query, rows, fileName)
+			return nil
+		})
+	if err != nil {
+		logger.Error("service", "user_service", "ExportUsers", "failed to start export job", err)
+		return nil, appError.NewInternalServerError("failed to start export", err)
+	}
+	return &UserExport{Job: job, FileName: fileName, ContentType: contentType}, nil
+}
+
+// GetExportJob returns an export job started by the actor.
+// Jobs of other users are reported as not found, so their existence is not disclosed.
+func (us *UserService) GetExportJob(ctx context.Context, actorId string, jobId string) (*jobs.Job, *appError.ApplicationError) {
+	return us.findOwnedJob(ctx, actorId, jobId, JobTypeUserExport)
+}
+
+// GetExportFile returns the path of the file produced by a completed export job of the actor.
+func (us *UserService) GetExportFile(ctx context.Context, actorId string, jobId string) (*jobs.Job, string, *appError.ApplicationError) {
+	job, appErr := us.GetExportJob(ctx, actorId, jobId)
+	if appErr != nil {
+		return nil, "", appErr
+	}
+	return us.completedJobFile(job)
+}
+
+// findOwnedJob retrieves a job of the given type started by ownerId. Jobs of other users are reported as not found.
+func (us *UserService) findOwnedJob(ctx context.Context, ownerId string, jobId string, jobType string) (*jobs.Job, *appError.ApplicationError) {
+	job, err := us.jobStore.Get(ctx, jobId)
+	if err != nil {
+		logger.Error("service", "user_service", "findOwnedJob", "failed to retrieve job", err)
+		return nil, appError.NewInternalServerError("failed to retrieve export", err)
+	}
+	if job == nil || job.Type != jobType || job.OwnerId != ownerId {
+		return nil, appError.NewNotFoundError("export_not_found", "export not found")
+	}
+	return job, nil
+}
+
+// completedJobFile returns the path of the file produced by the job, once the job has completed.
+func (us *UserService) completedJobFile(job *jobs.Job) (*jobs.Job, string, *appError.ApplicationError) {
+	if job.Status != jobs.StatusCompleted {
+		return nil, "", appError.NewApplicationError("export_not_ready", "export has not completed", http.StatusConflict)
+	}
+	return job, us.jobStore.FilePath(job), nil
+}
+
+// writeUserExport streams the users matching the query into w in the given format, one batch at a time.
+// It returns the number of exported rows and calls progress, when set, after every batch.
+func (us *UserService) writeUserExport(ctx context.Context, w io.Writer, format string, query userModule.ExportUsersQuery, progress func(processed int64)) (int64, error) {
+	writer, err := export.NewWriter(format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	header := make([]string, len(userExportColumns))
+	for i, column := range userExportColumns {
+		header[i] = column.name
+	}
+	if err := writer.WriteHeader(header); err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	values := make([]interface{}, len(userExportColumns))
+	err = us.userRepository.WithContext(ctx).FindInBatches(us.userFilterScopes(query.UserFilters), exportBatchSize, func(users []repository.User) error {
+		for i := range users {
+			for j, column := range userExportColumns {
+				values[j] = column.value(&users[i])
+				if column.sensitive && !query.IncludePII {
+					values[j] = column.redact(values[j])
+				}
+			}
+			if err := writer.WriteRow(values); err != nil {
+				return err
+			}
+		}
+		rows += int64(len(users))
+		if progress != nil {
+			progress(rows)
+		}
+		return nil
+	})
+	if err != nil {
+		return rows, err
+	}
+	return rows, writer.Close()
+}
+
+// recordExportAudit records that the actor exported users. Exports including PII are worth auditing
+// as much as any other access to personal data.
+func (us *UserService) recordExportAudit(ctx context.Context, actorId string, format string, query userModule.ExportUsersQuery, rows int64, fileName string) {
+	details := map[string]interface{}{"format": format, "rows": rows, "includePii": query.IncludePII}
+	if fileName != "" {
+		details["fileName"] = fileName
+	}
+	if err := us.auditService.Record(ctx, audit.Entry{
+		Action:     "user.exported",
+		ActorId:    actorId,
+		TargetType: "user",
+		Details:    details,
+	}); err != nil {
+		logger.Error("service", "user_service", "recordExportAudit", "failed to record audit log", err)
+	}
+}
+
+// stringValue dereferences an optional string, mapping nil to nil rather than to an empty string.
+func stringValue(value *string) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+// dateValue exports an optional date as YYYY-MM-DD text, or nil when it is not set.
+func dateValue(value *date.Date) interface{} {
+	if value == nil {
+		return nil
+	}
+	return value.String()
+}
+
+// redactEmail keeps the first character of the local part and the domain, e.g. "j***@example.com".
+func redactEmail(value interface{}) interface{} {
+	email, ok := value.(string)
+	if !ok || email == "" {
+		return value
+	}
+	local, domain, found := strings.Cut(email, "@")
+	if !found || local == "" {
+		return "***"
+	}
+	return local[:1] + "***@" + domain
+}
+
+// redactMobile keeps the last four digits of a mobile number, e.g. "******7890".
+func redactMobile(value interface{}) interface{} {
+	mobile, ok := value.(string)
+	if !ok || mobile == "" {
+		return value
+	}
+	if len(mobile) <= 4 {
+		return strings.Repeat("*", len(mobile))
+	}
+	return strings.Repeat("*", len(mobile)-4) + mobile[len(mobile)-4:]
+}
+
+// redactAll hides a value entirely.
+func redactAll(value interface{}) interface{} {
+	return nil
+}
@@ -0,0 +1,119 @@
+package userService
+
+import (
+	"backendService/internals/common/jobs"
+	"backendService/internals/modules/userModule/userModule"
+	"backendService/internals/setup/config"
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForJob waits until the job of the owner is no longer running.
+func waitForJob(t *testing.T, us *UserService, ownerId string, jobId string, jobType string) *jobs.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, appErr := us.findOwnedJob(context.Background(), ownerId, jobId, jobType)
+		if appErr != nil {
+			t.Fatalf("getting job %s: %s", jobId, appErr.Message)
+		}
+		if job.Status != jobs.StatusRunning {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s did not complete in time", jobId)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestExportUsers(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	config.Config.Export.MaxSyncRows = 10
+	admin := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	mobile := "5551234567"
+	if _, appErr := us.CreateUser(ctx, userModule.CreateUserBody{FirstName: "Grace", LastName: "Hopper", Email: "grace@example.com", Password: "password123", Mobile: &mobile}); appErr != nil {
+		t.Fatalf("CreateUser: %s", appErr.Message)
+	}
+
+	for _, test := range []struct {
+		query     userModule.ExportUsersQuery
+		want      []string
+		forbidden []string
+	}{
+		{
+			query:     userModule.ExportUsersQuery{UserFilters: userModule.UserFilters{Name: "grace"}},
+			want:      []string{"userId,firstName,lastName,email,mobile,dob,", "Grace,Hopper,g***@example.com,******4567,,true"},
+			forbidden: []string{"Ada", "grace@example.com", mobile, "password"},
+		},
+		{
+			query:     userModule.ExportUsersQuery{IncludePII: true, UserFilters: userModule.UserFilters{Name: "grace"}},
+			want:      []string{"Grace,Hopper,grace@example.com,5551234567,,true"},
+			forbidden: []string{"password"},
+		},
+	} {
+		export, appErr := us.ExportUsers(ctx, admin.UserId.String(), test.query)
+		if appErr != nil {
+			t.Fatalf("ExportUsers: %s", appErr.Message)
+		}
+		if export.Job != nil || export.ContentType != "text/csv; charset=utf-8" || !strings.HasSuffix(export.FileName, ".csv") {
+			t.Fatalf("got export %+v, want a CSV file written right away", export)
+		}
+		var output bytes.Buffer
+		if appErr := export.Write(&output); appErr != nil {
+			t.Fatalf("writing the export: %s", appErr.Message)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(output.String(), want) {
+				t.Errorf("including PII %v: export is missing %q in\n%s", test.query.IncludePII, want, output.String())
+			}
+		}
+		for _, forbidden := range test.forbidden {
+			if strings.Contains(output.String(), forbidden) {
+				t.Errorf("including PII %v: export contains %q", test.query.IncludePII, forbidden)
+			}
+		}
+	}
+	if actions := auditActions(t, us, admin); len(actions) != 2 || actions[1] != "user.exported" {
+		t.Errorf("got audit log %v, want both exports recorded", actions)
+	}
+}
+
+func TestExportUsersInBackground(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	config.Config.Export.MaxSyncRows = 1
+	admin := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	createTestUser(t, us, "Grace", "Hopper", "grace@example.com")
+
+	export, appErr := us.ExportUsers(ctx, admin.UserId.String(), userModule.ExportUsersQuery{Format: "ndjson"})
+	if appErr != nil {
+		t.Fatalf("ExportUsers: %s", appErr.Message)
+	}
+	if export.Job == nil || export.Write != nil {
+		t.Fatalf("got export %+v, want a background job for more rows than MaxSyncRows", export)
+	}
+
+	// Only the actor who started the export sees it.
+	if _, appErr := us.GetExportJob(ctx, "someone-else", export.Job.ID); appErr == nil || appErr.ErrorCode != "export_not_found" {
+		t.Errorf("getting the export of someone else: got %v, want export_not_found", appErr)
+	}
+
+	waitForJob(t, us, admin.UserId.String(), export.Job.ID, JobTypeUserExport)
+	job, path, appErr := us.GetExportFile(ctx, admin.UserId.String(), export.Job.ID)
+	if appErr != nil {
+		t.Fatalf("GetExportFile: %s", appErr.Message)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading the export file: %v", err)
+	}
+	if job.Status != jobs.StatusCompleted || job.Processed != 2 || strings.Count(string(content), "\n") != 2 {
+		t.Errorf("got job %+v with file\n%s\nwant 2 exported users", job, content)
+	}
+}
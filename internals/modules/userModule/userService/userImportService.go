@@ -0,0 +1,452 @@
+package userService
+
+import (
+	"backendService/internals/common/audit"
+	"backendService/internals/common/auth"
+	"backendService/internals/common/date"
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/logger"
+	"backendService/internals/common/validation"
+	"backendService/internals/modules/userModule/userModule"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	// Supported import formats
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+
+	importBatchSize    = 500
+	importInsertSize   = 100 // users inserted per statement, keeping statements under bind variable limits
+	maxReportedErrors  = 1000
+	maxNDJSONLineBytes = 64 * 1024
+)
+
+// ImportRowError describes why a row of an import was rejected.
+// Row is the 1-based data row number, not counting the CSV header.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportReport summarizes the outcome of a bulk user import.
+// Only the first rejected rows are listed in Errors; Failed always holds the full count.
+type ImportReport struct {
+	DryRun    bool             `json:"dryRun"`
+	TotalRows int              `json:"totalRows"`
+	Imported  int              `json:"imported"`
+	Failed    int              `json:"failed"`
+	Errors    []ImportRowError `json:"errors"`
+	Truncated bool             `json:"truncated"`
+}
+
+// importRow is a parsed row waiting to be inserted with the rest of its batch.
+type importRow struct {
+	number int
+	body   userModule.CreateUserBody
+	dob    *date.Date
+}
+
+// rowReader reads import rows one at a time from an underlying stream.
+// Next returns io.EOF once every row has been read. A row that cannot be parsed is reported
+// through rowErr so the import can carry on with the next row.
+type rowReader interface {
+	Next() (body userModule.CreateUserBody, rowErr *ImportRowError, err error)
+}
+
+// ImportUsers creates users from a CSV or NDJSON stream on behalf of the actor.
+// Each row is validated with the CreateUserBody rules and checked for duplicate emails and mobiles,
+// both within the file and against existing users. Valid rows are inserted in batches, each inside
+// its own transaction. In dry-run mode rows are validated and checked but nothing is inserted.
+func (us *UserService) ImportUsers(ctx context.Context, actorId string, source io.Reader, format string, dryRun bool) (*ImportReport, *appError.ApplicationError) {
+	var reader rowReader
+	switch format {
+	case ImportFormatCSV:
+		csvReader, err := newCSVRowReader(source)
+		if err != nil {
+			return nil, appError.NewBadRequestError("invalid_file", err.Error())
+		}
+		reader = csvReader
+	case ImportFormatNDJSON:
+		reader = newNDJSONRowReader(source)
+	default:
+		return nil, appError.NewBadRequestError("invalid_format", "format must be csv or ndjson")
+	}
+
+	importer := &userImporter{
+		userRepository: us.userRepository.WithContext(ctx),
+		dryRun:         dryRun,
+		validate:       validation.New(),
+		seenEmails:     map[string]int{},
+		seenMobiles:    map[string]int{},
+		report:         &ImportReport{DryRun: dryRun, Errors: []ImportRowError{}},
+	}
+
+	for {
+		body, rowErr, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			logger.Error("service", "UserService", "ImportUsers", "failed to read import", err)
+			return nil, appError.NewBadRequestError("invalid_file", "failed to read file: "+err.Error())
+		}
+
+		importer.report.TotalRows++
+		if rowErr != nil {
+			rowErr.Row = importer.report.TotalRows
+			importer.reject(*rowErr)
+			continue
+		}
+		if appErr := importer.add(importRow{number: importer.report.TotalRows, body: body}); appErr != nil {
+			return nil, appErr
+		}
+	}
+	if appErr := importer.flush(); appErr != nil {
+		return nil, appErr
+	}
+	// Rows rejected while flushing a batch are reported after later parse errors, so restore the row order
+	sort.SliceStable(importer.report.Errors, func(i, j int) bool {
+		return importer.report.Errors[i].Row < importer.report.Errors[j].Row
+	})
+
+	us.auditService.Record(ctx, audit.Entry{
+		Action:     "user.imported",
+		ActorId:    actorId,
+		TargetType: "user",
+		Details: map[string]interface{}{
+			"format":    format,
+			"dryRun":    dryRun,
+			"totalRows": importer.report.TotalRows,
+			"imported":  importer.report.Imported,
+			"failed":    importer.report.Failed,
+		},
+	})
+	return importer.report, nil
+}
+
+// userImporter accumulates validated rows into batches and keeps track of the import report.
+type userImporter struct {
+	userRepository *repository.UserRepository // userRepository is bound to the context of the import
+	dryRun         bool
+	validate       *validator.Validate
+	seenEmails     map[string]int
+	seenMobiles    map[string]int
+	batch          []importRow
+	report         *ImportReport
+}
+
+// add validates a row and queues it for insertion, flushing the batch once it is full.
+func (ui *userImporter) add(row importRow) *appError.ApplicationError {
+	if err := ui.validate.Struct(row.body); err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			rowErrors := make([]ImportRowError, len(validationErrors))
+			for i, fieldError := range validationErrors {
+				rowErrors[i] = ImportRowError{Row: row.number, Field: fieldError.Field(), Message: validationMessage(fieldError)}
+			}
+			ui.reject(rowErrors...)
+			return nil
+		}
+		ui.reject(ImportRowError{Row: row.number, Message: err.Error()})
+		return nil
+	}
+	if row.body.DOB != nil {
+		dob, _ := date.Parse(*row.body.DOB)
+		if message := checkDateOfBirth(dob); message != "" {
+			ui.reject(ImportRowError{Row: row.number, Field: "DOB", Message: message})
+			return nil
+		}
+		row.dob = &dob
+	}
+
+	email := row.body.Email
+	if firstRow, ok := ui.seenEmails[email]; ok {
+		ui.reject(ImportRowError{Row: row.number, Field: "Email", Message: fmt.Sprintf("email duplicates row %d", firstRow)})
+		return nil
+	}
+	if row.body.Mobile != nil {
+		if firstRow, ok := ui.seenMobiles[*row.body.Mobile]; ok {
+			ui.reject(ImportRowError{Row: row.number, Field: "Mobile", Message: fmt.Sprintf("mobile duplicates row %d", firstRow)})
+			return nil
+		}
+		ui.seenMobiles[*row.body.Mobile] = row.number
+	}
+	ui.seenEmails[email] = row.number
+
+	ui.batch = append(ui.batch, row)
+	if len(ui.batch) >= importBatchSize {
+		return ui.flush()
+	}
+	return nil
+}
+
+// flush checks the queued rows against existing users with a single query per column
+// and inserts the remaining rows in one transaction.
+func (ui *userImporter) flush() *appError.ApplicationError {
+	if len(ui.batch) == 0 {
+		return nil
+	}
+	batch := ui.batch
+	ui.batch = nil
+
+	emails := make([]string, 0, len(batch))
+	mobiles := make([]string, 0, len(batch))
+	for _, row := range batch {
+		emails = append(emails, row.body.Email)
+		if row.body.Mobile != nil {
+			mobiles = append(mobiles, *row.body.Mobile)
+		}
+	}
+	existingEmails, err := ui.userRepository.FindExistingValues("email", emails)
+	if err != nil {
+		return appError.NewApplicationError("internal_error", "failed to find users")
+	}
+	existingMobiles, err := ui.userRepository.FindExistingValues("mobile", mobiles)
+	if err != nil {
+		return appError.NewApplicationError("internal_error", "failed to find users")
+	}
+
+	users := make([]repository.User, 0, len(batch))
+	rows := make([]importRow, 0, len(batch))
+	for _, row := range batch {
+		if existingEmails[row.body.Email] {
+			ui.reject(ImportRowError{Row: row.number, Field: "Email", Message: "user with this email already exists"})
+			continue
+		}
+		if row.body.Mobile != nil && existingMobiles[*row.body.Mobile] {
+			ui.reject(ImportRowError{Row: row.number, Field: "Mobile", Message: "user with this mobile already exists"})
+			continue
+		}
+
+		email, password := row.body.Email, row.body.Password
+		users = append(users, repository.User{
+			UserId:    ulid.Make(),
+			FirstName: row.body.FirstName,
+			LastName:  row.body.LastName,
+			Email:     &email,
+			Password:  &password,
+			DOB:       row.dob,
+			Mobile:    row.body.Mobile,
+			IsActive:  true,
+		})
+		rows = append(rows, row)
+	}
+
+	if ui.dryRun || len(users) == 0 {
+		ui.report.Imported += len(users)
+		return nil
+	}
+
+	// A password that cannot be hashed only rejects its own row
+	hashErrs := hashPasswords(users)
+	hashed, hashedRows := users[:0], rows[:0]
+	for i, err := range hashErrs {
+		if err != nil {
+			logger.Error("service", "UserService", "ImportUsers", "failed to hash password", err)
+			ui.reject(ImportRowError{Row: rows[i].number, Field: "Password", Message: "failed to hash the password"})
+			continue
+		}
+		hashed = append(hashed, users[i])
+		hashedRows = append(hashedRows, rows[i])
+	}
+	users, rows = hashed, hashedRows
+	if len(users) == 0 {
+		return nil
+	}
+
+	if err := ui.userRepository.CreateMany(users, importInsertSize); err != nil {
+		logger.Error("service", "UserService", "ImportUsers", "failed to insert batch", err)
+		for _, row := range rows {
+			ui.reject(ImportRowError{Row: row.number, Message: "failed to insert the batch containing this row"})
+		}
+		return nil
+	}
+	ui.report.Imported += len(users)
+	return nil
+}
+
+// hashPasswords replaces the passwords of users with their hashes. Hashing is deliberately slow,
+// so the passwords of a batch are hashed on every CPU. The returned slice holds, at the index of
+// each user, the error hashing their password, or nil when it was hashed.
+func hashPasswords(users []repository.User) []error {
+	errs := make([]error, len(users))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < runtime.NumCPU(); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				hash, err := auth.HashPassword(*users[i].Password)
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				users[i].Password = &hash
+			}
+		}()
+	}
+	for i := range users {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return errs
+}
+
+// reject records a rejected row with its errors, keeping at most maxReportedErrors errors in the report.
+func (ui *userImporter) reject(rowErrors ...ImportRowError) {
+	ui.report.Failed++
+	for _, rowErr := range rowErrors {
+		if len(ui.report.Errors) >= maxReportedErrors {
+			ui.report.Truncated = true
+			return
+		}
+		ui.report.Errors = append(ui.report.Errors, rowErr)
+	}
+}
+
+// validationMessage describes a failed validation rule of an import row.
+func validationMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return fieldError.Field() + " is required"
+	case "email":
+		return fieldError.Field() + " must be a valid email address"
+	case "min", "max":
+		return fmt.Sprintf("%s must satisfy %s=%s", fieldError.Field(), fieldError.Tag(), fieldError.Param())
+	case "maxbytes":
+		return fmt.Sprintf("%s must not exceed %s bytes", fieldError.Field(), fieldError.Param())
+	case "datetime":
+		return fieldError.Field() + " must be a date in YYYY-MM-DD format"
+	default:
+		return fieldError.Field() + " is invalid"
+	}
+}
+
+// csvRowReader reads import rows from CSV. The first record is a header naming the columns,
+// using the JSON field names of CreateUserBody; columns may appear in any order.
+type csvRowReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+// newCSVRowReader reads the header of the CSV stream and returns a reader for the remaining rows.
+func newCSVRowReader(source io.Reader) (*csvRowReader, error) {
+	reader := csv.NewReader(source)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.New("missing CSV header")
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, required := range []string{"firstName", "lastName", "email", "password"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing CSV column: %s", required)
+		}
+	}
+	return &csvRowReader{reader: reader, columns: columns}, nil
+}
+
+func (cr *csvRowReader) Next() (userModule.CreateUserBody, *ImportRowError, error) {
+	var body userModule.CreateUserBody
+	record, err := cr.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return body, &ImportRowError{Message: parseErr.Err.Error()}, nil
+		}
+		return body, nil, err
+	}
+
+	value := func(column string) string {
+		index, ok := cr.columns[column]
+		if !ok || index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[index])
+	}
+
+	body.FirstName = value("firstName")
+	body.LastName = value("lastName")
+	body.Email = value("email")
+	body.Password = value("password")
+	if mobile := value("mobile"); mobile != "" {
+		body.Mobile = &mobile
+	}
+	if dob := value("dob"); dob != "" {
+		parsed, err := parseImportDate(dob)
+		if err != nil {
+			return body, &ImportRowError{Field: "DOB", Message: "dob must be a date in YYYY-MM-DD or RFC 3339 format"}, nil
+		}
+		formatted := parsed.String()
+		body.DOB = &formatted
+	}
+	return body, nil, nil
+}
+
+// parseImportDate parses a date written either as YYYY-MM-DD or as an RFC 3339 timestamp.
+// The date of a timestamp is the one in its own offset, so that it is not shifted to another day.
+func parseImportDate(value string) (date.Date, error) {
+	if parsed, err := date.Parse(value); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return date.Date{}, err
+	}
+	return date.Of(parsed), nil
+}
+
+// ndjsonRowReader reads import rows from newline delimited JSON, one CreateUserBody object per line.
+// Blank lines are skipped.
+type ndjsonRowReader struct {
+	scanner *bufio.Scanner
+}
+
+func newNDJSONRowReader(source io.Reader) *ndjsonRowReader {
+	scanner := bufio.NewScanner(source)
+	scanner.Buffer(make([]byte, 0, 4096), maxNDJSONLineBytes)
+	return &ndjsonRowReader{scanner: scanner}
+}
+
+func (nr *ndjsonRowReader) Next() (userModule.CreateUserBody, *ImportRowError, error) {
+	var body userModule.CreateUserBody
+	for nr.scanner.Scan() {
+		line := bytes.TrimSpace(nr.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(line, &body); err != nil {
+			return body, &ImportRowError{Message: "invalid JSON: " + err.Error()}, nil
+		}
+		return body, nil, nil
+	}
+	if err := nr.scanner.Err(); err != nil {
+		return body, nil, err
+	}
+	return body, nil, io.EOF
+}
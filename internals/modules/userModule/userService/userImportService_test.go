@@ -0,0 +1,115 @@
+package userService
+
+import (
+	baseRepository "backendService/internals/common/repository"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestImportUsersCSV(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	admin := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+
+	source := strings.Join([]string{
+		"email,firstName,lastName,password,mobile,dob",
+		"grace@example.com,Grace,Hopper,password123,5550000001,1906-12-09",
+		"alan@example.com,Alan,Turing,password123,5550000002,1912-06-23T23:30:00-05:00",
+		"grace@example.com,Grace,Duplicate,password123,,",
+		"ada@example.com,Ada,Existing,password123,,",
+		"katherine@example.com,Katherine,Johnson,password123,5550000001,",
+		"not-an-email,Bad,Email,password123,,",
+		"edsger@example.com,Edsger,Dijkstra,password123,,30/05/1930",
+	}, "\n")
+
+	report, appErr := us.ImportUsers(ctx, admin.UserId.String(), strings.NewReader(source), ImportFormatCSV, false)
+	if appErr != nil {
+		t.Fatalf("ImportUsers: %s", appErr.Message)
+	}
+	if report.TotalRows != 7 || report.Imported != 2 || report.Failed != 5 {
+		t.Errorf("got %d rows, %d imported, %d failed, want 7 rows, 2 imported, 5 failed", report.TotalRows, report.Imported, report.Failed)
+	}
+	wantErrors := []struct {
+		row   int
+		field string
+	}{{3, "Email"}, {4, "Email"}, {5, "Mobile"}, {6, "Email"}, {7, "DOB"}}
+	if len(report.Errors) != len(wantErrors) {
+		t.Fatalf("got errors %+v, want %d errors", report.Errors, len(wantErrors))
+	}
+	for i, want := range wantErrors {
+		if got := report.Errors[i]; got.Row != want.row || got.Field != want.field {
+			t.Errorf("error %d: got row %d field %s, want row %d field %s", i, got.Row, got.Field, want.row, want.field)
+		}
+	}
+
+	alan, err := us.userRepository.FindOneBy(baseRepository.Eq("email", "alan@example.com"))
+	if err != nil || alan == nil {
+		t.Fatalf("finding an imported user: %v, %v", alan, err)
+	}
+	if alan.DOB == nil || alan.DOB.String() != "1912-06-23" {
+		t.Errorf("got DOB %v, want the date of the timestamp in its own offset", alan.DOB)
+	}
+	if alan.Password == nil || !strings.HasPrefix(*alan.Password, "$2") {
+		t.Errorf("got password %v, want a bcrypt hash", alan.Password)
+	}
+}
+
+func TestImportUsersRejectsUnhashablePasswords(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+
+	// A password longer than bcrypt accepts only rejects the row holding it
+	source := strings.Join([]string{
+		"email,firstName,lastName,password",
+		"grace@example.com,Grace,Hopper,password123",
+		"alan@example.com,Alan,Turing," + strings.Repeat("p", 80),
+		"edsger@example.com,Edsger,Dijkstra,password123",
+	}, "\n")
+	report, appErr := us.ImportUsers(ctx, "", strings.NewReader(source), ImportFormatCSV, false)
+	if appErr != nil {
+		t.Fatalf("ImportUsers: %s", appErr.Message)
+	}
+	if report.Imported != 2 || report.Failed != 1 || len(report.Errors) != 1 || report.Errors[0].Row != 2 || report.Errors[0].Field != "Password" {
+		t.Errorf("got %d imported, %d failed, errors %+v, want row 2 rejected for its password", report.Imported, report.Failed, report.Errors)
+	}
+	if count, err := us.userRepository.Count(); err != nil || count != 2 {
+		t.Errorf("got %d users, %v, want the 2 other rows imported", count, err)
+	}
+}
+
+func TestImportUsersDryRun(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	admin := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+
+	source := strings.Join([]string{
+		`{"email":"grace@example.com","firstName":"Grace","lastName":"Hopper","password":"password123"}`,
+		``,
+		`{"email":"ada@example.com","firstName":"Ada","lastName":"Existing","password":"password123"}`,
+		`{"email":`,
+	}, "\n")
+
+	report, appErr := us.ImportUsers(ctx, admin.UserId.String(), strings.NewReader(source), ImportFormatNDJSON, true)
+	if appErr != nil {
+		t.Fatalf("ImportUsers: %s", appErr.Message)
+	}
+	if !report.DryRun || report.TotalRows != 3 || report.Imported != 1 || report.Failed != 2 {
+		t.Errorf("got dry run %v, %d rows, %d imported, %d failed, want a dry run of 3 rows, 1 imported, 2 failed", report.DryRun, report.TotalRows, report.Imported, report.Failed)
+	}
+	if count, err := us.userRepository.Count(); err != nil || count != 1 {
+		t.Errorf("got %d users, %v, want only the existing user after a dry run", count, err)
+	}
+}
+
+func TestImportUsersRejectsInvalidFiles(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+
+	if _, appErr := us.ImportUsers(ctx, "", strings.NewReader(""), "xml", false); appErr == nil || appErr.ErrorCode != "invalid_format" {
+		t.Errorf("importing xml: got %v, want invalid_format", appErr)
+	}
+	if _, appErr := us.ImportUsers(ctx, "", strings.NewReader("email,firstName,lastName\n"), ImportFormatCSV, false); appErr == nil || appErr.ErrorCode != "invalid_file" {
+		t.Errorf("importing CSV without a password column: got %v, want invalid_file", appErr)
+	}
+}
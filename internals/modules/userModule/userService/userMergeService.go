@@ -0,0 +1,376 @@
+package userService
+
+import (
+	"backendService/internals/common/audit"
+	"backendService/internals/common/auth"
+	"backendService/internals/common/cache"
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/logger"
+	"backendService/internals/common/notification"
+	"backendService/internals/common/privacy"
+	"backendService/internals/modules/userModule/userModule"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"context"
+	"crypto/hmac"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+const userMergeKeyPrefix = "user_merge:"
+
+// MergeRequest describes an account merge awaiting verification with the code sent to the other account.
+type MergeRequest struct {
+	Kind      string    `json:"kind"`
+	Value     string    `json:"value"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// pendingMerge is the state of a self-service merge kept in the cache until it is verified.
+type pendingMerge struct {
+	MergedUserId string    `json:"mergedUserId"`
+	Kind         string    `json:"kind"`
+	Value        string    `json:"value"`
+	CodeHash     string    `json:"codeHash"`
+	Attempts     int       `json:"attempts"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// mergeHandler moves the records a module holds about merged users.
+type mergeHandler struct {
+	name string
+	fn   repository.MergeFunc
+}
+
+// UserMergeService merges duplicate accounts of the same person, such as one created by signing in with
+// an email and another by signing in with a mobile. The surviving account takes over whatever it lacks
+// from the merged account, along with the records other modules hold about it, and the merged account
+// is soft-deleted. Signing in with a contact the merged account kept leads to the surviving account.
+type UserMergeService struct {
+	userRepository         *repository.UserRepository
+	userSettingsRepository *repository.UserSettingsRepository
+	sessionStore           *auth.SessionStore
+	cacheService           *cache.CacheService
+	notifier               notification.Notifier
+	auditService           *audit.AuditService
+	handlers               []mergeHandler
+}
+
+// NewUserMergeService creates a new instance of UserMergeService.
+func NewUserMergeService(userRepository *repository.UserRepository, userSettingsRepository *repository.UserSettingsRepository, sessionStore *auth.SessionStore, cacheService *cache.CacheService, notifier notification.Notifier, auditService *audit.AuditService) *UserMergeService {
+	return &UserMergeService{
+		userRepository:         userRepository,
+		userSettingsRepository: userSettingsRepository,
+		sessionStore:           sessionStore,
+		cacheService:           cacheService,
+		notifier:               notifier,
+		auditService:           auditService,
+	}
+}
+
+// RegisterMergeHandler registers a function moving the records a module holds about a merged user to the
+// surviving user. Handlers run in registration order within the merge transaction, and a failing handler
+// cancels the whole merge.
+func (ums *UserMergeService) RegisterMergeHandler(name string, fn repository.MergeFunc) {
+	ums.handlers = append(ums.handlers, mergeHandler{name: name, fn: fn})
+}
+
+// RequestMerge starts merging the account using the given email or mobile into the account of the user
+// identified by id, and sends a verification code to that email or mobile to prove it belongs to the same person.
+// Requesting again replaces the pending merge.
+func (ums *UserMergeService) RequestMerge(ctx context.Context, id string, body userModule.MergeAccountBody) (*MergeRequest, *appError.ApplicationError) {
+	if (body.Email == nil) == (body.Mobile == nil) {
+		return nil, appError.NewBadRequestError("missing_data", "either email or mobile is required")
+	}
+	survivor, appErr := findUserByPublicID(ums.userRepository.WithContext(ctx), id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	kind, value := repository.ContactMobile, body.Mobile
+	if body.Email != nil {
+		kind, value = repository.ContactEmail, body.Email
+	}
+
+	merged, err := ums.userRepository.WithContext(ctx).FindByUnique(kind, *value)
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to find user")
+	}
+	if merged == nil {
+		return nil, appError.NewNotFoundError("user_not_found", "no account uses this "+kind)
+	}
+	if appErr := checkMergeable(survivor, merged); appErr != nil {
+		return nil, appErr
+	}
+
+	previous, appErr := ums.findPendingMerge(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if previous != nil && time.Since(previous.CreatedAt) < contactChangeResendInterval {
+		return nil, appError.NewApplicationError("too_many_requests", "please wait before requesting another code", http.StatusTooManyRequests)
+	}
+
+	code, err := generateVerificationCode()
+	if err != nil {
+		return nil, appError.NewInternalServerError("failed to generate verification code", err)
+	}
+	now := time.Now()
+	pending := &pendingMerge{
+		MergedUserId: merged.UserId.String(),
+		Kind:         kind,
+		Value:        *value,
+		CodeHash:     hashVerificationCode(survivor.ID, "merge:"+kind, *value, code),
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(contactChangeTTL()),
+	}
+	if err := ums.cacheService.Set(ctx, userMergeKeyPrefix+id, pending, contactChangeTTL()); err != nil {
+		logger.Error("service", "user_merge_service", "RequestMerge", "failed to save pending merge", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to save pending merge")
+	}
+
+	message := "Your code to merge this account into another account is " + code + ". It expires in " +
+		fmt.Sprint(contactChangeTTL().Round(time.Minute)) + ". If you did not ask for this, please ignore this message."
+	if err := ums.notifier.Notify(ctx, *value, message); err != nil {
+		logger.Error("service", "user_merge_service", "RequestMerge", "failed to send verification code", err)
+		return nil, appError.NewBadRequestError("failed_to_send_otp", "failed to send verification code")
+	}
+
+	return &MergeRequest{Kind: kind, Value: *value, ExpiresAt: pending.ExpiresAt}, nil
+}
+
+// VerifyMerge completes the pending merge of the user identified by id with the code sent to the other account.
+// After too many wrong codes the pending merge is dropped and must be requested again.
+func (ums *UserMergeService) VerifyMerge(ctx context.Context, id string, body userModule.VerifyMergeBody) (*repository.User, *appError.ApplicationError) {
+	survivor, appErr := findUserByPublicID(ums.userRepository.WithContext(ctx), id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	pending, appErr := ums.findPendingMerge(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if pending == nil || time.Now().After(pending.ExpiresAt) {
+		return nil, appError.NewBadRequestError("otp_not_found", "no pending merge, please request a new code")
+	}
+
+	expected := hashVerificationCode(survivor.ID, "merge:"+pending.Kind, pending.Value, body.OTP)
+	if !hmac.Equal([]byte(expected), []byte(pending.CodeHash)) {
+		pending.Attempts++
+		if pending.Attempts >= maxContactChangeAttempts {
+			ums.dropPendingMerge(ctx, id)
+			return nil, appError.NewBadRequestError("otp_attempts_exceeded", "too many incorrect codes, please request a new code")
+		}
+		if err := ums.cacheService.Set(ctx, userMergeKeyPrefix+id, pending, time.Until(pending.ExpiresAt)); err != nil {
+			logger.Error("service", "user_merge_service", "VerifyMerge", "failed to record attempt", err)
+		}
+		return nil, appError.NewBadRequestError("otp_incorrect", "OTP is incorrect")
+	}
+	ums.dropPendingMerge(ctx, id)
+
+	merged, appErr := findUserByPublicID(ums.userRepository.WithContext(ctx), pending.MergedUserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	// The other account may have changed its contact since the code was sent
+	current := merged.Mobile
+	if pending.Kind == repository.ContactEmail {
+		current = merged.Email
+	}
+	if current == nil || *current != pending.Value {
+		return nil, appError.NewApplicationError("merge_conflict", "the other account changed in the meantime, please request a new code", http.StatusConflict)
+	}
+
+	return ums.merge(ctx, id, survivor, merged, map[string]interface{}{"method": "self_service", "verifiedBy": pending.Kind})
+}
+
+// CancelMerge drops the pending merge of the user identified by id.
+func (ums *UserMergeService) CancelMerge(ctx context.Context, id string) *appError.ApplicationError {
+	pending, appErr := ums.findPendingMerge(ctx, id)
+	if appErr != nil {
+		return appErr
+	}
+	if pending == nil {
+		return appError.NewNotFoundError("merge_not_found", "no pending merge")
+	}
+	ums.dropPendingMerge(ctx, id)
+	return nil
+}
+
+// MergeUsers merges the account in the body into the user identified by id on behalf of an admin, who is
+// trusted to have confirmed that both accounts belong to the same person. The reason is kept in the audit log.
+func (ums *UserMergeService) MergeUsers(ctx context.Context, actorId string, id string, body userModule.AdminMergeBody) (*repository.User, *appError.ApplicationError) {
+	survivor, appErr := findUserByPublicID(ums.userRepository.WithContext(ctx), id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	merged, appErr := findUserByPublicID(ums.userRepository.WithContext(ctx), body.UserId)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if appErr := checkMergeable(survivor, merged); appErr != nil {
+		return nil, appErr
+	}
+	return ums.merge(ctx, actorId, survivor, merged, map[string]interface{}{"method": "admin", "reason": body.Reason})
+}
+
+// merge merges the merged user into the surviving user, signs the merged user out and records the merge.
+func (ums *UserMergeService) merge(ctx context.Context, actorId string, survivor *repository.User, merged *repository.User, details map[string]interface{}) (*repository.User, *appError.ApplicationError) {
+	updates := mergedFields(survivor, merged)
+	fields := make([]string, 0, len(updates))
+	for column := range updates {
+		fields = append(fields, column)
+	}
+	sort.Strings(fields)
+
+	fns := []repository.MergeFunc{ums.userSettingsRepository.WithContext(ctx).MergeUsers}
+	for _, handler := range ums.handlers {
+		handler := handler
+		fns = append(fns, func(tx *gorm.DB, survivorID uint64, mergedID uint64) error {
+			if err := handler.fn(tx, survivorID, mergedID); err != nil {
+				return fmt.Errorf("%s: %w", handler.name, err)
+			}
+			return nil
+		})
+	}
+	if err := ums.userRepository.WithContext(ctx).MergeUsers(survivor, merged, updates, fns...); err != nil {
+		if err == repository.ErrMergeConflict {
+			return nil, appError.NewApplicationError("merge_conflict", "one of the accounts was deleted or merged in the meantime", http.StatusConflict)
+		}
+		logger.Error("service", "user_merge_service", "merge", "failed to merge users", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to merge users")
+	}
+
+	if err := ums.sessionStore.RevokeAll(ctx, merged.UserId.String()); err != nil {
+		logger.Error("service", "user_merge_service", "merge", "failed to revoke sessions", err)
+	}
+	for _, user := range []*repository.User{survivor, merged} {
+		if err := ums.cacheService.Delete(context.WithoutCancel(ctx), userSettingsKeyPrefix+user.UserId.String()); err != nil {
+			logger.Error("service", "user_merge_service", "merge", "failed to invalidate cached settings", err)
+		}
+	}
+
+	survivorDetails := map[string]interface{}{"mergedUserId": merged.UserId.String(), "fields": fields}
+	mergedDetails := map[string]interface{}{"survivorUserId": survivor.UserId.String()}
+	for key, value := range details {
+		survivorDetails[key] = value
+		mergedDetails[key] = value
+	}
+	ums.recordAudit(ctx, "user.merged", actorId, survivor, survivorDetails)
+	ums.recordAudit(ctx, "user.merged_into", actorId, merged, mergedDetails)
+
+	return findUserByPublicID(ums.userRepository.WithContext(ctx), survivor.UserId.String())
+}
+
+// RegisterPersonalData registers the accounts merged into a user with the personal data registry.
+// Erasing a user also erases the accounts merged into them, as they may still hold contacts of the user.
+func (ums *UserMergeService) RegisterPersonalData(registry *privacy.Registry) {
+	registry.RegisterSection("merged_accounts", func(ctx context.Context, subject privacy.Subject) (interface{}, error) {
+		users, err := ums.userRepository.WithContext(ctx).FindMergedInto(subject.ID)
+		if err != nil || len(users) == 0 {
+			return nil, err
+		}
+		for i := range users {
+			users[i].Password = nil
+		}
+		return users, nil
+	})
+	registry.RegisterEraser("merged_accounts", func(ctx context.Context, subject privacy.Subject) error {
+		users, err := ums.userRepository.WithContext(ctx).FindMergedInto(subject.ID)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if _, err := ums.userRepository.WithContext(ctx).Anonymize(user.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkMergeable returns an error unless the merged user can be merged into the surviving user.
+// Staff accounts are never merged, so that merging cannot be used to gain or lose privileges.
+func checkMergeable(survivor *repository.User, merged *repository.User) *appError.ApplicationError {
+	if survivor.ID == merged.ID {
+		return appError.NewBadRequestError("invalid_action", "an account cannot be merged into itself")
+	}
+	if merged.Role != repository.RoleUser || survivor.Role != repository.RoleUser {
+		return appError.NewApplicationError("merge_not_allowed", "staff accounts cannot be merged", http.StatusConflict)
+	}
+	return nil
+}
+
+// mergedFields returns the columns the surviving user takes over from the merged user: whatever the
+// surviving user lacks. Contacts keep their verification status, and the avatar moves as a whole.
+func mergedFields(survivor *repository.User, merged *repository.User) Filter {
+	updates := Filter{}
+	if survivor.Email == nil && merged.Email != nil {
+		updates["email"] = *merged.Email
+		updates["is_email_verified"] = merged.IsEmailVerified
+		updates["email_verified_at"] = merged.EmailVerifiedAt
+	}
+	if survivor.Mobile == nil && merged.Mobile != nil {
+		updates["mobile"] = *merged.Mobile
+		updates["is_mobile_verified"] = merged.IsMobileVerified
+	}
+	if survivor.Username == nil && merged.Username != nil {
+		updates["username"] = *merged.Username
+	}
+	if survivor.Password == nil && merged.Password != nil {
+		updates["password"] = *merged.Password
+	}
+	if survivor.DOB == nil && merged.DOB != nil {
+		updates["dob"] = *merged.DOB
+	}
+	if survivor.FirstName == "" && merged.FirstName != "" {
+		updates["first_name"] = merged.FirstName
+	}
+	if survivor.LastName == "" && merged.LastName != "" {
+		updates["last_name"] = merged.LastName
+	}
+	if survivor.AvatarKey == nil && merged.AvatarKey != nil {
+		updates["avatar_key"] = *merged.AvatarKey
+		updates["avatar_url"] = merged.AvatarURL
+		updates["avatar_thumbnail_url"] = merged.AvatarThumbnailURL
+	}
+	return updates
+}
+
+// findPendingMerge returns the pending merge of the user identified by id, or nil when there is none.
+func (ums *UserMergeService) findPendingMerge(ctx context.Context, id string) (*pendingMerge, *appError.ApplicationError) {
+	var pending pendingMerge
+	err := ums.cacheService.Get(ctx, userMergeKeyPrefix+id, &pending)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		logger.Error("service", "user_merge_service", "findPendingMerge", "failed to read pending merge", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve pending merge")
+	}
+	return &pending, nil
+}
+
+// dropPendingMerge deletes the pending merge of the user identified by id.
+func (ums *UserMergeService) dropPendingMerge(ctx context.Context, id string) {
+	if err := ums.cacheService.Delete(ctx, userMergeKeyPrefix+id); err != nil {
+		logger.Error("service", "user_merge_service", "dropPendingMerge", "failed to drop pending merge", err)
+	}
+}
+
+// recordAudit records an action on a user in the audit log.
+func (ums *UserMergeService) recordAudit(ctx context.Context, action string, actorId string, user *repository.User, details map[string]interface{}) {
+	if err := ums.auditService.Record(ctx, audit.Entry{
+		Action:     action,
+		ActorId:    actorId,
+		TargetType: "user",
+		TargetId:   user.UserId.String(),
+		Details:    details,
+	}); err != nil {
+		logger.Error("service", "user_merge_service", "recordAudit", "failed to record audit log", err)
+	}
+}
@@ -0,0 +1,182 @@
+package userService
+
+import (
+	"backendService/internals/common/auth"
+	"backendService/internals/modules/userModule/userModule"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"context"
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newTestUserMergeService(t *testing.T) (*UserMergeService, *recordingNotifier, *testEnvironment) {
+	t.Helper()
+	env := newTestEnvironment(t)
+	notifier := newRecordingNotifier()
+	ums := NewUserMergeService(env.userRepository, repository.NewUserSettingsRepository(env.db), auth.NewSessionStore(env.cacheService), env.cacheService, notifier, env.auditService)
+	return ums, notifier, env
+}
+
+// createDuplicateUser creates a second account of Ada, signed up with another email and a verified mobile.
+func createDuplicateUser(t *testing.T, us *UserService) *repository.User {
+	t.Helper()
+	user, appErr := us.CreateUser(context.Background(), userModule.CreateUserBody{
+		FirstName: "Ada",
+		LastName:  "Byron",
+		Email:     "countess@example.com",
+		Password:  "correct horse battery staple",
+		Mobile:    stringPointer("5550100000"),
+	})
+	if appErr != nil {
+		t.Fatalf("creating the duplicate user: %s", appErr.Message)
+	}
+	if err := us.userRepository.Update(Filter{"id": user.ID}, Filter{"is_mobile_verified": true}); err != nil {
+		t.Fatalf("verifying the mobile: %v", err)
+	}
+	user.IsMobileVerified = true
+	return user
+}
+
+func TestVerifyMerge(t *testing.T) {
+	ums, notifier, env := newTestUserMergeService(t)
+	ctx := context.Background()
+	survivor := createTestUser(t, env.userService, "Ada", "Lovelace", "ada@example.com")
+	merged := createDuplicateUser(t, env.userService)
+	id := survivor.UserId.String()
+	sessions := auth.NewSessionStore(env.cacheService)
+	if _, _, err := sessions.Create(ctx, merged.UserId.String(), merged.Role); err != nil {
+		t.Fatalf("signing in the duplicate user: %v", err)
+	}
+	var handled []uint64
+	ums.RegisterMergeHandler("test", func(tx *gorm.DB, survivorID uint64, mergedID uint64) error {
+		handled = append(handled, survivorID, mergedID)
+		return nil
+	})
+
+	request, appErr := ums.RequestMerge(ctx, id, userModule.MergeAccountBody{Mobile: merged.Mobile})
+	if appErr != nil {
+		t.Fatalf("RequestMerge: %s", appErr.Message)
+	}
+	if request.Kind != repository.ContactMobile || request.Value != "5550100000" {
+		t.Errorf("got request %+v", request)
+	}
+	code := notifier.sentCode(t, "5550100000")
+	if _, appErr := ums.VerifyMerge(ctx, id, userModule.VerifyMergeBody{OTP: wrongCode(code)}); appErr == nil || appErr.ErrorCode != "otp_incorrect" {
+		t.Errorf("verifying a wrong code: got %v, want otp_incorrect", appErr)
+	}
+
+	user, appErr := ums.VerifyMerge(ctx, id, userModule.VerifyMergeBody{OTP: code})
+	if appErr != nil {
+		t.Fatalf("VerifyMerge: %s", appErr.Message)
+	}
+	// The surviving user takes over what it lacked, and keeps the rest
+	if user.Mobile == nil || *user.Mobile != "5550100000" || !user.IsMobileVerified || *user.Email != "ada@example.com" || user.LastName != "Lovelace" {
+		t.Errorf("got %+v, want ada@example.com with the verified mobile of the duplicate", user)
+	}
+	if len(handled) != 2 || handled[0] != survivor.ID || handled[1] != merged.ID {
+		t.Errorf("got handler called with %v, want the survivor and merged IDs", handled)
+	}
+
+	var stored repository.User
+	if err := env.db.Unscoped().First(&stored, merged.ID).Error; err != nil {
+		t.Fatalf("reading the merged user: %v", err)
+	}
+	if !stored.IsDeleted || stored.MergedIntoID == nil || *stored.MergedIntoID != survivor.ID || stored.Mobile != nil || *stored.Email != "countess@example.com" {
+		t.Errorf("got merged user %+v, want it deleted, merged into the survivor and without the mobile", stored)
+	}
+	if list, err := sessions.List(ctx, merged.UserId.String()); err != nil || len(list) != 0 {
+		t.Errorf("got %d sessions of the merged user, %v, want none", len(list), err)
+	}
+	if actions := auditActions(t, env.userService, merged); len(actions) != 1 || actions[0] != "user.merged_into" {
+		t.Errorf("got audit actions %v on the merged user, want user.merged_into", actions)
+	}
+	if _, appErr := ums.VerifyMerge(ctx, id, userModule.VerifyMergeBody{OTP: code}); appErr == nil || appErr.ErrorCode != "otp_not_found" {
+		t.Errorf("verifying twice: got %v, want otp_not_found", appErr)
+	}
+}
+
+func TestVerifyMergeOfChangedAccount(t *testing.T) {
+	ums, notifier, env := newTestUserMergeService(t)
+	ctx := context.Background()
+	survivor := createTestUser(t, env.userService, "Ada", "Lovelace", "ada@example.com")
+	merged := createDuplicateUser(t, env.userService)
+	id := survivor.UserId.String()
+
+	if _, appErr := ums.RequestMerge(ctx, id, userModule.MergeAccountBody{Email: merged.Email}); appErr != nil {
+		t.Fatalf("RequestMerge: %s", appErr.Message)
+	}
+	if _, appErr := ums.RequestMerge(ctx, id, userModule.MergeAccountBody{Email: merged.Email}); appErr == nil || appErr.ErrorCode != "too_many_requests" {
+		t.Errorf("requesting again right away: got %v, want too_many_requests", appErr)
+	}
+	code := notifier.sentCode(t, "countess@example.com")
+
+	if err := env.userRepository.Update(Filter{"id": merged.ID}, Filter{"email": "byron@example.com"}); err != nil {
+		t.Fatalf("changing the email of the duplicate: %v", err)
+	}
+	if _, appErr := ums.VerifyMerge(ctx, id, userModule.VerifyMergeBody{OTP: code}); appErr == nil || appErr.ErrorCode != "merge_conflict" {
+		t.Errorf("verifying after the email changed: got %v, want merge_conflict", appErr)
+	}
+	if found, appErr := env.userService.GetUserByID(ctx, merged.UserId.String()); appErr != nil || found.IsDeleted {
+		t.Errorf("got %+v, %v, want the duplicate left alone", found, appErr)
+	}
+}
+
+func TestMergeUsers(t *testing.T) {
+	ums, _, env := newTestUserMergeService(t)
+	ctx := context.Background()
+	survivor := createTestUser(t, env.userService, "Ada", "Lovelace", "ada@example.com")
+	merged := createDuplicateUser(t, env.userService)
+	staff := createTestUser(t, env.userService, "Grace", "Hopper", "grace@example.com")
+	setRole(t, env.userService, staff, repository.RoleSupport)
+	body := func(user *repository.User) userModule.AdminMergeBody {
+		return userModule.AdminMergeBody{UserId: user.UserId.String(), Reason: "confirmed over the phone"}
+	}
+
+	tests := []struct {
+		name     string
+		survivor *repository.User
+		merged   *repository.User
+		want     string
+	}{
+		{"into itself", survivor, survivor, "invalid_action"},
+		{"a staff account", survivor, staff, "merge_not_allowed"},
+		{"into a staff account", staff, merged, "merge_not_allowed"},
+	}
+	for _, test := range tests {
+		_, appErr := ums.MergeUsers(ctx, staff.UserId.String(), test.survivor.UserId.String(), body(test.merged))
+		if appErr == nil || appErr.ErrorCode != test.want {
+			t.Errorf("merging %s: got %v, want %s", test.name, appErr, test.want)
+		}
+	}
+
+	// A failing handler cancels the whole merge
+	ums.RegisterMergeHandler("failing", func(tx *gorm.DB, survivorID uint64, mergedID uint64) error {
+		return errors.New("unavailable")
+	})
+	if _, appErr := ums.MergeUsers(ctx, staff.UserId.String(), survivor.UserId.String(), body(merged)); appErr == nil || appErr.ErrorCode != "internal_error" {
+		t.Fatalf("merging with a failing handler: got %v, want internal_error", appErr)
+	}
+	if found, appErr := env.userService.GetUserByID(ctx, survivor.UserId.String()); appErr != nil || found.Mobile != nil {
+		t.Errorf("got %+v, %v, want the survivor unchanged", found, appErr)
+	}
+	if found, appErr := env.userService.GetUserByID(ctx, merged.UserId.String()); appErr != nil || found.IsDeleted {
+		t.Errorf("got %+v, %v, want the duplicate unchanged", found, appErr)
+	}
+
+	ums.handlers = nil
+	user, appErr := ums.MergeUsers(ctx, staff.UserId.String(), survivor.UserId.String(), body(merged))
+	if appErr != nil {
+		t.Fatalf("MergeUsers: %s", appErr.Message)
+	}
+	if user.Mobile == nil || *user.Mobile != "5550100000" {
+		t.Errorf("got mobile %v, want the mobile of the duplicate", user.Mobile)
+	}
+	if _, appErr := env.userService.GetUserByID(ctx, merged.UserId.String()); appErr == nil || appErr.ErrorCode != "user_not_found" {
+		t.Errorf("getting the merged user: got %v, want user_not_found", appErr)
+	}
+	if _, appErr := ums.MergeUsers(ctx, staff.UserId.String(), survivor.UserId.String(), body(merged)); appErr == nil || appErr.ErrorCode != "user_not_found" {
+		t.Errorf("merging twice: got %v, want user_not_found", appErr)
+	}
+}
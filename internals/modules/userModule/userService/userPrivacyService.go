@@ -0,0 +1,203 @@
+package userService
+
+import (
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/jobs"
+	"backendService/internals/common/logger"
+	"backendService/internals/common/privacy"
+	baseRepository "backendService/internals/common/repository"
+	"backendService/internals/common/storage"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"backendService/internals/setup/config"
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// JobTypeDataExport is the type of the background jobs collecting the personal data of a user.
+	JobTypeDataExport = "user_data_export"
+	// erasureBatchSize is how many due accounts are erased per run of the erasure task.
+	erasureBatchSize = 100
+	// systemActorId is the audit log actor of actions nobody triggered directly, such as scheduled erasures.
+	systemActorId = "system"
+)
+
+// RegisterPersonalData registers the personal data held by the user service with the registry:
+// the profile, the avatar files and the sessions of the user.
+func (us *UserService) RegisterPersonalData(registry *privacy.Registry) {
+	registry.RegisterSection("profile", func(ctx context.Context, subject privacy.Subject) (interface{}, error) {
+		user, err := us.userRepository.WithContext(ctx).WithDeleted().FindOneBy(baseRepository.Eq("id", subject.ID))
+		if err != nil || user == nil {
+			return nil, err
+		}
+		// Only a bcrypt hash of the password is stored, which is of no use to the user
+		user.Password = nil
+		return user, nil
+	})
+	registry.RegisterFiles("avatar", func(ctx context.Context, subject privacy.Subject) ([]privacy.File, error) {
+		user, err := us.userRepository.WithContext(ctx).WithDeleted().FindOneBy(baseRepository.Eq("id", subject.ID))
+		if err != nil || user == nil || user.AvatarKey == nil {
+			return nil, err
+		}
+		fileKey := avatarFileKey(*user.AvatarKey, avatarSizes[0].suffix)
+		reader, _, err := us.fileStorage.Get(ctx, fileKey)
+		if err == storage.ErrNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		return []privacy.File{{Name: fileKey, Content: content}}, nil
+	})
+	registry.RegisterSection("sessions", func(ctx context.Context, subject privacy.Subject) (interface{}, error) {
+		return us.sessionStore.List(ctx, subject.UserId)
+	})
+
+	registry.RegisterEraser("sessions", func(ctx context.Context, subject privacy.Subject) error {
+		return us.sessionStore.RevokeAll(ctx, subject.UserId)
+	})
+	registry.RegisterEraser("avatar", func(ctx context.Context, subject privacy.Subject) error {
+		user, err := us.userRepository.WithContext(ctx).WithDeleted().FindOneBy(baseRepository.Eq("id", subject.ID))
+		if err != nil || user == nil {
+			return err
+		}
+		us.deleteAvatarFiles(ctx, user.AvatarKey)
+		return nil
+	})
+}
+
+// RequestDataExport starts a background job collecting the personal data held about the user identified by id,
+// across every module, into a zip archive. Only the user can read the job and download the archive.
+func (us *UserService) RequestDataExport(ctx context.Context, id string) (*jobs.Job, *appError.ApplicationError) {
+	user, appErr := us.GetUserByID(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	subject := privacy.Subject{ID: user.ID, UserId: id}
+	fileName := "personal-data-" + time.Now().UTC().Format("20060102-150405") + ".zip"
+	job, err := us.jobStore.StartFileJob(ctx, JobTypeDataExport, id, fileName, "application/zip",
+		func(ctx context.Context, w io.Writer, progress func(processed int64)) error {
+			return us.dataRegistry.WriteArchive(ctx, subject, w)
+		})
+	if err != nil {
+		logger.Error("service", "user_service", "RequestDataExport", "failed to start job", err)
+		return nil, appError.NewInternalServerError("failed to start data export", err)
+	}
+	us.recordAudit(ctx, "user.data_export_requested", id, user, map[string]interface{}{"jobId": job.ID})
+
+	return job, nil
+}
+
+// GetDataExportJob returns a data export job of the user identified by id.
+func (us *UserService) GetDataExportJob(ctx context.Context, id string, jobId string) (*jobs.Job, *appError.ApplicationError) {
+	return us.findOwnedJob(ctx, id, jobId, JobTypeDataExport)
+}
+
+// GetDataExportFile returns the path of the archive produced by a completed data export job of the user identified by id.
+func (us *UserService) GetDataExportFile(ctx context.Context, id string, jobId string) (*jobs.Job, string, *appError.ApplicationError) {
+	job, appErr := us.GetDataExportJob(ctx, id, jobId)
+	if appErr != nil {
+		return nil, "", appErr
+	}
+	return us.completedJobFile(job)
+}
+
+// RequestErasure schedules the erasure of the personal data of the user identified by id, after the configured
+// grace period. Until then the account keeps working and the user can cancel the erasure.
+func (us *UserService) RequestErasure(ctx context.Context, id string) (*repository.User, *appError.ApplicationError) {
+	user, appErr := us.GetUserByID(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if user.ErasureScheduledAt != nil {
+		return nil, appError.NewApplicationError("erasure_already_requested", "erasure of this account is already scheduled", http.StatusConflict)
+	}
+
+	scheduledAt := time.Now().Add(erasureGracePeriod())
+	if err := us.userRepository.WithContext(ctx).Update(Filter{"id": user.ID}, Filter{"erasure_scheduled_at": scheduledAt}); err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to update user")
+	}
+	user.ErasureScheduledAt = &scheduledAt
+	us.recordAudit(ctx, "user.erasure_requested", id, user, map[string]interface{}{"scheduledAt": scheduledAt})
+
+	return user, nil
+}
+
+// CancelErasure cancels the scheduled erasure of the user identified by id.
+func (us *UserService) CancelErasure(ctx context.Context, id string) (*repository.User, *appError.ApplicationError) {
+	user, appErr := us.GetUserByID(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if user.ErasureScheduledAt == nil {
+		return nil, appError.NewApplicationError("erasure_not_requested", "erasure of this account is not scheduled", http.StatusConflict)
+	}
+
+	if err := us.userRepository.WithContext(ctx).Update(Filter{"id": user.ID}, Filter{"erasure_scheduled_at": nil}); err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to update user")
+	}
+	user.ErasureScheduledAt = nil
+	us.recordAudit(ctx, "user.erasure_cancelled", id, user, nil)
+
+	return user, nil
+}
+
+// EraseDueUsers erases the users whose grace period is over. It is meant to run periodically,
+// and erases at most one batch per run. A user whose erasure fails is retried on the next run.
+func (us *UserService) EraseDueUsers(ctx context.Context) error {
+	users, err := us.userRepository.WithContext(ctx).FindDueForErasure(time.Now(), erasureBatchSize)
+	if err != nil {
+		return err
+	}
+	for i := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := us.eraseUser(ctx, &users[i]); err != nil {
+			logger.Error("service", "user_service", "EraseDueUsers", "failed to erase user", users[i].UserId.String(), err)
+		}
+	}
+	return nil
+}
+
+// eraseUser erases the personal data held about the user by every module, then anonymizes the user itself.
+// Unlike a soft delete, nothing identifying the user is left, but the row stays so that records referring
+// to the user remain valid.
+func (us *UserService) eraseUser(ctx context.Context, user *repository.User) error {
+	subject := privacy.Subject{ID: user.ID, UserId: user.UserId.String()}
+	if err := us.dataRegistry.Erase(ctx, subject); err != nil {
+		return err
+	}
+	erased, err := us.userRepository.WithContext(ctx).Anonymize(user.ID)
+	if err != nil {
+		return err
+	}
+	if erased {
+		us.recordAudit(ctx, "user.erased", systemActorId, user, nil)
+	}
+	return nil
+}
+
+// erasureGracePeriod returns how long after being requested an erasure happens, defaulting to thirty days.
+func erasureGracePeriod() time.Duration {
+	if config.Config.Privacy.ErasureGraceDays <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return time.Duration(config.Config.Privacy.ErasureGraceDays) * 24 * time.Hour
+}
+
+// ErasureInterval returns how often users due for erasure are looked for, defaulting to one hour.
+func ErasureInterval() time.Duration {
+	if config.Config.Privacy.ErasureIntervalMinutes <= 0 {
+		return time.Hour
+	}
+	return time.Duration(config.Config.Privacy.ErasureIntervalMinutes) * time.Minute
+}
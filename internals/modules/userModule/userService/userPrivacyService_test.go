@@ -0,0 +1,126 @@
+package userService
+
+import (
+	"archive/zip"
+	baseRepository "backendService/internals/common/repository"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestDataExport(t *testing.T) {
+	us := newTestUserService(t)
+	us.RegisterPersonalData(us.dataRegistry)
+	ctx := context.Background()
+	user := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	id := user.UserId.String()
+	if _, _, err := us.sessionStore.Create(ctx, id, user.Role); err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+
+	job, appErr := us.RequestDataExport(ctx, id)
+	if appErr != nil {
+		t.Fatalf("RequestDataExport: %s", appErr.Message)
+	}
+	if _, appErr := us.GetDataExportJob(ctx, "someone-else", job.ID); appErr == nil || appErr.ErrorCode != "export_not_found" {
+		t.Errorf("getting the data export of someone else: got %v, want export_not_found", appErr)
+	}
+	waitForJob(t, us, id, job.ID, JobTypeDataExport)
+
+	_, path, appErr := us.GetDataExportFile(ctx, id, job.ID)
+	if appErr != nil {
+		t.Fatalf("GetDataExportFile: %s", appErr.Message)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading the archive: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("opening the archive: %v", err)
+	}
+	entries := map[string]string{}
+	for _, file := range archive.File {
+		reader, _ := file.Open()
+		data, _ := io.ReadAll(reader)
+		entries[file.Name] = string(data)
+	}
+	if profile := entries["profile.json"]; !strings.Contains(profile, `"ada@example.com"`) || strings.Contains(profile, "$2") {
+		t.Errorf("got profile %s, want the email without the password hash", profile)
+	}
+	if sessions := entries["sessions.json"]; !strings.Contains(sessions, id) {
+		t.Errorf("got sessions %s, want the session of the user", sessions)
+	}
+}
+
+func TestErasure(t *testing.T) {
+	us := newTestUserService(t)
+	us.RegisterPersonalData(us.dataRegistry)
+	ctx := context.Background()
+	user := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	id := user.UserId.String()
+
+	if _, appErr := us.CancelErasure(ctx, id); appErr == nil || appErr.ErrorCode != "erasure_not_requested" {
+		t.Errorf("cancelling an erasure that was not requested: got %v, want erasure_not_requested", appErr)
+	}
+	scheduled, appErr := us.RequestErasure(ctx, id)
+	if appErr != nil {
+		t.Fatalf("RequestErasure: %s", appErr.Message)
+	}
+	if scheduled.ErasureScheduledAt == nil || scheduled.ErasureScheduledAt.Before(time.Now().Add(29*24*time.Hour)) {
+		t.Errorf("got erasure scheduled at %v, want it after the grace period", scheduled.ErasureScheduledAt)
+	}
+	if _, appErr := us.RequestErasure(ctx, id); appErr == nil || appErr.ErrorCode != "erasure_already_requested" {
+		t.Errorf("requesting the erasure again: got %v, want erasure_already_requested", appErr)
+	}
+
+	// Nothing is erased during the grace period.
+	if err := us.EraseDueUsers(ctx); err != nil {
+		t.Fatalf("EraseDueUsers: %v", err)
+	}
+	if _, appErr := us.GetUserByID(ctx, id); appErr != nil {
+		t.Fatalf("getting the user during the grace period: %s", appErr.Message)
+	}
+
+	if _, appErr := us.UploadAvatar(ctx, id, bytes.NewReader(testImage(t, 64, 64))); appErr != nil {
+		t.Fatalf("UploadAvatar: %s", appErr.Message)
+	}
+	token, _, err := us.sessionStore.Create(ctx, id, user.Role)
+	if err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+	if err := us.userRepository.Update(Filter{"id": user.ID}, Filter{"erasure_scheduled_at": time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("ending the grace period: %v", err)
+	}
+	withAvatar, _ := us.GetUserByID(ctx, id)
+
+	if err := us.EraseDueUsers(ctx); err != nil {
+		t.Fatalf("EraseDueUsers: %v", err)
+	}
+	erased, err := us.userRepository.WithDeleted().FindOneBy(baseRepository.Eq("id", user.ID))
+	if err != nil || erased == nil {
+		t.Fatalf("finding the erased user: %v, %v", erased, err)
+	}
+	if erased.ErasedAt == nil || !erased.IsDeleted || erased.Email != nil || erased.Password != nil || erased.FirstName != "" || erased.AvatarKey != nil {
+		t.Errorf("got user %+v, want every personal data cleared", erased)
+	}
+	if session, err := us.sessionStore.Get(ctx, token); err != nil || session != nil {
+		t.Errorf("session of the erased user: got %v, %v, want it revoked", session, err)
+	}
+	if _, _, err := us.fileStorage.Get(ctx, avatarFileKey(*withAvatar.AvatarKey, "256")); err == nil {
+		t.Error("got the avatar of the erased user, want it deleted")
+	}
+	if actions := auditActions(t, us, user); actions[len(actions)-1] != "user.erased" {
+		t.Errorf("got audit log %v, want the erasure recorded last", actions)
+	}
+	if _, appErr := us.RestoreUser(ctx, "admin", id); appErr == nil || appErr.ErrorCode != "user_erased" {
+		t.Errorf("restoring the erased user: got %v, want user_erased", appErr)
+	}
+
+	// The email is free again once erased.
+	createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+}
@@ -0,0 +1,59 @@
+package userService
+
+import (
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/logger"
+	baseRepository "backendService/internals/common/repository"
+	"backendService/internals/modules/userModule/userModule"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"context"
+)
+
+// UserSearchResult is a user matching a search. Highlights holds the fields of the user whose value matched,
+// keyed by their JSON name, HTML escaped and with the matched parts wrapped in <mark> tags.
+type UserSearchResult struct {
+	User       repository.User   `json:"user"`
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// UserSearchPage is a single page of users matching a search.
+type UserSearchPage = baseRepository.PageResult[UserSearchResult]
+
+// searchFieldNames maps the searched columns to the names of the fields clients know them by.
+var searchFieldNames = map[string]string{
+	"first_name": "firstName",
+	"last_name":  "lastName",
+	"username":   "username",
+	"email":      "email",
+	"mobile":     "mobile",
+}
+
+// SearchUsers retrieves a page of users matching the search query and filters, best matches first.
+func (us *UserService) SearchUsers(ctx context.Context, query userModule.SearchUsersQuery) (*UserSearchPage, *appError.ApplicationError) {
+	page, err := us.userRepository.WithContext(ctx).Search(query.Q, baseRepository.PageRequest{
+		Page:     query.Page,
+		PageSize: query.PageSize,
+		Scopes:   us.userFilterScopes(query.UserFilters),
+	})
+	if err != nil {
+		logger.Error("service", "user_service", "SearchUsers", "failed to search users", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to search users")
+	}
+
+	results := make([]UserSearchResult, len(page.Items))
+	for i, hit := range page.Items {
+		highlights := make(map[string]string, len(hit.Highlights))
+		for column, value := range hit.Highlights {
+			highlights[searchFieldNames[column]] = value
+		}
+		results[i] = UserSearchResult{User: hit.Item, Rank: hit.Rank, Highlights: highlights}
+	}
+	return &UserSearchPage{
+		Items:      results,
+		Total:      page.Total,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		TotalPages: page.TotalPages,
+	}, nil
+}
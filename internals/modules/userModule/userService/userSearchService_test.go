@@ -0,0 +1,132 @@
+package userService
+
+import (
+	"backendService/internals/modules/userModule/userModule"
+	"context"
+	"testing"
+)
+
+// searchEmails returns the emails of the users matching the search, in order.
+func searchEmails(t *testing.T, us *UserService, query userModule.SearchUsersQuery) []string {
+	t.Helper()
+	page, appErr := us.SearchUsers(context.Background(), query)
+	if appErr != nil {
+		t.Fatalf("searching %q: %s", query.Q, appErr.Message)
+	}
+	emails := make([]string, len(page.Items))
+	for i, result := range page.Items {
+		emails[i] = *result.User.Email
+	}
+	return emails
+}
+
+func TestSearchUsers(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	createTestUser(t, us, "Ada", "Lovelace", "countess@example.com")
+	createTestUser(t, us, "Adam", "Smith", "adam.smith@example.com")
+	createTestUser(t, us, "Grace", "Hopper", "grace@navy.mil")
+	createTestUser(t, us, "Zoë", "Adams", "zoe@example.com")
+
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"lovelace", []string{"countess@example.com"}},
+		{"LOVE", []string{"countess@example.com"}},
+		{"ada lovelace", []string{"countess@example.com"}},
+		{"grace example", nil},
+		{"navy", []string{"grace@navy.mil"}},
+		{"zoe", []string{"zoe@example.com"}},
+		{"race", nil},
+	}
+	for _, test := range tests {
+		if got := searchEmails(t, us, userModule.SearchUsersQuery{Q: test.q}); !equalStrings(got, test.want) {
+			t.Errorf("searching %q: got %q, want %q", test.q, got, test.want)
+		}
+	}
+
+	// Adam matches ada in his first name and in his email, so he ranks above Ada and Zoë Adams
+	page, appErr := us.SearchUsers(ctx, userModule.SearchUsersQuery{Q: "ada"})
+	if appErr != nil {
+		t.Fatalf("SearchUsers: %s", appErr.Message)
+	}
+	if page.Total != 3 || len(page.Items) != 3 {
+		t.Fatalf("got %d results out of %d, want 3", len(page.Items), page.Total)
+	}
+	first := page.Items[0]
+	if *first.User.Email != "adam.smith@example.com" || first.Rank <= page.Items[2].Rank {
+		t.Errorf("got %s ranked %v first, want adam.smith@example.com ranked above %v", *first.User.Email, first.Rank, page.Items[2].Rank)
+	}
+	wantHighlights := map[string]string{"firstName": "<mark>Ada</mark>m", "email": "<mark>ada</mark>m.smith@example.com"}
+	if len(first.Highlights) != len(wantHighlights) {
+		t.Errorf("got highlights %v, want %v", first.Highlights, wantHighlights)
+	}
+	for field, want := range wantHighlights {
+		if got := first.Highlights[field]; got != want {
+			t.Errorf("highlight of %s: got %q, want %q", field, got, want)
+		}
+	}
+}
+
+func TestSearchUsersFollowsChanges(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	ada := createTestUser(t, us, "Ada", "Byron", "ada@example.com")
+	grace := createTestUser(t, us, "Grace", "Hopper", "grace@example.com")
+
+	if _, appErr := us.UpdateUser(ctx, ada.UserId.String(), userModule.UpdateUserBody{LastName: stringPointer("Lovelace")}, nil); appErr != nil {
+		t.Fatalf("UpdateUser: %s", appErr.Message)
+	}
+	if got := searchEmails(t, us, userModule.SearchUsersQuery{Q: "byron"}); len(got) != 0 {
+		t.Errorf("searching the old name: got %q, want nothing", got)
+	}
+	if got := searchEmails(t, us, userModule.SearchUsersQuery{Q: "lovelace"}); !equalStrings(got, []string{"ada@example.com"}) {
+		t.Errorf("searching the new name: got %q, want ada@example.com", got)
+	}
+
+	if appErr := us.DeleteUser(ctx, grace.UserId.String()); appErr != nil {
+		t.Fatalf("DeleteUser: %s", appErr.Message)
+	}
+	if got := searchEmails(t, us, userModule.SearchUsersQuery{Q: "example"}); !equalStrings(got, []string{"ada@example.com"}) {
+		t.Errorf("searching after deleting grace: got %q, want ada@example.com", got)
+	}
+}
+
+func TestSearchUsersFilters(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	ada := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	alan := createTestUser(t, us, "Alan", "Turing", "alan@example.com")
+	if _, appErr := us.SetUserActive(ctx, ada.UserId.String(), alan.UserId.String(), false); appErr != nil {
+		t.Fatalf("SetUserActive: %s", appErr.Message)
+	}
+
+	inactive := false
+	query := userModule.SearchUsersQuery{Q: "example", UserFilters: userModule.UserFilters{IsActive: &inactive}}
+	if got := searchEmails(t, us, query); !equalStrings(got, []string{"alan@example.com"}) {
+		t.Errorf("searching inactive users: got %q, want alan@example.com", got)
+	}
+
+	page, appErr := us.SearchUsers(ctx, userModule.SearchUsersQuery{Q: "example", Page: 2, PageSize: 1})
+	if appErr != nil {
+		t.Fatalf("SearchUsers: %s", appErr.Message)
+	}
+	if page.Total != 2 || page.TotalPages != 2 || len(page.Items) != 1 {
+		t.Errorf("got page %d of %d with %d results out of %d, want page 2 of 2 with 1 result out of 2",
+			page.Page, page.TotalPages, len(page.Items), page.Total)
+	}
+}
+
+// equalStrings reports whether got and want hold the same values in the same order.
+func equalStrings(got []string, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,13 +1,30 @@
 package userService
 
 import (
+	"backendService/internals/common/audit"
+	"backendService/internals/common/auth"
+	controllers "backendService/internals/common/controller"
+	"backendService/internals/common/date"
 	appError "backendService/internals/common/errors"
+	"backendService/internals/common/history"
+	"backendService/internals/common/jobs"
+	"backendService/internals/common/logger"
+	"backendService/internals/common/privacy"
+	baseRepository "backendService/internals/common/repository"
+	"backendService/internals/common/storage"
 	"backendService/internals/modules/userModule/userModule"
 	repository "backendService/internals/modules/userModule/userRepository"
+	"backendService/internals/setup/config"
 
-	"strconv"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/oklog/ulid/v2"
+	"gorm.io/gorm"
 )
 
 type Filter = map[string]interface{}
@@ -15,28 +32,42 @@ type Filter = map[string]interface{}
 // UserService is a struct that represents the service for the user model
 type UserService struct {
 	userRepository *repository.UserRepository
+	sessionStore   *auth.SessionStore
+	auditService   *audit.AuditService
+	historyService *history.HistoryService
+	jobStore       *jobs.JobStore
+	fileStorage    storage.Storage
+	dataRegistry   *privacy.Registry
+	unitOfWork     *baseRepository.UnitOfWork
 }
 
 // NewUserService creates a new instance of UserService.
-// It takes a pointer to a UserRepository and returns a pointer to UserService.
-func NewUserService(userRepository *repository.UserRepository) *UserService {
-	return &UserService{userRepository: userRepository}
+// It takes a pointer to a UserRepository, a SessionStore, an AuditService, a HistoryService, a JobStore, the file Storage,
+// the personal data Registry and the UnitOfWork running transactions, and returns a pointer to UserService.
+func NewUserService(userRepository *repository.UserRepository, sessionStore *auth.SessionStore, auditService *audit.AuditService, historyService *history.HistoryService, jobStore *jobs.JobStore, fileStorage storage.Storage, dataRegistry *privacy.Registry, unitOfWork *baseRepository.UnitOfWork) *UserService {
+	return &UserService{userRepository: userRepository, sessionStore: sessionStore, auditService: auditService, historyService: historyService, jobStore: jobStore, fileStorage: fileStorage, dataRegistry: dataRegistry, unitOfWork: unitOfWork}
 }
 
 // CreateUser creates a new user with the provided user data.
 // It takes a CreateUserBody object as input and returns the created user and an error interface.
-func (us *UserService) CreateUser(createUserData userModule.CreateUserBody) (*repository.User, *appError.ApplicationError) {
+func (us *UserService) CreateUser(ctx context.Context, createUserData userModule.CreateUserBody) (*repository.User, *appError.ApplicationError) {
 	// Generate a new UUID for the user ID
 	userId := ulid.Make()
 
-	exixtingUser, err := us.userRepository.FindOneBy(Filter{
-		"email": createUserData.Email,
-	})
-	if err != nil {
-		return nil, appError.NewApplicationError("internal_error", "failed to find user")
+	var dob *date.Date
+	if createUserData.DOB != nil {
+		parsed, appErr := parseDateOfBirth(*createUserData.DOB)
+		if appErr != nil {
+			return nil, appErr
+		}
+		dob = parsed
 	}
-	if exixtingUser != nil {
-		return nil, appError.NewApplicationError("user_exists", "user with this email already exists")
+	password, err := auth.HashPassword(createUserData.Password)
+	if errors.Is(err, auth.ErrPasswordTooLong) {
+		return nil, appError.NewBadRequestError("password_too_long", fmt.Sprintf("password must not exceed %d bytes", auth.MaxPasswordBytes))
+	}
+	if err != nil {
+		return nil, appError.NewInternalServerError("failed to hash password", err)
 	}
 
 	// Map the request data to a User struct
@@ -45,29 +76,55 @@ func (us *UserService) CreateUser(createUserData userModule.CreateUserBody) (*re
 		FirstName: createUserData.FirstName,
 		LastName:  createUserData.LastName,
 		Email:     &createUserData.Email,
-		Password:  &createUserData.Password,
-		DOB:       createUserData.DOB,
+		Password:  &password,
+		DOB:       dob,
 		Mobile:    createUserData.Mobile,
 		IsActive:  true,
 	}
-	createdUser, err := us.userRepository.Create(user)
-	if err != nil {
-		return nil, appError.NewApplicationError("internal_error", "failed to create user")
+
+	// The check and the insert share a transaction, and the unique index settles concurrent requests
+	// that both passed the check.
+	var createdUser *repository.User
+	appErr := us.unitOfWork.Do(ctx, func(tx *baseRepository.Tx) *appError.ApplicationError {
+		users := us.userRepository.WithTx(tx)
+		// Deleted users still hold their email
+		existingUser, err := users.WithDeleted().FindOneBy(baseRepository.Eq("email", createUserData.Email))
+		if err != nil {
+			return appError.NewApplicationError("internal_error", "failed to find user")
+		}
+		if existingUser != nil {
+			return appError.NewApplicationError("user_exists", "user with this email already exists")
+		}
+
+		createdUser, err = users.Create(user)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return appError.NewApplicationError("user_exists", "user with this email or mobile already exists")
+		}
+		if err != nil {
+			return appError.NewApplicationError("internal_error", "failed to create user")
+		}
+		return nil
+	})
+	if appErr != nil {
+		return nil, appErr
 	}
 	return createdUser, nil
 }
 
-// GetUserByID retrieves a user from the repository based on the provided ID.
-// It takes an ID as a string parameter and returns a pointer to a User struct and an error.
-// If the ID cannot be parsed or the user is not found, an error is returned.
-func (us *UserService) GetUserByID(id string) (*repository.User, *appError.ApplicationError) {
-
-	num, err := strconv.ParseUint(id, 10, 64)
+// GetUserByID retrieves a user from the repository based on the provided public ID.
+// It takes the user's ULID as a string parameter and returns a pointer to a User struct and an error.
+// If the ID is not a valid ULID or the user is not found, an error is returned.
+func (us *UserService) GetUserByID(ctx context.Context, id string) (*repository.User, *appError.ApplicationError) {
+	return findUserByPublicID(us.userRepository.WithContext(ctx), id)
+}
 
-	if err != nil {
-		return nil, appError.NewBadRequestError("invalid_id", "invalid user ID")
+// findUserByPublicID retrieves a non-deleted user by their public ID.
+func findUserByPublicID(userRepository *repository.UserRepository, id string) (*repository.User, *appError.ApplicationError) {
+	userId, appErr := parseUserId(id)
+	if appErr != nil {
+		return nil, appErr
 	}
-	user, err := us.userRepository.FindByID(num)
+	user, err := userRepository.FindByPublicID(userId)
 	if err != nil {
 		return nil, appError.NewApplicationError("internal_error", "failed to retrieve user")
 	}
@@ -77,12 +134,346 @@ func (us *UserService) GetUserByID(id string) (*repository.User, *appError.Appli
 	return user, nil
 }
 
-// list of users
-func (us *UserService) GetUsers() ([]repository.User, *appError.ApplicationError) {
-	users, err := us.userRepository.FindAll(1, 10)
+// parseUserId parses the public ID of a user.
+func parseUserId(id string) (ulid.ULID, *appError.ApplicationError) {
+	userId, err := ulid.ParseStrict(id)
+	if err != nil {
+		return ulid.ULID{}, appError.NewBadRequestError("invalid_id", "invalid user ID")
+	}
+	return userId, nil
+}
+
+// sortableUserFields maps the sort fields accepted by the API to their database columns.
+var sortableUserFields = map[string]string{
+	"userId":    "user_id",
+	"createdAt": "created_at",
+	"updatedAt": "updated_at",
+	"firstName": "first_name",
+	"lastName":  "last_name",
+	"email":     "email",
+}
+
+// GetUsers retrieves a page of users matching the filters in the given query.
+// It returns a bad request error if the query asks to sort on an unknown field.
+func (us *UserService) GetUsers(ctx context.Context, query userModule.ListUsersQuery) (*repository.UserPage, *appError.ApplicationError) {
+	sort, appErr := parseSort(query.Sort)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	page, err := us.userRepository.WithContext(ctx).FindPage(baseRepository.PageRequest{
+		Page:     query.Page,
+		PageSize: query.PageSize,
+		Sort:     sort,
+		Scopes:   us.userFilterScopes(query.UserFilters),
+	})
 	if err != nil {
-		return nil, appError.NewBadRequestError("internal_error", "failed to retrieve users")
+		if errors.Is(err, baseRepository.ErrUnknownColumn) {
+			return nil, appError.NewBadRequestError("invalid_sort", "the users cannot be sorted by this field")
+		}
+		return nil, appError.NewInternalServerError("failed to retrieve users", err)
+	}
+	return page, nil
+}
+
+// GetUsersByCursor retrieves a page of users with keyset pagination, starting after the cursor in the query.
+// Keyset pagination orders by a single field, so the query may name at most one sort field.
+func (us *UserService) GetUsersByCursor(ctx context.Context, query userModule.ListUsersQuery) (*repository.UserCursorPage, *appError.ApplicationError) {
+	sort, appErr := parseSort(query.Sort)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if len(sort) > 1 {
+		return nil, appError.NewBadRequestError("invalid_sort", "cursor pagination supports a single sort field")
+	}
+
+	request := baseRepository.CursorRequest{
+		Cursor: query.Cursor,
+		Limit:  query.PageSize,
+		Scopes: us.userFilterScopes(query.UserFilters),
+	}
+	if len(sort) == 1 {
+		request.SortColumn = sort[0].Column
+		request.Desc = sort[0].Desc
 	}
-	return users, nil
 
+	page, err := us.userRepository.WithContext(ctx).FindByCursor(request)
+	if err != nil {
+		if errors.Is(err, baseRepository.ErrInvalidCursor) {
+			return nil, appError.NewBadRequestError("invalid_cursor", "cursor is invalid or does not match the requested sort")
+		}
+		if errors.Is(err, baseRepository.ErrUnknownColumn) {
+			return nil, appError.NewBadRequestError("invalid_sort", "the users cannot be sorted by this field")
+		}
+		return nil, appError.NewInternalServerError("failed to retrieve users", err)
+	}
+	return page, nil
+}
+
+// userFilter builds the filter matching the filters present in a list or export query.
+func userFilter(query userModule.UserFilters) baseRepository.Filter {
+	var filters []baseRepository.Filter
+	if query.IsActive != nil {
+		filters = append(filters, baseRepository.Eq("is_active", *query.IsActive))
+	}
+	if query.IsEmailVerified != nil {
+		filters = append(filters, baseRepository.Eq("is_email_verified", *query.IsEmailVerified))
+	}
+	if query.IsMobileVerified != nil {
+		filters = append(filters, baseRepository.Eq("is_mobile_verified", *query.IsMobileVerified))
+	}
+	if query.CreatedAfter != nil {
+		filters = append(filters, baseRepository.Gte("created_at", *query.CreatedAfter))
+	}
+	if query.CreatedBefore != nil {
+		filters = append(filters, baseRepository.Lte("created_at", *query.CreatedBefore))
+	}
+	if query.Email != "" {
+		filters = append(filters, baseRepository.Contains("email", query.Email))
+	}
+	if query.Name != "" {
+		filters = append(filters, baseRepository.Or(
+			baseRepository.Contains("first_name", query.Name),
+			baseRepository.Contains("last_name", query.Name),
+		))
+	}
+	return baseRepository.And(filters...)
+}
+
+// userFilterScopes returns the query scopes applying the filters present in a list or export query.
+func (us *UserService) userFilterScopes(query userModule.UserFilters) []baseRepository.Scope {
+	return []baseRepository.Scope{us.userRepository.FilterScope(userFilter(query))}
+}
+
+// parseSort parses a sort expression such as "createdAt:desc,firstName" into sort orders.
+// Only fields listed in sortableUserFields are accepted.
+func parseSort(sort string) ([]baseRepository.SortOrder, *appError.ApplicationError) {
+	var orders []baseRepository.SortOrder
+	if strings.TrimSpace(sort) == "" {
+		return orders, nil
+	}
+
+	for _, part := range strings.Split(sort, ",") {
+		field, direction, _ := strings.Cut(strings.TrimSpace(part), ":")
+		column, ok := sortableUserFields[field]
+		if !ok {
+			return nil, appError.NewBadRequestError("invalid_sort", "cannot sort by field: "+field)
+		}
+
+		var desc bool
+		switch strings.ToLower(direction) {
+		case "", "asc":
+			desc = false
+		case "desc":
+			desc = true
+		default:
+			return nil, appError.NewBadRequestError("invalid_sort", "invalid sort direction: "+direction)
+		}
+		orders = append(orders, baseRepository.SortOrder{Column: column, Desc: desc})
+	}
+	return orders, nil
+}
+
+// UpdateUser applies a partial update to the user identified by id.
+// Only the fields present in updateUserData are changed. The email and mobile are never changed here,
+// but through the contact change flow, so that no contact is taken over without being verified.
+// When version is set, the user is only updated if it is still at that version. Either way the update
+// fails with a conflict when the user changes between being read and being updated.
+func (us *UserService) UpdateUser(ctx context.Context, id string, updateUserData userModule.UpdateUserBody, version *uint64) (*repository.User, *appError.ApplicationError) {
+	if updateUserData.IsEmpty() {
+		return nil, appError.NewBadRequestError("missing_data", "at least one field is required")
+	}
+
+	user, appErr := us.GetUserByID(ctx, id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if version != nil && *version != user.Version {
+		return nil, versionConflictError()
+	}
+
+	updates := Filter{}
+	if updateUserData.FirstName != nil {
+		updates["first_name"] = *updateUserData.FirstName
+	}
+	if updateUserData.LastName != nil {
+		updates["last_name"] = *updateUserData.LastName
+	}
+	if updateUserData.DOB != nil {
+		dob, appErr := parseDateOfBirth(*updateUserData.DOB)
+		if appErr != nil {
+			return nil, appErr
+		}
+		updates["dob"] = *dob
+	}
+
+	if len(updates) > 0 {
+		err := us.userRepository.WithContext(ctx).UpdateVersioned(Filter{"id": user.ID}, user.Version, updates)
+		if errors.Is(err, baseRepository.ErrVersionConflict) {
+			return nil, versionConflictError()
+		}
+		if err != nil {
+			return nil, appError.NewApplicationError("internal_error", "failed to update user")
+		}
+	}
+
+	updatedUser, err := us.userRepository.WithContext(ctx).FindByID(user.ID)
+	if err != nil || updatedUser == nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve user")
+	}
+	return updatedUser, nil
+}
+
+// versionConflictError is returned when a user changed since the client or the service read it.
+func versionConflictError() *appError.ApplicationError {
+	return appError.NewApplicationError("version_conflict", "the user was modified in the meantime, fetch it again and retry", http.StatusConflict)
+}
+
+// DeleteUser soft-deletes the user identified by id and signs them out of every session.
+// It returns a not found error if the user does not exist or is already deleted.
+func (us *UserService) DeleteUser(ctx context.Context, id string) *appError.ApplicationError {
+	user, appErr := us.GetUserByID(ctx, id)
+	if appErr != nil {
+		return appErr
+	}
+
+	err := us.userRepository.WithContext(ctx).Delete(user.ID)
+	if err != nil {
+		return appError.NewApplicationError("internal_error", "failed to delete user")
+	}
+
+	err = us.sessionStore.RevokeAll(ctx, user.UserId.String())
+	if err != nil {
+		logger.Error("service", "UserService", "DeleteUser", "failed to revoke sessions", err)
+	}
+	return nil
+}
+
+// FindOrCreateByContact returns the user owning the given email or mobile, creating a new user when none exists.
+// It is called once the contact has been verified, so the matching verification flag is set on the user.
+// Exactly one of email and mobile is expected to be set.
+func (us *UserService) FindOrCreateByContact(ctx context.Context, email *string, mobile *string) (*repository.User, *appError.ApplicationError) {
+	column, value := "mobile", mobile
+	if email != nil {
+		column, value = "email", email
+	}
+	if value == nil {
+		return nil, appError.NewBadRequestError("missing_data", "mobile or email is required")
+	}
+
+	// Deleted users are included so that a deleted account cannot be silently replaced.
+	user, err := us.userRepository.WithContext(ctx).WithDeleted().FindOneBy(baseRepository.Eq(column, *value))
+	if err != nil {
+		return nil, appError.NewApplicationError("internal_error", "failed to find user")
+	}
+	if user != nil && user.MergedIntoID != nil {
+		// The contact was left on an account merged into another one, which signs in instead
+		return us.findMergeSurvivor(ctx, user)
+	}
+	if user != nil && user.IsDeleted {
+		return nil, appError.NewApplicationError("account_deleted", "this account has been deleted", http.StatusForbidden)
+	}
+	if user != nil && !user.IsActive {
+		return nil, appError.NewApplicationError("account_inactive", "this account has been deactivated", http.StatusForbidden)
+	}
+
+	now := time.Now()
+	if user == nil {
+		user = &repository.User{
+			UserId:       ulid.Make(),
+			IsActive:     true,
+			AuthProvider: "otp",
+		}
+		if email != nil {
+			user.Email = email
+			user.IsEmailVerified = true
+			user.EmailVerifiedAt = &now
+		} else {
+			user.Mobile = mobile
+			user.IsMobileVerified = true
+		}
+		createdUser, err := us.userRepository.WithContext(ctx).Create(user)
+		if err != nil {
+			return nil, appError.NewApplicationError("internal_error", "failed to create user")
+		}
+		return createdUser, nil
+	}
+
+	updates := Filter{}
+	if email != nil && !user.IsEmailVerified {
+		updates["is_email_verified"] = true
+		updates["email_verified_at"] = now
+		user.IsEmailVerified = true
+		user.EmailVerifiedAt = &now
+	}
+	if email == nil && !user.IsMobileVerified {
+		updates["is_mobile_verified"] = true
+		user.IsMobileVerified = true
+	}
+	if len(updates) > 0 {
+		if err := us.userRepository.WithContext(ctx).Update(Filter{"id": user.ID}, updates); err != nil {
+			return nil, appError.NewApplicationError("internal_error", "failed to update user")
+		}
+	}
+	return user, nil
+}
+
+// maxMergeChain bounds how many merges are followed to find the account a merged account ended up in.
+const maxMergeChain = 5
+
+// findMergeSurvivor returns the account the merged user ended up in, following later merges of the
+// surviving account too. It fails the same way signing in to the surviving account directly would.
+func (us *UserService) findMergeSurvivor(ctx context.Context, user *repository.User) (*repository.User, *appError.ApplicationError) {
+	for i := 0; i < maxMergeChain && user.MergedIntoID != nil; i++ {
+		survivor, err := us.userRepository.WithContext(ctx).WithDeleted().FindOneBy(baseRepository.Eq("id", *user.MergedIntoID))
+		if err != nil {
+			return nil, appError.NewApplicationError("internal_error", "failed to find user")
+		}
+		if survivor == nil {
+			break
+		}
+		user = survivor
+	}
+	if user.IsDeleted {
+		return nil, appError.NewApplicationError("account_deleted", "this account has been deleted", http.StatusForbidden)
+	}
+	if !user.IsActive {
+		return nil, appError.NewApplicationError("account_inactive", "this account has been deactivated", http.StatusForbidden)
+	}
+	return user, nil
+}
+
+// parseDateOfBirth parses a date of birth written as YYYY-MM-DD and checks it with checkDateOfBirth,
+// failing with the same validation error as an invalid request body.
+func parseDateOfBirth(value string) (*date.Date, *appError.ApplicationError) {
+	dob, err := date.Parse(value)
+	message := "DOB must be a date in YYYY-MM-DD format"
+	if err == nil {
+		message = checkDateOfBirth(dob)
+	}
+	if message != "" {
+		return nil, appError.NewUnprocessableEntityError("invalid_body", []controllers.ValidationErrorData{{Field: "DOB", Message: message}})
+	}
+	return &dob, nil
+}
+
+// checkDateOfBirth returns why a date of birth is not acceptable, or an empty string when it is.
+// It must be in the past, no older than the maximum plausible age, and the user must be at least
+// the configured minimum age.
+func checkDateOfBirth(dob date.Date) string {
+	today := date.Today()
+	if !dob.Before(today) {
+		return "DOB must be in the past"
+	}
+	maximumAge := config.Config.Users.MaximumAgeYears
+	if maximumAge <= 0 {
+		maximumAge = 120
+	}
+	if dob.YearsOn(today) > maximumAge {
+		return fmt.Sprintf("DOB must not be more than %d years ago", maximumAge)
+	}
+	if minimumAge := config.Config.Users.MinimumAgeYears; minimumAge > 0 && dob.YearsOn(today) < minimumAge {
+		return fmt.Sprintf("you must be at least %d years old", minimumAge)
+	}
+	return ""
 }
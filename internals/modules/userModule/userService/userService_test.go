@@ -0,0 +1,365 @@
+package userService
+
+import (
+	"backendService/internals/common/audit"
+	"backendService/internals/common/auth"
+	"backendService/internals/common/cache"
+	"backendService/internals/common/cache/cachetest"
+	"backendService/internals/common/date"
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/history"
+	"backendService/internals/common/jobs"
+	"backendService/internals/common/privacy"
+	baseRepository "backendService/internals/common/repository"
+	"backendService/internals/common/storage"
+	"backendService/internals/modules/userModule/userModule"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"backendService/internals/setup/config"
+	"backendService/internals/setup/database/databasetest"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"gorm.io/gorm"
+)
+
+// testEnvironment holds the dependencies shared by the services under test.
+type testEnvironment struct {
+	db             *gorm.DB
+	cacheService   *cache.CacheService
+	cache          *cachetest.Server
+	userRepository *repository.UserRepository
+	auditService   *audit.AuditService
+	userService    *UserService
+}
+
+// newTestEnvironment sets up a migrated test database, an in-memory cache and a UserService using them.
+func newTestEnvironment(t *testing.T) *testEnvironment {
+	t.Helper()
+	previous := config.Config
+	config.Config.App.SecretKey = "test_secret_key"
+	config.Config.Jobs.Directory = t.TempDir()
+	t.Cleanup(func() { config.Config = previous })
+
+	db := databasetest.Open(t)
+	cacheService, cacheServer := cachetest.NewCacheService(t)
+	env := &testEnvironment{
+		db:             db,
+		cacheService:   cacheService,
+		cache:          cacheServer,
+		userRepository: repository.NewUserRepository(db, cacheService),
+		auditService:   audit.NewAuditService(db),
+	}
+	env.userService = NewUserService(
+		env.userRepository,
+		auth.NewSessionStore(cacheService),
+		env.auditService,
+		history.NewHistoryService(db),
+		jobs.NewJobStore(cacheService),
+		storage.NewLocalStorage(t.TempDir(), "/files"),
+		privacy.NewRegistry(),
+		baseRepository.NewUnitOfWork(db),
+	)
+	return env
+}
+
+// newTestUserService returns a UserService backed by a migrated test database and an in-memory cache.
+func newTestUserService(t *testing.T) *UserService {
+	t.Helper()
+	return newTestEnvironment(t).userService
+}
+
+// createTestUser creates a user with the given name and email through the service.
+func createTestUser(t *testing.T, us *UserService, firstName string, lastName string, email string) *repository.User {
+	t.Helper()
+	user, appErr := us.CreateUser(context.Background(), userModule.CreateUserBody{
+		FirstName: firstName,
+		LastName:  lastName,
+		Email:     email,
+		Password:  "correct horse battery staple",
+	})
+	if appErr != nil {
+		t.Fatalf("creating user %s: %s", email, appErr.Message)
+	}
+	return user
+}
+
+func stringPointer(value string) *string {
+	return &value
+}
+
+// userEmails returns the emails of users, in order.
+func userEmails(users []repository.User) []string {
+	emails := make([]string, len(users))
+	for i, user := range users {
+		emails[i] = *user.Email
+	}
+	return emails
+}
+
+func TestCreateUserPasswordTooLong(t *testing.T) {
+	us := newTestUserService(t)
+	_, appErr := us.CreateUser(context.Background(), userModule.CreateUserBody{
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Email:     "ada@example.com",
+		Password:  strings.Repeat("é", 37),
+	})
+	if appErr == nil || appErr.ErrorCode != "password_too_long" {
+		t.Errorf("got %v, want password_too_long", appErr)
+	}
+}
+
+func TestGetUserByID(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	user := createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+
+	found, appErr := us.GetUserByID(ctx, user.UserId.String())
+	if appErr != nil {
+		t.Fatalf("GetUserByID: %s", appErr.Message)
+	}
+	if found.ID != user.ID || *found.Email != "ada@example.com" {
+		t.Errorf("got user %d %v, want user %d", found.ID, found.Email, user.ID)
+	}
+
+	// Clients only ever see the public ID of a user.
+	body, err := json.Marshal(found)
+	if err != nil {
+		t.Fatalf("serializing user: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("deserializing user: %v", err)
+	}
+	if fields["userId"] != user.UserId.String() {
+		t.Errorf("got userId %v, want %s", fields["userId"], user.UserId)
+	}
+	for _, field := range []string{"id", "ID", "password", "Password"} {
+		if _, ok := fields[field]; ok {
+			t.Errorf("serialized user has field %s", field)
+		}
+	}
+
+	if _, appErr := us.GetUserByID(ctx, strconv.FormatUint(user.ID, 10)); appErr == nil || appErr.ErrorCode != "invalid_id" {
+		t.Errorf("getting a user by internal ID: got %v, want invalid_id", appErr)
+	}
+	if _, appErr := us.GetUserByID(ctx, ulid.Make().String()); appErr == nil || appErr.ErrorCode != "user_not_found" {
+		t.Errorf("getting an unknown user: got %v, want user_not_found", appErr)
+	}
+}
+
+func TestGetUsers(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	createTestUser(t, us, "Grace", "Hopper", "grace@example.com")
+	createTestUser(t, us, "Alan", "Turing", "alan@example.com")
+
+	page, appErr := us.GetUsers(ctx, userModule.ListUsersQuery{Page: 2, PageSize: 2, Sort: "firstName:desc"})
+	if appErr != nil {
+		t.Fatalf("GetUsers: %s", appErr.Message)
+	}
+	if page.Total != 3 || page.TotalPages != 2 || page.Page != 2 || page.PageSize != 2 {
+		t.Errorf("got total %d, %d pages, page %d of size %d, want 3 users on 2 pages of size 2", page.Total, page.TotalPages, page.Page, page.PageSize)
+	}
+	if emails := userEmails(page.Items); len(emails) != 1 || emails[0] != "ada@example.com" {
+		t.Errorf("got second page %v, want [ada@example.com]", emails)
+	}
+
+	page, appErr = us.GetUsers(ctx, userModule.ListUsersQuery{Sort: "lastName", UserFilters: userModule.UserFilters{Name: "R"}})
+	if appErr != nil {
+		t.Fatalf("GetUsers filtered by name: %s", appErr.Message)
+	}
+	if emails := userEmails(page.Items); len(emails) != 2 || emails[0] != "grace@example.com" || emails[1] != "alan@example.com" {
+		t.Errorf("filtering by name: got %v, want [grace@example.com alan@example.com]", emails)
+	}
+
+	for _, sort := range []string{"password", "firstName:sideways"} {
+		if _, appErr := us.GetUsers(ctx, userModule.ListUsersQuery{Sort: sort}); appErr == nil || appErr.ErrorCode != "invalid_sort" {
+			t.Errorf("sorting by %q: got %v, want invalid_sort", sort, appErr)
+		}
+	}
+}
+
+func TestGetUsersDatabaseFailure(t *testing.T) {
+	us := newTestUserService(t)
+	createTestUser(t, us, "Ada", "Lovelace", "ada@example.com")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// A failing query is the server's fault, unlike an invalid sort or cursor
+	if _, appErr := us.GetUsers(ctx, userModule.ListUsersQuery{}); appErr == nil || appErr.HttpStatusCode != http.StatusInternalServerError {
+		t.Errorf("GetUsers: got %v, want a 500 error", appErr)
+	}
+	if _, appErr := us.GetUsersByCursor(ctx, userModule.ListUsersQuery{}); appErr == nil || appErr.HttpStatusCode != http.StatusInternalServerError {
+		t.Errorf("GetUsersByCursor: got %v, want a 500 error", appErr)
+	}
+	if _, appErr := us.GetUsersByCursor(context.Background(), userModule.ListUsersQuery{Cursor: "not-a-cursor"}); appErr == nil || appErr.ErrorCode != "invalid_cursor" {
+		t.Errorf("GetUsersByCursor with an invalid cursor: got %v, want invalid_cursor", appErr)
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	user := createTestUser(t, us, "Test", "User", "ada@example.com")
+
+	updated, appErr := us.UpdateUser(ctx, user.UserId.String(), userModule.UpdateUserBody{FirstName: stringPointer("Ada")}, &user.Version)
+	if appErr != nil {
+		t.Fatalf("UpdateUser: %s", appErr.Message)
+	}
+	if updated.FirstName != "Ada" || updated.LastName != "User" {
+		t.Errorf("got name %q %q, want only the first name changed", updated.FirstName, updated.LastName)
+	}
+	if updated.Version != user.Version+1 {
+		t.Errorf("got version %d, want %d", updated.Version, user.Version+1)
+	}
+
+	_, appErr = us.UpdateUser(ctx, user.UserId.String(), userModule.UpdateUserBody{LastName: stringPointer("Lovelace")}, &user.Version)
+	if appErr == nil || appErr.ErrorCode != "version_conflict" {
+		t.Errorf("updating a stale version: got %v, want version_conflict", appErr)
+	}
+
+	_, appErr = us.UpdateUser(ctx, user.UserId.String(), userModule.UpdateUserBody{}, nil)
+	if appErr == nil || appErr.ErrorCode != "missing_data" {
+		t.Errorf("updating nothing: got %v, want missing_data", appErr)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	user := createTestUser(t, us, "Grace", "Hopper", "grace@example.com")
+	token, _, err := us.sessionStore.Create(ctx, user.UserId.String(), user.Role)
+	if err != nil {
+		t.Fatalf("creating session: %v", err)
+	}
+
+	if appErr := us.DeleteUser(ctx, user.UserId.String()); appErr != nil {
+		t.Fatalf("DeleteUser: %s", appErr.Message)
+	}
+
+	if _, appErr := us.GetUserByID(ctx, user.UserId.String()); appErr == nil || appErr.ErrorCode != "user_not_found" {
+		t.Errorf("getting the deleted user: got %v, want user_not_found", appErr)
+	}
+	if session, err := us.sessionStore.Get(ctx, token); err != nil || session != nil {
+		t.Errorf("session of the deleted user: got %v, %v, want it revoked", session, err)
+	}
+	if appErr := us.DeleteUser(ctx, user.UserId.String()); appErr == nil || appErr.ErrorCode != "user_not_found" {
+		t.Errorf("deleting the user again: got %v, want user_not_found", appErr)
+	}
+}
+
+func TestCheckDateOfBirth(t *testing.T) {
+	previous := config.Config
+	config.Config.Users.MinimumAgeYears = 13
+	config.Config.Users.MaximumAgeYears = 0
+	t.Cleanup(func() { config.Config = previous })
+
+	today := date.Today()
+	tests := []struct {
+		name string
+		dob  date.Date
+		want string
+	}{
+		{"today", today, "DOB must be in the past"},
+		{"tomorrow", today.AddYears(1), "DOB must be in the past"},
+		{"a day short of the minimum age", date.Of(today.AddYears(-13).Time().AddDate(0, 0, 1)), "you must be at least 13 years old"},
+		{"exactly the minimum age", today.AddYears(-13), ""},
+		{"the default maximum age", date.Of(today.AddYears(-121).Time().AddDate(0, 0, 1)), ""},
+		{"past the default maximum age", today.AddYears(-121), "DOB must not be more than 120 years ago"},
+	}
+	for _, test := range tests {
+		if got := checkDateOfBirth(test.dob); got != test.want {
+			t.Errorf("%s (%s): got %q, want %q", test.name, test.dob, got, test.want)
+		}
+	}
+}
+
+func TestUserDateOfBirth(t *testing.T) {
+	us := newTestUserService(t)
+	ctx := context.Background()
+	user, appErr := us.CreateUser(ctx, userModule.CreateUserBody{
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Email:     "ada@example.com",
+		Password:  "correct horse battery staple",
+		DOB:       stringPointer("1990-12-10"),
+	})
+	if appErr != nil {
+		t.Fatalf("CreateUser: %s", appErr.Message)
+	}
+	found, appErr := us.GetUserByID(ctx, user.UserId.String())
+	if appErr != nil {
+		t.Fatalf("GetUserByID: %s", appErr.Message)
+	}
+	if found.DOB == nil || found.DOB.String() != "1990-12-10" {
+		t.Errorf("got DOB %v, want 1990-12-10", found.DOB)
+	}
+
+	future := date.Today().AddYears(1).String()
+	for _, dob := range []string{future, "1990-02-30"} {
+		_, appErr := us.UpdateUser(ctx, user.UserId.String(), userModule.UpdateUserBody{DOB: stringPointer(dob)}, nil)
+		if appErr == nil || appErr.ErrorCode != "invalid_body" {
+			t.Errorf("setting the DOB to %s: got %v, want invalid_body", dob, appErr)
+		}
+	}
+	updated, appErr := us.UpdateUser(ctx, user.UserId.String(), userModule.UpdateUserBody{DOB: stringPointer("1991-01-01")}, nil)
+	if appErr != nil {
+		t.Fatalf("UpdateUser: %s", appErr.Message)
+	}
+	if updated.DOB.String() != "1991-01-01" {
+		t.Errorf("got DOB %s, want 1991-01-01", updated.DOB)
+	}
+}
+
+func TestCachedUsersLeaveOutSecrets(t *testing.T) {
+	previous := config.Config
+	t.Cleanup(func() { config.Config = previous })
+	config.Config.RepositoryCache.TTLSeconds = map[string]int{"users": 60}
+	env := newTestEnvironment(t)
+	ctx := context.Background()
+	user := createTestUser(t, env.userService, "Ada", "Lovelace", "ada@example.com")
+	// The avatar key is set behind the repository, which would keep the user out of the cache for a while
+	if err := env.db.Table("users").Where("id = ?", user.ID).Update("avatar_key", "avatars/ada-secret").Error; err != nil {
+		t.Fatalf("setting the avatar key: %v", err)
+	}
+	stored, err := env.userRepository.WithDeleted().FindByID(user.ID)
+	if err != nil || stored.Password == nil {
+		t.Fatalf("reading the stored user: got %+v, %v", stored, err)
+	}
+
+	// The second read is served from the cache, completed with the columns left out of it
+	var cached *repository.User
+	for i := 0; i < 2; i++ {
+		var appErr *appError.ApplicationError
+		if cached, appErr = env.userService.GetUserByID(ctx, user.UserId.String()); appErr != nil {
+			t.Fatalf("GetUserByID: %s", appErr.Message)
+		}
+	}
+	if cached.Password == nil || *cached.Password != *stored.Password || cached.AvatarKey == nil || *cached.AvatarKey != "avatars/ada-secret" {
+		t.Errorf("got password %v and avatar key %v, want the stored ones", cached.Password, cached.AvatarKey)
+	}
+
+	cachedKeys := 0
+	for _, key := range env.cache.Keys() {
+		if !strings.HasPrefix(key, "repository:users:") {
+			continue
+		}
+		value, _ := env.cache.Get(key)
+		if value != "" {
+			cachedKeys++
+		}
+		if strings.Contains(value, *stored.Password) || strings.Contains(value, "avatars/ada-secret") {
+			t.Errorf("got the password hash or the avatar key cached under %s", key)
+		}
+	}
+	if cachedKeys == 0 {
+		t.Errorf("got keys %v, want the user cached", env.cache.Keys())
+	}
+}
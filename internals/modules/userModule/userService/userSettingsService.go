@@ -0,0 +1,189 @@
+package userService
+
+import (
+	"backendService/internals/common/audit"
+	"backendService/internals/common/cache"
+	appError "backendService/internals/common/errors"
+	"backendService/internals/common/logger"
+	"backendService/internals/common/privacy"
+	"backendService/internals/modules/userModule/userModule"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"backendService/internals/setup/config"
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const userSettingsKeyPrefix = "user_settings:"
+
+// UserSettingsService reads and updates the preferences of users.
+// Reads go through the cache, so other modules can look up preferences, e.g. before notifying a user,
+// without hitting the database every time.
+type UserSettingsService struct {
+	userRepository         *repository.UserRepository
+	userSettingsRepository *repository.UserSettingsRepository
+	cacheService           *cache.CacheService
+	auditService           *audit.AuditService
+}
+
+// NewUserSettingsService creates a new instance of UserSettingsService.
+func NewUserSettingsService(userRepository *repository.UserRepository, userSettingsRepository *repository.UserSettingsRepository, cacheService *cache.CacheService, auditService *audit.AuditService) *UserSettingsService {
+	return &UserSettingsService{
+		userRepository:         userRepository,
+		userSettingsRepository: userSettingsRepository,
+		cacheService:           cacheService,
+		auditService:           auditService,
+	}
+}
+
+// DefaultUserSettings returns the settings of a user who never changed them.
+// Every notification channel is on, and marketing consent must be given explicitly.
+func DefaultUserSettings() repository.UserSettings {
+	return repository.UserSettings{
+		Locale:        config.Config.Settings.DefaultLocale,
+		Timezone:      config.Config.Settings.DefaultTimezone,
+		Notifications: repository.NotificationPreferences{Email: true, SMS: true, Push: true},
+	}
+}
+
+// GetSettings returns the settings of the user identified by id, falling back to the defaults.
+func (uss *UserSettingsService) GetSettings(ctx context.Context, id string) (*repository.UserSettings, *appError.ApplicationError) {
+	var cached repository.UserSettings
+	err := uss.cacheService.Get(ctx, userSettingsKeyPrefix+id, &cached)
+	if err == nil {
+		return &cached, nil
+	}
+	if err != redis.Nil {
+		// The cache is only a shortcut, so fall back to the database when it is unavailable
+		logger.Error("service", "user_settings_service", "GetSettings", "failed to read cached settings", err)
+	}
+
+	user, appErr := findUserByPublicID(uss.userRepository.WithContext(ctx), id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	settings, appErr := uss.findSettings(ctx, user)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if err := uss.cacheService.Set(ctx, userSettingsKeyPrefix+id, settings, settingsCacheTTL()); err != nil {
+		logger.Error("service", "user_settings_service", "GetSettings", "failed to cache settings", err)
+	}
+	return settings, nil
+}
+
+// UpdateSettings partially updates the settings of the user identified by id.
+// Only the fields present in the body are changed. Granting or withdrawing marketing consent
+// is timestamped and recorded in the audit log, as proof of consent may be requested later.
+func (uss *UserSettingsService) UpdateSettings(ctx context.Context, id string, body userModule.UpdateSettingsBody) (*repository.UserSettings, *appError.ApplicationError) {
+	if body.IsEmpty() {
+		return nil, appError.NewBadRequestError("missing_data", "at least one setting is required")
+	}
+
+	user, appErr := findUserByPublicID(uss.userRepository.WithContext(ctx), id)
+	if appErr != nil {
+		return nil, appErr
+	}
+	settings, appErr := uss.findSettings(ctx, user)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if body.Locale != nil {
+		settings.Locale = *body.Locale
+	}
+	if body.Timezone != nil {
+		settings.Timezone = *body.Timezone
+	}
+	if body.Notifications != nil {
+		if body.Notifications.Email != nil {
+			settings.Notifications.Email = *body.Notifications.Email
+		}
+		if body.Notifications.SMS != nil {
+			settings.Notifications.SMS = *body.Notifications.SMS
+		}
+		if body.Notifications.Push != nil {
+			settings.Notifications.Push = *body.Notifications.Push
+		}
+	}
+	consentChanged := body.MarketingConsent != nil && *body.MarketingConsent != settings.MarketingConsent
+	if consentChanged {
+		now := time.Now()
+		settings.MarketingConsent = *body.MarketingConsent
+		settings.MarketingConsentAt = &now
+	}
+
+	if err := uss.userSettingsRepository.WithContext(ctx).Save(settings); err != nil {
+		logger.Error("service", "user_settings_service", "UpdateSettings", "failed to save settings", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to update settings")
+	}
+	uss.Invalidate(ctx, id)
+
+	if consentChanged {
+		action := "user.marketing_consent_withdrawn"
+		if settings.MarketingConsent {
+			action = "user.marketing_consent_granted"
+		}
+		if err := uss.auditService.Record(ctx, audit.Entry{
+			Action:     action,
+			ActorId:    id,
+			TargetType: "user",
+			TargetId:   id,
+			Details:    map[string]interface{}{"consentAt": settings.MarketingConsentAt},
+		}); err != nil {
+			logger.Error("service", "user_settings_service", "UpdateSettings", "failed to record audit log", err)
+		}
+	}
+	return settings, nil
+}
+
+// Invalidate drops the cached settings of the user identified by id, so the next read goes to the database.
+// It goes on even when ctx was cancelled, as a missed invalidation would keep stale settings in the cache.
+func (uss *UserSettingsService) Invalidate(ctx context.Context, id string) {
+	if err := uss.cacheService.Delete(context.WithoutCancel(ctx), userSettingsKeyPrefix+id); err != nil {
+		logger.Error("service", "user_settings_service", "Invalidate", "failed to invalidate cached settings", err)
+	}
+}
+
+// RegisterPersonalData registers the stored settings with the personal data registry.
+func (uss *UserSettingsService) RegisterPersonalData(registry *privacy.Registry) {
+	registry.RegisterSection("settings", func(ctx context.Context, subject privacy.Subject) (interface{}, error) {
+		settings, err := uss.userSettingsRepository.WithContext(ctx).FindByUserID(subject.ID)
+		if err != nil || settings == nil {
+			return nil, err
+		}
+		return settings, nil
+	})
+	registry.RegisterEraser("settings", func(ctx context.Context, subject privacy.Subject) error {
+		if err := uss.userSettingsRepository.WithContext(ctx).DeleteByUserID(subject.ID); err != nil {
+			return err
+		}
+		uss.Invalidate(ctx, subject.UserId)
+		return nil
+	})
+}
+
+// findSettings loads the stored settings of the user, or the defaults when none are stored.
+func (uss *UserSettingsService) findSettings(ctx context.Context, user *repository.User) (*repository.UserSettings, *appError.ApplicationError) {
+	settings, err := uss.userSettingsRepository.WithContext(ctx).FindByUserID(user.ID)
+	if err != nil {
+		logger.Error("service", "user_settings_service", "findSettings", "failed to retrieve settings", err)
+		return nil, appError.NewApplicationError("internal_error", "failed to retrieve settings")
+	}
+	if settings == nil {
+		defaults := DefaultUserSettings()
+		defaults.UserID = user.ID
+		settings = &defaults
+	}
+	return settings, nil
+}
+
+// settingsCacheTTL returns how long settings stay cached, defaulting to one hour.
+func settingsCacheTTL() time.Duration {
+	if config.Config.Settings.CacheTTLMinutes <= 0 {
+		return time.Hour
+	}
+	return time.Duration(config.Config.Settings.CacheTTLMinutes) * time.Minute
+}
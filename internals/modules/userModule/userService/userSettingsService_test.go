@@ -0,0 +1,86 @@
+package userService
+
+import (
+	"backendService/internals/modules/userModule/userModule"
+	repository "backendService/internals/modules/userModule/userRepository"
+	"backendService/internals/setup/config"
+	"context"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+)
+
+func boolPointer(value bool) *bool {
+	return &value
+}
+
+func TestUserSettings(t *testing.T) {
+	env := newTestEnvironment(t)
+	config.Config.Settings.DefaultLocale = "en-GB"
+	config.Config.Settings.DefaultTimezone = "Europe/London"
+	uss := NewUserSettingsService(env.userRepository, repository.NewUserSettingsRepository(env.db), env.cacheService, env.auditService)
+	ctx := context.Background()
+	user := createTestUser(t, env.userService, "Ada", "Lovelace", "ada@example.com")
+	id := user.UserId.String()
+
+	settings, appErr := uss.GetSettings(ctx, id)
+	if appErr != nil {
+		t.Fatalf("GetSettings: %s", appErr.Message)
+	}
+	if settings.Locale != "en-GB" || settings.Timezone != "Europe/London" || !settings.Notifications.Email || settings.MarketingConsent {
+		t.Errorf("got settings %+v, want the defaults", settings)
+	}
+	if _, ok := env.cache.Get(userSettingsKeyPrefix + id); !ok {
+		t.Error("got no cached settings after reading them")
+	}
+
+	updated, appErr := uss.UpdateSettings(ctx, id, userModule.UpdateSettingsBody{
+		Locale:        stringPointer("fr-FR"),
+		Notifications: &userModule.UpdateNotificationsBody{SMS: boolPointer(false)},
+	})
+	if appErr != nil {
+		t.Fatalf("UpdateSettings: %s", appErr.Message)
+	}
+	if updated.Locale != "fr-FR" || updated.Timezone != "Europe/London" || updated.Notifications.SMS || !updated.Notifications.Email {
+		t.Errorf("got settings %+v, want only the locale and SMS notifications changed", updated)
+	}
+	if _, ok := env.cache.Get(userSettingsKeyPrefix + id); ok {
+		t.Error("got cached settings after updating them, want them invalidated")
+	}
+	if settings, _ := uss.GetSettings(ctx, id); settings.Locale != "fr-FR" || settings.Notifications.SMS {
+		t.Errorf("got settings %+v after the update, want the updated settings", settings)
+	}
+
+	for i := 0; i < 2; i++ {
+		updated, appErr = uss.UpdateSettings(ctx, id, userModule.UpdateSettingsBody{MarketingConsent: boolPointer(true)})
+		if appErr != nil {
+			t.Fatalf("UpdateSettings granting consent: %s", appErr.Message)
+		}
+	}
+	if !updated.MarketingConsent || updated.MarketingConsentAt == nil {
+		t.Errorf("got consent %v at %v, want it granted with a timestamp", updated.MarketingConsent, updated.MarketingConsentAt)
+	}
+	if _, appErr := uss.UpdateSettings(ctx, id, userModule.UpdateSettingsBody{MarketingConsent: boolPointer(false)}); appErr != nil {
+		t.Fatalf("UpdateSettings withdrawing consent: %s", appErr.Message)
+	}
+	// Granting consent twice only records it once.
+	actions := auditActions(t, env.userService, user)
+	if len(actions) != 2 || actions[0] != "user.marketing_consent_granted" || actions[1] != "user.marketing_consent_withdrawn" {
+		t.Errorf("got audit log %v, want consent granted then withdrawn", actions)
+	}
+}
+
+func TestUpdateSettingsRejectsInvalidRequests(t *testing.T) {
+	env := newTestEnvironment(t)
+	uss := NewUserSettingsService(env.userRepository, repository.NewUserSettingsRepository(env.db), env.cacheService, env.auditService)
+	ctx := context.Background()
+	user := createTestUser(t, env.userService, "Ada", "Lovelace", "ada@example.com")
+
+	empty := userModule.UpdateSettingsBody{Notifications: &userModule.UpdateNotificationsBody{}}
+	if _, appErr := uss.UpdateSettings(ctx, user.UserId.String(), empty); appErr == nil || appErr.ErrorCode != "missing_data" {
+		t.Errorf("updating nothing: got %v, want missing_data", appErr)
+	}
+	if _, appErr := uss.GetSettings(ctx, ulid.Make().String()); appErr == nil || appErr.ErrorCode != "user_not_found" {
+		t.Errorf("getting the settings of an unknown user: got %v, want user_not_found", appErr)
+	}
+}
@@ -3,19 +3,31 @@ package app
 import (
 	"backendService/internals/common/cache"
 	"backendService/internals/common/logger"
+	"backendService/internals/common/storage"
 	"backendService/internals/setup/config"
 	"backendService/internals/setup/database"
+	"backendService/internals/setup/migrations"
 	"backendService/internals/setup/server"
 )
 
 func Start() {
 	// Loading Configs
 	config.LoadConfig()
+	// Refuse to sign cursors and verification codes with a key anyone can read
+	if err := config.Config.CheckSecretKey(); err != nil {
+		logger.Fatal("app", "Start", "checkSecretKey", err)
+	}
 
 	// Setup Database
 	database.InitializeDataBase(config.Config.Database.Type)
+	// Refuse to serve requests against a schema older than the code
+	if err := migrations.CheckSchema(database.Db); err != nil {
+		logger.Fatal("app", "Start", "checkSchema", err)
+	}
 	// Setup Cache
 	cache.InitializeCacheService()
+	// Setup Storage
+	storage.InitializeStorage()
 
 	// Setting up server
 	server := server.NewServer(&config.Config, database.Db)
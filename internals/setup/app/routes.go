@@ -1,7 +1,9 @@
 package app
 
 import (
+	"backendService/internals/common/storage"
 	"backendService/internals/modules/authModule"
+	"backendService/internals/modules/organizationModule"
 	"backendService/internals/modules/userModule"
 
 	"github.com/gin-gonic/gin"
@@ -10,9 +12,12 @@ import (
 func SetupAllRoutes(app *gin.Engine) {
 
 	userModule.Initialize()
+	organizationModule.Initialize()
 	authModule.Initialize()
 
 	userModule.UserRouter.SetupRoutes(app)
+	organizationModule.OrganizationRouter.SetupRoutes(app)
 	authModule.AuthRouter.SetupRoutes(app)
+	storage.NewStorageRouter(storage.Store).SetupRoutes(app)
 
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -13,6 +14,9 @@ import (
 
 var Config AppConfig
 
+// publicSecretKeys are the secret keys committed in the default config and in .env.example, only fit for development.
+var publicSecretKeys = map[string]bool{"development_secret_key": true, "change_me": true}
+
 // LoadConfig loads the configuration from environment variables and config files
 func LoadConfig() {
 	// Load environment variables from .env file
@@ -59,3 +63,16 @@ func LoadConfig() {
 		logger.Fatal("config", "LoadConfig", "unmarshal", err)
 	}
 }
+
+// CheckSecretKey returns an error when the secret key cannot be trusted to sign the values handed out to clients:
+// when it is empty, or when it is still one of the committed keys outside of development.
+// The key is set with the APP_SECRET_KEY environment variable.
+func (c AppConfig) CheckSecretKey() error {
+	if c.App.SecretKey == "" {
+		return errors.New("app.secret_key is empty, set APP_SECRET_KEY")
+	}
+	if publicSecretKeys[c.App.SecretKey] && c.App.Env != "development" {
+		return fmt.Errorf("app.secret_key is a committed development key in the %s environment, set APP_SECRET_KEY", c.App.Env)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package config
+
+import "testing"
+
+func TestCheckSecretKey(t *testing.T) {
+	tests := []struct {
+		env, key string
+		valid    bool
+	}{
+		{"development", "development_secret_key", true},
+		{"development", "", false},
+		{"production", "", false},
+		{"production", "development_secret_key", false},
+		{"staging", "change_me", false},
+		{"production", "a long random key", true},
+	}
+	for _, test := range tests {
+		var c AppConfig
+		c.App.Env = test.env
+		c.App.SecretKey = test.key
+		if err := c.CheckSecretKey(); (err == nil) != test.valid {
+			t.Errorf("key %q in %s: got error %v, want valid %v", test.key, test.env, err, test.valid)
+		}
+	}
+}
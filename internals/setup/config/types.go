@@ -8,6 +8,8 @@ type Database struct {
 	Database string `mapstructure:"database"`
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
+	// QueryTimeoutSeconds bounds the database and cache work done for a single request
+	QueryTimeoutSeconds int `mapstructure:"query_timeout_seconds"`
 }
 
 // ApplicationConfig holds the application configuration values
@@ -16,6 +18,9 @@ type ApplicationConfig struct {
 	Env      string `mapstructure:"env"`
 	LogLevel string `mapstructure:"log_level"`
 	GinMode  string `mapstructure:"gin_mode"`
+	// SecretKey signs opaque values handed out to clients, such as pagination cursors, and hashes verification codes.
+	// Startup fails when it is empty, or left at a committed development key outside of development.
+	SecretKey string `mapstructure:"secret_key"`
 }
 
 type CacheConfig struct {
@@ -25,9 +30,110 @@ type CacheConfig struct {
 	Database int    `mapstructure:"database"`
 }
 
+// AuthConfig holds the authentication configuration values
+type AuthConfig struct {
+	SessionTTLMinutes   int `mapstructure:"session_ttl_minutes"`   // SessionTTLMinutes is how long an access token stays valid
+	ReauthWindowMinutes int `mapstructure:"reauth_window_minutes"` // ReauthWindowMinutes is how recent a login must be for sensitive operations
+	// ImpersonationTTLMinutes is how long an impersonation token issued to support staff stays valid
+	ImpersonationTTLMinutes int `mapstructure:"impersonation_ttl_minutes"`
+	// ContactChangeTTLMinutes is how long the code sent to verify a new email or mobile stays valid
+	ContactChangeTTLMinutes int `mapstructure:"contact_change_ttl_minutes"`
+}
+
+// JobsConfig holds the background job configuration values
+type JobsConfig struct {
+	Directory      string `mapstructure:"directory"`       // Directory is where jobs write the files they produce
+	RetentionHours int    `mapstructure:"retention_hours"` // RetentionHours is how long finished jobs and their files are kept
+}
+
+// ExportConfig holds the data export configuration values
+type ExportConfig struct {
+	// MaxSyncRows is the largest export streamed directly in the response, larger exports run as a background job
+	MaxSyncRows int `mapstructure:"max_sync_rows"`
+}
+
+// StorageConfig holds the file storage configuration values
+type StorageConfig struct {
+	Driver    string             `mapstructure:"driver"`     // Driver selects the storage backend, either "local" or "s3"
+	PublicURL string             `mapstructure:"public_url"` // PublicURL is the base URL stored files are served from
+	Local     LocalStorageConfig `mapstructure:"local"`
+	S3        S3StorageConfig    `mapstructure:"s3"`
+}
+
+// LocalStorageConfig holds the configuration values of the local filesystem storage backend
+type LocalStorageConfig struct {
+	Directory string `mapstructure:"directory"`
+}
+
+// S3StorageConfig holds the configuration values of the S3 compatible storage backend.
+// UsePathStyle addresses the bucket in the path instead of the host name, as most S3 compatible servers expect.
+type S3StorageConfig struct {
+	Endpoint        string `mapstructure:"endpoint"`
+	Region          string `mapstructure:"region"`
+	Bucket          string `mapstructure:"bucket"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+	UsePathStyle    bool   `mapstructure:"use_path_style"`
+}
+
+// SettingsConfig holds the user settings configuration values
+type SettingsConfig struct {
+	DefaultLocale   string `mapstructure:"default_locale"`    // DefaultLocale applies to users who never chose a locale
+	DefaultTimezone string `mapstructure:"default_timezone"`  // DefaultTimezone applies to users who never chose a timezone
+	CacheTTLMinutes int    `mapstructure:"cache_ttl_minutes"` // CacheTTLMinutes is how long settings are cached after being read
+}
+
+// PrivacyConfig holds the personal data export and erasure configuration values
+type PrivacyConfig struct {
+	// ErasureGraceDays is how long after asking for it an account is erased, during which the user can change their mind
+	ErasureGraceDays int `mapstructure:"erasure_grace_days"`
+	// ErasureIntervalMinutes is how often accounts due for erasure are looked for
+	ErasureIntervalMinutes int `mapstructure:"erasure_interval_minutes"`
+}
+
+// OrganizationsConfig holds the organization configuration values
+type OrganizationsConfig struct {
+	// InvitationTTLHours is how long an invitation to join an organization can be accepted
+	InvitationTTLHours int `mapstructure:"invitation_ttl_hours"`
+	// InvitationURL is the page of the client where invitations are accepted, the invitation token is appended to it
+	InvitationURL string `mapstructure:"invitation_url"`
+}
+
+// UsersConfig holds the user profile configuration values
+type UsersConfig struct {
+	// MinimumAgeYears is how old users must be to sign up or to set their date of birth, zero allows any age
+	MinimumAgeYears int `mapstructure:"minimum_age_years"`
+	// MaximumAgeYears is the oldest plausible age, dates of birth further in the past are rejected
+	MaximumAgeYears int `mapstructure:"maximum_age_years"`
+}
+
+// SoftDeleteConfig holds the configuration values of soft-deleted records
+type SoftDeleteConfig struct {
+	// RetentionDays is how long soft-deleted records can be restored before they are purged for good
+	RetentionDays int `mapstructure:"retention_days"`
+	// PurgeIntervalMinutes is how often soft-deleted records due for purging are looked for
+	PurgeIntervalMinutes int `mapstructure:"purge_interval_minutes"`
+}
+
+// RepositoryCacheConfig holds the configuration values of the read-through cache of repositories
+type RepositoryCacheConfig struct {
+	// TTLSeconds is how long records stay cached after being read, by table name. Tables left out are not cached
+	TTLSeconds map[string]int `mapstructure:"ttl_seconds"`
+}
+
 // AppConfig holds the overall configuration
 type AppConfig struct {
-	Database Database          `mapstructure:"database"`
-	App      ApplicationConfig `mapstructure:"app"`
-	Cache    CacheConfig       `mapstructure:"cache"`
+	Database        Database              `mapstructure:"database"`
+	App             ApplicationConfig     `mapstructure:"app"`
+	Cache           CacheConfig           `mapstructure:"cache"`
+	Auth            AuthConfig            `mapstructure:"auth"`
+	Jobs            JobsConfig            `mapstructure:"jobs"`
+	Export          ExportConfig          `mapstructure:"export"`
+	Storage         StorageConfig         `mapstructure:"storage"`
+	Settings        SettingsConfig        `mapstructure:"settings"`
+	Privacy         PrivacyConfig         `mapstructure:"privacy"`
+	Organizations   OrganizationsConfig   `mapstructure:"organizations"`
+	Users           UsersConfig           `mapstructure:"users"`
+	SoftDelete      SoftDeleteConfig      `mapstructure:"soft_delete"`
+	RepositoryCache RepositoryCacheConfig `mapstructure:"repository_cache"`
 }
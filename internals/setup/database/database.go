@@ -4,6 +4,7 @@ import (
 	"backendService/internals/common/logger"
 	"backendService/internals/setup/config"
 	"fmt"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
@@ -39,13 +40,13 @@ func Connect(config *dbConfig) error {
 
 	switch config.dbType {
 	case "mysql":
-		Db, err = gorm.Open(mysql.Open(config.mysqlDSN()), &gorm.Config{})
+		Db, err = gorm.Open(mysql.Open(config.mysqlDSN()), &gorm.Config{TranslateError: true})
 
 	case "postgres":
-		Db, err = gorm.Open(postgres.Open(config.postgresDSN()), &gorm.Config{})
+		Db, err = gorm.Open(postgres.Open(config.postgresDSN()), &gorm.Config{TranslateError: true})
 
 	case "sqlite":
-		Db, err = gorm.Open(sqlite.Open(config.dbName+".sqlite"), &gorm.Config{})
+		Db, err = gorm.Open(sqlite.Open(config.dbName+".sqlite"), &gorm.Config{TranslateError: true})
 
 	default:
 		return fmt.Errorf("unsupported database type: %s", config.dbType)
@@ -90,6 +91,15 @@ func InitializeDataBase(databaseType string) error {
 	return nil
 }
 
+// QueryTimeout returns how long the database and cache work of a single request may take
+// before it is cancelled, defaulting to thirty seconds.
+func QueryTimeout() time.Duration {
+	if config.Config.Database.QueryTimeoutSeconds <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(config.Config.Database.QueryTimeoutSeconds) * time.Second
+}
+
 func CloseDB() {
 	logger.Info("database", "CloseDB", "Closing database connection")
 	dbConnection, _ := Db.DB()
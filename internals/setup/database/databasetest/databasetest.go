@@ -0,0 +1,47 @@
+// Package databasetest opens databases for tests.
+package databasetest
+
+import (
+	"backendService/internals/setup/database"
+	"backendService/internals/setup/migrations"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open opens a SQLite database in a temporary directory of the test and applies the migrations to it.
+// The database replaces the one of the application until the test ends, as repositories run their
+// queries on it whatever connection they are given.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.sqlite")+"?_busy_timeout=5000"), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("opening the test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("opening the test database: %v", err)
+	}
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("migrating the test database: %v", err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("migrating the test database: %v", err)
+	}
+
+	previous := database.Db
+	database.Db = db
+	t.Cleanup(func() {
+		database.Db = previous
+		sqlDB.Close()
+	})
+	return db
+}
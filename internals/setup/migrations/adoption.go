@@ -0,0 +1,112 @@
+package migrations
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// initialSchemaVersion is the version of the migration creating the tables previously created by AutoMigrate.
+	initialSchemaVersion = 20261019000000
+	// adoptionVersion precedes the initial schema, so that a users table created by AutoMigrate is brought up to date
+	// before the initial schema indexes its new columns. The version column is added later by add_version_columns.
+	adoptionVersion = 20261018235959
+)
+
+// adoptedColumn is a users column missing from the tables created by AutoMigrate, defined as in the initial schema.
+type adoptedColumn struct {
+	name       string
+	definition string
+	// index names the index of the column, if it has one.
+	index string
+}
+
+// adoptedColumns lists the columns added to the users table since it was last created by AutoMigrate, per dialect.
+var adoptedColumns = map[string][]adoptedColumn{
+	"mysql": {
+		{name: "role", definition: "varchar(191) NOT NULL DEFAULT 'user'"},
+		{name: "avatar_key", definition: "longtext"},
+		{name: "avatar_url", definition: "longtext"},
+		{name: "avatar_thumbnail_url", definition: "longtext"},
+		{name: "erasure_scheduled_at", definition: "timestamp NULL", index: "idx_users_erasure_scheduled_at"},
+		{name: "erased_at", definition: "timestamp NULL"},
+		{name: "merged_into_id", definition: "bigint unsigned", index: "idx_users_merged_into_id"},
+	},
+	"postgres": {
+		{name: "role", definition: "text NOT NULL DEFAULT 'user'"},
+		{name: "avatar_key", definition: "text"},
+		{name: "avatar_url", definition: "text"},
+		{name: "avatar_thumbnail_url", definition: "text"},
+		{name: "erasure_scheduled_at", definition: "timestamp", index: "idx_users_erasure_scheduled_at"},
+		{name: "erased_at", definition: "timestamp"},
+		{name: "merged_into_id", definition: "bigint", index: "idx_users_merged_into_id"},
+	},
+	"sqlite": {
+		{name: "role", definition: "text NOT NULL DEFAULT 'user'"},
+		{name: "avatar_key", definition: "text"},
+		{name: "avatar_url", definition: "text"},
+		{name: "avatar_thumbnail_url", definition: "text"},
+		{name: "erasure_scheduled_at", definition: "timestamp", index: "idx_users_erasure_scheduled_at"},
+		{name: "erased_at", definition: "timestamp"},
+		{name: "merged_into_id", definition: "integer", index: "idx_users_merged_into_id"},
+	},
+}
+
+// dateOfBirthToDate lists, per dialect, the statements turning the timestamp date of birth AutoMigrate created
+// into a date. SQLite cannot change the type of a column, so the dates are copied to a new column, keeping
+// the date the timestamp was written with rather than converting it to UTC.
+var dateOfBirthToDate = map[string][]string{
+	"mysql":    {"ALTER TABLE users MODIFY COLUMN dob date NULL"},
+	"postgres": {"ALTER TABLE users ALTER COLUMN dob TYPE date USING dob::date"},
+	"sqlite": {
+		"ALTER TABLE users ADD COLUMN dob_date date",
+		"UPDATE users SET dob_date = substr(dob, 1, 10)",
+		"ALTER TABLE users DROP COLUMN dob",
+		"ALTER TABLE users RENAME COLUMN dob_date TO dob",
+	},
+}
+
+func init() {
+	Register(Migration{Version: adoptionVersion, Name: "adopt_auto_migrated_schema", Up: adoptAutoMigratedSchema})
+}
+
+// adoptAutoMigratedSchema adds the columns missing from a users table created by AutoMigrate and stores its
+// dates of birth as dates. It does nothing on a new database, whose users table is created by the initial schema.
+// It cannot be rolled back, as the columns it adds may hold data by then.
+func adoptAutoMigratedSchema(tx *gorm.DB) error {
+	migrator := tx.Migrator()
+	if !migrator.HasTable("users") {
+		return nil
+	}
+	dialect := tx.Dialector.Name()
+
+	for _, column := range adoptedColumns[dialect] {
+		if !migrator.HasColumn("users", column.name) {
+			if err := tx.Exec("ALTER TABLE users ADD COLUMN " + column.name + " " + column.definition).Error; err != nil {
+				return err
+			}
+		}
+		if column.index != "" && !migrator.HasIndex("users", column.index) {
+			if err := tx.Exec("CREATE INDEX " + column.index + " ON users (" + column.name + ")").Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	columnTypes, err := migrator.ColumnTypes("users")
+	if err != nil {
+		return err
+	}
+	for _, columnType := range columnTypes {
+		if columnType.Name() != "dob" || strings.EqualFold(columnType.DatabaseTypeName(), "date") {
+			continue
+		}
+		for _, statement := range dateOfBirthToDate[dialect] {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
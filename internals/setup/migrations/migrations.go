@@ -0,0 +1,152 @@
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SourceDirectory is where the SQL migrations live in the source tree, one directory per dialect.
+// They are embedded into the binary, so a new migration needs a rebuild to be applied.
+const SourceDirectory = "internals/setup/migrations/sql"
+
+// Dialects are the databases migrations are written for, named like gorm dialectors.
+var Dialects = []string{"mysql", "postgres", "sqlite"}
+
+//go:embed sql
+var sqlFiles embed.FS
+
+var (
+	// ErrSchemaBehind is returned when the database is missing migrations known to the application.
+	ErrSchemaBehind = errors.New("database schema is behind")
+	// ErrIrreversible is returned when rolling back a migration that has no down migration.
+	ErrIrreversible = errors.New("migration cannot be rolled back")
+)
+
+// sqlFileName matches the name of a SQL migration file, e.g. 20261019000000_initial_schema.up.sql.
+var sqlFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned change to the database schema. Versions are timestamps, so migrations
+// written on different branches rarely collide, and are applied in increasing order.
+type Migration struct {
+	Version int64
+	Name    string
+	// Up applies the change and Down reverts it. A nil Down makes the migration irreversible.
+	Up   func(tx *gorm.DB) error
+	Down func(tx *gorm.DB) error
+}
+
+// goMigrations holds the migrations written in Go, which run on every dialect.
+var goMigrations []Migration
+
+// Register adds a migration written in Go, for changes SQL alone cannot express such as backfilling data.
+// It is meant to be called from an init function of this package. The migration can check
+// tx.Dialector.Name() when it has to behave differently per dialect.
+func Register(migration Migration) {
+	goMigrations = append(goMigrations, migration)
+}
+
+// load returns every migration for the given dialect, in the order they are applied.
+func load(dialect string) ([]Migration, error) {
+	byVersion := make(map[int64]*Migration)
+	for i := range goMigrations {
+		migration := goMigrations[i]
+		if _, exists := byVersion[migration.Version]; exists {
+			return nil, fmt.Errorf("migration %d is defined twice", migration.Version)
+		}
+		byVersion[migration.Version] = &migration
+	}
+
+	directory := path.Join("sql", dialect)
+	entries, err := fs.ReadDir(sqlFiles, directory)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	sqlVersions := make(map[int64]bool)
+	for _, entry := range entries {
+		match := sqlFileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(sqlFiles, path.Join(directory, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, exists := byVersion[version]
+		if exists && !sqlVersions[version] {
+			return nil, fmt.Errorf("migration %d is defined both in Go and in SQL", version)
+		}
+		if !exists {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+			sqlVersions[version] = true
+		}
+		if match[3] == "up" {
+			migration.Up = execSQL(string(content))
+		} else {
+			migration.Down = execSQL(string(content))
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == nil {
+			return nil, fmt.Errorf("migration %d_%s has no up migration for %s", migration.Version, migration.Name, dialect)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// execSQL returns a function running the statements of a SQL migration one at a time, since not every
+// driver accepts several statements in a single call. Statements must end with a semicolon at the end of a line.
+func execSQL(content string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, statement := range splitStatements(content) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// splitStatements splits a SQL script into its statements, leaving out comment lines.
+func splitStatements(content string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// newVersion returns the version of a migration created at the given time.
+func newVersion(at time.Time) int64 {
+	version, _ := strconv.ParseInt(at.UTC().Format("20060102150405"), 10, 64)
+	return version
+}
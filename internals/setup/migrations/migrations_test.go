@@ -0,0 +1,289 @@
+package migrations
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDatabase opens an empty SQLite database in a temporary directory of the test.
+func openTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.sqlite")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("opening the test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("opening the test database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// createTableMigration returns a migration creating a table on the way up and dropping it on the way down.
+func createTableMigration(version int64, table string) Migration {
+	return Migration{
+		Version: version,
+		Name:    "create_" + table,
+		Up:      execSQL("CREATE TABLE " + table + " (id INTEGER PRIMARY KEY);"),
+		Down:    execSQL("DROP TABLE " + table + ";"),
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- Creates the widgets
+CREATE TABLE widgets (
+    id INTEGER PRIMARY KEY,
+    name TEXT -- inline comments are kept
+);
+
+  -- Indented comment
+CREATE TRIGGER widgets_touch AFTER UPDATE ON widgets BEGIN
+    UPDATE widgets SET name = name WHERE id = new.id; END;
+INSERT INTO widgets (name) VALUES ('a;b')`
+
+	want := []string{
+		"CREATE TABLE widgets (\n    id INTEGER PRIMARY KEY,\n    name TEXT -- inline comments are kept\n);",
+		"CREATE TRIGGER widgets_touch AFTER UPDATE ON widgets BEGIN\n    UPDATE widgets SET name = name WHERE id = new.id; END;",
+		"INSERT INTO widgets (name) VALUES ('a;b')",
+	}
+	if got := splitStatements(script); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := splitStatements("-- nothing to do\n\n"); len(got) != 0 {
+		t.Errorf("got %q from a script of comments, want nothing", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	for _, dialect := range Dialects {
+		migrations, err := load(dialect)
+		if err != nil {
+			t.Errorf("loading the %s migrations: %v", dialect, err)
+			continue
+		}
+		if len(migrations) == 0 {
+			t.Errorf("got no %s migrations", dialect)
+		}
+		for i, migration := range migrations {
+			// Only the adoption of AutoMigrate tables and the initial schema cannot be rolled back
+			irreversible := migration.Version <= initialSchemaVersion
+			if migration.Up == nil || (migration.Down == nil) != irreversible {
+				t.Errorf("%s migration %d_%s: got up %t and down %t, want down %t", dialect, migration.Version, migration.Name,
+					migration.Up != nil, migration.Down != nil, !irreversible)
+			}
+			if i > 0 && migration.Version <= migrations[i-1].Version {
+				t.Errorf("%s migrations are not in order at %d", dialect, migration.Version)
+			}
+		}
+	}
+
+	previous := goMigrations
+	t.Cleanup(func() { goMigrations = previous })
+	goMigrations = []Migration{{Version: initialSchemaVersion, Name: "duplicate", Up: execSQL("")}}
+	if _, err := load("sqlite"); err == nil || !strings.Contains(err.Error(), "both in Go and in SQL") {
+		t.Errorf("loading a version defined in Go and SQL: got %v", err)
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	db := openTestDatabase(t)
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	if err := CheckSchema(db); !errors.Is(err, ErrSchemaBehind) {
+		t.Errorf("checking an empty database: got %v, want ErrSchemaBehind", err)
+	}
+
+	all := len(migrator.migrations)
+	applied, err := migrator.Up(1)
+	if err != nil || len(applied) != 1 {
+		t.Fatalf("applying one migration: got %d, %v", len(applied), err)
+	}
+	applied, err = migrator.Up(0)
+	if err != nil || len(applied) != all-1 {
+		t.Fatalf("applying the rest: got %d, %v, want %d", len(applied), err, all-1)
+	}
+	if err := CheckSchema(db); err != nil {
+		t.Errorf("CheckSchema: %v", err)
+	}
+	if !db.Migrator().HasTable("users") || !db.Migrator().HasTable("users_search") {
+		t.Error("got no users tables after migrating up")
+	}
+
+	// Every migration but the adoption of AutoMigrate tables and the initial schema can be rolled back and applied again
+	reversible := all - 2
+	rolledBack, err := migrator.Down(all)
+	if !errors.Is(err, ErrIrreversible) || len(rolledBack) != reversible {
+		t.Fatalf("rolling back everything: got %d, %v, want %d and ErrIrreversible", len(rolledBack), err, reversible)
+	}
+	if rolledBack[0].Version != migrator.migrations[all-1].Version {
+		t.Errorf("got %d rolled back first, want the latest migration", rolledBack[0].Version)
+	}
+	if !db.Migrator().HasTable("users") || db.Migrator().HasTable("users_search") {
+		t.Error("got the tables of the initial schema dropped or the later ones kept")
+	}
+	if pending, err := migrator.Pending(); err != nil || len(pending) != reversible {
+		t.Errorf("got %d pending migrations, %v, want %d", len(pending), err, reversible)
+	}
+	if applied, err := migrator.Up(0); err != nil || len(applied) != reversible {
+		t.Errorf("migrating up again: got %d, %v, want %d", len(applied), err, reversible)
+	}
+}
+
+// autoMigratedUser is the user model AutoMigrate created the users table from, before migrations existed.
+type autoMigratedUser struct {
+	ID               uint64         `gorm:"primary_key"`
+	CreatedAt        time.Time      `gorm:"not null"`
+	UpdatedAt        time.Time      `gorm:"not null"`
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+	IsDeleted        bool           `gorm:"boolean"`
+	UserId           []byte         `gorm:"uniqueIndex"`
+	Email            *string        `gorm:"uniqueIndex"`
+	Username         *string        `gorm:"uniqueIndex"`
+	DOB              *time.Time     `gorm:"type:timestamp"`
+	Password         *string
+	FirstName        string
+	LastName         string
+	IsEmailVerified  bool       `gorm:"type:boolean"`
+	EmailVerifiedAt  *time.Time `gorm:"type:timestamp"`
+	IsActive         bool       `gorm:"type:boolean"`
+	Mobile           *string    `gorm:"uniqueIndex"`
+	IsMobileVerified bool       `gorm:"type:boolean"`
+	AuthProvider     string
+}
+
+func (autoMigratedUser) TableName() string {
+	return "users"
+}
+
+func TestAdoptAutoMigratedSchema(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := db.AutoMigrate(&autoMigratedUser{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	email := "ada@example.com"
+	dob := time.Date(1815, time.December, 10, 0, 0, 0, 0, time.FixedZone("GMT+1", 3600))
+	if err := db.Create(&autoMigratedUser{Email: &email, DOB: &dob, FirstName: "Ada"}).Error; err != nil {
+		t.Fatalf("creating a user: %v", err)
+	}
+
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("migrating a database created by AutoMigrate: %v", err)
+	}
+
+	for _, column := range []string{"role", "avatar_key", "avatar_url", "avatar_thumbnail_url", "erasure_scheduled_at", "erased_at", "merged_into_id", "version"} {
+		if !db.Migrator().HasColumn("users", column) {
+			t.Errorf("got no %s column", column)
+		}
+	}
+	for _, index := range []string{"idx_users_erasure_scheduled_at", "idx_users_merged_into_id"} {
+		if !db.Migrator().HasIndex("users", index) {
+			t.Errorf("got no %s index", index)
+		}
+	}
+	var user struct {
+		Role    string
+		Dob     string
+		Version int
+	}
+	if err := db.Raw("SELECT role, CAST(dob AS text) AS dob, version FROM users WHERE email = ?", email).Scan(&user).Error; err != nil {
+		t.Fatalf("reading the user: %v", err)
+	}
+	// The date of birth keeps the day it was written with, whatever its time zone
+	if user.Role != "user" || user.Dob != "1815-12-10" || user.Version != 1 {
+		t.Errorf("got %+v, want role user, dob 1815-12-10 and version 1", user)
+	}
+	columnTypes, err := db.Migrator().ColumnTypes("users")
+	if err != nil {
+		t.Fatalf("ColumnTypes: %v", err)
+	}
+	for _, columnType := range columnTypes {
+		if columnType.Name() == "dob" && !strings.EqualFold(columnType.DatabaseTypeName(), "date") {
+			t.Errorf("got dob of type %s, want date", columnType.DatabaseTypeName())
+		}
+	}
+}
+
+func TestMigratorFailures(t *testing.T) {
+	db := openTestDatabase(t)
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("NewMigrator: %v", err)
+	}
+	irreversible := createTableMigration(1, "widgets")
+	irreversible.Down = nil
+	failing := Migration{
+		Version: 3,
+		Name:    "failing",
+		Up:      execSQL("CREATE TABLE gadgets (id INTEGER PRIMARY KEY);\nINSERT INTO missing VALUES (1);"),
+	}
+	migrator.migrations = []Migration{irreversible, createTableMigration(2, "parts"), failing}
+
+	applied, err := migrator.Up(0)
+	if err == nil || len(applied) != 2 {
+		t.Fatalf("got %d applied, %v, want the failing migration to stop after 2", len(applied), err)
+	}
+	// The failing migration is rolled back as a whole
+	if db.Migrator().HasTable("gadgets") {
+		t.Error("got the table of the failing migration")
+	}
+	if pending, _ := migrator.Pending(); len(pending) != 1 || pending[0].Version != 3 {
+		t.Errorf("got pending %v, want only the failing migration", pending)
+	}
+
+	rolledBack, err := migrator.Down(2)
+	if !errors.Is(err, ErrIrreversible) || len(rolledBack) != 1 {
+		t.Errorf("rolling back an irreversible migration: got %d, %v, want 1 and ErrIrreversible", len(rolledBack), err)
+	}
+
+	// A database migrated by a newer version of the application
+	migrator.migrations = nil
+	statuses, err := migrator.Status()
+	if err != nil || len(statuses) != 1 || !statuses[0].Missing || statuses[0].Version != 1 {
+		t.Errorf("got statuses %+v, %v, want the unknown migration 1", statuses, err)
+	}
+	if _, err := migrator.Down(1); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("rolling back an unknown migration: got %v", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	directory := t.TempDir()
+	if _, err := Create(directory, "Add Widgets!"); err == nil {
+		t.Error("creating a migration with an invalid name: got no error")
+	}
+
+	paths, err := Create(directory, " Add_Widgets ")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(paths) != 2*len(Dialects) {
+		t.Fatalf("got %d files, want an up and a down migration per dialect", len(paths))
+	}
+	for _, path := range paths {
+		match := sqlFileName.FindStringSubmatch(filepath.Base(path))
+		if match == nil || match[2] != "add_widgets" {
+			t.Errorf("got file %s, want a migration named add_widgets", path)
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || len(splitStatements(string(content))) != 0 {
+			t.Errorf("got %q, %v in %s, want only a comment", content, err, path)
+		}
+	}
+}
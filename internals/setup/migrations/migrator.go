@@ -0,0 +1,229 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// schemaMigration records a migration applied to the database.
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName keeps the table name stable whatever the naming strategy.
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status describes a migration and whether it was applied to the database.
+// Missing is set for a migration applied to the database that the application does not know,
+// usually because the database was migrated by a newer version of the application.
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Missing   bool
+}
+
+// Migrator applies and rolls back the migrations of the dialect of a database.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator creates a Migrator for the given database, loading the migrations of its dialect
+// and creating the table recording the applied migrations when it does not exist yet.
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	db = db.Session(&gorm.Session{NewDB: true})
+	migrations, err := load(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		if err := db.Migrator().CreateTable(&schemaMigration{}); err != nil {
+			return nil, err
+		}
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// applied returns the applied migrations by version.
+func (m *Migrator) applied() (map[int64]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := m.db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// Pending returns the migrations not applied yet, in the order they would be applied.
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies up to steps pending migrations, or all of them when steps is zero or less,
+// and returns the ones applied. Each migration runs in its own transaction along with its record,
+// so a failing migration leaves the database as it was before it. MySQL commits schema changes
+// implicitly though, so there a failing migration may have to be cleaned up by hand.
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	if steps > 0 && steps < len(pending) {
+		pending = pending[:steps]
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down rolls back up to steps of the latest applied migrations, latest first, or only the latest one
+// when steps is zero or less, and returns the ones rolled back. It stops with ErrIrreversible at a migration
+// without a down migration, and refuses to roll back a migration the application does not know.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	byVersion := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var rows []schemaMigration
+	if err := m.db.Order("version DESC").Limit(steps).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, row := range rows {
+		migration, ok := byVersion[row.Version]
+		if !ok {
+			return done, fmt.Errorf("migration %d_%s is unknown to this version of the application", row.Version, row.Name)
+		}
+		if migration.Down == nil {
+			return done, fmt.Errorf("%w: %d_%s", ErrIrreversible, migration.Version, migration.Name)
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, "version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("rolling back migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Status returns every known migration, and every applied migration the application does not know, by version.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	known := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	for version, row := range applied {
+		if !known[version] {
+			appliedAt := row.AppliedAt
+			statuses = append(statuses, Status{Version: version, Name: row.Name, AppliedAt: &appliedAt, Missing: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// CheckSchema returns ErrSchemaBehind when the database lacks migrations known to the application,
+// so the application stops at startup instead of failing on its first query.
+func CheckSchema(db *gorm.DB) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	pending, err := migrator.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migrations starting with %d_%s, run the migrate up command",
+			ErrSchemaBehind, len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// migrationName matches the names accepted for new migrations.
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Create writes empty up and down SQL migrations with the given name for every dialect
+// in the given directory, and returns the paths of the files written.
+func Create(directory string, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !migrationName.MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q, use lowercase letters, digits and underscores", name)
+	}
+
+	version := newVersion(time.Now())
+	var paths []string
+	for _, dialect := range Dialects {
+		if err := os.MkdirAll(filepath.Join(directory, dialect), 0o755); err != nil {
+			return paths, err
+		}
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(directory, dialect, fmt.Sprintf("%d_%s.%s.sql", version, name, direction))
+			content := fmt.Sprintf("-- %s %s migration for %s\n", strings.ReplaceAll(name, "_", " "), direction, dialect)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				return paths, err
+			}
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,140 @@
+-- Initial schema, matching the tables previously created by AutoMigrate.
+-- Tables are only created when missing, so databases created by AutoMigrate can adopt migrations, once
+-- the adopt_auto_migrated_schema migration brought their users table up to date.
+-- There is no down migration, as rolling back the initial schema would drop every table along with its data.
+-- Indexed text columns are limited to 191 characters, the longest a utf8mb4 index key can hold.
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigint unsigned AUTO_INCREMENT,
+    created_at datetime(3) NOT NULL,
+    updated_at datetime(3) NOT NULL,
+    deleted_at datetime(3) NULL,
+    is_deleted boolean,
+    user_id varbinary(16),
+    email varchar(191),
+    username varchar(191),
+    dob date,
+    password longtext,
+    first_name longtext,
+    last_name longtext,
+    is_email_verified boolean,
+    email_verified_at timestamp NULL,
+    is_active boolean,
+    mobile varchar(191),
+    is_mobile_verified boolean,
+    auth_provider longtext,
+    role varchar(191) NOT NULL DEFAULT 'user',
+    avatar_key longtext,
+    avatar_url longtext,
+    avatar_thumbnail_url longtext,
+    erasure_scheduled_at timestamp NULL,
+    erased_at timestamp NULL,
+    merged_into_id bigint unsigned,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_users_user_id (user_id),
+    UNIQUE INDEX idx_users_email (email),
+    UNIQUE INDEX idx_users_username (username),
+    UNIQUE INDEX idx_users_mobile (mobile),
+    INDEX idx_users_deleted_at (deleted_at),
+    INDEX idx_users_erasure_scheduled_at (erasure_scheduled_at),
+    INDEX idx_users_merged_into_id (merged_into_id)
+);
+
+CREATE TABLE IF NOT EXISTS pending_contact_changes (
+    id bigint unsigned AUTO_INCREMENT,
+    created_at datetime(3) NOT NULL,
+    updated_at datetime(3) NOT NULL,
+    user_id bigint unsigned NOT NULL,
+    kind varchar(191) NOT NULL,
+    value varchar(191) NOT NULL,
+    code_hash longtext NOT NULL,
+    attempts bigint NOT NULL DEFAULT 0,
+    expires_at datetime(3) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_pending_contact_user (user_id, kind),
+    UNIQUE INDEX idx_pending_contact_value (kind, value),
+    INDEX idx_pending_contact_changes_expires_at (expires_at),
+    CONSTRAINT fk_pending_contact_changes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    id bigint unsigned AUTO_INCREMENT,
+    created_at datetime(3) NOT NULL,
+    updated_at datetime(3) NOT NULL,
+    user_id bigint unsigned NOT NULL,
+    locale longtext NOT NULL,
+    timezone longtext NOT NULL,
+    notify_email boolean,
+    notify_sms boolean,
+    notify_push boolean,
+    marketing_consent boolean,
+    marketing_consent_at timestamp NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_user_settings_user_id (user_id),
+    CONSTRAINT fk_user_settings_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id bigint unsigned AUTO_INCREMENT,
+    created_at datetime(3) NOT NULL,
+    updated_at datetime(3) NOT NULL,
+    deleted_at datetime(3) NULL,
+    is_deleted boolean,
+    action varchar(191) NOT NULL,
+    actor_id varchar(191),
+    target_type varchar(191),
+    target_id varchar(191),
+    details longtext,
+    PRIMARY KEY (id),
+    INDEX idx_audit_logs_deleted_at (deleted_at),
+    INDEX idx_audit_logs_action (action),
+    INDEX idx_audit_logs_actor_id (actor_id),
+    INDEX idx_audit_target (target_type, target_id)
+);
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id bigint unsigned AUTO_INCREMENT,
+    created_at datetime(3) NOT NULL,
+    updated_at datetime(3) NOT NULL,
+    deleted_at datetime(3) NULL,
+    is_deleted boolean,
+    organization_id varbinary(16),
+    name longtext NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_organizations_organization_id (organization_id),
+    INDEX idx_organizations_deleted_at (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS memberships (
+    id bigint unsigned AUTO_INCREMENT,
+    created_at datetime(3) NOT NULL,
+    updated_at datetime(3) NOT NULL,
+    organization_id bigint unsigned NOT NULL,
+    user_id bigint unsigned NOT NULL,
+    role longtext NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_membership (organization_id, user_id),
+    INDEX idx_memberships_user_id (user_id),
+    CONSTRAINT fk_memberships_organization FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE,
+    CONSTRAINT fk_memberships_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS invitations (
+    id bigint unsigned AUTO_INCREMENT,
+    created_at datetime(3) NOT NULL,
+    updated_at datetime(3) NOT NULL,
+    invitation_id varbinary(16),
+    organization_id bigint unsigned NOT NULL,
+    email longtext,
+    mobile longtext,
+    role longtext NOT NULL,
+    token_hash varchar(191) NOT NULL,
+    invited_by_id bigint unsigned NOT NULL,
+    expires_at datetime(3) NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE INDEX idx_invitations_invitation_id (invitation_id),
+    UNIQUE INDEX idx_invitations_token_hash (token_hash),
+    INDEX idx_invitations_organization_id (organization_id),
+    INDEX idx_invitations_expires_at (expires_at),
+    CONSTRAINT fk_invitations_organization FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE
+);
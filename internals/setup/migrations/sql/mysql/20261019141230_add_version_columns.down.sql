@@ -0,0 +1,4 @@
+-- Drops the version columns of the models using optimistic locking.
+
+ALTER TABLE organizations DROP COLUMN version;
+ALTER TABLE users DROP COLUMN version;
@@ -0,0 +1,4 @@
+-- Version columns of the models using optimistic locking. Existing rows start at version 1.
+
+ALTER TABLE users ADD COLUMN version bigint unsigned NOT NULL DEFAULT 1;
+ALTER TABLE organizations ADD COLUMN version bigint unsigned NOT NULL DEFAULT 1;
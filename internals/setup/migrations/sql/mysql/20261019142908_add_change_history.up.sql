@@ -0,0 +1,16 @@
+-- Append-only history of the changes made to the models recording them, see history.Recorder.
+
+CREATE TABLE IF NOT EXISTS change_history (
+    id bigint unsigned AUTO_INCREMENT,
+    entity varchar(191) NOT NULL,
+    entity_id varchar(191) NOT NULL,
+    action varchar(191) NOT NULL,
+    actor_id varchar(191),
+    impersonator_id varchar(191),
+    request_id varchar(191),
+    changes longtext,
+    created_at datetime(3) NOT NULL,
+    PRIMARY KEY (id),
+    INDEX idx_change_history_entity (entity, entity_id),
+    INDEX idx_change_history_actor_id (actor_id)
+);
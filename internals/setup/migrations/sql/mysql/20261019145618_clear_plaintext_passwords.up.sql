@@ -0,0 +1,4 @@
+-- Clears the passwords stored before passwords were hashed. Sign-in is by one-time password,
+-- so clearing them only removes secrets that were kept in plain text.
+
+UPDATE users SET password = NULL WHERE password IS NOT NULL AND password NOT LIKE '$2_$%';
@@ -0,0 +1,3 @@
+-- Drops the full-text index of the user search, which then falls back to LIKE matching.
+
+DROP INDEX idx_users_search ON users;
@@ -0,0 +1,4 @@
+-- Full-text index of the user search. MATCH only uses an index over exactly the columns it lists,
+-- so the index must hold the columns given to WithSearch, in the same order.
+
+CREATE FULLTEXT INDEX idx_users_search ON users (first_name, last_name, username, email, mobile);
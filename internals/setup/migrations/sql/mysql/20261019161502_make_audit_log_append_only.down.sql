@@ -0,0 +1,5 @@
+-- Restores the soft delete and update columns of the audit log.
+
+ALTER TABLE audit_logs ADD COLUMN updated_at datetime(3) NULL, ADD COLUMN deleted_at datetime(3) NULL, ADD COLUMN is_deleted boolean;
+UPDATE audit_logs SET updated_at = created_at, is_deleted = false;
+ALTER TABLE audit_logs MODIFY updated_at datetime(3) NOT NULL, ADD INDEX idx_audit_logs_deleted_at (deleted_at);
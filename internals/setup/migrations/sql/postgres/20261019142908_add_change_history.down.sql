@@ -0,0 +1,3 @@
+-- Drops the history of the changes made to the models recording them.
+
+DROP TABLE IF EXISTS change_history;
@@ -0,0 +1,6 @@
+-- Full-text index of the user search. The indexed expression must stay the document searched by
+-- BaseRepository.Search for the columns given to WithSearch, or the index is not used.
+
+CREATE INDEX IF NOT EXISTS idx_users_search ON users USING GIN ((to_tsvector('simple', translate(
+    COALESCE(first_name, '') || ' ' || COALESCE(last_name, '') || ' ' || COALESCE(username, '') || ' ' || COALESCE(email, '') || ' ' || COALESCE(mobile, ''),
+    '@._-+', '     '))));
@@ -0,0 +1,6 @@
+-- Restores the soft delete and update columns of the audit log.
+
+ALTER TABLE audit_logs ADD COLUMN updated_at timestamptz, ADD COLUMN deleted_at timestamptz, ADD COLUMN is_deleted boolean;
+UPDATE audit_logs SET updated_at = created_at, is_deleted = false;
+ALTER TABLE audit_logs ALTER COLUMN updated_at SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_logs_deleted_at ON audit_logs (deleted_at);
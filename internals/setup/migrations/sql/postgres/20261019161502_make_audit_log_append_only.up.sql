@@ -0,0 +1,3 @@
+-- The audit log is append-only: its entries are never updated nor soft-deleted.
+
+ALTER TABLE audit_logs DROP COLUMN deleted_at, DROP COLUMN is_deleted, DROP COLUMN updated_at;
@@ -0,0 +1,132 @@
+-- Initial schema, matching the tables previously created by AutoMigrate.
+-- Tables and indexes are only created when missing, so databases created by AutoMigrate can adopt migrations,
+-- once the adopt_auto_migrated_schema migration brought their users table up to date.
+-- There is no down migration, as rolling back the initial schema would drop every table along with its data.
+
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime,
+    is_deleted boolean,
+    user_id blob,
+    email text,
+    username text,
+    dob date,
+    password text,
+    first_name text,
+    last_name text,
+    is_email_verified boolean,
+    email_verified_at timestamp,
+    is_active boolean,
+    mobile text,
+    is_mobile_verified boolean,
+    auth_provider text,
+    role text NOT NULL DEFAULT 'user',
+    avatar_key text,
+    avatar_url text,
+    avatar_thumbnail_url text,
+    erasure_scheduled_at timestamp,
+    erased_at timestamp,
+    merged_into_id integer
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_user_id ON users (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users (username);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_mobile ON users (mobile);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_erasure_scheduled_at ON users (erasure_scheduled_at);
+CREATE INDEX IF NOT EXISTS idx_users_merged_into_id ON users (merged_into_id);
+
+CREATE TABLE IF NOT EXISTS pending_contact_changes (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    user_id integer NOT NULL,
+    kind text NOT NULL,
+    value text NOT NULL,
+    code_hash text NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    expires_at datetime NOT NULL,
+    CONSTRAINT fk_pending_contact_changes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pending_contact_user ON pending_contact_changes (user_id, kind);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pending_contact_value ON pending_contact_changes (kind, value);
+CREATE INDEX IF NOT EXISTS idx_pending_contact_changes_expires_at ON pending_contact_changes (expires_at);
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    user_id integer NOT NULL,
+    locale text NOT NULL,
+    timezone text NOT NULL,
+    notify_email boolean,
+    notify_sms boolean,
+    notify_push boolean,
+    marketing_consent boolean,
+    marketing_consent_at timestamp,
+    CONSTRAINT fk_user_settings_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_settings_user_id ON user_settings (user_id);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime,
+    is_deleted boolean,
+    action text NOT NULL,
+    actor_id text,
+    target_type text,
+    target_id text,
+    details text
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_deleted_at ON audit_logs (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_target ON audit_logs (target_type, target_id);
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    deleted_at datetime,
+    is_deleted boolean,
+    organization_id blob,
+    name text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_organizations_organization_id ON organizations (organization_id);
+CREATE INDEX IF NOT EXISTS idx_organizations_deleted_at ON organizations (deleted_at);
+
+CREATE TABLE IF NOT EXISTS memberships (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    organization_id integer NOT NULL,
+    user_id integer NOT NULL,
+    role text NOT NULL,
+    CONSTRAINT fk_memberships_organization FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE,
+    CONSTRAINT fk_memberships_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_membership ON memberships (organization_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_memberships_user_id ON memberships (user_id);
+
+CREATE TABLE IF NOT EXISTS invitations (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    created_at datetime NOT NULL,
+    updated_at datetime NOT NULL,
+    invitation_id blob,
+    organization_id integer NOT NULL,
+    email text,
+    mobile text,
+    role text NOT NULL,
+    token_hash text NOT NULL,
+    invited_by_id integer NOT NULL,
+    expires_at datetime NOT NULL,
+    CONSTRAINT fk_invitations_organization FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invitations_invitation_id ON invitations (invitation_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invitations_token_hash ON invitations (token_hash);
+CREATE INDEX IF NOT EXISTS idx_invitations_organization_id ON invitations (organization_id);
+CREATE INDEX IF NOT EXISTS idx_invitations_expires_at ON invitations (expires_at);
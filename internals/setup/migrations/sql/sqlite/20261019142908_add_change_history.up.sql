@@ -0,0 +1,15 @@
+-- Append-only history of the changes made to the models recording them, see history.Recorder.
+
+CREATE TABLE IF NOT EXISTS change_history (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    entity text NOT NULL,
+    entity_id text NOT NULL,
+    action text NOT NULL,
+    actor_id text,
+    impersonator_id text,
+    request_id text,
+    changes text,
+    created_at datetime NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_change_history_entity ON change_history (entity, entity_id);
+CREATE INDEX IF NOT EXISTS idx_change_history_actor_id ON change_history (actor_id);
@@ -0,0 +1 @@
+-- Cleared passwords cannot be restored, so there is nothing to undo.
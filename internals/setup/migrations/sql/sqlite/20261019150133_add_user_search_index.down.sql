@@ -0,0 +1,6 @@
+-- Drops the FTS5 table of the user search and its triggers, after which the search falls back to LIKE matching.
+
+DROP TRIGGER IF EXISTS users_search_after_insert;
+DROP TRIGGER IF EXISTS users_search_after_update;
+DROP TRIGGER IF EXISTS users_search_after_delete;
+DROP TABLE IF EXISTS users_search;
@@ -0,0 +1,15 @@
+-- FTS5 table of the user search over the columns given to WithSearch, kept in sync with users by triggers.
+-- An FTS5 table with external content is told the indexed values of a row to remove it, so the triggers
+-- pass the old values along with the 'delete' command. Statements of a trigger share a line, as the migrator
+-- splits statements at the end of lines. SQLite must be built with FTS5, see the README.
+
+CREATE VIRTUAL TABLE users_search USING fts5(first_name, last_name, username, email, mobile, content='users', content_rowid='id', tokenize='unicode61');
+
+CREATE TRIGGER users_search_after_insert AFTER INSERT ON users BEGIN
+    INSERT INTO users_search(rowid, first_name, last_name, username, email, mobile) VALUES (new.id, new.first_name, new.last_name, new.username, new.email, new.mobile); END;
+CREATE TRIGGER users_search_after_update AFTER UPDATE ON users BEGIN
+    INSERT INTO users_search(users_search, rowid, first_name, last_name, username, email, mobile) VALUES ('delete', old.id, old.first_name, old.last_name, old.username, old.email, old.mobile); INSERT INTO users_search(rowid, first_name, last_name, username, email, mobile) VALUES (new.id, new.first_name, new.last_name, new.username, new.email, new.mobile); END;
+CREATE TRIGGER users_search_after_delete AFTER DELETE ON users BEGIN
+    INSERT INTO users_search(users_search, rowid, first_name, last_name, username, email, mobile) VALUES ('delete', old.id, old.first_name, old.last_name, old.username, old.email, old.mobile); END;
+
+INSERT INTO users_search(users_search) VALUES ('rebuild');
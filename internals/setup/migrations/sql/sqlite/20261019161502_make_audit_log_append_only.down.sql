@@ -0,0 +1,7 @@
+-- Restores the soft delete and update columns of the audit log.
+
+ALTER TABLE audit_logs ADD COLUMN updated_at datetime;
+ALTER TABLE audit_logs ADD COLUMN deleted_at datetime;
+ALTER TABLE audit_logs ADD COLUMN is_deleted boolean;
+UPDATE audit_logs SET updated_at = created_at, is_deleted = false;
+CREATE INDEX IF NOT EXISTS idx_audit_logs_deleted_at ON audit_logs (deleted_at);
@@ -0,0 +1,6 @@
+-- The audit log is append-only: its entries are never updated nor soft-deleted.
+
+DROP INDEX IF EXISTS idx_audit_logs_deleted_at;
+ALTER TABLE audit_logs DROP COLUMN deleted_at;
+ALTER TABLE audit_logs DROP COLUMN is_deleted;
+ALTER TABLE audit_logs DROP COLUMN updated_at;
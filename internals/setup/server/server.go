@@ -2,11 +2,16 @@ package server
 
 import (
 	"backendService/internals/common/logger"
+	"backendService/internals/common/request"
 	"backendService/internals/setup/config"
 	"backendService/internals/setup/database"
+	"context"
+	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/oklog/ulid/v2"
 )
 
 var (
@@ -25,6 +30,7 @@ func NewServer(config *config.AppConfig, db *database.Database) *server {
 	gin.SetMode(config.App.GinMode)
 
 	app := gin.Default()
+	app.Use(requestID(), requestTimeout(database.QueryTimeout()))
 
 	Server = &server{
 		Config: config,
@@ -34,6 +40,37 @@ func NewServer(config *config.AppConfig, db *database.Database) *server {
 	return Server
 }
 
+// requestIDHeader is the header carrying the ID of a request, set on every response.
+const requestIDHeader = "X-Request-Id"
+
+// validRequestID matches the request IDs accepted from clients and proxies, keeping them safe to log and store.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestID gives every request an ID, stored in its context and echoed in the X-Request-Id response header,
+// so that logs and recorded changes can be traced back to it. An ID set by the client or a proxy is kept.
+func requestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = ulid.Make().String()
+		}
+		c.Header(requestIDHeader, id)
+		c.Request = c.Request.WithContext(request.WithID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// requestTimeout sets a deadline on the context of every request, so that the database and cache work
+// done for it is cancelled once the deadline passes. The context is also cancelled when the client goes away.
+func requestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
 func (s *server) Start() {
 	address := ":" + strconv.Itoa(config.Config.App.Port)
 	logger.Info("server", "Start", "Listening on", address)
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"backendService/internals/common/request"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	app.Use(requestTimeout(time.Minute))
+	var deadline time.Time
+	var hasDeadline bool
+	app.GET("/", func(c *gin.Context) {
+		deadline, hasDeadline = c.Request.Context().Deadline()
+		c.Status(http.StatusNoContent)
+	})
+
+	start := time.Now()
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !hasDeadline {
+		t.Fatal("got no deadline on the request context")
+	}
+	if remaining := deadline.Sub(start); remaining < 59*time.Second || remaining > time.Minute+time.Second {
+		t.Errorf("got a deadline %v after the request started, want a minute", remaining)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	app.Use(requestID())
+	var contextID string
+	app.GET("/", func(c *gin.Context) {
+		contextID = request.ID(c.Request.Context())
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"valid", "trace-1.a_B", true},
+		{"missing", "", false},
+		{"unsafe characters", "id\nwith newline", false},
+		{"too long", strings.Repeat("a", 65), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(requestIDHeader, tt.header)
+			}
+			recorder := httptest.NewRecorder()
+			app.ServeHTTP(recorder, req)
+
+			id := recorder.Header().Get(requestIDHeader)
+			if id != contextID {
+				t.Errorf("got %q in the response header and %q in the context, want the same ID", id, contextID)
+			}
+			if tt.keep && id != tt.header {
+				t.Errorf("got %q, want %q kept", id, tt.header)
+			}
+			if !tt.keep {
+				if _, err := ulid.ParseStrict(id); err != nil {
+					t.Errorf("got %q, want a generated ULID", id)
+				}
+			}
+		})
+	}
+}